@@ -0,0 +1,277 @@
+package sumologic
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// archiveConfig holds the settings for archiving events to an S3-compatible
+// bucket.
+type archiveConfig struct {
+	endPoint  string
+	bucket    string
+	prefix    string
+	region    string
+	accessKey string
+	secretKey string
+	flushMs   int64
+	maxBytes  int64
+	only      bool
+}
+
+// archiveMaxPending is how many batches' worth of events the archive holds on
+// to while uploads are failing, before it stops taking new ones.
+const archiveMaxPending = 10
+
+// archiver collects events as newline-delimited json and periodically
+// uploads them, gzipped, to an S3-compatible bucket. A batch is kept until
+// it's been uploaded, so one that fails is retried with the next flush. A
+// nil *archiver doesn't archive anything.
+type archiver struct {
+	mu     sync.Mutex
+	config archiveConfig
+	buf    bytes.Buffer
+	full   chan struct{}
+	// failing is set while uploads are failing, so that they're only
+	// retried on the flush interval rather than each time the batch fills.
+	failing bool
+	// flushing is held for the whole of a flush, so that shutdown's final
+	// flush can't upload the same batch as one the watcher is running.
+	flushing sync.Mutex
+}
+
+// newArchiver returns an archiver for the given config, or nil if no bucket
+// is configured.
+func newArchiver(config archiveConfig) *archiver {
+	if config.bucket == "" {
+		return nil
+	}
+	return &archiver{config: config, full: make(chan struct{}, 1)}
+}
+
+// add appends an event to the current archive batch. If the batch has grown
+// past its maximum size, a flush is requested.
+func (a *archiver) add(data *Data) {
+	if a == nil {
+		return
+	}
+	line, err := json.Marshal(data)
+	if err != nil {
+		log.WithError(err).Error("Unable to build json data, skipping archive")
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.failing && int64(a.buf.Len()) >= archiveMaxPending*a.config.maxBytes {
+		log.Warn("Archive uploads are failing and the archive is full, " +
+			"not archiving event")
+		return
+	}
+	a.buf.Write(line)
+	a.buf.WriteByte('\n')
+	if int64(a.buf.Len()) >= a.config.maxBytes && !a.failing {
+		select {
+		case a.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// take returns the current batch, gzipped, along with its size before
+// compression, which should be passed to uploaded once it's been uploaded.
+// Events added in the meantime go into the next batch. It returns nil if the
+// batch is empty.
+func (a *archiver) take() ([]byte, int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.buf.Len() == 0 {
+		return nil, 0, nil
+	}
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	if _, err := zw.Write(a.buf.Bytes()); err != nil {
+		return nil, 0, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, 0, err
+	}
+	return compressed.Bytes(), a.buf.Len(), nil
+}
+
+// uploaded discards a batch of the given size returned by take, now that it's
+// been uploaded.
+func (a *archiver) uploaded(size int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.buf.Next(size)
+	a.failing = false
+}
+
+// failed records that a batch couldn't be uploaded, leaving it to be retried
+// with the next flush.
+func (a *archiver) failed() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.failing = true
+}
+
+// key returns the object key for a batch uploaded at the given time. Keys are
+// partitioned by date and hour so that they can be expired or queried by
+// time range.
+func (a *archiver) key(now time.Time, routeID string) string {
+	now = now.UTC()
+	name := fmt.Sprintf("%s/%d-%s.ndjson.gz",
+		now.Format("2006/01/02/15"), now.UnixNano(), routeID)
+	if a.config.prefix == "" {
+		return name
+	}
+	return strings.Trim(a.config.prefix, "/") + "/" + name
+}
+
+// watchArchive flushes the archive whenever the flush interval passes or the
+// batch fills up, until the adapter is closed.
+func (s *Adapter) watchArchive() {
+	for {
+		timer := s.clock.NewTimer(
+			time.Duration(s.archive.config.flushMs) * time.Millisecond)
+		select {
+		case <-timer.C():
+		case <-s.archive.full:
+			timer.Stop()
+		case <-s.ctx.Done():
+			timer.Stop()
+			return
+		}
+		s.flushArchive()
+	}
+}
+
+// flushArchive uploads the current archive batch, keeping it to retry with
+// the next flush if the upload fails. Flushes run one at a time.
+func (s *Adapter) flushArchive() {
+	defer s.recoverPanic("flushArchive")
+	s.archive.flushing.Lock()
+	defer s.archive.flushing.Unlock()
+
+	body, size, err := s.archive.take()
+	if err != nil {
+		log.WithError(err).Error("Unable to compress archive batch")
+		return
+	}
+	if body == nil {
+		return
+	}
+	if err = s.uploadArchive(body); err != nil {
+		log.WithError(err).Error("Failed to upload archive batch, keeping it")
+		s.archive.failed()
+		return
+	}
+	s.archive.uploaded(size)
+}
+
+// uploadArchive puts a gzipped archive batch in the bucket.
+func (s *Adapter) uploadArchive(body []byte) error {
+	req, err := s.archive.request(
+		s.archive.key(s.clock.Now(), s.route.ID), body, s.clock.Now())
+	if err != nil {
+		return err
+	}
+	resp, err := s.currentClient().Do(req.WithContext(s.ctx))
+	if err != nil {
+		return err
+	}
+	defer closeBody(resp)
+	if _, err = ioutil.ReadAll(resp.Body); err != nil {
+		log.WithError(err).Error("Unable to read response body.")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return &statusError{resp.StatusCode}
+	}
+	return nil
+}
+
+// request builds a signed S3 PutObject request for the given key, using
+// path-style addressing so that it works with MinIO and friends as well.
+func (a *archiver) request(
+	key string, body []byte, now time.Time) (*http.Request, error) {
+	u, err := url.Parse(strings.TrimRight(a.config.endPoint, "/"))
+	if err != nil {
+		return nil, err
+	}
+	u.Path += "/" + a.config.bucket + "/" + key
+	req, err := http.NewRequest(http.MethodPut, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	req.Header.Set("Content-Encoding", "gzip")
+	signV4(req, body, a.config.region, a.config.accessKey, a.config.secretKey,
+		now)
+	return req, nil
+}
+
+// signV4 adds AWS Signature Version 4 headers for the S3 service to a
+// request.
+func signV4(req *http.Request, body []byte, region string,
+	accessKey string, secretKey string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		"host:" + req.URL.Host,
+		"x-amz-content-sha256:" + payloadHash,
+		"x-amz-date:" + amzDate,
+		"",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, signature))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
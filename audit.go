@@ -0,0 +1,184 @@
+package sumologic
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// The outcomes an audit record can show for a request.
+const (
+	auditSent     = "sent"
+	auditBuffered = "buffered"
+	auditFailed   = "failed"
+)
+
+// auditLog writes a line of json to a local file for every request sent to
+// Sumologic, saying which containers' events it held and whether it was
+// delivered, so that there's a record of what was shipped and when. Once the
+// file reaches its maximum size it's rotated, keeping a number of old files
+// alongside it as <file>.1, <file>.2 and so on. A nil *auditLog doesn't
+// record anything.
+type auditLog struct {
+	mu       sync.Mutex
+	path     string
+	maxBytes int64
+	maxFiles int64
+	file     *os.File
+	size     int64
+}
+
+// auditRecord is a single line of the audit log.
+type auditRecord struct {
+	Time       string           `json:"time"`
+	Containers map[string]int64 `json:"containers"`
+	Events     int64            `json:"events"`
+	Bytes      int64            `json:"bytes"`
+	Category   string           `json:"category,omitempty"`
+	Status     string           `json:"status"`
+	Error      string           `json:"error,omitempty"`
+}
+
+// newAuditLog returns an audit log appending to the file at path, rotated
+// once it reaches maxMB megabytes, or nil if no path is configured or the
+// file can't be opened.
+func newAuditLog(path string, maxMB int64, maxFiles int64) *auditLog {
+	if path == "" {
+		return nil
+	}
+	a := &auditLog{path: path, maxBytes: maxMB * 1024 * 1024, maxFiles: maxFiles}
+	if err := a.open(); err != nil {
+		log.WithError(err).WithField("file", path).Error(
+			"Unable to open audit log, not auditing deliveries")
+		return nil
+	}
+	return a
+}
+
+// open opens the audit file for appending, creating it if needed.
+func (a *auditLog) open() error {
+	if err := os.MkdirAll(filepath.Dir(a.path), 0700); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(a.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	a.file = file
+	a.size = info.Size()
+	return nil
+}
+
+// record writes the outcome of a request holding the given number of events
+// from each container.
+func (a *auditLog) record(now time.Time, containers map[string]int64,
+	bytes int64, category string, status string, err error) {
+	if a == nil {
+		return
+	}
+	record := &auditRecord{
+		Time:       now.UTC().Format(time.RFC3339Nano),
+		Containers: containers,
+		Events:     countEvents(containers),
+		Bytes:      bytes,
+		Category:   category,
+		Status:     status,
+	}
+	if err != nil {
+		record.Error = maskError(err).Error()
+	}
+	line, jsonErr := json.Marshal(record)
+	if jsonErr != nil {
+		log.WithError(jsonErr).Error("Unable to build audit record")
+		return
+	}
+	line = append(line, '\n')
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.file == nil {
+		return
+	}
+	if a.size > 0 && a.size+int64(len(line)) > a.maxBytes {
+		a.rotate()
+		if a.file == nil {
+			return
+		}
+	}
+	n, writeErr := a.file.Write(line)
+	a.size += int64(n)
+	if writeErr != nil {
+		log.WithError(writeErr).Error("Unable to write audit record")
+	}
+}
+
+// rotate moves the current file aside and starts a new one, dropping the
+// oldest file if there are already maxFiles of them. It must be called with
+// the lock held.
+func (a *auditLog) rotate() {
+	a.file.Close()
+	a.file = nil
+	for i := a.maxFiles - 1; i >= 1; i-- {
+		from := fmt.Sprintf("%s.%d", a.path, i)
+		if err := os.Rename(from, fmt.Sprintf("%s.%d", a.path, i+1)); err != nil &&
+			!os.IsNotExist(err) {
+			log.WithError(err).WithField("file", from).Error(
+				"Unable to rotate audit log")
+		}
+	}
+	var err error
+	if a.maxFiles > 0 {
+		err = os.Rename(a.path, a.path+".1")
+	} else {
+		err = os.Remove(a.path)
+	}
+	if err != nil {
+		log.WithError(err).Error("Unable to rotate audit log")
+	}
+	if err = a.open(); err != nil {
+		log.WithError(err).Error("Unable to reopen audit log, not auditing deliveries")
+	}
+}
+
+// close closes the audit file.
+func (a *auditLog) close() {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.file != nil {
+		a.file.Close()
+		a.file = nil
+	}
+}
+
+// auditStatus describes the outcome of a delivery for the audit log.
+func (s *Adapter) auditStatus(err error) string {
+	switch {
+	case err == nil:
+		return auditSent
+	case spoolable(err) && s.spool != nil:
+		return auditBuffered
+	}
+	return auditFailed
+}
+
+// countEvents adds up the events from each container.
+func countEvents(containers map[string]int64) int64 {
+	var events int64
+	for _, count := range containers {
+		events += count
+	}
+	return events
+}
@@ -0,0 +1,83 @@
+package sumologic
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+// auditDir returns a temporary directory for an audit log.
+func (ts *TestSuite) auditDir() string {
+	dir := ts.WithoutError(ioutil.TempDir("", "audit")).(string)
+	ts.AddCleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+// audited returns the records in an audit file.
+func (ts *TestSuite) audited(path string) []auditRecord {
+	data := ts.WithoutError(ioutil.ReadFile(path)).([]byte)
+	records := []auditRecord{}
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if line == "" {
+			continue
+		}
+		record := auditRecord{}
+		ts.NoError(json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func (ts *TestSuite) Test_audit_disabled_by_default() {
+	adapter := ts.mkAdapter(&router.Route{Address: "https://example.com/"})
+	ts.Nil(adapter.audit)
+}
+
+func (ts *TestSuite) Test_audit_records_each_request() {
+	path := filepath.Join(ts.auditDir(), "logs", "audit.log")
+	ts.Setenv("SUMOLOGIC_AUDIT_FILE", path)
+	ts.Setenv("SUMOLOGIC_SOURCE_CATEGORY", "prod/app")
+	requests := make(chan *RequestData, 1)
+	adapter := ts.FakeSumo(requests)
+
+	ts.NoError(adapter.Send(mkContainerMessage("abc", "/foo")))
+	<-requests
+	adapter.Close()
+
+	records := ts.audited(path)
+	ts.Len(records, 1)
+	ts.Equal(map[string]int64{"abc": 1}, records[0].Containers)
+	ts.EqualValues(1, records[0].Events)
+	ts.NotZero(records[0].Bytes)
+	ts.Equal("prod/app", records[0].Category)
+	ts.Equal(auditSent, records[0].Status)
+	ts.Empty(records[0].Error)
+	ts.NotEmpty(records[0].Time)
+}
+
+func (ts *TestSuite) Test_auditLog_rotates() {
+	dir := ts.auditDir()
+	path := filepath.Join(dir, "audit.log")
+	a := newAuditLog(path, 1, 2)
+	ts.AddCleanup(a.close)
+	// Make room for a single record in each file.
+	a.maxBytes = 100
+	for _, id := range []string{"one", "two", "three", "four"} {
+		a.record(mkTime(0), map[string]int64{id: 1}, 10, "", auditFailed,
+			errors.New("boom"))
+	}
+
+	ts.Equal(map[string]int64{"four": 1}, ts.audited(path)[0].Containers)
+	ts.Equal(map[string]int64{"three": 1}, ts.audited(path + ".1")[0].Containers)
+	ts.Equal(map[string]int64{"two": 1}, ts.audited(path + ".2")[0].Containers)
+	names := []string{}
+	for _, info := range ts.WithoutError(ioutil.ReadDir(dir)).([]os.FileInfo) {
+		names = append(names, info.Name())
+	}
+	ts.Equal([]string{"audit.log", "audit.log.1", "audit.log.2"}, names)
+}
@@ -0,0 +1,138 @@
+package sumologic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// awsMetadataTimeout bounds how long startup waits for each request to the
+// EC2 and ECS metadata endpoints, which don't answer at all off AWS.
+const awsMetadataTimeout = 2 * time.Second
+
+// ecsMetadataEnv is the variable the ECS agent sets to the task metadata
+// endpoint of each container it runs.
+const ecsMetadataEnv = "ECS_CONTAINER_METADATA_URI_V4"
+
+// AWSData holds the EC2 instance and ECS task logspout is running on, sent
+// with each log in SUMOLOGIC_AWS_METADATA mode.
+type AWSData struct {
+	InstanceID       string `json:"aws_instance_id,omitempty"`
+	AvailabilityZone string `json:"aws_availability_zone,omitempty"`
+	Region           string `json:"aws_region,omitempty"`
+	Cluster          string `json:"aws_cluster,omitempty"`
+	TaskARN          string `json:"aws_task_arn,omitempty"`
+}
+
+// awsMetadata looks up the EC2 instance and ECS task logspout is running on.
+// Whatever can't be looked up is left out and logged; it returns nil if
+// nothing could be.
+func awsMetadata(ctx context.Context, config *Config) *AWSData {
+	if !config.awsMetadata {
+		return nil
+	}
+	// Each lookup gets its own deadline, so that the instance metadata
+	// service not answering, as on Fargate, doesn't use up the task's.
+	client := &http.Client{Timeout: awsMetadataTimeout}
+	data := &AWSData{}
+	if err := data.fetchEC2(ctx, client, config.awsIMDSEndpoint); err != nil {
+		log.WithError(err).Warn("Unable to look up EC2 instance metadata")
+	}
+	if endpoint := os.Getenv(ecsMetadataEnv); endpoint != "" {
+		if err := data.fetchECS(ctx, client, endpoint); err != nil {
+			log.WithError(err).Warn("Unable to look up ECS task metadata")
+		}
+	}
+	if *data == (AWSData{}) {
+		return nil
+	}
+	return data
+}
+
+// fetchEC2 looks up the instance from the instance metadata service, using
+// an IMDSv2 session token.
+func (d *AWSData) fetchEC2(
+	ctx context.Context, client *http.Client, endpoint string) error {
+	endpoint = strings.TrimRight(endpoint, "/")
+	token, err := awsGet(ctx, client, http.MethodPut,
+		endpoint+"/latest/api/token",
+		http.Header{"X-Aws-Ec2-Metadata-Token-Ttl-Seconds": {"60"}})
+	if err != nil {
+		return err
+	}
+	headers := http.Header{"X-Aws-Ec2-Metadata-Token": {token}}
+	for path, field := range map[string]*string{
+		"instance-id":                 &d.InstanceID,
+		"placement/availability-zone": &d.AvailabilityZone,
+		"placement/region":            &d.Region,
+	} {
+		value, err := awsGet(ctx, client, http.MethodGet,
+			endpoint+"/latest/meta-data/"+path, headers)
+		if err != nil {
+			return err
+		}
+		*field = value
+	}
+	return nil
+}
+
+// fetchECS looks up the task from the ECS task metadata endpoint. The
+// cluster may be a name or an ARN, depending on the agent's version.
+func (d *AWSData) fetchECS(
+	ctx context.Context, client *http.Client, endpoint string) error {
+	body, err := awsGet(ctx, client, http.MethodGet,
+		strings.TrimRight(endpoint, "/")+"/task", nil)
+	if err != nil {
+		return err
+	}
+	var task struct {
+		Cluster          string
+		TaskARN          string
+		AvailabilityZone string
+	}
+	if err := json.Unmarshal([]byte(body), &task); err != nil {
+		return err
+	}
+	d.Cluster = task.Cluster
+	d.TaskARN = task.TaskARN
+	if d.AvailabilityZone == "" {
+		d.AvailabilityZone = task.AvailabilityZone
+	}
+	if d.Region == "" && d.AvailabilityZone != "" {
+		// Availability zones are the region plus a letter, e.g. eu-west-1a.
+		d.Region = d.AvailabilityZone[:len(d.AvailabilityZone)-1]
+	}
+	return nil
+}
+
+// awsGet makes a request to a metadata endpoint and returns the body.
+func awsGet(ctx context.Context, client *http.Client, method string,
+	url string, headers http.Header) (string, error) {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return "", err
+	}
+	for key, values := range headers {
+		req.Header[key] = values
+	}
+	res, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return "", err
+	}
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s %s: %s", method, url, res.Status)
+	}
+	return strings.TrimSpace(string(body)), nil
+}
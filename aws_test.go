@@ -0,0 +1,117 @@
+package sumologic
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+// FakeIMDS starts a fake EC2 instance metadata service and returns its URL.
+// It only answers requests with a session token.
+func (ts *TestSuite) FakeIMDS() string {
+	return ts.serve(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/latest/api/token" && r.Method == http.MethodPut {
+				w.Write([]byte("tok"))
+				return
+			}
+			if r.Header.Get("X-Aws-Ec2-Metadata-Token") != "tok" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			value, ok := map[string]string{
+				"/latest/meta-data/instance-id":                 "i-0abc",
+				"/latest/meta-data/placement/availability-zone": "eu-west-1a",
+				"/latest/meta-data/placement/region":            "eu-west-1",
+			}[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write([]byte(value))
+		}))
+}
+
+// FakeECSMetadata starts a fake ECS task metadata endpoint and points
+// ECS_CONTAINER_METADATA_URI_V4 at it.
+func (ts *TestSuite) FakeECSMetadata() {
+	url := ts.serve(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			ts.Equal("/v4/abc/task", r.URL.Path)
+			w.Write([]byte(`{"Cluster": "shop", "TaskARN": ` +
+				`"arn:aws:ecs:eu-west-2:123:task/shop/f00", ` +
+				`"AvailabilityZone": "eu-west-2b"}`))
+		}))
+	ts.Setenv(ecsMetadataEnv, url+"/v4/abc")
+}
+
+func (ts *TestSuite) Test_awsMetadata_disabled() {
+	ts.Nil(awsMetadata(context.Background(), buildConfig(&router.Route{})))
+}
+
+func (ts *TestSuite) Test_awsMetadata_ec2() {
+	ts.Setenv("SUMOLOGIC_AWS_METADATA", "true")
+	ts.Setenv("SUMOLOGIC_AWS_IMDS_ENDPOINT", ts.FakeIMDS())
+	ts.Setenv(ecsMetadataEnv, "")
+	ts.Equal(&AWSData{
+		InstanceID:       "i-0abc",
+		AvailabilityZone: "eu-west-1a",
+		Region:           "eu-west-1",
+	}, awsMetadata(context.Background(), buildConfig(&router.Route{})))
+}
+
+func (ts *TestSuite) Test_awsMetadata_ecs_without_ec2() {
+	ts.Setenv("SUMOLOGIC_AWS_METADATA", "true")
+	ts.Setenv("SUMOLOGIC_AWS_IMDS_ENDPOINT", noServer)
+	ts.FakeECSMetadata()
+	hook, _ := ts.CaptureLogs()
+	ts.Equal(&AWSData{
+		AvailabilityZone: "eu-west-2b",
+		Region:           "eu-west-2",
+		Cluster:          "shop",
+		TaskARN:          "arn:aws:ecs:eu-west-2:123:task/shop/f00",
+	}, awsMetadata(context.Background(), buildConfig(&router.Route{})))
+	var messages []string
+	for _, entry := range hook.AllEntries() {
+		messages = append(messages, entry.Message)
+	}
+	ts.Contains(messages, "Unable to look up EC2 instance metadata")
+}
+
+func (ts *TestSuite) Test_awsMetadata_ec2_and_ecs() {
+	ts.Setenv("SUMOLOGIC_AWS_METADATA", "true")
+	ts.Setenv("SUMOLOGIC_AWS_IMDS_ENDPOINT", ts.FakeIMDS())
+	ts.FakeECSMetadata()
+	// The instance's own placement wins over the task's.
+	ts.Equal(&AWSData{
+		InstanceID:       "i-0abc",
+		AvailabilityZone: "eu-west-1a",
+		Region:           "eu-west-1",
+		Cluster:          "shop",
+		TaskARN:          "arn:aws:ecs:eu-west-2:123:task/shop/f00",
+	}, awsMetadata(context.Background(), buildConfig(&router.Route{})))
+}
+
+func (ts *TestSuite) Test_awsMetadata_unavailable() {
+	ts.Setenv("SUMOLOGIC_AWS_METADATA", "true")
+	ts.Setenv("SUMOLOGIC_AWS_IMDS_ENDPOINT", noServer)
+	ts.Setenv(ecsMetadataEnv, "")
+	ts.CaptureLogs()
+	ts.Nil(awsMetadata(context.Background(), buildConfig(&router.Route{})))
+}
+
+func (ts *TestSuite) Test_sendLog_aws_fields() {
+	ts.Setenv("SUMOLOGIC_AWS_METADATA", "true")
+	ts.Setenv("SUMOLOGIC_AWS_IMDS_ENDPOINT", ts.FakeIMDS())
+	ts.Setenv(ecsMetadataEnv, "")
+	bodies := make(chan string, 1)
+	adapter := ts.FakeSumoRaw(bodies)
+	adapter.sendLog(mkContainerMessage("abc", "/foo"))
+	var body jsonobj
+	ts.NoError(json.Unmarshal([]byte(<-bodies), &body))
+	ts.Equal("i-0abc", body["aws_instance_id"])
+	ts.Equal("eu-west-1", body["aws_region"])
+	ts.NotContains(body, "aws_cluster")
+}
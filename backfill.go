@@ -0,0 +1,79 @@
+package sumologic
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+)
+
+// backfillFields is sent as X-Sumo-Fields with backfilled events, so that
+// they can be told apart from live ones.
+const backfillFields = "backfill=true"
+
+// Backfill replays events from an archive batch (newline-delimited json,
+// optionally gzipped, as written to the archive bucket) to Sumologic. Each
+// event keeps its original timestamp, and is marked as backfilled both in
+// its json and with an X-Sumo-Fields header, so that it lands at the time it
+// was logged rather than as a spike at replay time. Events are sent one at a
+// time, in order; Backfill stops at the first one that can't be sent and
+// returns how many were.
+func (s *Adapter) Backfill(r io.Reader) (int, error) {
+	reader := bufio.NewReader(r)
+	if magic, _ := reader.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		zr, err := gzip.NewReader(reader)
+		if err != nil {
+			return 0, err
+		}
+		defer zr.Close()
+		reader = bufio.NewReader(zr)
+	}
+
+	config := s.config()
+	decoder := json.NewDecoder(reader)
+	sent := 0
+	for {
+		data := &Data{}
+		err := decoder.Decode(data)
+		if err == io.EOF {
+			return sent, nil
+		}
+		if err != nil {
+			return sent, err
+		}
+		data.Backfill = true
+		headers := buildHeaders(backfillMessage(data), config)
+		headers.Set("X-Sumo-Fields", backfillFields)
+		if err = s.send(data, headers, nil); err != nil {
+			return sent, err
+		}
+		sent++
+	}
+}
+
+// backfillMessage reconstructs enough of the original message for an
+// archived event to render the header templates against.
+func backfillMessage(data *Data) *router.Message {
+	msg := &router.Message{Data: data.Message}
+	if ms, err := strconv.ParseInt(data.Timestamp, 10, 64); err == nil {
+		msg.Time = time.Unix(0, ms*int64(time.Millisecond))
+	}
+	if data.Container != nil {
+		msg.Source = data.Container.Source
+		msg.Container = &docker.Container{
+			ID:   data.Container.ID,
+			Name: data.Container.Name,
+			Config: &docker.Config{
+				Hostname: data.Container.Hostname,
+				Image:    data.Container.Image,
+			},
+		}
+	}
+	return msg
+}
@@ -0,0 +1,76 @@
+package sumologic
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"time"
+
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+)
+
+func (ts *TestSuite) Test_Backfill_preserves_timestamps() {
+	a := newArchiver(archiveConfig{bucket: "logs", maxBytes: 1000})
+	config := buildConfig(&router.Route{})
+	for i, text := range []string{"one", "two"} {
+		a.add(buildData(&router.Message{
+			Data:   text,
+			Time:   mkTime(time.Duration(i)),
+			Source: "stdout",
+			Container: &docker.Container{
+				ID:     "abc",
+				Name:   "foo",
+				Config: &docker.Config{Hostname: "example.com"},
+			},
+		}, config))
+	}
+	batch, _, err := a.take()
+	ts.Require().NoError(err)
+
+	requests := make(chan *RequestData, 2)
+	adapter := ts.FakeSumo(requests)
+	ts.Equal(2, ts.WithoutError(adapter.Backfill(bytes.NewReader(batch))))
+
+	for i, text := range []string{"one", "two"} {
+		request := <-requests
+		ts.Equal(map[string]string{
+			"X-Sumo-Name":   "foo",
+			"X-Sumo-Host":   "example.com",
+			"X-Sumo-Fields": backfillFields,
+		}, request.Headers)
+		ts.Equal(text, request.Body["message"])
+		ts.Equal(formatTimestamp(mkTime(time.Duration(i))),
+			request.Body["timestamp"])
+		ts.Equal(true, request.Body["backfill"])
+	}
+}
+
+func (ts *TestSuite) Test_Backfill_uncompressed() {
+	requests := make(chan *RequestData, 1)
+	adapter := ts.FakeSumo(requests)
+	n, err := adapter.Backfill(strings.NewReader(
+		`{"message":"one","container":null,"timestamp":"1514898000000"}` + "\n"))
+	ts.NoError(err)
+	ts.Equal(1, n)
+	ts.Equal("1514898000000", (<-requests).Body["timestamp"])
+}
+
+func (ts *TestSuite) Test_Backfill_stops_at_errors() {
+	requests := make(chan *RequestData, 1)
+	adapter := ts.FakeSumo(requests)
+	n, err := adapter.Backfill(strings.NewReader(
+		`{"message":"one","timestamp":"1"}` + "\n" + `{"message":`))
+	ts.Error(err)
+	ts.Equal(1, n)
+
+	ts.CaptureLogs()
+	adapter = ts.adapterFor(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTooManyRequests)
+		}), newRealClock())
+	n, err = adapter.Backfill(strings.NewReader(
+		`{"message":"one","timestamp":"1"}` + "\n"))
+	ts.Equal(0, n)
+	ts.Equal(ErrThrottled, err.(*SendError).Kind)
+}
@@ -0,0 +1,59 @@
+package sumologic
+
+import (
+	"time"
+)
+
+// Backoff strategies for retries.
+const (
+	// backoffConstant waits SUMOLOGIC_BACKOFF between every retry.
+	backoffConstant = "constant"
+	// backoffExponential waits SUMOLOGIC_BACKOFF before the first retry, and
+	// twice as long before each one after that, up to SUMOLOGIC_BACKOFF_MAX_MS.
+	backoffExponential = "exponential"
+)
+
+// getbackofftypeopt retrieves the backoff strategy for retries.
+func (o routeOptions) getbackofftypeopt(name string) string {
+	value := o.getopt(name, backoffConstant)
+	if value != backoffConstant && value != backoffExponential {
+		parseFailed(name, value, nil)
+		return backoffConstant
+	}
+	return value
+}
+
+// backoff works out how long to wait before each retry. A random jitter is
+// added to every wait, so that hosts which failed at the same moment don't
+// all retry at the same moment too.
+type backoff struct {
+	kind     string
+	interval time.Duration
+	max      time.Duration
+	jitter   time.Duration
+	clock    Clock
+}
+
+func newBackoff(config *Config, clock Clock) *backoff {
+	return &backoff{
+		kind:     config.backoffType,
+		interval: time.Duration(config.backoff) * time.Millisecond,
+		max:      time.Duration(config.backoffMaxMs) * time.Millisecond,
+		jitter:   time.Duration(config.backoffJitterMs) * time.Millisecond,
+		clock:    clock,
+	}
+}
+
+// Next returns how long to wait before the given retry, counting from 0.
+func (b *backoff) Next(retry int) time.Duration {
+	wait := b.interval
+	if b.kind == backoffExponential {
+		for i := 0; i < retry && wait < b.max; i++ {
+			wait *= 2
+		}
+		if wait > b.max {
+			wait = b.max
+		}
+	}
+	return wait + b.clock.Jitter(b.jitter)
+}
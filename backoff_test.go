@@ -0,0 +1,68 @@
+package sumologic
+
+import (
+	"time"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+func (ts *TestSuite) Test_backoff_constant() {
+	b := newBackoff(&Config{backoffType: backoffConstant, backoff: 100,
+		backoffMaxMs: 150}, newFakeClock())
+	for retry := 0; retry < 5; retry++ {
+		ts.Equal(100*time.Millisecond, b.Next(retry))
+	}
+}
+
+func (ts *TestSuite) Test_backoff_exponential() {
+	b := newBackoff(&Config{backoffType: backoffExponential, backoff: 100,
+		backoffMaxMs: 1000}, newFakeClock())
+	var waits []time.Duration
+	for retry := 0; retry < 6; retry++ {
+		waits = append(waits, b.Next(retry))
+	}
+	ts.Equal([]time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+		time.Second,
+	}, waits)
+}
+
+func (ts *TestSuite) Test_backoff_jitter() {
+	// The fake clock's jitter is always half the maximum.
+	b := newBackoff(&Config{backoffType: backoffExponential, backoff: 100,
+		backoffMaxMs: 1000, backoffJitterMs: 50}, newFakeClock())
+	ts.Equal(125*time.Millisecond, b.Next(0))
+	ts.Equal(1025*time.Millisecond, b.Next(10))
+
+	b = newBackoff(&Config{backoffType: backoffConstant, backoff: 100,
+		backoffJitterMs: 50}, newRealClock())
+	for i := 0; i < 100; i++ {
+		wait := b.Next(i)
+		ts.True(wait >= 100*time.Millisecond && wait < 150*time.Millisecond)
+	}
+}
+
+func (ts *TestSuite) Test_getbackofftypeopt() {
+	hook, _ := ts.CaptureLogs()
+	ts.Equal(backoffConstant, envOptions.getbackofftypeopt("SUMOLOGIC_BACKOFF_TYPE"))
+	ts.Setenv("SUMOLOGIC_BACKOFF_TYPE", "exponential")
+	ts.Equal(backoffExponential, envOptions.getbackofftypeopt("SUMOLOGIC_BACKOFF_TYPE"))
+	ts.Setenv("SUMOLOGIC_BACKOFF_TYPE", "fibonacci")
+	ts.Equal(backoffConstant, envOptions.getbackofftypeopt("SUMOLOGIC_BACKOFF_TYPE"))
+	ts.Equal("Failed to parse", hook.LastEntry().Message)
+}
+
+func (ts *TestSuite) Test_clients_not_shared_across_backoff_settings() {
+	address := "https://collectors.example.com/receiver"
+	a := ts.mkAdapter(&router.Route{ID: "a", Address: address})
+	ts.Setenv("SUMOLOGIC_BACKOFF_TYPE", "exponential")
+	b := ts.mkAdapter(&router.Route{ID: "b", Address: address})
+	ts.Setenv("SUMOLOGIC_BACKOFF_JITTER_MS", "500")
+	c := ts.mkAdapter(&router.Route{ID: "c", Address: address})
+	ts.False(a.client == b.client)
+	ts.False(b.client == c.client)
+}
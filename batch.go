@@ -0,0 +1,187 @@
+package sumologic
+
+import (
+	"bytes"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// batcher groups events by their headers, so that each group can be sent to
+// Sumologic as newline-delimited json in a single request. A nil *batcher
+// doesn't batch anything.
+type batcher struct {
+	mu       sync.Mutex
+	size     int
+	maxBytes int
+	interval time.Duration
+	batches  map[string]*batch
+}
+
+// batch is a group of events with the same headers.
+type batch struct {
+	headers http.Header
+	body    bytes.Buffer
+	count   int
+	// ids is the container ID of each event in the batch, in order.
+	ids []string
+	// ends is the offset in the body where each event ends, in order.
+	// Events can't be told apart by their newlines, as a raw or templated
+	// event may hold newlines of its own.
+	ends []int
+	// containers counts the events in the batch by container ID.
+	containers map[string]int64
+}
+
+// newBatcher returns a batcher that sends batches once they hold size events
+// or when the interval passes, or nil if batches would only hold a single
+// event or the interval isn't positive. Batches are also kept to maxBytes,
+// if it's positive, so that requests stay under the collector's limit.
+func newBatcher(size int64, interval time.Duration, maxBytes int64) *batcher {
+	if size <= 1 || interval <= 0 {
+		return nil
+	}
+	return &batcher{
+		size:     int(size),
+		maxBytes: int(maxBytes),
+		interval: interval,
+		batches:  map[string]*batch{},
+	}
+}
+
+// add appends an event from a container, already encoded as json, to the
+// batch for its headers. If that fills the batch up, the batch is returned so
+// that it can be sent straight away, and a new one is started. If the event
+// won't fit in the batch's bytes, the batch is returned without it, and the
+// event starts the new one. An event too large to fit even on its own is
+// sent on its own.
+func (b *batcher) add(
+	line []byte, containerID string, headers http.Header) *batch {
+	key := headerKey(headers)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	current, ok := b.batches[key]
+	var full *batch
+	if ok && b.maxBytes > 0 && current.body.Len()+len(line)+1 > b.maxBytes {
+		full, ok = current, false
+	}
+	if !ok {
+		current = &batch{headers: headers, containers: map[string]int64{}}
+		b.batches[key] = current
+	}
+	current.append(line, containerID)
+	// A batch just started to make room can't be full yet, as it only
+	// holds one event.
+	if current.count < b.size {
+		return full
+	}
+	delete(b.batches, key)
+	return current
+}
+
+// take returns every pending batch, ordered by their headers, and starts
+// from scratch.
+func (b *batcher) take() []*batch {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	keys := make([]string, 0, len(b.batches))
+	for key := range b.batches {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	result := make([]*batch, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, b.batches[key])
+	}
+	b.batches = map[string]*batch{}
+	return result
+}
+
+// flushBatches sends every pending batch.
+func (s *Adapter) flushBatches() {
+	for _, b := range s.batches.take() {
+		s.sendBatch(b)
+	}
+}
+
+// sendBatch sends a batch to Sumologic in a single request, or in smaller
+// ones if it's too large.
+func (s *Adapter) sendBatch(b *batch) {
+	defer s.recoverPanic("sendBatch")
+	s.deliverBatch(b)
+}
+
+// deliverBatch sends a batch in a single request. If it's too large, it's
+// split in half, and each half is sent the same way, until the requests are
+// small enough or only hold a single event.
+func (s *Adapter) deliverBatch(b *batch) {
+	split := false
+	err := s.deliver(b.body.Bytes(), b.headers, b.containers, func() (bool, error) {
+		first, second := b.split()
+		if first == nil {
+			return false, nil
+		}
+		log.WithField("events", b.count).Warn(
+			"Batch too large, sending it in two halves")
+		split = true
+		s.deliverBatch(first)
+		s.deliverBatch(second)
+		return true, nil
+	})
+	if !split {
+		s.containers.delivered(b.containers, err)
+	}
+}
+
+// append adds an event from a container to the end of the batch, followed
+// by a newline.
+func (b *batch) append(line []byte, containerID string) {
+	b.body.Write(line)
+	b.body.WriteByte('\n')
+	b.count++
+	b.ids = append(b.ids, containerID)
+	b.ends = append(b.ends, b.body.Len())
+	b.containers[containerID]++
+}
+
+// split divides a batch into two with half the events each, or returns nils
+// if it only holds a single event.
+func (b *batch) split() (*batch, *batch) {
+	if b.count < 2 {
+		return nil, nil
+	}
+	half := b.count / 2
+	return b.slice(0, half), b.slice(half, b.count)
+}
+
+// slice returns a new batch of the events from index i up to j.
+func (b *batch) slice(i, j int) *batch {
+	result := &batch{headers: b.headers, containers: map[string]int64{}}
+	body := b.body.Bytes()
+	start := 0
+	if i > 0 {
+		start = b.ends[i-1]
+	}
+	for k := i; k < j; k++ {
+		// The event is appended without the newline that ends it.
+		result.append(body[start:b.ends[k]-1], b.ids[k])
+		start = b.ends[k]
+	}
+	return result
+}
+
+// headerKey returns a string that's the same for equal sets of headers.
+func headerKey(headers http.Header) string {
+	var parts []string
+	for name, values := range headers {
+		for _, value := range values {
+			parts = append(parts, name+": "+value)
+		}
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, "\n")
+}
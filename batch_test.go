@@ -0,0 +1,249 @@
+package sumologic
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+type batchRequest struct {
+	headers  http.Header
+	messages []string
+}
+
+// FakeSumoBatches starts a fake Sumo Logic server that accepts batches of
+// newline-delimited json, and returns an Adapter pointing at it. If
+// maxEvents is positive, batches of more events than that are rejected as
+// too large.
+func (ts *TestSuite) FakeSumoBatches(
+	requests chan *batchRequest, clock Clock, maxEvents int) *Adapter {
+	return ts.adapterFor(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			request := &batchRequest{headers: r.Header}
+			body, _ := ioutil.ReadAll(r.Body)
+			scanner := bufio.NewScanner(strings.NewReader(string(body)))
+			for scanner.Scan() {
+				var data Data
+				ts.NoError(json.Unmarshal(scanner.Bytes(), &data))
+				request.messages = append(request.messages, data.Message)
+			}
+			if maxEvents > 0 && len(request.messages) > maxEvents {
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+				return
+			}
+			requests <- request
+		}), clock)
+}
+
+func (ts *TestSuite) Test_newBatcher_disabled() {
+	ts.Nil(newBatcher(1, time.Second, 0))
+	ts.Nil(newBatcher(10, 0, 0))
+}
+
+func (ts *TestSuite) Test_batcher_groups_by_headers() {
+	b := newBatcher(3, time.Second, 0)
+	a := http.Header{"X-Sumo-Name": {"a"}}
+	other := http.Header{"X-Sumo-Name": {"b"}}
+	ts.Nil(b.add([]byte(`1`), "abc", a))
+	ts.Nil(b.add([]byte(`2`), "abc", other))
+	ts.Nil(b.add([]byte(`3`), "abc", a))
+	full := b.add([]byte(`4`), "abc", a)
+	ts.Equal(a, full.headers)
+	ts.Equal("1\n3\n4\n", full.body.String())
+	ts.Equal(3, full.count)
+
+	ts.Nil(b.add([]byte(`5`), "abc", a))
+	pending := b.take()
+	ts.Len(pending, 2)
+	ts.Equal("5\n", pending[0].body.String())
+	ts.Equal("2\n", pending[1].body.String())
+	ts.Empty(b.take())
+}
+
+func (ts *TestSuite) Test_sendLog_batches_by_size() {
+	ts.Setenv("SUMOLOGIC_BATCH_SIZE", "2")
+	requests := make(chan *batchRequest, 1)
+	adapter := ts.FakeSumoBatches(requests, newFakeClock(), 0)
+
+	one := mkContainerMessage("abc", "foo")
+	one.Data = "one"
+	two := mkContainerMessage("abc", "foo")
+	two.Data = "two"
+	adapter.sendLog(one)
+	ts.Empty(requests)
+	adapter.sendLog(two)
+
+	request := <-requests
+	ts.Equal("foo", request.headers.Get("X-Sumo-Name"))
+	ts.Equal([]string{"one", "two"}, request.messages)
+	status := adapter.Status()
+	ts.EqualValues(2, status.Sent)
+	ts.EqualValues(2, status.Categories[uncategorized].Events)
+}
+
+func (ts *TestSuite) Test_sendLog_batches_flushed_on_interval() {
+	ts.Setenv("SUMOLOGIC_BATCH_SIZE", "100")
+	ts.Setenv("SUMOLOGIC_FLUSH_INTERVAL_MS", "5000")
+	requests := make(chan *batchRequest, 2)
+	clock := newFakeClock()
+	adapter := ts.FakeSumoBatches(requests, clock, 0)
+
+	adapter.sendLog(mkContainerMessage("abc", "foo"))
+	adapter.sendLog(mkContainerMessage("def", "bar"))
+	adapter.sendLog(mkContainerMessage("abc", "foo"))
+	clock.WaitForTimers(1)
+	clock.Advance(5 * time.Second)
+
+	names := map[string]int{}
+	for i := 0; i < 2; i++ {
+		select {
+		case request := <-requests:
+			names[request.headers.Get("X-Sumo-Name")] = len(request.messages)
+		case <-time.After(time.Second):
+			ts.Fail("Timeout waiting for batch.")
+		}
+	}
+	ts.Equal(map[string]int{"foo": 2, "bar": 1}, names)
+}
+
+func (ts *TestSuite) Test_batcher_keeps_batches_under_max_bytes() {
+	b := newBatcher(10, time.Second, 8)
+	headers := http.Header{"X-Sumo-Name": {"a"}}
+	ts.Nil(b.add([]byte(`aaa`), "abc", headers))
+	ts.Nil(b.add([]byte(`bbb`), "def", headers))
+	full := b.add([]byte(`ccc`), "abc", headers)
+	ts.Equal("aaa\nbbb\n", full.body.String())
+	ts.Equal(2, full.count)
+	ts.Equal(map[string]int64{"abc": 1, "def": 1}, full.containers)
+
+	// An event too large to share a request is sent on its own.
+	full = b.add([]byte(`dddddddddd`), "abc", headers)
+	ts.Equal("ccc\n", full.body.String())
+	full = b.add([]byte(`e`), "abc", headers)
+	ts.Equal("dddddddddd\n", full.body.String())
+	ts.Equal(1, full.count)
+
+	pending := b.take()
+	ts.Len(pending, 1)
+	ts.Equal("e\n", pending[0].body.String())
+}
+
+func (ts *TestSuite) Test_sendLog_batches_by_bytes() {
+	ts.Setenv("SUMOLOGIC_BATCH_SIZE", "100")
+	msg := mkContainerMessage("abc", "foo")
+	config := buildConfig(&router.Route{})
+	line := ts.WithoutError(encodeEvent(buildData(msg, config), config)).([]byte)
+	// Room for two and a half events.
+	ts.Setenv("SUMOLOGIC_MAX_REQUEST_BYTES",
+		strconv.Itoa(5*(len(line)+1)/2))
+	requests := make(chan *batchRequest, 1)
+	adapter := ts.FakeSumoBatches(requests, newFakeClock(), 0)
+
+	for i := 0; i < 3; i++ {
+		adapter.sendLog(mkContainerMessage("abc", "foo"))
+	}
+	ts.Len((<-requests).messages, 2)
+}
+
+func (ts *TestSuite) Test_batch_split() {
+	b := newBatcher(10, time.Second, 0)
+	headers := http.Header{"X-Sumo-Name": {"a"}}
+	b.add([]byte(`1`), "abc", headers)
+	b.add([]byte(`2`), "def", headers)
+	b.add([]byte(`3`), "abc", headers)
+	full := b.take()[0]
+
+	first, second := full.split()
+	ts.Equal("1\n", first.body.String())
+	ts.Equal(1, first.count)
+	ts.Equal(map[string]int64{"abc": 1}, first.containers)
+	ts.Equal(headers, first.headers)
+	ts.Equal("2\n3\n", second.body.String())
+	ts.Equal(2, second.count)
+	ts.Equal([]string{"def", "abc"}, second.ids)
+	ts.Equal(map[string]int64{"abc": 1, "def": 1}, second.containers)
+
+	first, second = first.split()
+	ts.Nil(first)
+	ts.Nil(second)
+}
+
+func (ts *TestSuite) Test_batch_split_events_with_newlines() {
+	b := newBatcher(10, time.Second, 0)
+	headers := http.Header{"X-Sumo-Name": {"a"}}
+	b.add([]byte("1\n2"), "abc", headers)
+	b.add([]byte("3"), "def", headers)
+	b.add([]byte("4\n5\n6"), "abc", headers)
+	full := b.take()[0]
+
+	first, second := full.split()
+	ts.Equal("1\n2\n", first.body.String())
+	ts.Equal(1, first.count)
+	ts.Equal("3\n4\n5\n6\n", second.body.String())
+	ts.Equal(2, second.count)
+	ts.Equal([]string{"def", "abc"}, second.ids)
+
+	first, second = second.split()
+	ts.Equal("3\n", first.body.String())
+	ts.Equal("4\n5\n6\n", second.body.String())
+}
+
+func (ts *TestSuite) Test_sendLog_splits_batches_that_are_too_large() {
+	ts.CaptureLogs()
+	ts.Setenv("SUMOLOGIC_BATCH_SIZE", "5")
+	requests := make(chan *batchRequest, 5)
+	adapter := ts.FakeSumoBatches(requests, newFakeClock(), 2)
+
+	for _, data := range []string{"one", "two", "three", "four", "five"} {
+		msg := mkContainerMessage("abc", "foo")
+		msg.Data = data
+		adapter.sendLog(msg)
+	}
+
+	// Five events are split into two and three, and the three into one and
+	// two.
+	ts.Equal([]string{"one", "two"}, (<-requests).messages)
+	ts.Equal([]string{"three"}, (<-requests).messages)
+	ts.Equal([]string{"four", "five"}, (<-requests).messages)
+	ts.Empty(requests)
+	ts.EqualValues(5, adapter.Status().Sent)
+	statuses := adapter.containers.snapshot("foo")
+	ts.Len(statuses, 1)
+	ts.EqualValues(5, statuses[0].Sent)
+	ts.EqualValues(0, statuses[0].Failed)
+}
+
+func (ts *TestSuite) Test_sendLog_splits_raw_batches_with_multiline_events() {
+	ts.CaptureLogs()
+	ts.Setenv("SUMOLOGIC_FORMAT", "raw")
+	ts.Setenv("SUMOLOGIC_BATCH_SIZE", "3")
+	bodies := make(chan string, 3)
+	// Bodies of more than six bytes are rejected as too large.
+	adapter := ts.adapterFor(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			if len(body) > 6 {
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+				return
+			}
+			bodies <- string(body)
+		}), newFakeClock())
+
+	for _, data := range []string{"a\nb", "c", "d\ne"} {
+		msg := mkContainerMessage("abc", "foo")
+		msg.Data = data
+		adapter.sendLog(msg)
+	}
+
+	// The multiline events are kept whole when the batch is split.
+	ts.Equal("a\nb\n", <-bodies)
+	ts.Equal("c\nd\ne\n", <-bodies)
+	ts.Empty(bodies)
+	ts.EqualValues(3, adapter.Status().Sent)
+}
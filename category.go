@@ -0,0 +1,109 @@
+package sumologic
+
+import (
+	"strings"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+// composeServiceLabel is the label docker-compose sets to a container's
+// service name.
+const composeServiceLabel = "com.docker.compose.service"
+
+// categorySource is somewhere a message's source category can come from when
+// the category template doesn't yield one.
+type categorySource struct {
+	kind string
+	arg  string
+}
+
+// getcategorysourcesopt retrieves an environment variable as a list of
+// category sources if it's set to a non-empty string of comma-separated
+// sources, e.g. "label:sumologic.category,compose_service,image,static:misc".
+// Sources that can't be parsed are logged and ignored.
+func (o routeOptions) getcategorysourcesopt(name string) []categorySource {
+	var sources []categorySource
+	for _, entry := range strings.Split(o.lookupopt(name), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		kv := strings.SplitN(entry, ":", 2)
+		source := categorySource{kind: kv[0]}
+		if len(kv) == 2 {
+			source.arg = kv[1]
+		}
+		switch {
+		case source.kind == "compose_service" && len(kv) == 1:
+		case source.kind == "image" && len(kv) == 1:
+		case source.kind == "label" && source.arg != "":
+		case source.kind == "static" && source.arg != "":
+		default:
+			parseFailed(name, entry, nil)
+			continue
+		}
+		sources = append(sources, source)
+	}
+	return sources
+}
+
+// value returns the category this source yields for a message, which may be
+// empty.
+func (c categorySource) value(msg *router.Message) string {
+	if c.kind == "static" {
+		return c.arg
+	}
+	if msg.Container == nil || msg.Container.Config == nil {
+		return ""
+	}
+	switch c.kind {
+	case "label":
+		return msg.Container.Config.Labels[c.arg]
+	case "compose_service":
+		return msg.Container.Config.Labels[composeServiceLabel]
+	case "image":
+		return imageName(msg.Container.Config.Image)
+	}
+	return ""
+}
+
+// imageName strips the tag and digest from an image reference, e.g.
+// "registry:5000/org/app:1.2" becomes "registry:5000/org/app".
+func imageName(image string) string {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+	return image
+}
+
+// sourceCategory returns the source category for a message: the rendered
+// stderr category template for messages written to stderr, or the rendered
+// category template, if that's non-empty, otherwise the first non-empty value
+// from the fallback sources. The bool result is false if there's no category
+// to send at all.
+func sourceCategory(msg *router.Message, config *Config) (string, bool) {
+	if msg.Source == stderrStream && config.stderrSourceCategory != "" {
+		category, err := renderTemplate(
+			msg, config.route, config.stderrSourceCategory)
+		if err == nil && category != "" {
+			return category, true
+		}
+	}
+	found := false
+	if config.sourceCategory != "" {
+		category, err := renderTemplate(msg, config.route, config.sourceCategory)
+		if err == nil && category != "" {
+			return category, true
+		}
+		found = err == nil
+	}
+	for _, source := range config.categorySources {
+		if category := source.value(msg); category != "" {
+			return category, true
+		}
+	}
+	return "", found
+}
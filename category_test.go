@@ -0,0 +1,65 @@
+package sumologic
+
+import (
+	"github.com/gliderlabs/logspout/router"
+	"github.com/sirupsen/logrus"
+)
+
+func (ts *TestSuite) Test_getcategorysourcesopt_unset_envar_returns_nil() {
+	ts.Nil(envOptions.getcategorysourcesopt("UNSET_ENV_VAR"))
+}
+
+func (ts *TestSuite) Test_getcategorysourcesopt_set_envar_returns_sources() {
+	ts.Setenv("SET_ENV_VAR",
+		"label:sumologic.category, compose_service,image,static:a:b")
+	ts.Equal([]categorySource{
+		{"label", "sumologic.category"},
+		{"compose_service", ""},
+		{"image", ""},
+		{"static", "a:b"},
+	}, envOptions.getcategorysourcesopt("SET_ENV_VAR"))
+}
+
+func (ts *TestSuite) Test_getcategorysourcesopt_invalid_sources_skipped() {
+	hook, _ := ts.CaptureLogs()
+
+	ts.Setenv("SET_ENV_VAR", "label:,static:,image:foo,magic,static:ok")
+	ts.Equal([]categorySource{{"static", "ok"}},
+		envOptions.getcategorysourcesopt("SET_ENV_VAR"))
+	ts.Len(hook.AllEntries(), 4)
+	ts.Equal(logrus.ErrorLevel, hook.LastEntry().Level)
+	ts.Equal("Failed to parse", hook.LastEntry().Message)
+}
+
+func (ts *TestSuite) Test_imageName() {
+	ts.Equal("nginx", imageName("nginx"))
+	ts.Equal("nginx", imageName("nginx:1.15"))
+	ts.Equal("registry:5000/org/app", imageName("registry:5000/org/app"))
+	ts.Equal("registry:5000/org/app", imageName("registry:5000/org/app:1.2"))
+	ts.Equal("org/app", imageName("org/app:1.2@sha256:abcd"))
+}
+
+func (ts *TestSuite) Test_buildHeaders_category_fallbacks() {
+	ts.Setenv("SUMOLOGIC_SOURCE_CATEGORY",
+		`{{index .Container.Config.Labels "category"}}`)
+	ts.Setenv("SUMOLOGIC_SOURCE_CATEGORY_FALLBACKS",
+		"label:team,compose_service,image,static:misc")
+	config := buildConfig(&router.Route{})
+	category := func(labels map[string]string, image string) string {
+		msg := mkContainerMessage("abc", "foo")
+		msg.Container.Config.Labels = labels
+		msg.Container.Config.Image = image
+		return buildHeaders(msg, config).Get("X-Sumo-Category")
+	}
+
+	ts.Equal("web", category(map[string]string{
+		"category": "web", "team": "a", composeServiceLabel: "app"}, "nginx"))
+	ts.Equal("a", category(map[string]string{
+		"team": "a", composeServiceLabel: "app"}, "nginx"))
+	ts.Equal("app", category(map[string]string{
+		composeServiceLabel: "app"}, "nginx:1.15"))
+	ts.Equal("nginx", category(nil, "nginx:1.15"))
+	ts.Equal("misc", category(nil, ""))
+	ts.Equal("misc", buildHeaders(&router.Message{}, config).Get(
+		"X-Sumo-Category"))
+}
@@ -0,0 +1,115 @@
+package sumologic
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// errChaos is the cause of the connection failures chaos injects.
+var errChaos = errors.New("connection refused (injected by SUMOLOGIC_CHAOS)")
+
+// chaos injects faults into requests before they're sent, to check how
+// retries, buffering and alerting behave while the endpoint is failing,
+// without having to break anything. A nil *chaos sends every request as is.
+type chaos struct {
+	// errors is the fraction of requests that fail to connect.
+	errors float64
+	// throttle is the fraction of requests that get a 429 response.
+	throttle float64
+	// latency is how long every request is held up for.
+	latency time.Duration
+}
+
+// getchaosopt retrieves the faults to inject, which are comma-separated
+// kind:value pairs, e.g. "errors:0.1,throttle:0.05,latency:500ms".
+func (o routeOptions) getchaosopt(name string) *chaos {
+	value := o.getopt(name, "")
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+	c := &chaos{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		kv := strings.SplitN(entry, ":", 2)
+		if len(kv) != 2 {
+			parseFailed(name, entry, nil)
+			continue
+		}
+		var err error
+		switch kv[0] {
+		case "errors":
+			c.errors, err = parseFraction(kv[1])
+		case "throttle":
+			c.throttle, err = parseFraction(kv[1])
+		case "latency":
+			c.latency, err = time.ParseDuration(kv[1])
+		default:
+			err = errors.New("unknown fault")
+		}
+		if err != nil {
+			parseFailed(name, entry, err)
+		}
+	}
+	log.WithField(name, value).Warn("Injecting faults into requests")
+	return c
+}
+
+// parseFraction parses a number between 0 and 1.
+func parseFraction(value string) (float64, error) {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, err
+	}
+	if f < 0 || f > 1 {
+		return 0, errors.New("not between 0 and 1")
+	}
+	return f, nil
+}
+
+// injectFault holds up a request for the configured latency, then decides
+// whether it should fail. It returns either the response or the error to
+// pretend the request got, or neither if the request should be sent.
+func (s *Adapter) injectFault(
+	c *chaos, req *http.Request) (*http.Response, error) {
+	if c == nil {
+		return nil, nil
+	}
+	if c.latency > 0 {
+		timer := s.clock.NewTimer(c.latency)
+		select {
+		case <-timer.C():
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		}
+	}
+	if s.chance(c.errors) {
+		return nil, &net.OpError{Op: "dial", Net: "tcp", Err: errChaos}
+	}
+	if s.chance(c.throttle) {
+		return &http.Response{
+			Status:     "429 Too Many Requests",
+			StatusCode: http.StatusTooManyRequests,
+			Header:     http.Header{},
+			Body:       ioutil.NopCloser(strings.NewReader("")),
+			Request:    req,
+		}, nil
+	}
+	return nil, nil
+}
+
+// chance returns true with probability p.
+func (s *Adapter) chance(p float64) bool {
+	const scale = 1000000
+	return p > 0 && s.clock.Jitter(scale) < time.Duration(p*scale)
+}
@@ -0,0 +1,62 @@
+package sumologic
+
+import (
+	"time"
+)
+
+func (ts *TestSuite) Test_getchaosopt() {
+	ts.Nil(envOptions.getchaosopt("SUMOLOGIC_CHAOS"))
+	ts.Setenv("SUMOLOGIC_CHAOS", "errors:0.1, throttle:0.05,latency:500ms")
+	ts.Equal(&chaos{errors: 0.1, throttle: 0.05, latency: 500 * time.Millisecond},
+		envOptions.getchaosopt("SUMOLOGIC_CHAOS"))
+}
+
+func (ts *TestSuite) Test_getchaosopt_bad_entries_skipped() {
+	ts.CaptureLogs()
+	ts.Setenv("SUMOLOGIC_CHAOS", "errors:2,latency:soon,explode:1,throttle")
+	ts.Equal(&chaos{}, envOptions.getchaosopt("SUMOLOGIC_CHAOS"))
+}
+
+func (ts *TestSuite) Test_Send_chaos_errors() {
+	ts.CaptureLogs()
+	ts.Setenv("SUMOLOGIC_CHAOS", "errors:1")
+	requests := make(chan *RequestData, 1)
+	adapter := ts.FakeSumoWithClock(requests, newFakeClock())
+
+	err := adapter.Send(mkContainerMessage("abc", "foo"))
+	ts.IsType(&SendError{}, err)
+	ts.Equal(ErrNetwork, err.(*SendError).Kind)
+	ts.Contains(err.Error(), "SUMOLOGIC_CHAOS")
+	ts.EqualValues(1, adapter.Status().Failures[errorClassConnect])
+	ts.Empty(requests)
+}
+
+func (ts *TestSuite) Test_Send_chaos_throttle() {
+	ts.CaptureLogs()
+	ts.Setenv("SUMOLOGIC_CHAOS", "throttle:1")
+	ts.Setenv("SUMOLOGIC_RETRIES", "0")
+	requests := make(chan *RequestData, 1)
+	adapter := ts.FakeSumoWithClock(requests, newFakeClock())
+
+	err := adapter.Send(mkContainerMessage("abc", "foo"))
+	ts.IsType(&SendError{}, err)
+	ts.Equal(ErrThrottled, err.(*SendError).Kind)
+	ts.Empty(requests)
+}
+
+func (ts *TestSuite) Test_Send_chaos_latency() {
+	// The fake clock's jitter is always half the range, so faults injected
+	// less than half the time never are.
+	ts.Setenv("SUMOLOGIC_CHAOS", "errors:0.1,latency:500ms")
+	requests := make(chan *RequestData, 1)
+	clock := newFakeClock()
+	adapter := ts.FakeSumoWithClock(requests, clock)
+
+	errs := make(chan error, 1)
+	go func() { errs <- adapter.Send(mkContainerMessage("abc", "foo")) }()
+	clock.WaitForTimers(1)
+	ts.Empty(requests)
+	clock.Advance(500 * time.Millisecond)
+	ts.NoError(<-errs)
+	ts.Equal("Some data.", (<-requests).Body["message"])
+}
@@ -0,0 +1,119 @@
+package sumologic
+
+import (
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// clients shares HTTP clients between routes that send to the same endpoint
+// with the same client settings, so that duplicate routes don't double up on
+// connections.
+var clients = &clientPool{clients: map[clientKey]*sharedClient{}}
+
+// clientKey is everything an HTTP client's behaviour depends on.
+type clientKey struct {
+	endPoint      string
+	timeout       int64
+	retries       int64
+	backoff       int64
+	backoffType   string
+	backoffMax    int64
+	backoffJitter int64
+	tls           tlsOptions
+	proxyURL      string
+	pool          connPool
+}
+
+type sharedClient struct {
+	client *httpClient
+	users  map[*Adapter]bool
+}
+
+type clientPool struct {
+	mu      sync.Mutex
+	clients map[clientKey]*sharedClient
+}
+
+func keyForConfig(config *Config) clientKey {
+	return clientKey{
+		endPoint:      config.endPoint,
+		timeout:       config.timeout,
+		retries:       config.retries,
+		backoff:       config.backoff,
+		backoffType:   config.backoffType,
+		backoffMax:    config.backoffMaxMs,
+		backoffJitter: config.backoffJitterMs,
+		tls:           config.tls,
+		proxyURL:      config.proxyURL,
+		pool:          config.pool,
+	}
+}
+
+// acquire returns the client for an adapter, creating it if no other adapter
+// is using an equivalent one.
+func (p *clientPool) acquire(a *Adapter) *httpClient {
+	return p.acquireWith(a, a.config())
+}
+
+// acquireWith returns the client for an adapter to use with a particular
+// config, e.g. one for another endpoint, creating it if no other adapter is
+// using an equivalent one.
+func (p *clientPool) acquireWith(a *Adapter, config *Config) *httpClient {
+	key := keyForConfig(config)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	shared, ok := p.clients[key]
+	if !ok {
+		shared = &sharedClient{
+			client: newClient(config, a.clock),
+			users:  map[*Adapter]bool{},
+		}
+		p.clients[key] = shared
+	}
+	for other := range shared.users {
+		if other == a {
+			continue
+		}
+		log.WithFields(log.Fields{
+			"route":        a.route.ID,
+			"duplicate_of": other.route.ID,
+		}).Info("Route sends to the same endpoint as another, sharing its client")
+		break
+	}
+	shared.users[a] = true
+	return shared.client
+}
+
+// reacquire swaps the client an adapter acquired with an old config for the
+// one for its current config, once it's been reloaded.
+func (p *clientPool) reacquire(a *Adapter, old *Config) *httpClient {
+	p.mu.Lock()
+	p.drop(a, keyForConfig(old))
+	p.mu.Unlock()
+	return p.acquire(a)
+}
+
+// release stops an adapter using its clients, including any for its
+// containers' endpoints. A client is discarded once no adapters are using
+// it. Releasing an adapter more than once is harmless.
+func (p *clientPool) release(a *Adapter) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for key := range p.clients {
+		p.drop(a, key)
+	}
+}
+
+// drop stops an adapter using the client for a key, which must be called
+// with the lock held.
+func (p *clientPool) drop(a *Adapter, key clientKey) {
+	shared, ok := p.clients[key]
+	if !ok {
+		return
+	}
+	delete(shared.users, a)
+	if len(shared.users) == 0 {
+		delete(p.clients, key)
+	}
+}
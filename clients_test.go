@@ -0,0 +1,67 @@
+package sumologic
+
+import (
+	"github.com/gliderlabs/logspout/router"
+)
+
+func (ts *TestSuite) Test_clients_shared_between_duplicate_routes() {
+	hook, _ := ts.CaptureLogs()
+	address := "https://collectors.example.com/receiver"
+	a := ts.mkAdapter(&router.Route{ID: "a", Address: address})
+	b := ts.mkAdapter(&router.Route{ID: "b", Address: address})
+	ts.Equal("Route sends to the same endpoint as another, sharing its client",
+		hook.LastEntry().Message)
+	ts.Equal("a", hook.LastEntry().Data["duplicate_of"])
+	ts.True(a.client == b.client)
+
+	ts.Setenv("SUMOLOGIC_RETRIES", "5")
+	c := ts.mkAdapter(&router.Route{ID: "c", Address: address})
+	ts.False(a.client == c.client)
+	d := ts.mkAdapter(&router.Route{ID: "d", Address: address + "/other"})
+	ts.False(c.client == d.client)
+}
+
+func (ts *TestSuite) Test_clients_released_on_close() {
+	address := "https://collectors.example.com/receiver"
+	a := ts.mkAdapter(&router.Route{ID: "a", Address: address})
+	b := ts.mkAdapter(&router.Route{ID: "b", Address: address})
+	key := keyForConfig(a.config())
+	ts.Len(clients.clients[key].users, 2)
+
+	a.Close()
+	a.Close()
+	ts.Len(clients.clients[key].users, 1)
+	b.Close()
+	ts.NotContains(clients.clients, key)
+
+	c := ts.mkAdapter(&router.Route{ID: "c", Address: address})
+	ts.False(a.client == c.client)
+}
+
+func (ts *TestSuite) Test_clients_released_after_reload() {
+	a := ts.mkAdapter(&router.Route{
+		ID: "a", Address: "https://collectors.example.com/receiver"})
+	key := keyForConfig(a.config())
+
+	ts.Setenv("SUMOLOGIC_ENDPOINT", "https://other.example.com/receiver")
+	a.Reload()
+	ts.NotContains(clients.clients, key)
+	a.Close()
+	ts.NotContains(clients.clients, keyForConfig(a.config()))
+}
+
+func (ts *TestSuite) Test_clients_follow_reloaded_endpoint() {
+	ts.CaptureLogs()
+	first := "https://collectors.example.com/receiver"
+	second := "https://other.example.com/receiver"
+	a := ts.mkAdapter(&router.Route{ID: "a", Address: first})
+	b := ts.mkAdapter(&router.Route{ID: "b", Address: second})
+	c := ts.mkAdapter(&router.Route{ID: "c", Address: first})
+	ts.True(a.client == c.client)
+
+	a.route.Address = second
+	a.Reload()
+	ts.True(a.client == b.client)
+	ts.False(a.client == c.client)
+	ts.Len(clients.clients[keyForConfig(c.config())].users, 1)
+}
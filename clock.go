@@ -0,0 +1,64 @@
+package sumologic
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Clock is the source of time and randomness for everything in the adapter
+// that depends on timing. It can be replaced (see NewAdapterWithClock) to
+// make that behaviour deterministic.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// NewTimer returns a Timer that fires once after the given duration.
+	NewTimer(d time.Duration) Timer
+	// Jitter returns a random duration in [0, max).
+	Jitter(max time.Duration) time.Duration
+}
+
+// Timer is the subset of *time.Timer that the adapter uses.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+// realClock is a Clock backed by the time and math/rand packages.
+type realClock struct {
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+func newRealClock() *realClock {
+	return &realClock{rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+func (c *realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (c *realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+func (c *realClock) Jitter(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return time.Duration(c.rand.Int63n(int64(max)))
+}
+
+type realTimer struct {
+	timer *time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.timer.C
+}
+
+func (t realTimer) Stop() bool {
+	return t.timer.Stop()
+}
@@ -0,0 +1,116 @@
+package sumologic
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+// fakeClock is a Clock for tests. Time only moves when Advance is called, and
+// Jitter always returns half of the maximum.
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	timers  []*fakeTimer
+	created chan struct{}
+}
+
+type fakeTimer struct {
+	clock    *fakeClock
+	deadline time.Time
+	c        chan time.Time
+	stopped  bool
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: mkTime(0), created: make(chan struct{}, 100)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) NewTimer(d time.Duration) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &fakeTimer{clock: c, deadline: c.now.Add(d), c: make(chan time.Time, 1)}
+	c.timers = append(c.timers, t)
+	c.created <- struct{}{}
+	return t
+}
+
+func (c *fakeClock) Jitter(max time.Duration) time.Duration {
+	return max / 2
+}
+
+// Advance moves the clock forward, firing any timers that are due.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		switch {
+		case t.stopped:
+		case !t.deadline.After(c.now):
+			t.c <- c.now
+		default:
+			pending = append(pending, t)
+		}
+	}
+	c.timers = pending
+}
+
+// WaitForTimers blocks until n timers have been created, so that tests can
+// be sure something is waiting on the clock before advancing it.
+func (c *fakeClock) WaitForTimers(n int) {
+	for i := 0; i < n; i++ {
+		<-c.created
+	}
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	wasActive := !t.stopped
+	t.stopped = true
+	return wasActive
+}
+
+func (ts *TestSuite) Test_realClock_Jitter() {
+	clock := newRealClock()
+	ts.Equal(time.Duration(0), clock.Jitter(0))
+	for i := 0; i < 100; i++ {
+		jitter := clock.Jitter(time.Second)
+		ts.True(jitter >= 0 && jitter < time.Second)
+	}
+}
+
+func (ts *TestSuite) Test_realClock_NewTimer() {
+	timer := newRealClock().NewTimer(time.Millisecond)
+	select {
+	case <-timer.C():
+	case <-time.After(100 * time.Millisecond):
+		ts.Fail("Timeout waiting for timer.")
+	}
+	ts.False(timer.Stop())
+}
+
+func (ts *TestSuite) Test_NewAdapterWithClock_uses_clock() {
+	ts.CaptureLogs()
+	clock := newFakeClock()
+	adapter := ts.WithoutError(
+		NewAdapterWithClock(&router.Route{ID: "foo", Address: noServer}, clock)).(*Adapter)
+	ts.AddCleanup(adapter.Close)
+
+	adapter.deliveryFailed(errors.New("boom"))
+	ts.Equal("2018-01-02T13:00:00Z", adapter.Status().LastErrorTime)
+}
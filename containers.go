@@ -0,0 +1,150 @@
+package sumologic
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gliderlabs/logspout/router"
+	log "github.com/sirupsen/logrus"
+)
+
+// ContainerStatus is a snapshot of what a route has done with a single
+// container's logs.
+type ContainerStatus struct {
+	Route         string `json:"route"`
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	Received      int64  `json:"received"`
+	Sent          int64  `json:"sent"`
+	Failed        int64  `json:"failed"`
+	Dropped       int64  `json:"dropped"`
+	LastError     string `json:"last_error,omitempty"`
+	LastErrorTime string `json:"last_error_time,omitempty"`
+}
+
+// containerTracker counts what happens to each container's logs.
+type containerTracker struct {
+	mu         sync.Mutex
+	clock      Clock
+	containers map[string]*containerCounts
+}
+
+type containerCounts struct {
+	name        string
+	received    int64
+	sent        int64
+	failed      int64
+	dropped     int64
+	lastError   string
+	lastErrorAt time.Time
+}
+
+func newContainerTracker(clock Clock) *containerTracker {
+	return &containerTracker{
+		clock:      clock,
+		containers: map[string]*containerCounts{},
+	}
+}
+
+// get returns the counts for a container, which must be called with the lock
+// held.
+func (t *containerTracker) get(id string, name string) *containerCounts {
+	counts, ok := t.containers[id]
+	if !ok {
+		counts = &containerCounts{}
+		t.containers[id] = counts
+	}
+	if name != "" {
+		counts.name = name
+	}
+	return counts
+}
+
+// received counts a message from a container.
+func (t *containerTracker) received(msg *router.Message) {
+	if msg.Container == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.get(msg.Container.ID, msg.Container.Name).received++
+}
+
+// dropped counts a message from a container that wasn't sent.
+func (t *containerTracker) dropped(msg *router.Message) {
+	if msg.Container == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.get(msg.Container.ID, msg.Container.Name).dropped++
+}
+
+// delivered counts the outcome of sending events from containers, given as
+// the number of events by container ID.
+func (t *containerTracker) delivered(events map[string]int64, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for id, n := range events {
+		if id == "" {
+			continue
+		}
+		counts := t.get(id, "")
+		if err == nil {
+			counts.sent += n
+			continue
+		}
+		counts.failed += n
+		counts.lastError = err.Error()
+		counts.lastErrorAt = t.clock.Now()
+	}
+}
+
+// snapshot returns the status of every container, ordered by name.
+func (t *containerTracker) snapshot(route string) []ContainerStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	result := make([]ContainerStatus, 0, len(t.containers))
+	for id, counts := range t.containers {
+		status := ContainerStatus{
+			Route:    route,
+			ID:       id,
+			Name:     counts.name,
+			Received: counts.received,
+			Sent:     counts.sent,
+			Failed:   counts.failed,
+			Dropped:  counts.dropped,
+		}
+		if !counts.lastErrorAt.IsZero() {
+			status.LastError = counts.lastError
+			status.LastErrorTime = counts.lastErrorAt.Format(time.RFC3339)
+		}
+		result = append(result, status)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Name != result[j].Name {
+			return result[i].Name < result[j].Name
+		}
+		return result[i].ID < result[j].ID
+	})
+	return result
+}
+
+// containersHandler serves the status of every container on every sumologic
+// route as json. It's registered with logspout's HTTP server, so it's
+// available at /debug/containers.
+func containersHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		statuses := []ContainerStatus{}
+		for _, a := range adapters.all() {
+			statuses = append(statuses, a.containers.snapshot(a.route.ID)...)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(statuses); err != nil {
+			log.WithError(err).Error("Unable to write container status response")
+		}
+	})
+}
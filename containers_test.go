@@ -0,0 +1,64 @@
+package sumologic
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+func (ts *TestSuite) Test_containersHandler() {
+	ts.CaptureLogs()
+	code := int64(http.StatusOK)
+	adapter := ts.adapterFor(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(int(atomic.LoadInt64(&code)))
+		}), newRealClock())
+
+	// The stream is left open, since the route goes away when it's closed.
+	logstream := make(chan *router.Message)
+	go adapter.Stream(logstream)
+	ts.AddCleanup(func() { close(logstream) })
+	empty := mkContainerMessage("def", "/bar")
+	empty.Data = ""
+	logstream <- empty
+	logstream <- &router.Message{Data: "no container"}
+
+	msg := mkContainerMessage("abc", "/foo")
+	adapter.containers.received(msg)
+	ts.NoError(adapter.Send(msg))
+	atomic.StoreInt64(&code, http.StatusTooManyRequests)
+	adapter.containers.received(msg)
+	ts.Error(adapter.Send(msg))
+
+	recorder := httptest.NewRecorder()
+	containersHandler().ServeHTTP(recorder,
+		httptest.NewRequest(http.MethodGet, "/debug/containers", nil))
+	ts.Equal(http.StatusOK, recorder.Code)
+	ts.Equal("application/json", recorder.Header().Get("Content-Type"))
+	ts.JSONEq(`[
+		{"route": "foo", "id": "def", "name": "/bar", "received": 1,
+		 "sent": 0, "failed": 0, "dropped": 1},
+		{"route": "foo", "id": "abc", "name": "/foo", "received": 2,
+		 "sent": 1, "failed": 1, "dropped": 0,
+		 "last_error": "throttled: unexpected status code 429",
+		 "last_error_time": "`+adapter.containers.containers["abc"].lastErrorAt.Format("2006-01-02T15:04:05Z07:00")+`"}
+	]`, recorder.Body.String())
+}
+
+func (ts *TestSuite) Test_containerTracker_batches() {
+	ts.Setenv("SUMOLOGIC_BATCH_SIZE", "3")
+	requests := make(chan *batchRequest, 1)
+	adapter := ts.FakeSumoBatches(requests, newFakeClock(), 0)
+
+	adapter.sendLog(mkContainerMessage("abc", "foo"))
+	adapter.sendLog(mkContainerMessage("def", "foo"))
+	adapter.sendLog(mkContainerMessage("abc", "foo"))
+	<-requests
+
+	statuses := adapter.containers.snapshot("foo")
+	ts.Len(statuses, 2)
+	ts.EqualValues(2, statuses[0].Sent)
+	ts.EqualValues(1, statuses[1].Sent)
+}
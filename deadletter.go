@@ -0,0 +1,264 @@
+package sumologic
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// deadLetterSummaryInterval is how often a summary of the requests written
+// to the dead-letter directory is logged.
+const deadLetterSummaryInterval = time.Minute
+
+// deadLetterSuffix marks the files in the dead-letter directory that hold
+// requests, as opposed to ones still being written.
+const deadLetterSuffix = ".json"
+
+const (
+	// replayOldest replays buffered and dead-lettered requests in the order
+	// they were sent, so that they arrive in strict chronology.
+	replayOldest = "oldest"
+	// replayNewest replays the most recent requests first, so that the
+	// current state of things shows up as soon as possible.
+	replayNewest = "newest"
+)
+
+// getreplayorderopt retrieves the order buffered and dead-lettered requests
+// are replayed in.
+func (o routeOptions) getreplayorderopt(name string) string {
+	value := o.getopt(name, replayOldest)
+	if value != replayOldest && value != replayNewest {
+		parseFailed(name, value, nil)
+		return replayOldest
+	}
+	return value
+}
+
+// deadLetters keeps the requests that failed for good in a directory on
+// disk, along with why they failed, rather than discarding them, so that
+// they can be looked into and resubmitted with ReplayDeadLetters. Requests
+// that are buffered to be retried don't end up here unless they're later
+// dropped. Once the directory is full, new requests are discarded. A nil
+// *deadLetters doesn't keep anything.
+type deadLetters struct {
+	mu       sync.Mutex
+	dir      string
+	maxBytes int64
+	size     int64
+	seq      int64
+	// written, events and reasons count what has been written since the
+	// last summary.
+	written int64
+	events  int64
+	reasons map[string]int64
+}
+
+// deadLetter is a request as it's stored on disk.
+type deadLetter struct {
+	Time       string           `json:"time"`
+	Error      string           `json:"error"`
+	ErrorClass string           `json:"error_class"`
+	Headers    http.Header      `json:"headers"`
+	Body       string           `json:"body"`
+	Events     int64            `json:"events"`
+	Containers map[string]int64 `json:"containers,omitempty"`
+}
+
+// newDeadLetters returns dead letters for requests to endPoint kept in up to
+// maxMB megabytes of their own subdirectory of dir, or nil if no directory is
+// configured or it can't be used.
+func newDeadLetters(dir string, endPoint string, maxMB int64) *deadLetters {
+	if dir == "" {
+		return nil
+	}
+	dir = routeDir(dir, endPoint)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		log.WithError(err).WithField("dir", dir).Error(
+			"Unable to create dead-letter directory, discarding failed sends")
+		return nil
+	}
+	d := &deadLetters{
+		dir:      dir,
+		maxBytes: maxMB * 1024 * 1024,
+		reasons:  map[string]int64{},
+	}
+	for _, file := range d.files() {
+		d.size += file.size
+	}
+	return d
+}
+
+type deadLetterFile struct {
+	path string
+	size int64
+}
+
+// files returns the dead letters, oldest first.
+func (d *deadLetters) files() []deadLetterFile {
+	infos, err := ioutil.ReadDir(d.dir)
+	if err != nil {
+		log.WithError(err).Error("Unable to read dead-letter directory")
+		return nil
+	}
+	files := []deadLetterFile{}
+	for _, info := range infos {
+		if info.IsDir() || !strings.HasSuffix(info.Name(), deadLetterSuffix) {
+			continue
+		}
+		files = append(files, deadLetterFile{
+			path: filepath.Join(d.dir, info.Name()),
+			size: info.Size(),
+		})
+	}
+	// The names start with a zero-padded timestamp, so they sort by age.
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].path < files[j].path
+	})
+	return files
+}
+
+// add keeps a request holding the given number of events from each
+// container that failed with err.
+func (d *deadLetters) add(now time.Time, body []byte, headers http.Header,
+	containers map[string]int64, err error) {
+	if d == nil {
+		return
+	}
+	reason := maskError(err).Error()
+	events := countEvents(containers)
+	data, jsonErr := json.MarshalIndent(&deadLetter{
+		Time:       now.UTC().Format(time.RFC3339Nano),
+		Error:      reason,
+		ErrorClass: classifyError(err),
+		Headers:    headers,
+		Body:       string(body),
+		Events:     events,
+		Containers: containers,
+	}, "", "  ")
+	if jsonErr != nil {
+		log.WithError(jsonErr).Error("Unable to encode dead letter")
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.size+int64(len(data)) > d.maxBytes {
+		log.WithField("bytes", len(data)).Error(
+			"Dead-letter directory is full, discarding failed request")
+		return
+	}
+	d.seq++
+	name := fmt.Sprintf("%020d-%06d", now.UnixNano(), d.seq)
+	tmp := filepath.Join(d.dir, name+".tmp")
+	if writeErr := ioutil.WriteFile(tmp, data, 0600); writeErr != nil {
+		log.WithError(writeErr).Error("Unable to write dead letter")
+		os.Remove(tmp)
+		return
+	}
+	if renameErr := os.Rename(
+		tmp, filepath.Join(d.dir, name+deadLetterSuffix)); renameErr != nil {
+		log.WithError(renameErr).Error("Unable to write dead letter")
+		os.Remove(tmp)
+		return
+	}
+	d.size += int64(len(data))
+	d.written++
+	d.events += events
+	d.reasons[reason]++
+}
+
+// remove deletes a dead letter once it's been resubmitted.
+func (d *deadLetters) remove(file deadLetterFile) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := os.Remove(file.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	d.size -= file.size
+	return nil
+}
+
+// summarize logs how many requests have been written since the last
+// summary, and why, if there have been any.
+func (d *deadLetters) summarize() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.written == 0 {
+		return
+	}
+	log.WithFields(log.Fields{
+		"dir":      d.dir,
+		"requests": d.written,
+		"events":   d.events,
+		"reasons":  d.reasons,
+	}).Warn("Wrote failed requests to the dead-letter directory")
+	d.written = 0
+	d.events = 0
+	d.reasons = map[string]int64{}
+}
+
+// reportDeadLetters logs a summary of recent dead letters.
+func (s *Adapter) reportDeadLetters() {
+	defer s.recoverPanic("reportDeadLetters")
+	s.deadLetters.summarize()
+}
+
+// ReplayDeadLetters resubmits the requests to the adapter's endpoint in a
+// dead-letter directory (laid out like SUMOLOGIC_DEAD_LETTER_DIR) to that
+// endpoint, in SUMOLOGIC_REPLAY_ORDER, removing each one once it's
+// delivered. Other endpoints' requests are left alone. It stops at the first
+// one that fails, and returns how many were delivered. It lets dead letters
+// be replayed once whatever made them fail is fixed, e.g.
+//
+//	adapter, _ := sumologic.NewAdapter(&router.Route{ID: "replay"})
+//	replayed, err := adapter.(*sumologic.Adapter).ReplayDeadLetters(dir)
+func (s *Adapter) ReplayDeadLetters(dir string) (int, error) {
+	dir = routeDir(dir, s.config().endPoint)
+	letters := s.deadLetters
+	if letters == nil || letters.dir != dir {
+		letters = &deadLetters{dir: dir}
+	}
+	files := letters.files()
+	if s.config().replayOrder == replayNewest {
+		for i, j := 0, len(files)-1; i < j; i, j = i+1, j-1 {
+			files[i], files[j] = files[j], files[i]
+		}
+	}
+	replayed := 0
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file.path)
+		if err != nil {
+			return replayed, err
+		}
+		letter := &deadLetter{}
+		if err = json.Unmarshal(data, letter); err != nil {
+			return replayed, fmt.Errorf("%s: %v", file.path, err)
+		}
+		body := []byte(letter.Body)
+		ctx, cancel := s.deliveryContext()
+		err = s.attempt(ctx, body, letter.Headers, letter.Events)
+		cancel()
+		if payloadTooLarge(err) {
+			s.deliveryFailed(err.(*SendError).Err)
+		}
+		if err != nil {
+			return replayed, err
+		}
+		s.audit.record(s.clock.Now(), letter.Containers, int64(len(body)),
+			letter.Headers.Get("X-Sumo-Category"), auditSent, nil)
+		if err = letters.remove(file); err != nil {
+			return replayed, err
+		}
+		replayed++
+	}
+	return replayed, nil
+}
@@ -0,0 +1,240 @@
+package sumologic
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+// deadLettered returns the dead letters in a directory, oldest first.
+func (ts *TestSuite) deadLettered(dir string) []deadLetter {
+	letters := []deadLetter{}
+	for _, file := range (&deadLetters{dir: dir}).files() {
+		letter := deadLetter{}
+		ts.NoError(json.Unmarshal(
+			ts.WithoutError(ioutil.ReadFile(file.path)).([]byte), &letter))
+		letters = append(letters, letter)
+	}
+	return letters
+}
+
+func (ts *TestSuite) Test_deadLetters_disabled_by_default() {
+	adapter := ts.mkAdapter(&router.Route{Address: "https://example.com/"})
+	ts.Nil(adapter.deadLetters)
+
+	var d *deadLetters
+	d.add(mkTime(0), []byte("{}"), http.Header{}, nil, errors.New("x"))
+}
+
+func (ts *TestSuite) Test_deadLetters_keep_permanent_failures() {
+	ts.CaptureLogs()
+	code := int64(http.StatusBadRequest)
+	adapter, _ := ts.FakeFlakySumo(
+		&code, make(chan *RequestData, 1), "SUMOLOGIC_DEAD_LETTER_DIR")
+
+	ts.Error(adapter.Send(mkContainerMessage("abc", "/foo")))
+	letters := ts.deadLettered(adapter.deadLetters.dir)
+	ts.Require().Len(letters, 1)
+	ts.Equal("permanent failure: unexpected status code 400", letters[0].Error)
+	ts.Equal(errorClassStatus, letters[0].ErrorClass)
+	ts.EqualValues(1, letters[0].Events)
+	ts.Equal(map[string]int64{"abc": 1}, letters[0].Containers)
+	ts.Equal("foo", letters[0].Headers.Get("X-Sumo-Name"))
+	body := jsonobj{}
+	ts.NoError(json.Unmarshal([]byte(letters[0].Body), &body))
+	ts.Equal("Some data.", body["message"])
+}
+
+func (ts *TestSuite) Test_deadLetters_keep_retryable_failures_without_buffer() {
+	ts.CaptureLogs()
+	code := int64(http.StatusServiceUnavailable)
+	adapter, _ := ts.FakeFlakySumo(
+		&code, make(chan *RequestData, 1), "SUMOLOGIC_DEAD_LETTER_DIR")
+
+	ts.Error(adapter.Send(mkLine("abc", "Some data.")))
+	letters := ts.deadLettered(adapter.deadLetters.dir)
+	ts.Require().Len(letters, 1)
+	ts.Equal("throttled: unexpected status code 503", letters[0].Error)
+}
+
+func (ts *TestSuite) Test_deadLetters_not_kept_for_successful_sends() {
+	code := int64(http.StatusOK)
+	requests := make(chan *RequestData, 1)
+	adapter, _ := ts.FakeFlakySumo(
+		&code, requests, "SUMOLOGIC_DEAD_LETTER_DIR")
+
+	ts.NoError(adapter.Send(mkLine("abc", "Some data.")))
+	<-requests
+	ts.Empty(ts.deadLettered(adapter.deadLetters.dir))
+}
+
+func (ts *TestSuite) Test_deadLetters_discard_once_full() {
+	hook, _ := ts.CaptureLogs()
+	dir := ts.WithoutError(ioutil.TempDir("", "deadletter")).(string)
+	ts.AddCleanup(func() { os.RemoveAll(dir) })
+	d := newDeadLetters(dir, "https://example.com/", 1)
+	d.maxBytes = 600
+
+	err := &SendError{Kind: ErrPermanent, Err: errors.New("rejected")}
+	body := []byte(strings.Repeat("x", 200))
+	d.add(mkTime(0), body, http.Header{}, map[string]int64{"abc": 1}, err)
+	d.add(mkTime(0), body, http.Header{}, map[string]int64{"abc": 1}, err)
+	ts.Len(ts.deadLettered(d.dir), 1)
+	ts.Equal("Dead-letter directory is full, discarding failed request",
+		hook.LastEntry().Message)
+
+	// Dead letters from a previous run count towards the size.
+	ts.Equal(d.size, newDeadLetters(dir, "https://example.com/", 1).size)
+}
+
+func (ts *TestSuite) Test_deadLetters_summarize() {
+	hook, _ := ts.CaptureLogs()
+	dir := ts.WithoutError(ioutil.TempDir("", "deadletter")).(string)
+	ts.AddCleanup(func() { os.RemoveAll(dir) })
+	d := newDeadLetters(dir, "https://example.com/", 1)
+
+	d.summarize()
+	ts.Nil(hook.LastEntry())
+
+	rejected := &SendError{Kind: ErrPermanent, Err: errors.New("rejected")}
+	d.add(mkTime(0), []byte("{}"), http.Header{},
+		map[string]int64{"abc": 2, "def": 1}, rejected)
+	d.add(mkTime(0), []byte("{}"), http.Header{},
+		map[string]int64{"abc": 1}, rejected)
+	d.summarize()
+	entry := hook.LastEntry()
+	ts.Equal("Wrote failed requests to the dead-letter directory", entry.Message)
+	ts.EqualValues(2, entry.Data["requests"])
+	ts.EqualValues(4, entry.Data["events"])
+	ts.Equal(map[string]int64{"permanent failure: rejected": 2},
+		entry.Data["reasons"])
+
+	hook.Reset()
+	d.summarize()
+	ts.Nil(hook.LastEntry())
+}
+
+func (ts *TestSuite) Test_ReplayDeadLetters() {
+	ts.CaptureLogs()
+	code := int64(http.StatusBadRequest)
+	requests := make(chan *RequestData, 2)
+	adapter, dir := ts.FakeFlakySumo(
+		&code, requests, "SUMOLOGIC_DEAD_LETTER_DIR")
+	ts.Error(adapter.Send(mkLine("abc", "one")))
+	ts.Error(adapter.Send(mkLine("abc", "two")))
+	ts.Len(ts.deadLettered(adapter.deadLetters.dir), 2)
+
+	replayed, err := adapter.ReplayDeadLetters(dir)
+	ts.Equal(0, replayed)
+	ts.Error(err)
+	ts.Len(ts.deadLettered(adapter.deadLetters.dir), 2,
+		"failed replays are kept")
+
+	atomic.StoreInt64(&code, http.StatusOK)
+	replayed, err = adapter.ReplayDeadLetters(dir)
+	ts.NoError(err)
+	ts.Equal(2, replayed)
+	ts.Equal("one", (<-requests).Body["message"])
+	ts.Equal("two", (<-requests).Body["message"])
+	ts.Empty(ts.deadLettered(adapter.deadLetters.dir))
+	ts.Zero(adapter.deadLetters.size)
+}
+
+func (ts *TestSuite) Test_ReplayDeadLetters_newest_first() {
+	ts.CaptureLogs()
+	ts.Setenv("SUMOLOGIC_REPLAY_ORDER", "newest")
+	code := int64(http.StatusBadRequest)
+	requests := make(chan *RequestData, 2)
+	adapter, dir := ts.FakeFlakySumo(
+		&code, requests, "SUMOLOGIC_DEAD_LETTER_DIR")
+	ts.Error(adapter.Send(mkLine("abc", "one")))
+	ts.Error(adapter.Send(mkLine("abc", "two")))
+
+	atomic.StoreInt64(&code, http.StatusOK)
+	replayed, err := adapter.ReplayDeadLetters(dir)
+	ts.NoError(err)
+	ts.Equal(2, replayed)
+	ts.Equal("two", (<-requests).Body["message"])
+	ts.Equal("one", (<-requests).Body["message"])
+}
+
+func (ts *TestSuite) Test_getreplayorderopt() {
+	ts.CaptureLogs()
+	ts.Equal(replayOldest, envOptions.getreplayorderopt("SUMOLOGIC_REPLAY_ORDER"))
+	ts.Setenv("SUMOLOGIC_REPLAY_ORDER", "newest")
+	ts.Equal(replayNewest, envOptions.getreplayorderopt("SUMOLOGIC_REPLAY_ORDER"))
+	ts.Setenv("SUMOLOGIC_REPLAY_ORDER", "sideways")
+	ts.Equal(replayOldest, envOptions.getreplayorderopt("SUMOLOGIC_REPLAY_ORDER"))
+}
+
+func (ts *TestSuite) Test_ReplayDeadLetters_from_another_directory() {
+	ts.CaptureLogs()
+	code := int64(http.StatusBadRequest)
+	requests := make(chan *RequestData, 1)
+	adapter, _ := ts.FakeFlakySumo(
+		&code, requests, "SUMOLOGIC_DEAD_LETTER_DIR")
+	ts.Error(adapter.Send(mkLine("abc", "one")))
+
+	other := ts.WithoutError(ioutil.TempDir("", "deadletter")).(string)
+	ts.AddCleanup(func() { os.RemoveAll(other) })
+	otherLetters := routeDir(other, adapter.config().endPoint)
+	ts.Require().NoError(os.MkdirAll(otherLetters, 0700))
+	for _, file := range adapter.deadLetters.files() {
+		ts.Require().NoError(os.Rename(file.path,
+			filepath.Join(otherLetters, filepath.Base(file.path))))
+	}
+	ts.Require().NoError(ioutil.WriteFile(
+		filepath.Join(otherLetters, "notes.txt"), []byte("ignored"), 0600))
+
+	atomic.StoreInt64(&code, http.StatusOK)
+	replayed, err := adapter.ReplayDeadLetters(other)
+	ts.NoError(err)
+	ts.Equal(1, replayed)
+	ts.Equal("one", (<-requests).Body["message"])
+	ts.Empty(ts.deadLettered(otherLetters))
+	ts.Empty(ts.deadLettered(adapter.deadLetters.dir))
+}
+
+func (ts *TestSuite) Test_ReplayDeadLetters_leaves_other_endpoints_alone() {
+	ts.CaptureLogs()
+	code := int64(http.StatusBadRequest)
+	requests := make(chan *RequestData, 1)
+	adapter, dir := ts.FakeFlakySumo(
+		&code, requests, "SUMOLOGIC_DEAD_LETTER_DIR")
+	other := newDeadLetters(dir, "https://example.com/other", 1)
+	other.add(mkTime(0), []byte("{}"), http.Header{},
+		map[string]int64{"abc": 1}, errors.New("rejected"))
+	ts.Error(adapter.Send(mkLine("abc", "one")))
+	ts.NotEqual(other.dir, adapter.deadLetters.dir)
+	ts.Equal(adapter.deadLetters.size,
+		newDeadLetters(dir, adapter.config().endPoint, 1).size,
+		"other endpoints' letters don't count towards the size")
+
+	atomic.StoreInt64(&code, http.StatusOK)
+	replayed, err := adapter.ReplayDeadLetters(dir)
+	ts.NoError(err)
+	ts.Equal(1, replayed)
+	ts.Equal("one", (<-requests).Body["message"])
+	ts.Len(ts.deadLettered(other.dir), 1)
+}
+
+func (ts *TestSuite) Test_ReplayDeadLetters_bad_file() {
+	adapter := ts.mkAdapter(&router.Route{Address: noServer})
+	dir := ts.WithoutError(ioutil.TempDir("", "deadletter")).(string)
+	ts.AddCleanup(func() { os.RemoveAll(dir) })
+	letters := routeDir(dir, adapter.config().endPoint)
+	ts.Require().NoError(os.MkdirAll(letters, 0700))
+	path := filepath.Join(letters, "0-0"+deadLetterSuffix)
+	ts.Require().NoError(ioutil.WriteFile(path, []byte("garbage"), 0600))
+
+	replayed, err := adapter.ReplayDeadLetters(dir)
+	ts.Equal(0, replayed)
+	ts.Contains(err.Error(), path)
+}
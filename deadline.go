@@ -0,0 +1,33 @@
+package sumologic
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// deliveryContext returns the context to deliver a request in. It's the
+// adapter's own context, limited to SUMOLOGIC_DELIVERY_DEADLINE_MS if that's
+// set, so that a request can't hold up fresher ones by retrying (or waiting
+// for its turn to be sent) for longer than that in total. Each attempt at
+// sending it is still limited by SUMOLOGIC_TIMEOUT_MS as well.
+func (s *Adapter) deliveryContext() (context.Context, context.CancelFunc) {
+	deadline := s.config().deliveryDeadline
+	if deadline <= 0 {
+		return context.WithCancel(s.ctx)
+	}
+	return context.WithTimeout(s.ctx, time.Duration(deadline)*time.Millisecond)
+}
+
+// abandoned records a request that was given up on before it could be sent.
+// Running out of time counts as a failed delivery that may be worth retrying
+// later, but the adapter being closed doesn't.
+func (s *Adapter) abandoned(err error) error {
+	log.WithError(err).Error("Failed to send log to Sumologic")
+	if err != context.DeadlineExceeded {
+		return err
+	}
+	s.deliveryFailed(err)
+	return &SendError{Kind: ErrNetwork, Err: err}
+}
@@ -0,0 +1,64 @@
+package sumologic
+
+import (
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+func (ts *TestSuite) Test_deliveryContext_deadline() {
+	adapter := ts.mkAdapter(&router.Route{Address: noServer})
+	ctx, cancel := adapter.deliveryContext()
+	_, ok := ctx.Deadline()
+	ts.False(ok)
+	cancel()
+
+	ts.Setenv("SUMOLOGIC_DELIVERY_DEADLINE_MS", "5000")
+	adapter = ts.mkAdapter(&router.Route{Address: noServer})
+	ctx, cancel = adapter.deliveryContext()
+	defer cancel()
+	deadline, ok := ctx.Deadline()
+	ts.True(ok)
+	ts.WithinDuration(time.Now().Add(5*time.Second), deadline, time.Second)
+}
+
+func (ts *TestSuite) Test_Send_gives_up_at_delivery_deadline() {
+	ts.CaptureLogs()
+	ts.Setenv("SUMOLOGIC_DELIVERY_DEADLINE_MS", "50")
+	adapter := ts.adapterFor(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			// The body has to be read for the server to notice the client
+			// giving up.
+			ioutil.ReadAll(r.Body)
+			<-r.Context().Done()
+		}), newRealClock())
+
+	// Well within the 10s SUMOLOGIC_TIMEOUT_MS for the attempt itself.
+	start := time.Now()
+	err := adapter.Send(mkContainerMessage("abc", "/foo"))
+	ts.True(time.Since(start) < 5*time.Second)
+	ts.True(spoolable(err))
+	ts.Equal(errorClassTimeout, classifyError(err))
+	ts.Equal(map[string]int64{"timeout": 1}, adapter.Status().Failures)
+}
+
+func (ts *TestSuite) Test_Send_strict_delivery_stops_at_delivery_deadline() {
+	ts.CaptureLogs()
+	ts.Setenv("SUMOLOGIC_STRICT_DELIVERY", "true")
+	ts.Setenv("SUMOLOGIC_RETRIES", "0")
+	ts.Setenv("SUMOLOGIC_DELIVERY_DEADLINE_MS", "50")
+	adapter := ts.adapterFor(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}), newFakeClock())
+
+	// The strict retry never comes round on the fake clock, so only the
+	// deadline can end the delivery. The last failure is kept, so the request
+	// can still be buffered.
+	err := adapter.Send(mkContainerMessage("abc", "/foo"))
+	ts.True(spoolable(err))
+	ts.Equal(ErrThrottled, err.(*SendError).Kind)
+	ts.False(adapter.Status().Stalled)
+}
@@ -0,0 +1,50 @@
+package sumologic
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+// collectorHosts maps each Sumo Logic deployment to the host its HTTP
+// sources receive data on.
+var collectorHosts = map[string]string{
+	"us1": "collectors.sumologic.com",
+	"us2": "collectors.us2.sumologic.com",
+	"eu":  "collectors.eu.sumologic.com",
+	"de":  "collectors.de.sumologic.com",
+	"au":  "collectors.au.sumologic.com",
+	"jp":  "collectors.jp.sumologic.com",
+	"ca":  "collectors.ca.sumologic.com",
+	"in":  "collectors.in.sumologic.com",
+	"kr":  "collectors.kr.sumologic.com",
+	"ch":  "collectors.ch.sumologic.com",
+	"fed": "collectors.fed.sumologic.com",
+}
+
+// getendpointopt retrieves the endpoint to send logs to. SUMOLOGIC_ENDPOINT
+// is used if it's set. Otherwise the endpoint is built from
+// SUMOLOGIC_DEPLOYMENT and SUMOLOGIC_COLLECTOR_TOKEN if they're set, falling
+// back to the route's address.
+func (o routeOptions) getendpointopt(route *router.Route) string {
+	deployment := strings.ToLower(o.getopt("SUMOLOGIC_DEPLOYMENT", ""))
+	if deployment == "" {
+		return o.getopt("SUMOLOGIC_ENDPOINT", route.Address)
+	}
+	host, ok := collectorHosts[deployment]
+	if !ok {
+		parseFailed("SUMOLOGIC_DEPLOYMENT", deployment,
+			errors.New("unknown deployment"))
+		return o.getopt("SUMOLOGIC_ENDPOINT", route.Address)
+	}
+	token := strings.TrimSpace(o.getopt("SUMOLOGIC_COLLECTOR_TOKEN", ""))
+	if token == "" {
+		parseFailed("SUMOLOGIC_COLLECTOR_TOKEN", token,
+			errors.New("required with SUMOLOGIC_DEPLOYMENT"))
+		return o.getopt("SUMOLOGIC_ENDPOINT", route.Address)
+	}
+	return o.getopt("SUMOLOGIC_ENDPOINT",
+		"https://"+host+"/receiver/v1/http/"+url.PathEscape(token))
+}
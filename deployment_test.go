@@ -0,0 +1,46 @@
+package sumologic
+
+import (
+	"github.com/gliderlabs/logspout/router"
+)
+
+func (ts *TestSuite) Test_getendpointopt_from_deployment() {
+	ts.Setenv("SUMOLOGIC_DEPLOYMENT", "US2")
+	ts.Setenv("SUMOLOGIC_COLLECTOR_TOKEN", " Zm9vCg== ")
+	ts.Equal("https://collectors.us2.sumologic.com/receiver/v1/http/Zm9vCg==",
+		envOptions.getendpointopt(&router.Route{Address: "https://example.com/"}))
+
+	ts.Setenv("SUMOLOGIC_DEPLOYMENT", "us1")
+	ts.Equal("https://collectors.sumologic.com/receiver/v1/http/Zm9vCg==",
+		envOptions.getendpointopt(&router.Route{}))
+}
+
+func (ts *TestSuite) Test_getendpointopt_explicit_endpoint_wins() {
+	ts.Setenv("SUMOLOGIC_DEPLOYMENT", "eu")
+	ts.Setenv("SUMOLOGIC_COLLECTOR_TOKEN", "Zm9vCg==")
+	ts.Setenv("SUMOLOGIC_ENDPOINT", "https://example.com/explicit")
+	ts.Equal("https://example.com/explicit", envOptions.getendpointopt(&router.Route{}))
+}
+
+func (ts *TestSuite) Test_getendpointopt_without_deployment() {
+	ts.Equal("https://example.com/",
+		envOptions.getendpointopt(&router.Route{Address: "https://example.com/"}))
+}
+
+func (ts *TestSuite) Test_getendpointopt_bad_deployment() {
+	hook, _ := ts.CaptureLogs()
+	ts.Setenv("SUMOLOGIC_DEPLOYMENT", "mars")
+	ts.Setenv("SUMOLOGIC_COLLECTOR_TOKEN", "Zm9vCg==")
+	ts.Equal("https://example.com/",
+		envOptions.getendpointopt(&router.Route{Address: "https://example.com/"}))
+	ts.Equal("Failed to parse", hook.LastEntry().Message)
+	ts.Equal("mars", hook.LastEntry().Data["SUMOLOGIC_DEPLOYMENT"])
+}
+
+func (ts *TestSuite) Test_getendpointopt_missing_token() {
+	ts.Setenv("SUMOLOGIC_DEPLOYMENT", "au")
+	problems := validateConfig(&router.Route{})
+	ts.Contains(problems, `SUMOLOGIC_COLLECTOR_TOKEN: can't parse "": `+
+		"required with SUMOLOGIC_DEPLOYMENT")
+	ts.Contains(problems, "SUMOLOGIC_ENDPOINT: not set")
+}
@@ -0,0 +1,135 @@
+package sumologic
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Error classes reported in logs and delivery status.
+const (
+	errorClassDNS      = "dns"
+	errorClassTimeout  = "timeout"
+	errorClassConnect  = "connect"
+	errorClassCanceled = "canceled"
+	errorClassStatus   = "status"
+	errorClassOther    = "other"
+)
+
+// statusError is returned for requests that got a response, but not a
+// successful one.
+type statusError struct {
+	code int
+}
+
+func (e *statusError) Error() string {
+	return "unexpected status code " + strconv.Itoa(e.code)
+}
+
+// classifyError works out what kind of failure a send error represents, so
+// that e.g. DNS failures can be told apart from timeouts.
+func classifyError(err error) string {
+	for {
+		switch e := err.(type) {
+		case *statusError:
+			return errorClassStatus
+		case *net.DNSError:
+			return errorClassDNS
+		case *maskedError:
+			err = e.err
+			continue
+		case *SendError:
+			err = e.Err
+			continue
+		case *url.Error:
+			err = e.Err
+			continue
+		case *net.OpError:
+			if e.Timeout() {
+				return errorClassTimeout
+			}
+			if _, ok := e.Err.(*net.DNSError); ok {
+				return errorClassDNS
+			}
+			if e.Op == "dial" {
+				return errorClassConnect
+			}
+			return errorClassOther
+		case net.Error:
+			if e.Timeout() {
+				return errorClassTimeout
+			}
+		}
+		if err == context.Canceled {
+			return errorClassCanceled
+		}
+		if err == context.DeadlineExceeded {
+			return errorClassTimeout
+		}
+		return errorClassOther
+	}
+}
+
+// dnsChecker resolves the endpoint's host before each send, caching the
+// result, so that sends fail fast (and are reported as DNS failures) while
+// the host can't be resolved. A nil *dnsChecker doesn't check anything.
+type dnsChecker struct {
+	mu      sync.Mutex
+	clock   Clock
+	ttl     time.Duration
+	resolve func(ctx context.Context, host string) ([]string, error)
+	results map[string]dnsResult
+}
+
+type dnsResult struct {
+	err     error
+	expires time.Time
+}
+
+// newDNSChecker returns a dnsChecker that caches results for the given
+// duration, or nil if it isn't enabled.
+func newDNSChecker(clock Clock, enabled bool, ttl time.Duration) *dnsChecker {
+	if !enabled {
+		return nil
+	}
+	return &dnsChecker{
+		clock:   clock,
+		ttl:     ttl,
+		resolve: net.DefaultResolver.LookupHost,
+		results: map[string]dnsResult{},
+	}
+}
+
+// check returns an error if the endpoint's host can't be resolved. Both
+// successful and failed lookups are cached.
+func (c *dnsChecker) check(ctx context.Context, endPoint string) error {
+	if c == nil {
+		return nil
+	}
+	u, err := url.Parse(endPoint)
+	if err != nil || u.Hostname() == "" || net.ParseIP(u.Hostname()) != nil {
+		return nil
+	}
+	host := u.Hostname()
+
+	c.mu.Lock()
+	result, ok := c.results[host]
+	c.mu.Unlock()
+	if ok && c.clock.Now().Before(result.expires) {
+		return result.err
+	}
+
+	_, err = c.resolve(ctx, host)
+	if err != nil {
+		if _, ok := err.(*net.DNSError); !ok {
+			err = &net.DNSError{Err: err.Error(), Name: host}
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.results[host] = dnsResult{err: err, expires: c.clock.Now().Add(c.ttl)}
+	return err
+}
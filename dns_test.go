@@ -0,0 +1,99 @@
+package sumologic
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func (ts *TestSuite) Test_classifyError() {
+	dnsErr := &net.DNSError{Err: "no such host", Name: "example.invalid"}
+	wrap := func(err error) error {
+		return &url.Error{Op: "Post", URL: "https://example.com", Err: err}
+	}
+
+	ts.Equal(errorClassDNS, classifyError(dnsErr))
+	ts.Equal(errorClassDNS, classifyError(wrap(dnsErr)))
+	ts.Equal(errorClassDNS, classifyError(wrap(&net.OpError{
+		Op: "dial", Err: dnsErr})))
+	ts.Equal(errorClassTimeout, classifyError(wrap(&net.OpError{
+		Op: "dial", Err: timeoutError{}})))
+	ts.Equal(errorClassTimeout, classifyError(wrap(timeoutError{})))
+	ts.Equal(errorClassConnect, classifyError(wrap(&net.OpError{
+		Op: "dial", Err: errors.New("connection refused")})))
+	ts.Equal(errorClassCanceled, classifyError(wrap(context.Canceled)))
+	ts.Equal(errorClassTimeout, classifyError(context.DeadlineExceeded))
+	ts.Equal(errorClassStatus, classifyError(&statusError{503}))
+	ts.Equal(errorClassOther, classifyError(errors.New("boom")))
+}
+
+func (ts *TestSuite) Test_newDNSChecker_disabled() {
+	checker := newDNSChecker(newFakeClock(), false, time.Minute)
+	ts.Nil(checker)
+	ts.NoError(checker.check(context.Background(), "https://example.invalid"))
+}
+
+func (ts *TestSuite) Test_dnsChecker_caches_results() {
+	clock := newFakeClock()
+	checker := newDNSChecker(clock, true, time.Minute)
+	lookups := 0
+	checker.resolve = func(ctx context.Context, host string) ([]string, error) {
+		lookups++
+		if host == "bad.example.com" {
+			return nil, errors.New("no such host")
+		}
+		return []string{"127.0.0.1"}, nil
+	}
+
+	ts.NoError(checker.check(context.Background(), "https://example.com/x"))
+	ts.NoError(checker.check(context.Background(), "https://example.com/y"))
+	ts.Equal(1, lookups)
+
+	err := checker.check(context.Background(), "https://bad.example.com/x")
+	ts.EqualError(err, "lookup bad.example.com: no such host")
+	ts.Equal(errorClassDNS, classifyError(err))
+	ts.Error(checker.check(context.Background(), "https://bad.example.com/x"))
+	ts.Equal(2, lookups)
+
+	clock.Advance(time.Minute)
+	ts.NoError(checker.check(context.Background(), "https://example.com/x"))
+	ts.Equal(3, lookups)
+}
+
+func (ts *TestSuite) Test_dnsChecker_skips_ip_addresses() {
+	checker := newDNSChecker(newFakeClock(), true, time.Minute)
+	checker.resolve = func(ctx context.Context, host string) ([]string, error) {
+		ts.Fail("Shouldn't resolve an IP address.")
+		return nil, nil
+	}
+	ts.NoError(checker.check(context.Background(), "http://127.0.0.1:8080/"))
+	ts.NoError(checker.check(context.Background(), ""))
+}
+
+func (ts *TestSuite) Test_sendLog_dns_precheck_failure() {
+	hook, _ := ts.CaptureLogs()
+	ts.Setenv("SUMOLOGIC_DNS_PRECHECK", "true")
+	adapter := ts.mkAdapter(&router.Route{
+		ID:      "foo",
+		Address: "https://collectors.example.invalid/receiver",
+	})
+	adapter.dns.resolve = func(
+		ctx context.Context, host string) ([]string, error) {
+		return nil, &net.DNSError{Err: "no such host", Name: host}
+	}
+
+	adapter.sendLog(mkContainerMessage("abc", "foo"))
+	ts.Equal("Unable to resolve Sumologic endpoint", hook.LastEntry().Message)
+	ts.Equal(errorClassDNS, hook.LastEntry().Data["error_class"])
+	ts.Equal(map[string]int64{errorClassDNS: 1}, adapter.Status().Failures)
+}
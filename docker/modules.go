@@ -1,7 +1,19 @@
 package main
 
 import (
+	"os"
+	"strconv"
+
 	_ "github.com/gliderlabs/logspout/httpstream"
 	_ "github.com/gliderlabs/logspout/routesapi"
-	_ "github.com/praekeltfoundation/logspout-sumologic"
+	"github.com/praekeltfoundation/logspout-sumologic"
 )
+
+// init checks the config and exits, rather than letting logspout start, if
+// SUMOLOGIC_VALIDATE_CONFIG is set.
+func init() {
+	if validate, _ := strconv.ParseBool(
+		os.Getenv("SUMOLOGIC_VALIDATE_CONFIG")); validate {
+		os.Exit(sumologic.ValidateConfig(os.Stdout))
+	}
+}
@@ -0,0 +1,135 @@
+package sumologic
+
+import (
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+// encodingLabel is the label a container can set to override
+// SUMOLOGIC_ENCODING for its own output.
+const encodingLabel = "sumologic.encoding"
+
+// Byte order marks.
+const (
+	bomUTF8    = "\xef\xbb\xbf"
+	bomUTF16LE = "\xff\xfe"
+	bomUTF16BE = "\xfe\xff"
+)
+
+// Encodings that container output can be transcoded from.
+const (
+	encodingAuto    = "auto"
+	encodingUTF8    = "utf-8"
+	encodingLatin1  = "latin-1"
+	encodingUTF16   = "utf-16"
+	encodingUTF16LE = "utf-16le"
+	encodingUTF16BE = "utf-16be"
+)
+
+// encodingAliases maps other common names for the encodings to theirs.
+var encodingAliases = map[string]string{
+	encodingAuto:    encodingAuto,
+	encodingUTF8:    encodingUTF8,
+	"utf8":          encodingUTF8,
+	encodingLatin1:  encodingLatin1,
+	"latin1":        encodingLatin1,
+	"iso-8859-1":    encodingLatin1,
+	encodingUTF16:   encodingUTF16,
+	encodingUTF16LE: encodingUTF16LE,
+	encodingUTF16BE: encodingUTF16BE,
+}
+
+// getencodingopt retrieves the encoding container output is in. It returns
+// "" if the output should be left alone.
+func (o routeOptions) getencodingopt(name string) string {
+	value := o.getopt(name, "")
+	if value == "" {
+		return ""
+	}
+	encoding, ok := encodingAliases[strings.ToLower(value)]
+	if !ok {
+		parseFailed(name, value, nil)
+		return ""
+	}
+	return encoding
+}
+
+// normalizeEncoding returns a copy of a message with its output transcoded
+// to UTF-8 from the encoding its container's label or SUMOLOGIC_ENCODING
+// says it's in, so that it's searchable rather than arriving as mojibake.
+// Messages that don't need transcoding are returned unchanged.
+func normalizeEncoding(msg *router.Message, config *Config) *router.Message {
+	encoding := config.encoding
+	if msg.Container != nil && msg.Container.Config != nil {
+		if label, ok := msg.Container.Config.Labels[encodingLabel]; ok {
+			encoding = encodingAliases[strings.ToLower(label)]
+		}
+	}
+	if encoding == "" {
+		return msg
+	}
+	data := decode(msg.Data, encoding)
+	if data == msg.Data {
+		return msg
+	}
+	normalized := *msg
+	normalized.Data = data
+	return &normalized
+}
+
+// decode transcodes text in an encoding to UTF-8, dropping any byte order
+// mark. In auto mode, text with a UTF-16 byte order mark is decoded as
+// UTF-16, and anything else that isn't valid UTF-8 is taken to be latin-1.
+func decode(text string, encoding string) string {
+	switch {
+	case strings.HasPrefix(text, bomUTF8):
+		return text[len(bomUTF8):]
+	case encoding == encodingUTF8:
+		return text
+	case strings.HasPrefix(text, bomUTF16LE) &&
+		(encoding == encodingAuto || encoding == encodingUTF16):
+		return decodeUTF16(text[len(bomUTF16LE):], false)
+	case strings.HasPrefix(text, bomUTF16BE) &&
+		(encoding == encodingAuto || encoding == encodingUTF16):
+		return decodeUTF16(text[len(bomUTF16BE):], true)
+	case encoding == encodingUTF16 || encoding == encodingUTF16LE:
+		return decodeUTF16(strings.TrimPrefix(text, bomUTF16LE), false)
+	case encoding == encodingUTF16BE:
+		return decodeUTF16(strings.TrimPrefix(text, bomUTF16BE), true)
+	case encoding == encodingLatin1 ||
+		(encoding == encodingAuto && !utf8.ValidString(text)):
+		return decodeLatin1(text)
+	}
+	return text
+}
+
+// decodeLatin1 transcodes latin-1 text, in which each byte is the code point
+// of the same value.
+func decodeLatin1(text string) string {
+	runes := make([]rune, len(text))
+	for i := 0; i < len(text); i++ {
+		runes[i] = rune(text[i])
+	}
+	return string(runes)
+}
+
+// decodeUTF16 transcodes UTF-16 text. Docker splits output into lines at
+// each newline byte, so a little-endian line after the first starts with the
+// zero byte left over from the newline before it, which is skipped.
+func decodeUTF16(text string, bigEndian bool) string {
+	if !bigEndian && len(text)%2 == 1 && text[0] == 0 {
+		text = text[1:]
+	}
+	units := make([]uint16, len(text)/2)
+	for i := range units {
+		hi, lo := text[2*i], text[2*i+1]
+		if !bigEndian {
+			hi, lo = lo, hi
+		}
+		units[i] = uint16(hi)<<8 | uint16(lo)
+	}
+	return string(utf16.Decode(units))
+}
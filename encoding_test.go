@@ -0,0 +1,57 @@
+package sumologic
+
+import (
+	"github.com/gliderlabs/logspout/router"
+)
+
+func (ts *TestSuite) Test_getencodingopt() {
+	ts.Equal("", envOptions.getencodingopt("SUMOLOGIC_ENCODING"))
+	ts.Setenv("SUMOLOGIC_ENCODING", "ISO-8859-1")
+	ts.Equal(encodingLatin1, envOptions.getencodingopt("SUMOLOGIC_ENCODING"))
+	ts.CaptureLogs()
+	ts.Setenv("SUMOLOGIC_ENCODING", "ebcdic")
+	ts.Equal("", envOptions.getencodingopt("SUMOLOGIC_ENCODING"))
+}
+
+func (ts *TestSuite) Test_decode() {
+	for _, c := range []struct{ text, encoding, expected string }{
+		{"caf\xc3\xa9", encodingAuto, "café"},
+		{bomUTF8 + "caf\xc3\xa9", encodingAuto, "café"},
+		{bomUTF8 + "caf\xc3\xa9", encodingUTF8, "café"},
+		{"caf\xe9", encodingAuto, "café"},
+		{"caf\xe9", encodingUTF8, "caf\xe9"},
+		{"caf\xc3\xa9", encodingLatin1, "cafÃ©"},
+		{bomUTF16LE + "c\x00a\x00f\x00\xe9\x00", encodingAuto, "café"},
+		{bomUTF16BE + "\x00c\x00a\x00f\x00\xe9", encodingAuto, "café"},
+		{"c\x00a\x00f\x00\xe9\x00", encodingUTF16, "café"},
+		{"\x00c\x00a\x00f\x00\xe9\x00", encodingUTF16LE, "café"},
+		{"\x00c\x00a\x00f\x00\xe9", encodingUTF16BE, "café"},
+		{"\xd8\x3d\xde\x00", encodingUTF16BE, "😀"},
+	} {
+		ts.Equal(c.expected, decode(c.text, c.encoding), c.text)
+	}
+}
+
+func (ts *TestSuite) Test_normalizeEncoding_label_overrides_config() {
+	ts.Setenv("SUMOLOGIC_ENCODING", "auto")
+	config := buildConfig(&router.Route{})
+	msg := mkContainerMessage("abc", "foo")
+	msg.Data = "caf\xe9"
+	normalized := normalizeEncoding(msg, config)
+	ts.Equal("café", normalized.Data)
+	ts.Equal("caf\xe9", msg.Data)
+
+	msg.Container.Config.Labels = map[string]string{encodingLabel: "utf-8"}
+	ts.Equal(msg, normalizeEncoding(msg, config))
+
+	ts.Setenv("SUMOLOGIC_ENCODING", "")
+	config = buildConfig(&router.Route{})
+	msg.Container.Config.Labels = map[string]string{encodingLabel: "latin1"}
+	ts.Equal("café", normalizeEncoding(msg, config).Data)
+}
+
+func (ts *TestSuite) Test_normalizeEncoding_disabled() {
+	msg := mkContainerMessage("abc", "foo")
+	msg.Data = "caf\xe9"
+	ts.Equal(msg, normalizeEncoding(msg, buildConfig(&router.Route{})))
+}
@@ -0,0 +1,127 @@
+package sumologic
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sync"
+
+	"github.com/gliderlabs/logspout/router"
+	log "github.com/sirupsen/logrus"
+)
+
+// endpointLabel is the label a container can set to send its logs to an
+// endpoint of its own, e.g. another team's collector. Only endpoints listed
+// in SUMOLOGIC_CONTAINER_ENDPOINTS are used.
+const endpointLabel = "sumologic.endpoint"
+
+// endpointHeader carries the ID of a message's own endpoint along with its
+// headers, so that it's batched, buffered and replayed with them without the
+// endpoint's token ending up on disk. It's removed before a request is sent.
+const endpointHeader = "X-Logspout-Sumologic-Endpoint-Id"
+
+// endpointID returns the ID an endpoint is referred to by in headers.
+func endpointID(endPoint string) string {
+	sum := sha256.Sum256([]byte(endPoint))
+	return hex.EncodeToString(sum[:8])
+}
+
+// getcontainerendpointsopt retrieves the endpoints containers may choose to
+// send to, keyed by their IDs.
+func (o routeOptions) getcontainerendpointsopt(name string) map[string]string {
+	endPoints := map[string]string{}
+	for _, endPoint := range splitEndpoints(o.getopt(name, "")) {
+		endPoints[endpointID(endPoint)] = endPoint
+	}
+	return endPoints
+}
+
+// containerEndpoint returns the ID of the endpoint a message's container has
+// asked to be sent to, with its endpointLabel or SUMOLOGIC_ENDPOINT_TEMPLATE,
+// or "" if it should go to the route's own endpoint. Endpoints that aren't
+// in SUMOLOGIC_CONTAINER_ENDPOINTS are ignored, so that a container can't
+// have logs sent anywhere the host hasn't approved.
+func containerEndpoint(msg *router.Message, config *Config) string {
+	if len(config.containerEndPoints) == 0 {
+		return ""
+	}
+	endPoint := ""
+	if msg.Container != nil && msg.Container.Config != nil {
+		endPoint = msg.Container.Config.Labels[endpointLabel]
+	}
+	if endPoint == "" && config.endPointTemplate != "" {
+		rendered, err := renderTemplate(msg, config.route, config.endPointTemplate)
+		if err != nil {
+			log.WithError(err).Error("Unable to render SUMOLOGIC_ENDPOINT_TEMPLATE")
+			return ""
+		}
+		endPoint = rendered
+	}
+	if endPoint == "" || endPoint == config.endPoint {
+		return ""
+	}
+	id := endpointID(endPoint)
+	if _, ok := config.containerEndPoints[id]; !ok {
+		log.WithField("endpoint", maskToken(endPoint)).Warn(
+			"Ignoring container's endpoint that isn't in SUMOLOGIC_CONTAINER_ENDPOINTS")
+		return ""
+	}
+	return id
+}
+
+// isContainerEndpoint reports whether an endpoint is one that containers
+// chose, rather than one of the route's own. Requests to them are sent
+// without the route's extra headers, signature or client certificate.
+func (config *Config) isContainerEndpoint(endPoint string) bool {
+	if _, ok := config.containerEndPoints[endpointID(endPoint)]; !ok {
+		return false
+	}
+	for _, routeEndPoint := range config.endPoints {
+		if endPoint == routeEndPoint {
+			return false
+		}
+	}
+	return true
+}
+
+// withoutEndpoint returns the headers to send a request with, leaving out
+// the ID of the endpoint it's sent to, if it has its own.
+func withoutEndpoint(headers http.Header) http.Header {
+	if headers.Get(endpointHeader) == "" {
+		return headers
+	}
+	headers = copyHeader(headers)
+	headers.Del(endpointHeader)
+	return headers
+}
+
+// endpointClients caches an adapter's clients for the endpoints containers
+// send to instead of the route's, so that each endpoint keeps its own
+// connections. There's at most one for each of SUMOLOGIC_CONTAINER_ENDPOINTS.
+// They're shared with other routes through clients, like the adapter's own
+// client.
+type endpointClients struct {
+	mu      sync.Mutex
+	clients map[string]*httpClient
+}
+
+func newEndpointClients() *endpointClients {
+	return &endpointClients{clients: map[string]*httpClient{}}
+}
+
+// clientFor returns the client for one of SUMOLOGIC_CONTAINER_ENDPOINTS. It
+// doesn't present the route's TLS client certificate.
+func (s *Adapter) clientFor(endPoint string) *httpClient {
+	c := s.endpointClients
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	client, ok := c.clients[endPoint]
+	if !ok {
+		config := *s.config()
+		config.endPoint = endPoint
+		config.tls.certFile, config.tls.keyFile = "", ""
+		client = clients.acquireWith(s, &config)
+		c.clients[endPoint] = client
+	}
+	return client
+}
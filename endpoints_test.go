@@ -0,0 +1,122 @@
+package sumologic
+
+import (
+	"net/http"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+// mkEndpointMessage returns a message from a container labelled with its own
+// endpoint.
+func mkEndpointMessage(id string, endPoint string) *router.Message {
+	msg := mkContainerMessage(id, "/"+id)
+	msg.Container.Config.Labels = map[string]string{endpointLabel: endPoint}
+	return msg
+}
+
+func (ts *TestSuite) Test_containerEndpoint_needs_allowed_endpoints() {
+	config := buildConfig(&router.Route{Address: "http://default.example.com"})
+	ts.Equal("", containerEndpoint(
+		mkEndpointMessage("abc", "https://other.example.com/receiver"), config))
+}
+
+func (ts *TestSuite) Test_containerEndpoint_label() {
+	hook, _ := ts.CaptureLogs()
+	ts.Setenv("SUMOLOGIC_CONTAINER_ENDPOINTS",
+		"https://other.example.com/receiver,http://default.example.com")
+	config := buildConfig(&router.Route{Address: "http://default.example.com"})
+	ts.Equal("", containerEndpoint(mkContainerMessage("abc", "/abc"), config))
+	ts.Equal("", containerEndpoint(&router.Message{Data: "no container"}, config))
+	ts.Equal(endpointID("https://other.example.com/receiver"),
+		containerEndpoint(
+			mkEndpointMessage("abc", "https://other.example.com/receiver"), config))
+	// The route's own endpoint isn't one of the container's own.
+	ts.Equal("", containerEndpoint(
+		mkEndpointMessage("abc", "http://default.example.com"), config))
+
+	ts.Equal("", containerEndpoint(
+		mkEndpointMessage("abc", "https://evil.example.com/receiver"), config))
+	var messages []string
+	for _, entry := range hook.AllEntries() {
+		messages = append(messages, entry.Message)
+	}
+	ts.Contains(messages,
+		"Ignoring container's endpoint that isn't in SUMOLOGIC_CONTAINER_ENDPOINTS")
+}
+
+func (ts *TestSuite) Test_containerEndpoint_template() {
+	ts.Setenv("SUMOLOGIC_CONTAINER_ENDPOINTS",
+		"http://abc.example.com,http://label.example.com")
+	ts.Setenv("SUMOLOGIC_ENDPOINT_TEMPLATE",
+		`{{if eq .Container.Name "/abc"}}http://abc.example.com{{end}}`)
+	config := buildConfig(&router.Route{Address: "http://default.example.com"})
+	ts.Equal(endpointID("http://abc.example.com"),
+		containerEndpoint(mkContainerMessage("abc", "/abc"), config))
+	ts.Equal("", containerEndpoint(mkContainerMessage("def", "/def"), config))
+	// The label takes precedence.
+	ts.Equal(endpointID("http://label.example.com"), containerEndpoint(
+		mkEndpointMessage("abc", "http://label.example.com"), config))
+}
+
+func (ts *TestSuite) Test_checkConfig_checks_container_endpoints() {
+	ts.Setenv("SUMOLOGIC_CONTAINER_ENDPOINTS", "ftp://other.example.com")
+	ts.Contains(validateConfig(&router.Route{Address: "http://example.com"}),
+		`SUMOLOGIC_CONTAINER_ENDPOINTS: "ftp://other.example.com" is not an http or https URL`)
+}
+
+func (ts *TestSuite) Test_clientFor_caches_clients_per_endpoint() {
+	adapter := ts.mkAdapter(&router.Route{Address: noServer})
+	a := adapter.clientFor("http://a.example.com")
+	ts.True(a == adapter.clientFor("http://a.example.com"))
+	ts.False(a == adapter.clientFor("http://b.example.com"))
+	ts.False(a == adapter.client)
+}
+
+func (ts *TestSuite) Test_clientFor_leaves_out_client_certificate() {
+	cert, key := ts.mkClientCert(ts.tlsDir())
+	ts.Setenv("SUMOLOGIC_TLS_CERT_FILE", cert)
+	ts.Setenv("SUMOLOGIC_TLS_KEY_FILE", key)
+	adapter := ts.mkAdapter(&router.Route{Address: noServer})
+	client := adapter.clientFor("https://other.example.com")
+
+	clients.mu.Lock()
+	defer clients.mu.Unlock()
+	for k, shared := range clients.clients {
+		switch shared.client {
+		case client:
+			ts.Equal(tlsOptions{}, k.tls)
+		case adapter.client:
+			ts.Equal(cert, k.tls.certFile)
+		}
+	}
+}
+
+func (ts *TestSuite) Test_Send_to_containers_own_endpoint() {
+	received := func() (string, chan http.Header) {
+		headers := make(chan http.Header, 1)
+		return ts.serve(http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) { headers <- r.Header })), headers
+	}
+	routeURL, routeHeaders := received()
+	ownURL, ownHeaders := received()
+	ts.Setenv("SUMOLOGIC_CONTAINER_ENDPOINTS", ownURL)
+	ts.Setenv("SUMOLOGIC_EXTRA_HEADERS", "Authorization: Bearer secret")
+	ts.Setenv("SUMOLOGIC_SIGNING_KEY", "secret")
+	adapter := ts.mkAdapter(&router.Route{ID: "foo", Address: routeURL})
+
+	ts.NoError(adapter.Send(mkEndpointMessage("abc", ownURL)))
+	headers := <-ownHeaders
+	ts.Equal("abc", headers.Get("X-Sumo-Name"))
+	ts.Equal("", headers.Get(endpointHeader))
+	// The route's credentials aren't sent to the container's endpoint.
+	ts.Equal("", headers.Get("Authorization"))
+	ts.Equal("", headers.Get(signatureHeader))
+	ts.Empty(routeHeaders)
+
+	ts.NoError(adapter.Send(mkContainerMessage("def", "/def")))
+	headers = <-routeHeaders
+	ts.Equal("def", headers.Get("X-Sumo-Name"))
+	ts.Equal("Bearer secret", headers.Get("Authorization"))
+	ts.NotEqual("", headers.Get(signatureHeader))
+	ts.Empty(ownHeaders)
+}
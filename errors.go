@@ -0,0 +1,83 @@
+package sumologic
+
+import (
+	"errors"
+	"net/http"
+)
+
+// The kinds of failure a send can end in. A failed Send returns a *SendError
+// whose Kind is one of these.
+var (
+	// ErrPermanent means the event was rejected and retrying it won't help.
+	ErrPermanent = errors.New("permanent failure")
+	// ErrThrottled means Sumologic is rate limiting the endpoint, so the
+	// event should be retried later.
+	ErrThrottled = errors.New("throttled")
+	// ErrNetwork means the endpoint couldn't be reached or didn't respond
+	// properly, so the event may be retried.
+	ErrNetwork = errors.New("network failure")
+	// ErrPayloadTooLarge means the request body was too large to accept, so
+	// it would need to be split up to be retried.
+	ErrPayloadTooLarge = errors.New("payload too large")
+)
+
+// SendError describes why a send failed.
+type SendError struct {
+	// Kind is ErrPermanent, ErrThrottled, ErrNetwork or ErrPayloadTooLarge.
+	Kind error
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *SendError) Error() string {
+	return e.Kind.Error() + ": " + e.Err.Error()
+}
+
+// Is lets errors.Is match a SendError against its Kind.
+func (e *SendError) Is(target error) bool {
+	return target == e.Kind
+}
+
+// Unwrap returns the underlying error.
+func (e *SendError) Unwrap() error {
+	return e.Err
+}
+
+// newSendError classifies an error from posting an event. Cancellation isn't
+// a delivery failure as such, so that error is returned as it is.
+func newSendError(err error) error {
+	if classifyError(err) == errorClassCanceled {
+		return err
+	}
+	if e, ok := err.(*statusError); ok {
+		return &SendError{Kind: kindForStatus(e.code), Err: err}
+	}
+	return &SendError{Kind: ErrNetwork, Err: err}
+}
+
+// kindForStatus works out what kind of failure a response status represents.
+func kindForStatus(code int) error {
+	switch {
+	case code == http.StatusRequestEntityTooLarge:
+		return ErrPayloadTooLarge
+	case code == http.StatusTooManyRequests ||
+		code == http.StatusServiceUnavailable:
+		return ErrThrottled
+	case code >= 500:
+		return ErrNetwork
+	}
+	return ErrPermanent
+}
+
+// payloadTooLarge reports whether a send failed because the request was too
+// large.
+func payloadTooLarge(err error) bool {
+	e, ok := err.(*SendError)
+	return ok && e.Kind == ErrPayloadTooLarge
+}
+
+// spoolable reports whether a failed send is worth keeping to retry later.
+func spoolable(err error) bool {
+	e, ok := err.(*SendError)
+	return ok && (e.Kind == ErrNetwork || e.Kind == ErrThrottled)
+}
@@ -0,0 +1,62 @@
+package sumologic
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+func (ts *TestSuite) Test_kindForStatus() {
+	ts.Equal(ErrPayloadTooLarge, kindForStatus(413))
+	ts.Equal(ErrThrottled, kindForStatus(429))
+	ts.Equal(ErrThrottled, kindForStatus(503))
+	ts.Equal(ErrNetwork, kindForStatus(502))
+	ts.Equal(ErrPermanent, kindForStatus(400))
+	ts.Equal(ErrPermanent, kindForStatus(401))
+}
+
+func (ts *TestSuite) Test_newSendError() {
+	err := newSendError(&statusError{429})
+	ts.Equal(&SendError{Kind: ErrThrottled, Err: &statusError{429}}, err)
+	ts.Equal("throttled: unexpected status code 429", err.Error())
+	ts.True(err.(*SendError).Is(ErrThrottled))
+	ts.False(err.(*SendError).Is(ErrNetwork))
+	ts.Equal(errorClassStatus, classifyError(err))
+
+	connErr := maskError(errors.New("connection refused"))
+	ts.Equal(&SendError{Kind: ErrNetwork, Err: connErr}, newSendError(connErr))
+	ts.Equal(context.Canceled, newSendError(context.Canceled))
+}
+
+func (ts *TestSuite) Test_Send_returns_error_kind() {
+	ts.CaptureLogs()
+	code := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(code)
+		}))
+	ts.AddCleanup(server.Close)
+	adapter := ts.mkAdapter(&router.Route{ID: "foo", Address: server.URL})
+	msg := mkContainerMessage("abc", "foo")
+
+	ts.NoError(adapter.Send(msg))
+	for status, kind := range map[int]error{
+		http.StatusRequestEntityTooLarge: ErrPayloadTooLarge,
+		http.StatusTooManyRequests:       ErrThrottled,
+		http.StatusBadGateway:            ErrNetwork,
+		http.StatusUnauthorized:          ErrPermanent,
+	} {
+		code = status
+		err := adapter.Send(msg)
+		ts.IsType(&SendError{}, err)
+		ts.Equal(kind, err.(*SendError).Kind)
+	}
+
+	server.Close()
+	err := adapter.Send(msg)
+	ts.IsType(&SendError{}, err)
+	ts.Equal(ErrNetwork, err.(*SendError).Kind)
+}
@@ -0,0 +1,49 @@
+package sumologic
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// headerName matches the characters a header name may be made of.
+var headerName = regexp.MustCompile("^[!#$%&'*+.^_`|~0-9A-Za-z-]+$")
+
+// getheadersopt retrieves an environment variable as a set of headers if
+// it's set to a non-empty string of semicolon-separated "Name: value" pairs,
+// e.g. "X-Tenant: acme; Proxy-Authorization: Bearer abc". Pairs that can't be
+// parsed are logged and ignored.
+func (o routeOptions) getheadersopt(name string) http.Header {
+	headers := http.Header{}
+	for _, pair := range strings.Split(o.lookupopt(name), ";") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, ":", 2)
+		key := strings.TrimSpace(kv[0])
+		if len(kv) != 2 || !headerName.MatchString(key) {
+			parseFailed(name, pair, errors.New("expected Name: value"))
+			continue
+		}
+		headers.Add(key, strings.TrimSpace(kv[1]))
+	}
+	return headers
+}
+
+// withExtraHeaders adds the SUMOLOGIC_EXTRA_HEADERS to a request's headers.
+// Headers the adapter sets itself take precedence. The headers are copied
+// rather than changed in place, since they may be shared with other
+// requests.
+func (config *Config) withExtraHeaders(headers http.Header) http.Header {
+	if len(config.extraHeaders) == 0 {
+		return headers
+	}
+	headers = copyHeader(headers)
+	for key, values := range config.extraHeaders {
+		if _, ok := headers[key]; !ok {
+			headers[key] = append([]string(nil), values...)
+		}
+	}
+	return headers
+}
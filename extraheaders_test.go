@@ -0,0 +1,46 @@
+package sumologic
+
+import "net/http"
+
+func (ts *TestSuite) Test_getheadersopt() {
+	ts.CaptureLogs()
+	ts.Setenv("SUMOLOGIC_EXTRA_HEADERS",
+		"X-Tenant: acme; x-other:two:parts;;Bad Name: x;missing")
+	ts.Equal(http.Header{
+		"X-Tenant": {"acme"},
+		"X-Other":  {"two:parts"},
+	}, envOptions.getheadersopt("SUMOLOGIC_EXTRA_HEADERS"))
+}
+
+func (ts *TestSuite) Test_withExtraHeaders() {
+	config := &Config{}
+	headers := http.Header{"X-Sumo-Category": {"prod"}}
+	ts.Equal(headers, config.withExtraHeaders(headers))
+
+	config.extraHeaders = http.Header{
+		"X-Tenant":        {"acme"},
+		"X-Sumo-Category": {"overridden"},
+	}
+	ts.Equal(http.Header{
+		"X-Tenant":        {"acme"},
+		"X-Sumo-Category": {"prod"},
+	}, config.withExtraHeaders(headers))
+	ts.Equal(http.Header{"X-Sumo-Category": {"prod"}}, headers,
+		"the original headers are unchanged")
+}
+
+func (ts *TestSuite) Test_Send_adds_extra_headers() {
+	ts.Setenv("SUMOLOGIC_EXTRA_HEADERS",
+		"X-Tenant: acme; Proxy-Authorization: Bearer abc")
+	received := make(chan http.Header, 1)
+	adapter := ts.adapterFor(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			received <- r.Header
+		}), newRealClock())
+
+	ts.NoError(adapter.Send(mkContainerMessage("abc", "/foo")))
+	headers := <-received
+	ts.Equal("acme", headers.Get("X-Tenant"))
+	ts.Equal("Bearer abc", headers.Get("Proxy-Authorization"))
+	ts.Equal("foo", headers.Get("X-Sumo-Name"))
+}
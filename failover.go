@@ -0,0 +1,134 @@
+package sumologic
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// failover sends to the next of the configured endpoints once the one in use
+// has failed enough times in a row, and fails back to the primary (the first
+// one) once it can be reached again. Only failures that may be down to the
+// endpoint count: those that can't reach it, are throttled or get a 5xx. A
+// nil *failover always uses the primary.
+type failover struct {
+	mu        sync.Mutex
+	threshold int64
+	active    int
+	failures  int64
+}
+
+// newFailover returns a failover for a config, or nil if there's only one
+// endpoint.
+func newFailover(config *Config) *failover {
+	if len(config.endPoints) < 2 {
+		return nil
+	}
+	threshold := config.failoverThreshold
+	if threshold <= 0 {
+		threshold = 1
+	}
+	return &failover{threshold: threshold}
+}
+
+// splitEndpoints splits a comma-separated list of endpoints.
+func splitEndpoints(value string) []string {
+	var endPoints []string
+	for _, endPoint := range strings.Split(value, ",") {
+		if endPoint = strings.TrimSpace(endPoint); endPoint != "" {
+			endPoints = append(endPoints, endPoint)
+		}
+	}
+	return endPoints
+}
+
+// endpoint returns the endpoint to send to.
+func (f *failover) endpoint(config *Config) string {
+	if f == nil {
+		return config.endPoint
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	// The endpoints may have been reloaded since the last failover.
+	if f.active >= len(config.endPoints) {
+		f.active = 0
+	}
+	return config.endPoints[f.active]
+}
+
+// failedOver reports whether an endpoint other than the primary is in use.
+func (f *failover) failedOver() bool {
+	if f == nil {
+		return false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.active != 0
+}
+
+// record counts the outcome of a send to an endpoint, failing over to the
+// next one if it's the one in use and it's failed too often.
+func (f *failover) record(config *Config, endPoint string, err error) {
+	if f == nil || (err != nil && !spoolable(err)) {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.active >= len(config.endPoints) || config.endPoints[f.active] != endPoint {
+		return
+	}
+	if err == nil {
+		f.failures = 0
+		return
+	}
+	f.failures++
+	if f.failures < f.threshold {
+		return
+	}
+	f.failures = 0
+	f.active = (f.active + 1) % len(config.endPoints)
+	log.WithFields(log.Fields{
+		"from": maskToken(endPoint),
+		"to":   maskToken(config.endPoints[f.active]),
+	}).Warn("Endpoint keeps failing, failing over")
+}
+
+// failBack goes back to the primary endpoint.
+func (f *failover) failBack() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.active = 0
+	f.failures = 0
+}
+
+// probePrimary fails back to the primary endpoint if it's not in use and it
+// accepts requests again. It's probed with an empty request, which doesn't
+// ingest anything, and only a 2xx response counts, so that an endpoint that
+// accepts connections but is still failing isn't failed back to.
+func (s *Adapter) probePrimary() {
+	defer s.recoverPanic("probePrimary")
+
+	if !s.failover.failedOver() {
+		return
+	}
+	config := s.config()
+	ctx, cancel := context.WithTimeout(
+		s.ctx, time.Duration(config.timeout)*time.Millisecond)
+	defer cancel()
+	resp, err := s.postWith(ctx, s.currentClient(), config.endPoint, nil,
+		http.Header{})
+	if err != nil {
+		return
+	}
+	closeBody(resp)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return
+	}
+	s.failover.failBack()
+	log.WithField("endpoint", maskToken(config.endPoint)).Info(
+		"Primary endpoint reachable again, failing back")
+}
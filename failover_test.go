@@ -0,0 +1,132 @@
+package sumologic
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+// FakeSumoEndpoints starts a fake Sumo Logic server for each status code,
+// which responds with whatever code is stored in it, and returns an Adapter
+// configured with all of them as endpoints, in order, along with a channel
+// that receives the index of the server each request arrives at.
+func (ts *TestSuite) FakeSumoEndpoints(codes ...*int64) (*Adapter, chan int) {
+	ts.Setenv("SUMOLOGIC_RETRIES", "0")
+	arrived := make(chan int, 10)
+	urls := ""
+	for i, code := range codes {
+		i, code := i, code
+		if i > 0 {
+			urls += ","
+		}
+		urls += ts.serve(http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				arrived <- i
+				w.WriteHeader(int(atomic.LoadInt64(code)))
+			}))
+	}
+	ts.Setenv("SUMOLOGIC_ENDPOINT", urls)
+	adapter := ts.WithoutError(NewAdapterWithClock(
+		&router.Route{ID: "foo"}, newFakeClock())).(*Adapter)
+	ts.AddCleanup(adapter.Close)
+	return adapter, arrived
+}
+
+func (ts *TestSuite) Test_splitEndpoints() {
+	ts.Equal([]string{"https://a.example.com/x", "https://b.example.com/y"},
+		splitEndpoints(" https://a.example.com/x,,https://b.example.com/y "))
+	ts.Nil(splitEndpoints(""))
+}
+
+func (ts *TestSuite) Test_failover_disabled_with_one_endpoint() {
+	adapter := ts.mkAdapter(&router.Route{Address: noServer})
+	ts.Nil(adapter.failover)
+	ts.Equal(noServer, adapter.failover.endpoint(adapter.config()))
+	ts.False(adapter.Status().FailedOver)
+}
+
+func (ts *TestSuite) Test_failover_after_repeated_failures() {
+	ts.CaptureLogs()
+	ts.Setenv("SUMOLOGIC_FAILOVER_THRESHOLD", "2")
+	primary, secondary := int64(http.StatusServiceUnavailable), int64(http.StatusOK)
+	adapter, arrived := ts.FakeSumoEndpoints(&primary, &secondary)
+
+	ts.Error(adapter.Send(mkLine("abc", "one")))
+	ts.Equal(0, <-arrived)
+	ts.False(adapter.Status().FailedOver)
+	ts.Error(adapter.Send(mkLine("abc", "two")))
+	ts.Equal(0, <-arrived)
+	ts.True(adapter.Status().FailedOver)
+
+	ts.NoError(adapter.Send(mkLine("abc", "three")))
+	ts.Equal(1, <-arrived)
+}
+
+func (ts *TestSuite) Test_failover_wraps_around() {
+	ts.CaptureLogs()
+	ts.Setenv("SUMOLOGIC_FAILOVER_THRESHOLD", "1")
+	primary := int64(http.StatusServiceUnavailable)
+	secondary := int64(http.StatusServiceUnavailable)
+	adapter, arrived := ts.FakeSumoEndpoints(&primary, &secondary)
+
+	for _, expected := range []int{0, 1, 0} {
+		ts.Error(adapter.Send(mkLine("abc", "Some data.")))
+		ts.Equal(expected, <-arrived)
+	}
+}
+
+func (ts *TestSuite) Test_failover_ignores_permanent_failures() {
+	ts.CaptureLogs()
+	ts.Setenv("SUMOLOGIC_FAILOVER_THRESHOLD", "1")
+	primary, secondary := int64(http.StatusBadRequest), int64(http.StatusOK)
+	adapter, arrived := ts.FakeSumoEndpoints(&primary, &secondary)
+
+	ts.Error(adapter.Send(mkLine("abc", "one")))
+	ts.Error(adapter.Send(mkLine("abc", "two")))
+	ts.Equal(0, <-arrived)
+	ts.Equal(0, <-arrived)
+	ts.False(adapter.Status().FailedOver)
+}
+
+func (ts *TestSuite) Test_probePrimary_fails_back_once_reachable() {
+	ts.CaptureLogs()
+	ts.Setenv("SUMOLOGIC_FAILOVER_THRESHOLD", "1")
+	primary, secondary := int64(http.StatusBadGateway), int64(http.StatusOK)
+	adapter, arrived := ts.FakeSumoEndpoints(&primary, &secondary)
+
+	ts.Error(adapter.Send(mkLine("abc", "one")))
+	ts.Equal(0, <-arrived)
+	ts.True(adapter.Status().FailedOver)
+
+	// Accepting connections isn't enough: the primary has to accept the
+	// probe.
+	adapter.probePrimary()
+	ts.Equal(0, <-arrived)
+	ts.Empty(arrived)
+	ts.True(adapter.Status().FailedOver, "the primary is still failing")
+
+	atomic.StoreInt64(&primary, http.StatusOK)
+	adapter.probePrimary()
+	ts.Equal(0, <-arrived)
+	ts.False(adapter.Status().FailedOver)
+	ts.NoError(adapter.Send(mkLine("abc", "two")))
+	ts.Equal(0, <-arrived)
+}
+
+func (ts *TestSuite) Test_failover_record_ignores_other_endpoints() {
+	config := &Config{endPoints: []string{"https://a", "https://b"}}
+	f := &failover{threshold: 1}
+	f.record(config, "https://b", &SendError{Kind: ErrNetwork, Err: errors.New("x")})
+	ts.False(f.failedOver())
+	f.record(config, "https://a", context.Canceled)
+	ts.False(f.failedOver())
+}
+
+func (ts *TestSuite) Test_checkConfig_checks_every_endpoint() {
+	ts.Setenv("SUMOLOGIC_ENDPOINT", "https://collectors.example.com/a,ftp://nope")
+	ts.Equal([]string{`SUMOLOGIC_ENDPOINT: "ftp://nope" is not an http or https URL`},
+		validateConfig(&router.Route{}))
+}
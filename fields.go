@@ -0,0 +1,109 @@
+package sumologic
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+// containerFieldKeys maps the names SUMOLOGIC_CONTAINER_FIELDS accepts to
+// the keys they're sent under in the container block.
+var containerFieldKeys = map[string]string{
+	"time":     "time",
+	"source":   "source",
+	"name":     "docker_name",
+	"id":       "docker_id",
+	"image":    "docker_image",
+	"hostname": "docker_hostname",
+	"full_id":  "docker_full_id",
+}
+
+// shortIDLength is how long container IDs are with SUMOLOGIC_SHORT_ID, the
+// same as docker shows them.
+const shortIDLength = 12
+
+// getcontainerfieldsopt retrieves the set of container fields to send, by
+// key. It returns nil, meaning every field, if the option isn't set.
+func (o routeOptions) getcontainerfieldsopt(name string) map[string]bool {
+	value := o.getopt(name, "")
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+	fields := map[string]bool{}
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		key, ok := containerFieldKeys[field]
+		if !ok {
+			parseFailed(name, field, nil)
+			continue
+		}
+		fields[key] = true
+	}
+	return fields
+}
+
+// getincludeenvopt retrieves the names of the container environment variables
+// to send with each log, which are separated by commas.
+func (o routeOptions) getincludeenvopt(name string) []string {
+	var names []string
+	for _, envName := range strings.Split(o.getopt(name, ""), ",") {
+		if envName = strings.TrimSpace(envName); envName != "" {
+			names = append(names, envName)
+		}
+	}
+	return names
+}
+
+// includedEnv returns the container environment variables named in
+// SUMOLOGIC_INCLUDE_ENV that the container sets, or nil if there are none.
+func includedEnv(msg *router.Message, config *Config) map[string]string {
+	if len(config.includeEnv) == 0 {
+		return nil
+	}
+	env := containerEnv(msg)
+	var included map[string]string
+	for _, name := range config.includeEnv {
+		if value, ok := env[name]; ok {
+			if included == nil {
+				included = map[string]string{}
+			}
+			included[name] = value
+		}
+	}
+	return included
+}
+
+// MarshalJSON encodes the container block, leaving out any fields that
+// weren't selected with SUMOLOGIC_CONTAINER_FIELDS.
+func (c *ContainerData) MarshalJSON() ([]byte, error) {
+	// The alias has the same fields but not this method, so that it can be
+	// encoded the usual way.
+	type containerData ContainerData
+	if c.fields == nil {
+		return json.Marshal((*containerData)(c))
+	}
+	selected := map[string]interface{}{}
+	for key, value := range map[string]string{
+		"time":            c.Time,
+		"source":          c.Source,
+		"docker_name":     c.Name,
+		"docker_id":       c.ID,
+		"docker_image":    c.Image,
+		"docker_hostname": c.Hostname,
+		"docker_full_id":  c.FullID,
+	} {
+		if c.fields[key] && (value != "" || key != "docker_full_id") {
+			selected[key] = value
+		}
+	}
+	// The environment is only there when it's been asked for, so it isn't
+	// one of the fields to select.
+	if c.Env != nil {
+		selected["docker_env"] = c.Env
+	}
+	return json.Marshal(selected)
+}
@@ -0,0 +1,129 @@
+package sumologic
+
+import (
+	"encoding/json"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+// containerBlock returns the container block of a message as it's sent.
+func (ts *TestSuite) containerBlock(data *Data) map[string]string {
+	var decoded struct {
+		Container map[string]string `json:"container"`
+	}
+	ts.NoError(json.Unmarshal(
+		ts.WithoutError(json.Marshal(data)).([]byte), &decoded))
+	return decoded.Container
+}
+
+func (ts *TestSuite) Test_getcontainerfieldsopt() {
+	ts.Nil(envOptions.getcontainerfieldsopt("SUMOLOGIC_CONTAINER_FIELDS"))
+	ts.Setenv("SUMOLOGIC_CONTAINER_FIELDS", "name, id,,bogus")
+	ts.Equal(map[string]bool{"docker_name": true, "docker_id": true},
+		envOptions.getcontainerfieldsopt("SUMOLOGIC_CONTAINER_FIELDS"))
+}
+
+func (ts *TestSuite) Test_buildData_all_container_fields() {
+	msg := mkContainerMessage("abc", "foo")
+	container := ts.containerBlock(buildData(msg, buildConfig(&router.Route{})))
+	ts.Len(container, 6)
+	ts.Equal("abc", container["docker_id"])
+	ts.Contains(container, "docker_hostname")
+}
+
+func (ts *TestSuite) Test_buildData_selected_container_fields() {
+	ts.Setenv("SUMOLOGIC_CONTAINER_FIELDS", "name,id,image")
+	msg := mkContainerMessage("abc", "foo")
+	msg.Container.Config.Image = "nginx"
+	container := ts.containerBlock(buildData(msg, buildConfig(&router.Route{})))
+	ts.Equal(map[string]string{
+		"docker_name":  "foo",
+		"docker_id":    "abc",
+		"docker_image": "nginx",
+	}, container)
+}
+
+func (ts *TestSuite) Test_buildData_short_id() {
+	id := "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	msg := mkContainerMessage(id, "foo")
+	ts.Equal(id, ts.containerBlock(
+		buildData(msg, buildConfig(&router.Route{})))["docker_id"])
+
+	ts.Setenv("SUMOLOGIC_SHORT_ID", "true")
+	container := ts.containerBlock(buildData(msg, buildConfig(&router.Route{})))
+	ts.Equal("0123456789ab", container["docker_id"])
+	ts.NotContains(container, "docker_full_id")
+
+	// IDs that are already short are left alone.
+	short := ts.containerBlock(buildData(
+		mkContainerMessage("abc", "foo"), buildConfig(&router.Route{})))
+	ts.Equal("abc", short["docker_id"])
+}
+
+func (ts *TestSuite) Test_buildData_short_id_with_full_id() {
+	ts.Setenv("SUMOLOGIC_SHORT_ID", "true")
+	ts.Setenv("SUMOLOGIC_FULL_ID", "true")
+	id := "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	msg := mkContainerMessage(id, "foo")
+	container := ts.containerBlock(buildData(msg, buildConfig(&router.Route{})))
+	ts.Equal("0123456789ab", container["docker_id"])
+	ts.Equal(id, container["docker_full_id"])
+
+	ts.Setenv("SUMOLOGIC_CONTAINER_FIELDS", "id")
+	container = ts.containerBlock(buildData(msg, buildConfig(&router.Route{})))
+	ts.Equal(map[string]string{"docker_id": "0123456789ab"}, container)
+
+	ts.Setenv("SUMOLOGIC_CONTAINER_FIELDS", "id,full_id")
+	container = ts.containerBlock(buildData(msg, buildConfig(&router.Route{})))
+	ts.Equal(map[string]string{
+		"docker_id":      "0123456789ab",
+		"docker_full_id": id,
+	}, container)
+}
+
+func (ts *TestSuite) Test_getincludeenvopt() {
+	ts.Nil(envOptions.getincludeenvopt("SUMOLOGIC_INCLUDE_ENV"))
+	ts.Setenv("SUMOLOGIC_INCLUDE_ENV", "SERVICE_NAME, DEPLOY_ENV,,")
+	ts.Equal([]string{"SERVICE_NAME", "DEPLOY_ENV"},
+		envOptions.getincludeenvopt("SUMOLOGIC_INCLUDE_ENV"))
+}
+
+// containerEnvBlock returns the docker_env object of a message as it's sent.
+func (ts *TestSuite) containerEnvBlock(data *Data) map[string]string {
+	var decoded struct {
+		Container struct {
+			Env map[string]string `json:"docker_env"`
+		} `json:"container"`
+	}
+	ts.NoError(json.Unmarshal(
+		ts.WithoutError(json.Marshal(data)).([]byte), &decoded))
+	return decoded.Container.Env
+}
+
+func (ts *TestSuite) Test_buildData_include_env() {
+	msg := mkContainerMessage("abc", "foo")
+	msg.Container.Config.Env = []string{
+		"SERVICE_NAME=web", "DEPLOY_ENV=prod", "SECRET=hunter2"}
+	ts.Nil(ts.containerEnvBlock(buildData(msg, buildConfig(&router.Route{}))))
+
+	ts.Setenv("SUMOLOGIC_INCLUDE_ENV", "SERVICE_NAME,DEPLOY_ENV,REGION")
+	ts.Equal(map[string]string{"SERVICE_NAME": "web", "DEPLOY_ENV": "prod"},
+		ts.containerEnvBlock(buildData(msg, buildConfig(&router.Route{}))))
+
+	// It's sent alongside whichever fields are selected.
+	ts.Setenv("SUMOLOGIC_CONTAINER_FIELDS", "name")
+	data := buildData(msg, buildConfig(&router.Route{}))
+	ts.Equal(map[string]string{"SERVICE_NAME": "web", "DEPLOY_ENV": "prod"},
+		ts.containerEnvBlock(data))
+	ts.Contains(string(ts.WithoutError(json.Marshal(data)).([]byte)),
+		`"docker_name":"foo"`)
+}
+
+func (ts *TestSuite) Test_buildData_include_env_none_set() {
+	ts.Setenv("SUMOLOGIC_INCLUDE_ENV", "SERVICE_NAME")
+	msg := mkContainerMessage("abc", "foo")
+	msg.Container.Config.Env = []string{"OTHER=1"}
+	body := string(ts.WithoutError(json.Marshal(
+		buildData(msg, buildConfig(&router.Route{})))).([]byte))
+	ts.NotContains(body, "docker_env")
+}
@@ -0,0 +1,60 @@
+package sumologic
+
+import (
+	"regexp"
+	"strconv"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+// excludeLabel is the label a container can set to "true" to opt out of
+// having its logs sent.
+const excludeLabel = "sumologic.exclude"
+
+// anyLabelValue matches any value of a label in SUMOLOGIC_FILTER_LABELS.
+const anyLabelValue = "*"
+
+// filtered reports whether a message's container has opted out of having its
+// logs sent, or hasn't opted in when SUMOLOGIC_FILTER_LABELS requires it to.
+// Messages that aren't from a container are never filtered.
+func filtered(msg *router.Message, config *Config) bool {
+	if msg.Container == nil || msg.Container.Config == nil {
+		return false
+	}
+	labels := msg.Container.Config.Labels
+	if exclude, err := strconv.ParseBool(labels[excludeLabel]); err == nil &&
+		exclude {
+		return true
+	}
+	if len(config.filterLabels) == 0 {
+		return false
+	}
+	for label, value := range config.filterLabels {
+		actual, ok := labels[label]
+		if ok && (value == anyLabelValue || value == actual) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchFilterReason returns why a message should be dropped for its content:
+// "excluded" if it matches SUMOLOGIC_FILTER_EXCLUDE, or "not included" if
+// SUMOLOGIC_FILTER_INCLUDE is set and it doesn't match. It returns "" if the
+// message should be sent. An empty pattern isn't applied.
+func matchFilterReason(data string, config *Config) string {
+	if patternSet(config.filterExclude) &&
+		config.filterExclude.MatchString(data) {
+		return "excluded"
+	}
+	if patternSet(config.filterInclude) &&
+		!config.filterInclude.MatchString(data) {
+		return "not included"
+	}
+	return ""
+}
+
+// patternSet reports whether a pattern option was set to something.
+func patternSet(re *regexp.Regexp) bool {
+	return re != nil && re.String() != ""
+}
@@ -0,0 +1,106 @@
+package sumologic
+
+import (
+	"regexp"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+func mkLabelledMessage(labels map[string]string) *router.Message {
+	msg := mkContainerMessage("abc", "/foo")
+	msg.Container.Config.Labels = labels
+	return msg
+}
+
+func (ts *TestSuite) Test_filtered_exclude_label() {
+	config := &Config{}
+	ts.False(filtered(&router.Message{Data: "Some data."}, config))
+	ts.False(filtered(mkLabelledMessage(nil), config))
+	ts.True(filtered(mkLabelledMessage(
+		map[string]string{"sumologic.exclude": "true"}), config))
+	ts.False(filtered(mkLabelledMessage(
+		map[string]string{"sumologic.exclude": "false"}), config))
+	ts.False(filtered(mkLabelledMessage(
+		map[string]string{"sumologic.exclude": "maybe"}), config))
+}
+
+func (ts *TestSuite) Test_filtered_filter_labels() {
+	config := &Config{filterLabels: map[string]string{
+		"logging": "sumo", "team": "*"}}
+	ts.True(filtered(mkLabelledMessage(nil), config))
+	ts.True(filtered(mkLabelledMessage(
+		map[string]string{"logging": "elsewhere"}), config))
+	ts.False(filtered(mkLabelledMessage(
+		map[string]string{"logging": "sumo"}), config))
+	ts.False(filtered(mkLabelledMessage(
+		map[string]string{"team": "web"}), config))
+	ts.True(filtered(mkLabelledMessage(map[string]string{
+		"logging": "sumo", "sumologic.exclude": "true"}), config))
+	ts.False(filtered(&router.Message{Data: "Some data."}, config))
+}
+
+func (ts *TestSuite) Test_Stream_skips_filtered_containers() {
+	ts.Setenv("SUMOLOGIC_FILTER_LABELS", "logging=sumo")
+	requests := make(chan *RequestData, 1)
+	adapter := ts.FakeSumo(requests)
+
+	ch := make(chan *router.Message)
+	done := make(chan struct{})
+	go func() {
+		adapter.Stream(ch)
+		close(done)
+	}()
+	skipped := mkLabelledMessage(nil)
+	skipped.Container.ID = "def"
+	skipped.Data = "skipped"
+	ch <- skipped
+	sent := mkLabelledMessage(map[string]string{"logging": "sumo"})
+	sent.Data = "sent"
+	ch <- sent
+	close(ch)
+	<-done
+
+	ts.Equal("sent", (<-requests).Body["message"])
+	ts.Equal(int64(0), adapter.Status().Dropped)
+	ts.Len(adapter.containers.snapshot("foo"), 1)
+}
+
+func (ts *TestSuite) Test_matchFilterReason() {
+	config := &Config{}
+	ts.Equal("", matchFilterReason("GET /healthz 200", config))
+
+	config.filterExclude = regexp.MustCompile(`GET /healthz`)
+	ts.Equal("excluded", matchFilterReason("GET /healthz 200", config))
+	ts.Equal("", matchFilterReason("GET /orders 200", config))
+
+	config.filterInclude = regexp.MustCompile(`(?i)error|healthz`)
+	ts.Equal("excluded", matchFilterReason("GET /healthz 500 error", config))
+	ts.Equal("not included", matchFilterReason("GET /orders 200", config))
+	ts.Equal("", matchFilterReason("GET /orders 500 Error", config))
+
+	config.filterInclude = regexp.MustCompile(``)
+	ts.Equal("", matchFilterReason("GET /orders 200", config))
+}
+
+func (ts *TestSuite) Test_Stream_drops_excluded_and_not_included_messages() {
+	ts.Setenv("SUMOLOGIC_FILTER_INCLUDE", "GET")
+	ts.Setenv("SUMOLOGIC_FILTER_EXCLUDE", "/healthz")
+	requests := make(chan *RequestData, 3)
+	adapter := ts.FakeSumo(requests)
+
+	ch := make(chan *router.Message)
+	done := make(chan struct{})
+	go func() {
+		adapter.Stream(ch)
+		close(done)
+	}()
+	ch <- mkLine("abc", "GET /healthz 200")
+	ch <- mkLine("abc", "debug: cache warm")
+	ch <- mkLine("abc", "GET /orders 200")
+	close(ch)
+	<-done
+
+	ts.Equal("GET /orders 200", (<-requests).Body["message"])
+	ts.Empty(requests)
+	ts.Equal(int64(2), adapter.Status().Dropped)
+}
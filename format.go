@@ -0,0 +1,46 @@
+package sumologic
+
+import (
+	"encoding/json"
+)
+
+// Payload formats.
+const (
+	// formatJSON sends each event as a json object holding the message and
+	// the container's metadata.
+	formatJSON = "json"
+	// formatRaw sends just each event's message, for HTTP sources that
+	// expect plain text. The metadata is only sent in the headers.
+	formatRaw = "raw"
+)
+
+// getformatopt retrieves the payload format.
+func (o routeOptions) getformatopt(name string) string {
+	value := o.getopt(name, formatJSON)
+	if value != formatJSON && value != formatRaw {
+		parseFailed(name, value, nil)
+		return formatJSON
+	}
+	return value
+}
+
+// encodeEvent encodes an event in the configured payload format, or by
+// rendering SUMOLOGIC_BODY_TEMPLATE for the message it came from if that's
+// set.
+func encodeEvent(data *Data, config *Config) ([]byte, error) {
+	if config.bodyTemplate != "" && data.msg != nil {
+		body, err := renderTemplate(data.msg, config.route, config.bodyTemplate)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(body), nil
+	}
+	if config.format == formatRaw {
+		return []byte(data.Message), nil
+	}
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return reshape(encoded, config)
+}
@@ -0,0 +1,105 @@
+package sumologic
+
+import (
+	"io/ioutil"
+	"net/http"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+// FakeSumoRaw starts a fake Sumo Logic server that passes on the body of each
+// request as it was sent, and returns an Adapter pointing at it.
+func (ts *TestSuite) FakeSumoRaw(bodies chan string) *Adapter {
+	return ts.adapterFor(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			bodies <- string(body)
+		}), newFakeClock())
+}
+
+func (ts *TestSuite) Test_getformatopt() {
+	ts.Equal(formatJSON, envOptions.getformatopt("SUMOLOGIC_FORMAT"))
+	ts.Setenv("SUMOLOGIC_FORMAT", "raw")
+	ts.Equal(formatRaw, envOptions.getformatopt("SUMOLOGIC_FORMAT"))
+	ts.Setenv("SUMOLOGIC_FORMAT", "xml")
+	ts.Equal(formatJSON, envOptions.getformatopt("SUMOLOGIC_FORMAT"))
+}
+
+func (ts *TestSuite) Test_sendLog_raw_format() {
+	ts.Setenv("SUMOLOGIC_FORMAT", "raw")
+	bodies := make(chan string, 1)
+	adapter := ts.FakeSumoRaw(bodies)
+
+	msg := mkContainerMessage("abc", "foo")
+	msg.Data = "hello world"
+	adapter.sendLog(msg)
+	ts.Equal("hello world", <-bodies)
+}
+
+func (ts *TestSuite) Test_sendLog_raw_format_batched() {
+	ts.Setenv("SUMOLOGIC_FORMAT", "raw")
+	ts.Setenv("SUMOLOGIC_BATCH_SIZE", "2")
+	bodies := make(chan string, 1)
+	adapter := ts.FakeSumoRaw(bodies)
+
+	one := mkContainerMessage("abc", "foo")
+	one.Data = "one"
+	two := mkContainerMessage("abc", "foo")
+	two.Data = "two"
+	adapter.sendLog(one)
+	adapter.sendLog(two)
+	ts.Equal("one\ntwo\n", <-bodies)
+}
+
+func (ts *TestSuite) Test_sendLog_body_template() {
+	ts.Setenv("SUMOLOGIC_BODY_TEMPLATE",
+		`{"log": {{json .Data}}, "app": {{.Container.Name | trimSlash | json}}, "stream": "{{.Source}}"}`)
+	bodies := make(chan string, 1)
+	adapter := ts.FakeSumoRaw(bodies)
+
+	msg := mkContainerMessage("abc", "/foo")
+	msg.Data = `say "hi"`
+	msg.Source = "stderr"
+	adapter.sendLog(msg)
+	ts.Equal(`{"log": "say \"hi\"", "app": "foo", "stream": "stderr"}`, <-bodies)
+}
+
+func (ts *TestSuite) Test_sendLog_body_template_batched() {
+	ts.Setenv("SUMOLOGIC_BODY_TEMPLATE", "{{.Container.Name}}: {{.Data}}")
+	ts.Setenv("SUMOLOGIC_BATCH_SIZE", "2")
+	bodies := make(chan string, 1)
+	adapter := ts.FakeSumoRaw(bodies)
+
+	one := mkContainerMessage("abc", "/foo")
+	one.Data = "one"
+	two := mkContainerMessage("abc", "/foo")
+	two.Data = "two"
+	adapter.sendLog(one)
+	adapter.sendLog(two)
+	ts.Equal("/foo: one\n/foo: two\n", <-bodies)
+}
+
+func (ts *TestSuite) Test_encodeEvent_body_template_only_for_messages() {
+	ts.Setenv("SUMOLOGIC_BODY_TEMPLATE", "{{.Data}}")
+	config := buildConfig(&router.Route{})
+	// Events the adapter makes up itself, such as diagnostics, are sent as
+	// usual.
+	encoded, err := encodeEvent(&Data{Message: "diagnostic"}, config)
+	ts.NoError(err)
+	ts.Contains(string(encoded), `"message":"diagnostic"`)
+}
+
+func (ts *TestSuite) Test_encodeEvent_body_template_render_error() {
+	ts.Setenv("SUMOLOGIC_BODY_TEMPLATE", "{{.Container.Config.Hostname}}")
+	config := buildConfig(&router.Route{})
+	_, err := encodeEvent(buildData(&router.Message{Data: "x"}, config), config)
+	ts.Error(err)
+}
+
+func (ts *TestSuite) Test_checkConfig_reports_bad_body_template() {
+	ts.Setenv("SUMOLOGIC_ENDPOINT", "https://collectors.example.com/a")
+	ts.Setenv("SUMOLOGIC_BODY_TEMPLATE", "{{.Data")
+	problems := validateConfig(&router.Route{})
+	ts.Len(problems, 1)
+	ts.Contains(problems[0], "SUMOLOGIC_BODY_TEMPLATE: ")
+}
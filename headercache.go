@@ -0,0 +1,106 @@
+package sumologic
+
+import (
+	"net/http"
+	"regexp"
+	"sync"
+
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+)
+
+// maxCachedHeaders bounds the header cache. It's cleared when it fills up, so
+// that containers that have gone away don't accumulate forever.
+const maxCachedHeaders = 10000
+
+// perMessageField matches template references to the parts of a message that
+// change from one message to the next.
+var perMessageField = regexp.MustCompile(`\.(Data|Time)\b`)
+
+// headerCache remembers the headers rendered for each container and stream,
+// since they normally depend only on the container's metadata and the
+// config. Entries are keyed on the container ID, and are only used for the
+// same *docker.Container they were rendered for, since logspout hands over a
+// new one when a container's metadata changes.
+type headerCache struct {
+	mu      sync.Mutex
+	entries map[headerCacheKey]headerCacheEntry
+}
+
+type headerCacheKey struct {
+	id     string
+	source string
+}
+
+type headerCacheEntry struct {
+	container *docker.Container
+	config    *Config
+	headers   http.Header
+}
+
+func newHeaderCache() *headerCache {
+	return &headerCache{entries: map[headerCacheKey]headerCacheEntry{}}
+}
+
+func (c *headerCache) get(
+	msg *router.Message, config *Config) (http.Header, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[headerCacheKey{msg.Container.ID, msg.Source}]
+	if !ok || entry.container != msg.Container || entry.config != config {
+		return nil, false
+	}
+	return copyHeader(entry.headers), true
+}
+
+func (c *headerCache) put(
+	msg *router.Message, config *Config, headers http.Header) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= maxCachedHeaders {
+		c.entries = map[headerCacheKey]headerCacheEntry{}
+	}
+	c.entries[headerCacheKey{msg.Container.ID, msg.Source}] = headerCacheEntry{
+		container: msg.Container,
+		config:    config,
+		headers:   copyHeader(headers),
+	}
+}
+
+// headers returns the classification headers for a message, rendering them
+// only if they haven't been already for the message's container and config.
+func (s *Adapter) headers(msg *router.Message, config *Config) http.Header {
+	if msg.Container == nil {
+		return buildHeaders(msg, config)
+	}
+	if headers, ok := s.headerCache.get(msg, config); ok {
+		return headers
+	}
+	headers := buildHeaders(msg, config)
+	if perContainerHeaders(config.withContainerOverrides(msg)) {
+		s.headerCache.put(msg, config, headers)
+	}
+	return headers
+}
+
+// perContainerHeaders reports whether the header templates in a config are
+// the same for every message from a container.
+func perContainerHeaders(config *Config) bool {
+	for _, text := range []string{
+		config.sourceName, config.sourceHost, config.sourceCategory,
+		config.endPointTemplate,
+	} {
+		if perMessageField.MatchString(text) {
+			return false
+		}
+	}
+	return true
+}
+
+func copyHeader(headers http.Header) http.Header {
+	result := make(http.Header, len(headers))
+	for name, values := range headers {
+		result[name] = append([]string(nil), values...)
+	}
+	return result
+}
@@ -0,0 +1,49 @@
+package sumologic
+
+import (
+	"github.com/gliderlabs/logspout/router"
+)
+
+func (ts *TestSuite) Test_headers_cached_per_container() {
+	adapter := ts.mkAdapter(&router.Route{Address: noServer})
+	msg := mkContainerMessage("abc", "foo")
+	ts.Equal("foo", adapter.headers(msg, adapter.config()).Get("X-Sumo-Name"))
+
+	// Changing the config behind the cache's back shows what's cached.
+	adapter.config().sourceName = "changed"
+	ts.Equal("foo", adapter.headers(msg, adapter.config()).Get("X-Sumo-Name"))
+	next := *msg
+	next.Data = "More data."
+	ts.Equal("foo", adapter.headers(&next, adapter.config()).Get("X-Sumo-Name"))
+
+	stderr := *msg
+	stderr.Source = "stderr"
+	ts.Equal("changed", adapter.headers(&stderr, adapter.config()).Get("X-Sumo-Name"))
+
+	renamed := mkContainerMessage("abc", "bar")
+	ts.Equal("changed", adapter.headers(renamed, adapter.config()).Get("X-Sumo-Name"))
+}
+
+func (ts *TestSuite) Test_headers_cache_returns_copies() {
+	adapter := ts.mkAdapter(&router.Route{Address: noServer})
+	msg := mkContainerMessage("abc", "foo")
+	adapter.headers(msg, adapter.config()).Set("X-Sumo-Name", "mutated")
+	ts.Equal("foo", adapter.headers(msg, adapter.config()).Get("X-Sumo-Name"))
+	adapter.headers(msg, adapter.config()).Set("X-Sumo-Name", "mutated")
+	ts.Equal("foo", adapter.headers(msg, adapter.config()).Get("X-Sumo-Name"))
+}
+
+func (ts *TestSuite) Test_headers_not_cached_for_per_message_templates() {
+	ts.Setenv("SUMOLOGIC_SOURCE_NAME", "{{.Data}}")
+	adapter := ts.mkAdapter(&router.Route{Address: noServer})
+	msg := mkContainerMessage("abc", "foo")
+	ts.Equal("Some data.", adapter.headers(msg, adapter.config()).Get("X-Sumo-Name"))
+	msg.Data = "Other data."
+	ts.Equal("Other data.", adapter.headers(msg, adapter.config()).Get("X-Sumo-Name"))
+
+	ts.Setenv("SUMOLOGIC_SOURCE_NAME", "")
+	adapter = ts.mkAdapter(&router.Route{Address: noServer})
+	msg.Container.Config.Env = []string{"SUMOLOGIC_SOURCE_HOST={{.Time}}"}
+	adapter.headers(msg, adapter.config())
+	ts.Empty(adapter.headerCache.entries)
+}
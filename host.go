@@ -0,0 +1,83 @@
+package sumologic
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"os"
+)
+
+// processInstanceID identifies this run of logspout when neither
+// SUMOLOGIC_INSTANCE_ID nor its container ID is available.
+var processInstanceID = newInstanceID()
+
+// HostData holds the machine logspout is running on, sent with each log in
+// SUMOLOGIC_HOST_METADATA mode so that logs can be traced back to the node
+// they came from.
+type HostData struct {
+	Hostname   string `json:"host_hostname"`
+	IP         string `json:"host_ip,omitempty"`
+	InstanceID string `json:"logspout_instance_id"`
+}
+
+// hostMetadata returns the host's details, or nil if they're not to be sent.
+// The hostname is SUMOLOGIC_HOSTNAME, or otherwise os.Hostname, which is the
+// container's own unless logspout shares the host's network. The IP is
+// SUMOLOGIC_HOST_IP, or otherwise the first non-loopback IPv4 address.
+func hostMetadata(opts routeOptions) *HostData {
+	if !opts.getboolopt("SUMOLOGIC_HOST_METADATA", false) {
+		return nil
+	}
+	hostname := opts.getopt("SUMOLOGIC_HOSTNAME", "")
+	if hostname == "" {
+		hostname, _ = os.Hostname()
+	}
+	ip := opts.getopt("SUMOLOGIC_HOST_IP", "")
+	if ip == "" {
+		ip = firstIPv4()
+	}
+	return &HostData{
+		Hostname:   hostname,
+		IP:         ip,
+		InstanceID: instanceID(opts),
+	}
+}
+
+// instanceID returns SUMOLOGIC_INSTANCE_ID, or otherwise the ID of the
+// container logspout is running in, or otherwise an ID made up at startup.
+func instanceID(opts routeOptions) string {
+	if id := opts.getopt("SUMOLOGIC_INSTANCE_ID", ""); id != "" {
+		return id
+	}
+	if id := selfContainerID(opts); id != "" {
+		return id
+	}
+	return processInstanceID
+}
+
+func newInstanceID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// firstIPv4 returns the first non-loopback IPv4 address of the machine's
+// interfaces, or "" if there isn't one.
+func firstIPv4() string {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return ""
+	}
+	for _, addr := range addrs {
+		ipnet, ok := addr.(*net.IPNet)
+		if !ok || ipnet.IP.IsLoopback() {
+			continue
+		}
+		if ip4 := ipnet.IP.To4(); ip4 != nil {
+			return ip4.String()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,55 @@
+package sumologic
+
+import (
+	"encoding/json"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+func (ts *TestSuite) Test_hostMetadata_disabled() {
+	ts.Nil(hostMetadata(envOptions))
+	plain := buildData(mkContainerMessage("abc", "/foo"), buildConfig(&router.Route{}))
+	ts.NotContains(string(ts.WithoutError(json.Marshal(plain)).([]byte)), "host_hostname")
+}
+
+func (ts *TestSuite) Test_hostMetadata_from_options() {
+	ts.Setenv("SUMOLOGIC_HOST_METADATA", "true")
+	ts.Setenv("SUMOLOGIC_HOSTNAME", "node-1")
+	ts.Setenv("SUMOLOGIC_HOST_IP", "10.0.0.1")
+	ts.Setenv("SUMOLOGIC_INSTANCE_ID", "logspout-a")
+	ts.Equal(&HostData{
+		Hostname: "node-1", IP: "10.0.0.1", InstanceID: "logspout-a",
+	}, hostMetadata(envOptions))
+}
+
+func (ts *TestSuite) Test_hostMetadata_defaults() {
+	ts.Setenv("SUMOLOGIC_HOST_METADATA", "true")
+	ts.Setenv("SUMOLOGIC_SELF_CONTAINER_ID", "")
+	host := hostMetadata(envOptions)
+	ts.NotEmpty(host.Hostname)
+	ts.Equal(host.IP, firstIPv4())
+	ts.NotEmpty(host.InstanceID)
+	// The made up ID stays the same for as long as logspout runs.
+	ts.Equal(host.InstanceID, hostMetadata(envOptions).InstanceID)
+}
+
+func (ts *TestSuite) Test_instanceID_self_container() {
+	ts.Setenv("SUMOLOGIC_SELF_CONTAINER_ID", "0123456789ab")
+	ts.Equal("0123456789ab", instanceID(envOptions))
+	ts.Setenv("SUMOLOGIC_INSTANCE_ID", "logspout-a")
+	ts.Equal("logspout-a", instanceID(envOptions))
+}
+
+func (ts *TestSuite) Test_buildData_host_fields() {
+	ts.Setenv("SUMOLOGIC_HOST_METADATA", "true")
+	ts.Setenv("SUMOLOGIC_HOSTNAME", "node-1")
+	ts.Setenv("SUMOLOGIC_HOST_IP", "10.0.0.1")
+	ts.Setenv("SUMOLOGIC_INSTANCE_ID", "logspout-a")
+	var body jsonobj
+	ts.NoError(json.Unmarshal(ts.WithoutError(json.Marshal(buildData(
+		mkContainerMessage("abc", "/foo"),
+		buildConfig(&router.Route{})))).([]byte), &body))
+	ts.Equal("node-1", body["host_hostname"])
+	ts.Equal("10.0.0.1", body["host_ip"])
+	ts.Equal("logspout-a", body["logspout_instance_id"])
+}
@@ -0,0 +1,119 @@
+package sumologic
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// doer sends HTTP requests. The adapter's clients are *httpClients, but
+// anything that sends requests will do, e.g. to wrap one in tests.
+type doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// httpClient sends requests over net/http, and sends them again if they
+// don't get a response at all, up to SUMOLOGIC_RETRIES times, waiting
+// SUMOLOGIC_BACKOFF in between. Responses with a status worth retrying are
+// left to postRetrying, since each attempt is signed afresh. Waits end as
+// soon as the request's context is done.
+type httpClient struct {
+	client  *http.Client
+	retries int64
+	backoff *backoff
+	clock   Clock
+}
+
+// newClient builds an HTTP client with the timeout, retry, TLS, proxy and
+// connection pool settings from a config.
+func newClient(config *Config, clock Clock) *httpClient {
+	return newClientWithTransport(config, clock, newTransport(config))
+}
+
+// newClientWithTransport builds an HTTP client with the timeout and retry
+// settings from a config, sending requests over the given transport.
+func newClientWithTransport(
+	config *Config, clock Clock, transport *http.Transport) *httpClient {
+	return &httpClient{
+		client: &http.Client{
+			Timeout:   time.Duration(config.timeout) * time.Millisecond,
+			Transport: transport,
+		},
+		retries: config.retries,
+		backoff: newBackoff(config, clock),
+		clock:   clock,
+	}
+}
+
+// Do sends a request, retrying it if it doesn't get a response. Each attempt
+// is reported to the request context's attempt observer, if it has one.
+func (c *httpClient) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	for retry := 0; ; retry++ {
+		attempt, err := rewind(req, retry)
+		if err != nil {
+			return nil, err
+		}
+		start := c.clock.Now()
+		resp, err := c.client.Do(attempt)
+		observeAttempt(ctx, requestAttempt{
+			Retry:    retry,
+			Response: resp,
+			Err:      err,
+			Elapsed:  c.clock.Now().Sub(start),
+		})
+		if err == nil || int64(retry) >= c.retries || ctx.Err() != nil {
+			return resp, err
+		}
+		timer := c.clock.NewTimer(c.backoff.Next(retry))
+		select {
+		case <-timer.C():
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// rewind returns the request to send for an attempt: the request itself the
+// first time, and a copy with a fresh body after that, since the previous
+// attempt may have read some of it.
+func rewind(req *http.Request, retry int) (*http.Request, error) {
+	if retry == 0 || req.GetBody == nil {
+		return req, nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	attempt := req.WithContext(req.Context())
+	attempt.Body = body
+	return attempt, nil
+}
+
+// requestAttempt is the outcome of a single attempt at sending a request.
+type requestAttempt struct {
+	// Retry counts the attempts before this one.
+	Retry    int
+	Response *http.Response
+	Err      error
+	Elapsed  time.Duration
+}
+
+// attemptObserverKey is the context key for a request's attempt observer.
+type attemptObserverKey struct{}
+
+// withAttemptObserver returns a context whose requests report each attempt
+// at sending them to observe. Clients are shared between adapters, so this
+// is how each adapter hears about its own requests.
+func withAttemptObserver(
+	ctx context.Context, observe func(requestAttempt)) context.Context {
+	return context.WithValue(ctx, attemptObserverKey{}, observe)
+}
+
+// observeAttempt reports an attempt to the context's observer, if any.
+func observeAttempt(ctx context.Context, attempt requestAttempt) {
+	if observe, ok := ctx.Value(attemptObserverKey{}).(func(requestAttempt)); ok {
+		observe(attempt)
+	}
+}
@@ -0,0 +1,109 @@
+package sumologic
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+// FakeFlakyServer starts a server that drops the first connection without
+// responding, and echoes the body of every request after that. It returns
+// the server's URL and a count of the requests.
+func (ts *TestSuite) FakeFlakyServer() (string, *int64) {
+	var count int64
+	url := ts.serve(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt64(&count, 1) == 1 {
+				conn, _, err := w.(http.Hijacker).Hijack()
+				ts.NoError(err)
+				conn.Close()
+				return
+			}
+			body, err := ioutil.ReadAll(r.Body)
+			ts.NoError(err)
+			w.Write(body)
+		}))
+	return url, &count
+}
+
+func (ts *TestSuite) Test_httpClient_retries_without_response() {
+	url, count := ts.FakeFlakyServer()
+	clock := newFakeClock()
+	client := newClient(buildConfig(&router.Route{}), clock)
+
+	var retries []int
+	ctx := withAttemptObserver(context.Background(),
+		func(attempt requestAttempt) { retries = append(retries, attempt.Retry) })
+	req, err := http.NewRequest(
+		http.MethodPost, url, bytes.NewReader([]byte("hello")))
+	ts.NoError(err)
+
+	done := make(chan *http.Response)
+	go func() {
+		resp, err := client.Do(req.WithContext(ctx))
+		ts.NoError(err)
+		done <- resp
+	}()
+	clock.WaitForTimers(1)
+	// The default 10ms constant backoff.
+	clock.Advance(10 * time.Millisecond)
+	resp := <-done
+	defer closeBody(resp)
+
+	// The body is sent again in full.
+	body, err := ioutil.ReadAll(resp.Body)
+	ts.NoError(err)
+	ts.Equal("hello", string(body))
+	ts.Equal(int64(2), atomic.LoadInt64(count))
+	ts.Equal([]int{0, 1}, retries)
+}
+
+func (ts *TestSuite) Test_httpClient_retries_run_out() {
+	ts.Setenv("SUMOLOGIC_RETRIES", "0")
+	url, count := ts.FakeFlakyServer()
+	client := newClient(buildConfig(&router.Route{}), newFakeClock())
+
+	req, err := http.NewRequest(http.MethodPost, url, nil)
+	ts.NoError(err)
+	_, err = client.Do(req)
+	ts.Error(err)
+	ts.Equal(int64(1), atomic.LoadInt64(count))
+}
+
+func (ts *TestSuite) Test_httpClient_wait_abandoned_on_cancel() {
+	clock := newFakeClock()
+	client := newClient(buildConfig(&router.Route{}), clock)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	req, err := http.NewRequest(http.MethodPost, noServer, nil)
+	ts.NoError(err)
+	done := make(chan error)
+	go func() {
+		_, err := client.Do(req.WithContext(ctx))
+		done <- err
+	}()
+	clock.WaitForTimers(1)
+	cancel()
+	ts.Equal(context.Canceled, <-done)
+}
+
+func (ts *TestSuite) Test_Send_counts_client_retries() {
+	ts.CaptureLogs()
+	url, _ := ts.FakeFlakyServer()
+	clock := newFakeClock()
+	adapter := ts.WithoutError(NewAdapterWithClock(
+		&router.Route{ID: "foo", Address: url}, clock)).(*Adapter)
+	ts.AddCleanup(adapter.Close)
+
+	done := make(chan error)
+	go func() { done <- adapter.Send(mkContainerMessage("abc", "/foo")) }()
+	clock.WaitForTimers(1)
+	clock.Advance(10 * time.Millisecond)
+	ts.NoError(<-done)
+	ts.Equal(int64(1), adapter.Status().Retries)
+}
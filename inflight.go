@@ -0,0 +1,130 @@
+package sumologic
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// byteLimiter caps the total size of the request bodies that are in flight
+// at once. A nil *byteLimiter doesn't limit anything.
+type byteLimiter struct {
+	mu       sync.Mutex
+	max      int64
+	inflight int64
+	// released is closed (and replaced) every time bytes are released, to
+	// wake up anything waiting in acquire.
+	released chan struct{}
+}
+
+// newByteLimiter returns a byteLimiter allowing up to max bytes in flight, or
+// nil if max isn't positive.
+func newByteLimiter(max int64) *byteLimiter {
+	if max <= 0 {
+		return nil
+	}
+	return &byteLimiter{max: max, released: make(chan struct{})}
+}
+
+// acquire blocks until n bytes can be sent without exceeding the limit, or
+// the context is done. Requests larger than the limit are let through on
+// their own once nothing else is in flight. It returns the number of bytes
+// that were reserved, which must be passed to release afterwards.
+func (l *byteLimiter) acquire(ctx context.Context, n int64) (int64, error) {
+	if l == nil {
+		return 0, nil
+	}
+	if n > l.max {
+		n = l.max
+	}
+	for {
+		l.mu.Lock()
+		if l.inflight+n <= l.max {
+			l.inflight += n
+			l.mu.Unlock()
+			return n, nil
+		}
+		released := l.released
+		l.mu.Unlock()
+
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+}
+
+// release returns n previously acquired bytes to the limiter.
+func (l *byteLimiter) release(n int64) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inflight -= n
+	close(l.released)
+	l.released = make(chan struct{})
+}
+
+// requestLimiter caps the number of requests in flight at once, so that a
+// slow endpoint doesn't tie up thousands of sockets and run the host out of
+// file descriptors. A request holds its slot until its response body is
+// closed. A nil *requestLimiter doesn't limit anything.
+type requestLimiter struct {
+	slots chan struct{}
+}
+
+// newRequestLimiter returns a requestLimiter allowing up to max requests in
+// flight, or nil if max isn't positive.
+func newRequestLimiter(max int64) *requestLimiter {
+	if max <= 0 {
+		return nil
+	}
+	return &requestLimiter{slots: make(chan struct{}, max)}
+}
+
+// acquire blocks until a request can be sent without exceeding the limit, or
+// the context is done.
+func (l *requestLimiter) acquire(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release frees a previously acquired slot.
+func (l *requestLimiter) release() {
+	if l == nil {
+		return
+	}
+	<-l.slots
+}
+
+// hold makes a response's body release a slot when it's closed.
+func (l *requestLimiter) hold(resp *http.Response) {
+	if l == nil {
+		return
+	}
+	resp.Body = &slotBody{ReadCloser: resp.Body, release: l.release}
+}
+
+// slotBody is a response body that releases its request's slot once it's
+// closed.
+type slotBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *slotBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
@@ -0,0 +1,157 @@
+package sumologic
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+)
+
+func (ts *TestSuite) Test_newByteLimiter_zero_is_unlimited() {
+	limiter := newByteLimiter(0)
+	ts.Nil(limiter)
+	reserved := ts.WithoutError(
+		limiter.acquire(context.Background(), 1000)).(int64)
+	ts.EqualValues(0, reserved)
+	limiter.release(reserved)
+}
+
+func (ts *TestSuite) Test_byteLimiter_clamps_oversized_requests() {
+	limiter := newByteLimiter(10)
+	reserved := ts.WithoutError(
+		limiter.acquire(context.Background(), 100)).(int64)
+	ts.EqualValues(10, reserved)
+	limiter.release(reserved)
+	ts.EqualValues(0, limiter.inflight)
+}
+
+func (ts *TestSuite) Test_byteLimiter_blocks_until_released() {
+	limiter := newByteLimiter(10)
+	first := ts.WithoutError(limiter.acquire(context.Background(), 6)).(int64)
+
+	acquired := make(chan int64)
+	go func() {
+		n, _ := limiter.acquire(context.Background(), 6)
+		acquired <- n
+	}()
+
+	select {
+	case <-acquired:
+		ts.Fail("Acquired bytes beyond the limit.")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	limiter.release(first)
+	select {
+	case n := <-acquired:
+		ts.EqualValues(6, n)
+	case <-time.After(100 * time.Millisecond):
+		ts.Fail("Timeout waiting for bytes to be acquired.")
+	}
+}
+
+func (ts *TestSuite) Test_byteLimiter_acquire_cancelled() {
+	limiter := newByteLimiter(10)
+	ts.WithoutError(limiter.acquire(context.Background(), 10))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := limiter.acquire(ctx, 1)
+	ts.Equal(context.Canceled, err)
+}
+
+func (ts *TestSuite) Test_sendLog_with_inflight_limit() {
+	ts.Setenv("SUMOLOGIC_MAX_INFLIGHT_BYTES", "1")
+	expectedRequestData := []RequestData{
+		{
+			Headers: map[string]string{
+				"X-Sumo-Name": "",
+				"X-Sumo-Host": "",
+			},
+			Body: mkExpectedBody(jsonobj{}),
+		},
+	}
+	requests := make(chan *RequestData, len(expectedRequestData))
+	adapter := ts.FakeSumo(requests)
+
+	adapter.sendLog(&router.Message{
+		Container: &docker.Container{Config: &docker.Config{}},
+	})
+	ts.verifyExpectedRequests(expectedRequestData, requests)
+	ts.EqualValues(0, adapter.inflight.inflight)
+}
+
+func (ts *TestSuite) Test_newRequestLimiter_zero_is_unlimited() {
+	limiter := newRequestLimiter(0)
+	ts.Nil(limiter)
+	ts.NoError(limiter.acquire(context.Background()))
+	limiter.release()
+	resp := &http.Response{Body: ioutil.NopCloser(strings.NewReader(""))}
+	limiter.hold(resp)
+	ts.NoError(resp.Body.Close())
+}
+
+func (ts *TestSuite) Test_requestLimiter_blocks_until_released() {
+	limiter := newRequestLimiter(1)
+	ts.NoError(limiter.acquire(context.Background()))
+
+	acquired := make(chan error)
+	go func() { acquired <- limiter.acquire(context.Background()) }()
+	select {
+	case <-acquired:
+		ts.Fail("Acquired a slot beyond the limit.")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	limiter.release()
+	select {
+	case err := <-acquired:
+		ts.NoError(err)
+	case <-time.After(100 * time.Millisecond):
+		ts.Fail("Timeout waiting for a slot to be acquired.")
+	}
+}
+
+func (ts *TestSuite) Test_requestLimiter_acquire_cancelled() {
+	limiter := newRequestLimiter(1)
+	ts.NoError(limiter.acquire(context.Background()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	ts.Equal(context.Canceled, limiter.acquire(ctx))
+}
+
+func (ts *TestSuite) Test_requestLimiter_held_until_body_closed() {
+	limiter := newRequestLimiter(1)
+	ts.NoError(limiter.acquire(context.Background()))
+	resp := &http.Response{Body: ioutil.NopCloser(strings.NewReader(""))}
+	limiter.hold(resp)
+	ts.Len(limiter.slots, 1)
+
+	ts.NoError(resp.Body.Close())
+	ts.NoError(resp.Body.Close())
+	ts.Len(limiter.slots, 0)
+}
+
+func (ts *TestSuite) Test_Send_with_max_inflight() {
+	ts.Setenv("SUMOLOGIC_MAX_INFLIGHT", "1")
+	release := make(chan struct{})
+	adapter, arrived := ts.FakeSlowSumo(release)
+
+	go adapter.Send(mkLine("abc", "one"))
+	ts.Equal("one", <-arrived)
+	go adapter.Send(mkLine("abc", "two"))
+	select {
+	case data := <-arrived:
+		ts.Fail("Sent beyond the in-flight limit: " + data)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	release <- struct{}{}
+	ts.Equal("two", <-arrived)
+	release <- struct{}{}
+}
@@ -0,0 +1,57 @@
+package sumologic
+
+import (
+	"strings"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+// Labels the kubelet sets on the containers it runs.
+const (
+	kubernetesPodLabel       = "io.kubernetes.pod.name"
+	kubernetesNamespaceLabel = "io.kubernetes.pod.namespace"
+	kubernetesContainerLabel = "io.kubernetes.container.name"
+)
+
+// kubernetesCategory is the default source category in kubernetes mode. It
+// renders empty for containers that kubernetes isn't running, so that they
+// fall back to SUMOLOGIC_SOURCE_CATEGORY_FALLBACKS.
+const kubernetesCategory = "{{with .Kubernetes.Namespace}}{{.}}/" +
+	"{{$.Kubernetes.ContainerName}}{{end}}"
+
+// KubernetesData holds the pod a container belongs to, for containers run by
+// kubernetes.
+type KubernetesData struct {
+	Pod           string `json:"pod"`
+	Namespace     string `json:"namespace"`
+	ContainerName string `json:"container_name"`
+}
+
+// kubernetesMetadata returns the pod a message's container belongs to, or
+// nil if kubernetes isn't running it. The kubelet's labels are used if
+// they're there, otherwise the container's name, which the kubelet sets to
+// k8s_<container>_<pod>_<namespace>_<uid>_<attempt>.
+func kubernetesMetadata(msg *router.Message) *KubernetesData {
+	if msg.Container == nil {
+		return nil
+	}
+	if msg.Container.Config != nil {
+		labels := msg.Container.Config.Labels
+		if labels[kubernetesPodLabel] != "" && labels[kubernetesNamespaceLabel] != "" {
+			return &KubernetesData{
+				Pod:           labels[kubernetesPodLabel],
+				Namespace:     labels[kubernetesNamespaceLabel],
+				ContainerName: labels[kubernetesContainerLabel],
+			}
+		}
+	}
+	parts := strings.Split(strings.TrimPrefix(msg.Container.Name, "/"), "_")
+	if len(parts) < 6 || parts[0] != "k8s" {
+		return nil
+	}
+	return &KubernetesData{
+		Pod:           parts[2],
+		Namespace:     parts[3],
+		ContainerName: parts[1],
+	}
+}
@@ -0,0 +1,66 @@
+package sumologic
+
+import (
+	"encoding/json"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+func (ts *TestSuite) Test_kubernetesMetadata_from_labels() {
+	msg := mkContainerMessage("abc", "/whatever")
+	msg.Container.Config.Labels = map[string]string{
+		kubernetesPodLabel:       "web-7d9f8-x2x9q",
+		kubernetesNamespaceLabel: "shop",
+		kubernetesContainerLabel: "nginx",
+	}
+	ts.Equal(&KubernetesData{
+		Pod: "web-7d9f8-x2x9q", Namespace: "shop", ContainerName: "nginx",
+	}, kubernetesMetadata(msg))
+}
+
+func (ts *TestSuite) Test_kubernetesMetadata_from_name() {
+	msg := mkContainerMessage(
+		"abc", "/k8s_nginx_web-7d9f8-x2x9q_shop_0f3c2b1a-uid_3")
+	ts.Equal(&KubernetesData{
+		Pod: "web-7d9f8-x2x9q", Namespace: "shop", ContainerName: "nginx",
+	}, kubernetesMetadata(msg))
+}
+
+func (ts *TestSuite) Test_kubernetesMetadata_not_kubernetes() {
+	ts.Nil(kubernetesMetadata(mkContainerMessage("abc", "/foo")))
+	ts.Nil(kubernetesMetadata(mkContainerMessage("abc", "/k8s_foo")))
+	ts.Nil(kubernetesMetadata(&router.Message{}))
+}
+
+func (ts *TestSuite) Test_buildData_kubernetes_fields() {
+	ts.Setenv("SUMOLOGIC_KUBERNETES", "true")
+	msg := mkContainerMessage(
+		"abc", "/k8s_nginx_web-7d9f8-x2x9q_shop_0f3c2b1a-uid_3")
+	var body jsonobj
+	ts.NoError(json.Unmarshal(ts.WithoutError(json.Marshal(
+		buildData(msg, buildConfig(&router.Route{})))).([]byte), &body))
+	ts.Equal("web-7d9f8-x2x9q", body["pod"])
+	ts.Equal("shop", body["namespace"])
+	ts.Equal("nginx", body["container_name"])
+
+	plain := buildData(mkContainerMessage("abc", "/foo"), buildConfig(&router.Route{}))
+	ts.NotContains(string(ts.WithoutError(json.Marshal(plain)).([]byte)), "pod")
+}
+
+func (ts *TestSuite) Test_buildHeaders_kubernetes_category() {
+	ts.Setenv("SUMOLOGIC_KUBERNETES", "true")
+	ts.Setenv("SUMOLOGIC_SOURCE_CATEGORY_FALLBACKS", "static:misc")
+	config := buildConfig(&router.Route{})
+	msg := mkContainerMessage(
+		"abc", "/k8s_nginx_web-7d9f8-x2x9q_shop_0f3c2b1a-uid_3")
+	ts.Equal("shop/nginx", buildHeaders(msg, config).Get("X-Sumo-Category"))
+	msg = mkContainerMessage("abc", "/foo")
+	ts.Equal("misc", buildHeaders(msg, config).Get("X-Sumo-Category"))
+
+	ts.Setenv("SUMOLOGIC_SOURCE_CATEGORY", "prod/{{.Kubernetes.Pod}}")
+	config = buildConfig(&router.Route{})
+	msg = mkContainerMessage(
+		"abc", "/k8s_nginx_web-7d9f8-x2x9q_shop_0f3c2b1a-uid_3")
+	ts.Equal("prod/web-7d9f8-x2x9q",
+		buildHeaders(msg, config).Get("X-Sumo-Category"))
+}
@@ -0,0 +1,116 @@
+package sumologic
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+// maxLanes caps the number of categories that get a lane of their own.
+// Messages for categories beyond the cap share the adapter's queue.
+const maxLanes = 64
+
+// lanes gives each source category its own queue and workers, so that a
+// category flooding the adapter can only use up its own share of the
+// workers, rather than starving every other category's delivery. A nil
+// *lanes sends everything through the adapter's shared queue.
+type lanes struct {
+	mu      sync.Mutex
+	workers int64
+	size    int64
+	weights map[string]int64
+	queues  map[string]chan *router.Message
+}
+
+// newLanes returns the lanes for a config, or nil if categories aren't given
+// workers of their own.
+func newLanes(config *Config) *lanes {
+	if config.categoryWorkers <= 0 {
+		return nil
+	}
+	return &lanes{
+		workers: config.categoryWorkers,
+		size:    config.queueSize,
+		weights: config.categoryWeights,
+		queues:  map[string]chan *router.Message{},
+	}
+}
+
+// getweightsopt retrieves an environment variable as a map of positive
+// integer weights, e.g. "prod/api=4,prod/batch=1".
+func (o routeOptions) getweightsopt(name string) map[string]int64 {
+	weights := map[string]int64{}
+	for key, value := range o.getmapopt(name) {
+		weight, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || weight <= 0 {
+			parseFailed(name, key+"="+value, err)
+			continue
+		}
+		weights[key] = weight
+	}
+	return weights
+}
+
+// weight returns a category's weight, which is 1 unless it's configured.
+func (l *lanes) weight(category string) int64 {
+	if weight, ok := l.weights[category]; ok {
+		return weight
+	}
+	return 1
+}
+
+// queueFor returns the queue to send a message through: its container's
+// ordered queue, if messages are sent in order, its category's lane, which
+// is started the first time the category is seen, or the adapter's shared
+// queue. It must be called with the adapter's queueMu read lock held,
+// so that new lanes aren't started once they're being closed.
+func (s *Adapter) queueFor(msg *router.Message) chan *router.Message {
+	if s.ordered != nil {
+		return s.ordered.queueFor(msg)
+	}
+	l := s.lanes
+	if l == nil {
+		return s.queue
+	}
+	category := s.headers(msg, s.config()).Get("X-Sumo-Category")
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	queue, ok := l.queues[category]
+	if !ok {
+		if len(l.queues) >= maxLanes {
+			return s.queue
+		}
+		queue = newQueue(l.size)
+		l.queues[category] = queue
+		s.startWorkers(queue, l.workers*l.weight(category))
+	}
+	return queue
+}
+
+// queued returns how many messages are waiting in the lanes' queues.
+func (l *lanes) queued() int64 {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	queued := 0
+	for _, queue := range l.queues {
+		queued += len(queue)
+	}
+	return int64(queued)
+}
+
+// close closes every lane's queue, so that their workers stop once they've
+// sent what's queued.
+func (l *lanes) close() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, queue := range l.queues {
+		close(queue)
+	}
+}
@@ -0,0 +1,74 @@
+package sumologic
+
+import (
+	"net/http"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+// FakeFloodedSumo starts a fake Sumo Logic server that holds up requests for
+// the "flood" category until release is closed, and returns an Adapter
+// pointing at it that sends each container's logs to a category named after
+// it, and a channel that receives each message as it arrives.
+func (ts *TestSuite) FakeFloodedSumo(release chan struct{}) (*Adapter, chan string) {
+	ts.Setenv("SUMOLOGIC_SOURCE_CATEGORY", "{{.Container.Name}}")
+	arrived := make(chan string, 10)
+	adapter := ts.adapterFor(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			arrived <- ts.ReadJSON(r.Body)["message"].(string)
+			if r.Header.Get("X-Sumo-Category") == "flood" {
+				<-release
+			}
+		}), newRealClock())
+	ts.AddCleanup(func() { close(release) })
+	return adapter, arrived
+}
+
+func (ts *TestSuite) Test_lanes_disabled_by_default() {
+	adapter := ts.mkAdapter(&router.Route{Address: noServer})
+	ts.Nil(adapter.lanes)
+	ts.True(adapter.queue == adapter.queueFor(mkLine("abc", "Some data.")))
+}
+
+func (ts *TestSuite) Test_getweightsopt() {
+	ts.CaptureLogs()
+	ts.Setenv("SUMOLOGIC_CATEGORY_WEIGHTS", "prod/api=4, prod/batch=1,x=0,y=z")
+	ts.Equal(map[string]int64{"prod/api": 4, "prod/batch": 1},
+		envOptions.getweightsopt("SUMOLOGIC_CATEGORY_WEIGHTS"))
+}
+
+func (ts *TestSuite) Test_lanes_keep_a_flooding_category_from_starving_others() {
+	ts.Setenv("SUMOLOGIC_CATEGORY_WORKERS", "1")
+	adapter, arrived := ts.FakeFloodedSumo(make(chan struct{}))
+
+	ch := make(chan *router.Message)
+	go adapter.Stream(ch)
+	ts.AddCleanup(func() { close(ch) })
+	for _, data := range []string{"one", "two"} {
+		msg := mkContainerMessage("abc", "flood")
+		msg.Data = data
+		ch <- msg
+	}
+	ts.Equal("one", <-arrived)
+	msg := mkContainerMessage("def", "quiet")
+	msg.Data = "three"
+	ch <- msg
+	ts.Equal("three", <-arrived)
+	ts.Empty(arrived, "the flood lane only has one worker")
+}
+
+func (ts *TestSuite) Test_lanes_give_weighted_categories_more_workers() {
+	ts.Setenv("SUMOLOGIC_CATEGORY_WORKERS", "1")
+	ts.Setenv("SUMOLOGIC_CATEGORY_WEIGHTS", "flood=2")
+	adapter, arrived := ts.FakeFloodedSumo(make(chan struct{}))
+
+	ch := make(chan *router.Message)
+	go adapter.Stream(ch)
+	ts.AddCleanup(func() { close(ch) })
+	for _, data := range []string{"one", "two"} {
+		msg := mkContainerMessage("abc", "flood")
+		msg.Data = data
+		ch <- msg
+	}
+	ts.ElementsMatch([]string{"one", "two"}, []string{<-arrived, <-arrived})
+}
@@ -0,0 +1,102 @@
+package sumologic
+
+import (
+	docker "github.com/fsouza/go-dockerclient"
+	log "github.com/sirupsen/logrus"
+)
+
+// eventSource is where docker events come from.
+type eventSource interface {
+	AddEventListener(listener chan<- *docker.APIEvents) error
+	RemoveEventListener(listener chan *docker.APIEvents) error
+}
+
+// newEventSource connects to the docker daemon logspout is reading from.
+var newEventSource = func() (eventSource, error) {
+	return docker.NewClientFromEnv()
+}
+
+// watchContainerEvents starts forgetting everything kept about each
+// container once it's removed, until the adapter is closed, so that hosts with
+// a lot of container churn don't accumulate state for containers that are
+// gone.
+func (s *Adapter) watchContainerEvents() {
+	source, err := newEventSource()
+	if err != nil {
+		log.WithError(err).Error(
+			"Unable to connect to docker, not watching for removed containers")
+		return
+	}
+	events := make(chan *docker.APIEvents, 16)
+	if err = source.AddEventListener(events); err != nil {
+		log.WithError(err).Error(
+			"Unable to listen for docker events, not watching for removed containers")
+		return
+	}
+	go func() {
+		defer source.RemoveEventListener(events)
+		for {
+			select {
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+				if id := removedContainer(event); id != "" {
+					s.forgetContainer(id)
+				}
+			case <-s.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// removedContainer returns the ID of the container a docker event reports
+// the removal of, or "" if it's some other event.
+func removedContainer(event *docker.APIEvents) string {
+	if event.Type != "" && event.Type != "container" {
+		return ""
+	}
+	if event.Action != "destroy" && event.Status != "destroy" {
+		return ""
+	}
+	if event.Actor.ID != "" {
+		return event.Actor.ID
+	}
+	return event.ID
+}
+
+// forgetContainer drops everything kept about a container.
+func (s *Adapter) forgetContainer(id string) {
+	log.WithField("container", id).Debug("Forgetting removed container")
+	s.headerCache.forget(id)
+	s.containers.forget(id)
+	s.silence.forget(id)
+	s.restarts.forget(id)
+	s.fixedSampler.forget(id)
+}
+
+func (c *headerCache) forget(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.entries {
+		if key.id == id {
+			delete(c.entries, key)
+		}
+	}
+}
+
+func (t *containerTracker) forget(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.containers, id)
+}
+
+func (d *silenceDetector) forget(id string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.containers, id)
+}
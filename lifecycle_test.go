@@ -0,0 +1,113 @@
+package sumologic
+
+import (
+	"errors"
+	"time"
+
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+)
+
+// fakeEventSource hands each listener it's given to the test.
+type fakeEventSource struct {
+	listeners chan chan<- *docker.APIEvents
+}
+
+func (f *fakeEventSource) AddEventListener(
+	listener chan<- *docker.APIEvents) error {
+	if f.listeners != nil {
+		f.listeners <- listener
+	}
+	return nil
+}
+
+func (f *fakeEventSource) RemoveEventListener(
+	listener chan *docker.APIEvents) error {
+	return nil
+}
+
+// FakeDockerEvents replaces the docker daemon adapters get events from for
+// the duration of the test. Each adapter's listener is sent to listeners, if
+// it isn't nil.
+func (ts *TestSuite) FakeDockerEvents(
+	listeners chan chan<- *docker.APIEvents) {
+	original := newEventSource
+	newEventSource = func() (eventSource, error) {
+		return &fakeEventSource{listeners: listeners}, nil
+	}
+	ts.AddCleanup(func() { newEventSource = original })
+}
+
+func (ts *TestSuite) Test_removedContainer() {
+	for _, tc := range []struct {
+		event docker.APIEvents
+		id    string
+	}{
+		{docker.APIEvents{Type: "container", Action: "destroy",
+			Actor: docker.APIActor{ID: "abc"}}, "abc"},
+		{docker.APIEvents{Status: "destroy", ID: "abc"}, "abc"},
+		{docker.APIEvents{Type: "container", Action: "die",
+			Actor: docker.APIActor{ID: "abc"}}, ""},
+		{docker.APIEvents{Type: "network", Action: "destroy",
+			Actor: docker.APIActor{ID: "abc"}}, ""},
+	} {
+		ts.Equal(tc.id, removedContainer(&tc.event))
+	}
+}
+
+func (ts *TestSuite) Test_forgetContainer() {
+	ts.Setenv("SUMOLOGIC_SILENCE_THRESHOLD_MS", "60000")
+	adapter := ts.mkAdapter(&router.Route{Address: "https://example.com/"})
+	abc := mkContainerMessage("abc", "/foo")
+	def := mkContainerMessage("def", "/bar")
+	for _, msg := range []*router.Message{abc, def} {
+		adapter.containers.received(msg)
+		adapter.silence.seen(msg)
+		adapter.headers(msg, adapter.config())
+	}
+
+	adapter.forgetContainer("abc")
+	ts.NotContains(adapter.containers.containers, "abc")
+	ts.Contains(adapter.containers.containers, "def")
+	ts.NotContains(adapter.silence.containers, "abc")
+	ts.Contains(adapter.silence.containers, "def")
+	ts.NotContains(adapter.headerCache.entries, headerCacheKey{"abc", abc.Source})
+	ts.Contains(adapter.headerCache.entries, headerCacheKey{"def", def.Source})
+}
+
+func (ts *TestSuite) Test_watchContainerEvents() {
+	listeners := make(chan chan<- *docker.APIEvents, 1)
+	ts.FakeDockerEvents(listeners)
+	adapter := ts.mkAdapter(&router.Route{Address: "https://example.com/"})
+	adapter.containers.received(mkContainerMessage("abc", "/foo"))
+	events := <-listeners
+
+	events <- &docker.APIEvents{Type: "container", Action: "die",
+		Actor: docker.APIActor{ID: "abc"}}
+	events <- &docker.APIEvents{Type: "container", Action: "destroy",
+		Actor: docker.APIActor{ID: "abc"}}
+	ts.Eventually(func() bool {
+		return len(adapter.containers.snapshot("")) == 0
+	}, time.Second, time.Millisecond)
+}
+
+func (ts *TestSuite) Test_watchContainerEvents_disabled() {
+	ts.Setenv("SUMOLOGIC_FORGET_REMOVED_CONTAINERS", "false")
+	listeners := make(chan chan<- *docker.APIEvents, 1)
+	ts.FakeDockerEvents(listeners)
+	ts.mkAdapter(&router.Route{Address: "https://example.com/"})
+	ts.Empty(listeners)
+}
+
+func (ts *TestSuite) Test_watchContainerEvents_without_docker() {
+	hook, _ := ts.CaptureLogs()
+	original := newEventSource
+	newEventSource = func() (eventSource, error) {
+		return nil, errors.New("no docker here")
+	}
+	ts.AddCleanup(func() { newEventSource = original })
+
+	ts.mkAdapter(&router.Route{Address: "https://example.com/"})
+	ts.Equal("Unable to connect to docker, not watching for removed containers",
+		hook.LastEntry().Message)
+}
@@ -0,0 +1,31 @@
+package sumologic
+
+import "regexp"
+
+// receiverToken matches the token at the end of a Sumo Logic HTTP source URL,
+// e.g. https://endpoint1.collection.sumologic.com/receiver/v1/http/<token>.
+// The token is a credential, so it's kept out of everything the adapter logs.
+var receiverToken = regexp.MustCompile(`(/receiver/v1/[A-Za-z0-9]+/)[^/\s"'?#]+`)
+
+// maskToken replaces any receiver tokens in text with asterisks.
+func maskToken(text string) string {
+	return receiverToken.ReplaceAllString(text, "${1}****")
+}
+
+// maskedError is an error whose message has had any receiver tokens masked.
+type maskedError struct {
+	err error
+}
+
+func (e *maskedError) Error() string {
+	return maskToken(e.err.Error())
+}
+
+// maskError returns err with any receiver tokens masked in its message, or
+// nil if err is nil.
+func maskError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &maskedError{err}
+}
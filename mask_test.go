@@ -0,0 +1,42 @@
+package sumologic
+
+import (
+	"net/http/httptest"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+func (ts *TestSuite) Test_maskToken() {
+	ts.Equal(
+		`Post "https://endpoint1.collection.sumologic.com/receiver/v1/http/****": EOF`,
+		maskToken(`Post "https://endpoint1.collection.sumologic.com/receiver/v1/http/ZaVnC4dhaV3=": EOF`))
+	ts.Equal("https://example.com/receiver/v1/http/****?x=1",
+		maskToken("https://example.com/receiver/v1/http/s3cr3t?x=1"))
+	ts.Equal("https://example.com/other/s3cr3t",
+		maskToken("https://example.com/other/s3cr3t"))
+}
+
+func (ts *TestSuite) Test_maskError() {
+	ts.Nil(maskError(nil))
+	err := maskError(&statusError{503})
+	ts.Equal("unexpected status code 503", err.Error())
+	ts.Equal(errorClassStatus, classifyError(err))
+}
+
+func (ts *TestSuite) Test_sendLog_failure_masks_token() {
+	hook, _ := ts.CaptureLogs()
+	server := httptest.NewServer(nil)
+	server.Close()
+	adapter := ts.mkAdapter(&router.Route{
+		ID:      "foo",
+		Address: server.URL + "/receiver/v1/http/s3cr3t",
+	})
+
+	adapter.sendLog(mkContainerMessage("abc", "foo"))
+	entry := hook.LastEntry()
+	ts.Equal("Failed to send log to Sumologic", entry.Message)
+	ts.Equal(errorClassConnect, entry.Data["error_class"])
+	ts.Contains(entry.Data["error"].(error).Error(), "/receiver/v1/http/****")
+	ts.NotContains(entry.Data["error"].(error).Error(), "s3cr3t")
+	ts.NotContains(adapter.Status().LastError, "s3cr3t")
+}
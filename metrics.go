@@ -0,0 +1,221 @@
+//go:build !sumologic_nometrics
+// +build !sumologic_nometrics
+
+package sumologic
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gliderlabs/logspout/router"
+	log "github.com/sirupsen/logrus"
+)
+
+func init() {
+	registerModule("metrics")
+}
+
+// metricRule counts the messages matching a pattern as the named metric.
+type metricRule struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+// getmetricrulesopt retrieves an environment variable as a list of metric
+// rules if it's set to a non-empty string of semicolon-separated name=regex
+// pairs, e.g. "http_5xx= 5\d\d ;panics=^panic:".
+// Rules that can't be parsed are logged and ignored.
+func (o routeOptions) getmetricrulesopt(name string) []metricRule {
+	var rules []metricRule
+	for _, rule := range strings.Split(o.lookupopt(name), ";") {
+		if strings.TrimSpace(rule) == "" {
+			continue
+		}
+		kv := strings.SplitN(rule, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			parseFailed(name, rule, nil)
+			continue
+		}
+		pattern, err := regexp.Compile(kv[1])
+		if err != nil {
+			parseFailed(name, rule, err)
+			continue
+		}
+		rules = append(rules, metricRule{strings.TrimSpace(kv[0]), pattern})
+	}
+	return rules
+}
+
+// metricCounter counts the messages from each container that match each
+// metric rule. A nil *metricCounter doesn't count anything.
+type metricCounter struct {
+	mu         sync.Mutex
+	rules      []metricRule
+	interval   time.Duration
+	containers map[string]*containerMetrics
+}
+
+type containerMetrics struct {
+	name   string
+	id     string
+	msg    *router.Message
+	counts map[string]int64
+}
+
+// metricBatch is a Carbon 2.0 request body and the headers to send it with.
+type metricBatch struct {
+	headers http.Header
+	body    []byte
+}
+
+// newMetricCounter returns a metricCounter for the given rules, or nil if
+// there are no rules or the interval isn't positive.
+func newMetricCounter(
+	rules []metricRule, interval time.Duration) *metricCounter {
+	if len(rules) == 0 || interval <= 0 {
+		return nil
+	}
+	return &metricCounter{
+		rules:      rules,
+		interval:   interval,
+		containers: map[string]*containerMetrics{},
+	}
+}
+
+// count checks a message against every rule.
+func (m *metricCounter) count(msg *router.Message) {
+	if m == nil || msg.Container == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.containers[msg.Container.ID]
+	if !ok {
+		c = &containerMetrics{
+			name:   msg.Container.Name,
+			id:     msg.Container.ID,
+			msg:    msg,
+			counts: map[string]int64{},
+		}
+		for _, rule := range m.rules {
+			c.counts[rule.name] = 0
+		}
+		m.containers[msg.Container.ID] = c
+	}
+	for _, rule := range m.rules {
+		if rule.pattern.MatchString(msg.Data) {
+			c.counts[rule.name]++
+		}
+	}
+}
+
+// flush renders the counts collected since the last flush in Sumologic's
+// Carbon 2.0 metrics format and starts counting from scratch. Every rule is
+// reported for every container that logged anything, even if the count is
+// zero. The headers for each container's metrics are built from the first
+// message it logged, and containers with the same headers share a batch.
+func (m *metricCounter) flush(
+	now time.Time, headers func(msg *router.Message) http.Header) []metricBatch {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	batches := map[string]*metricBatch{}
+	lines := map[string][]string{}
+	for _, c := range m.containers {
+		h := headers(c.msg)
+		key := headerKey(h)
+		if _, ok := batches[key]; !ok {
+			batches[key] = &metricBatch{headers: h}
+		}
+		for rule, count := range c.counts {
+			lines[key] = append(lines[key], fmt.Sprintf(
+				"metric=%s container=%s container_id=%s  %d %d",
+				carbonValue(rule), carbonValue(c.name), carbonValue(c.id),
+				count, now.Unix()))
+		}
+	}
+	m.containers = map[string]*containerMetrics{}
+
+	keys := make([]string, 0, len(batches))
+	for key := range batches {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	result := make([]metricBatch, 0, len(keys))
+	for _, key := range keys {
+		sort.Strings(lines[key])
+		batch := batches[key]
+		batch.body = []byte(strings.Join(lines[key], "\n") + "\n")
+		result = append(result, *batch)
+	}
+	return result
+}
+
+// carbonValue makes a string safe to use as a Carbon 2.0 tag value, which
+// can't be empty or contain whitespace.
+func carbonValue(value string) string {
+	value = strings.Join(strings.Fields(value), "_")
+	if value == "" {
+		return "none"
+	}
+	return value
+}
+
+// reportMetrics sends the metrics collected since the last report to the
+// Sumologic metrics endpoint.
+func (s *Adapter) reportMetrics() {
+	defer s.recoverPanic("reportMetrics")
+
+	for _, batch := range s.metrics.flush(s.clock.Now(), s.metricHeaders) {
+		s.sendMetrics(batch)
+	}
+}
+
+// metricHeaders builds the headers for a container's metrics. The dimensions
+// and metadata templates are rendered against a message from the container,
+// the same way the source templates are.
+func (s *Adapter) metricHeaders(msg *router.Message) http.Header {
+	config := s.config()
+	headers := http.Header{}
+	headers.Set("Content-Type", "application/vnd.sumologic.carbon2")
+	if config.metricsCategory != "" {
+		headers.Set("X-Sumo-Category", config.metricsCategory)
+	}
+	if config.metricsDimensions != "" {
+		dimensions, err := renderTemplate(
+			msg, config.route, config.metricsDimensions)
+		if err == nil && dimensions != "" {
+			headers.Set("X-Sumo-Dimensions", dimensions)
+		}
+	}
+	if config.metricsMetadata != "" {
+		metadata, err := renderTemplate(
+			msg, config.route, config.metricsMetadata)
+		if err == nil && metadata != "" {
+			headers.Set("X-Sumo-Metadata", metadata)
+		}
+	}
+	return headers
+}
+
+// sendMetrics posts a batch of metrics to the Sumologic metrics endpoint.
+func (s *Adapter) sendMetrics(batch metricBatch) {
+	resp, err := s.postTo(s.config().metricsEndPoint, batch.body, batch.headers)
+	if err != nil {
+		log.WithError(err).Error("Failed to send metrics to Sumologic")
+		return
+	}
+	defer closeBody(resp)
+	if _, err = ioutil.ReadAll(resp.Body); err != nil {
+		log.WithError(err).Error("Unable to read response body.")
+	}
+	if resp.StatusCode != http.StatusOK {
+		log.WithField("StatusCode", resp.StatusCode).Error(
+			"Failed to send metrics to Sumologic")
+	}
+}
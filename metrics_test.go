@@ -0,0 +1,143 @@
+//go:build !sumologic_nometrics
+// +build !sumologic_nometrics
+
+package sumologic
+
+import (
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/gliderlabs/logspout/router"
+	"github.com/sirupsen/logrus"
+)
+
+func (ts *TestSuite) Test_getmetricrulesopt_unset_envar_returns_nil() {
+	ts.Nil(envOptions.getmetricrulesopt("UNSET_ENV_VAR"))
+}
+
+func (ts *TestSuite) Test_getmetricrulesopt_set_envar_returns_rules() {
+	ts.Setenv("SET_ENV_VAR", `http_5xx=" 5\d\d ; panics=^panic:,x{1,2};`)
+	rules := envOptions.getmetricrulesopt("SET_ENV_VAR")
+	ts.Len(rules, 2)
+	ts.Equal("http_5xx", rules[0].name)
+	ts.Equal(`" 5\d\d `, rules[0].pattern.String())
+	ts.Equal("panics", rules[1].name)
+	ts.Equal(`^panic:,x{1,2}`, rules[1].pattern.String())
+}
+
+func (ts *TestSuite) Test_getmetricrulesopt_set_envar_invalid_rules_skipped() {
+	hook, _ := ts.CaptureLogs()
+
+	ts.Setenv("SET_ENV_VAR", `foo;=bar;bad=(;good=ok`)
+	rules := envOptions.getmetricrulesopt("SET_ENV_VAR")
+	ts.Len(rules, 1)
+	ts.Equal("good", rules[0].name)
+	ts.Len(hook.AllEntries(), 3)
+	ts.Equal(logrus.ErrorLevel, hook.LastEntry().Level)
+	ts.Equal("Failed to parse", hook.LastEntry().Message)
+}
+
+func (ts *TestSuite) Test_newMetricCounter_disabled() {
+	ts.Nil(newMetricCounter(nil, time.Minute))
+	rules := []metricRule{{"errors", regexp.MustCompile("error")}}
+	ts.Nil(newMetricCounter(rules, 0))
+
+	var m *metricCounter
+	m.count(mkContainerMessage("abc", "foo"))
+}
+
+func (ts *TestSuite) Test_metricCounter_flush() {
+	m := newMetricCounter([]metricRule{
+		{"errors", regexp.MustCompile("error")},
+		{"http_5xx", regexp.MustCompile(` 5\d\d `)},
+	}, time.Minute)
+	noHeaders := func(*router.Message) http.Header { return http.Header{} }
+	ts.Empty(m.flush(mkTime(0), noHeaders))
+
+	errMsg := mkContainerMessage("abc", "foo")
+	errMsg.Data = `an error: "GET /" 503 12`
+	m.count(errMsg)
+	m.count(errMsg)
+	m.count(mkContainerMessage("def", "bar baz"))
+	m.count(&router.Message{})
+
+	batches := m.flush(mkTime(0), noHeaders)
+	ts.Len(batches, 1)
+	ts.Equal(
+		"metric=errors container=bar_baz container_id=def  0 1514898000\n"+
+			"metric=errors container=foo container_id=abc  2 1514898000\n"+
+			"metric=http_5xx container=bar_baz container_id=def  0 1514898000\n"+
+			"metric=http_5xx container=foo container_id=abc  2 1514898000\n",
+		string(batches[0].body))
+	ts.Empty(m.flush(mkTime(60), noHeaders))
+}
+
+func (ts *TestSuite) Test_metricCounter_flush_groups_by_headers() {
+	m := newMetricCounter([]metricRule{
+		{"errors", regexp.MustCompile("error")},
+	}, time.Minute)
+	m.count(mkContainerMessage("abc", "foo"))
+	m.count(mkContainerMessage("def", "bar"))
+	m.count(mkContainerMessage("ghi", "foo"))
+
+	batches := m.flush(mkTime(0), func(msg *router.Message) http.Header {
+		return http.Header{"X-Sumo-Dimensions": {"service=" + msg.Container.Name}}
+	})
+	ts.Len(batches, 2)
+	ts.Equal("service=bar", batches[0].headers.Get("X-Sumo-Dimensions"))
+	ts.Equal("metric=errors container=bar container_id=def  0 1514898000\n",
+		string(batches[0].body))
+	ts.Equal("service=foo", batches[1].headers.Get("X-Sumo-Dimensions"))
+	ts.Equal("metric=errors container=foo container_id=abc  0 1514898000\n"+
+		"metric=errors container=foo container_id=ghi  0 1514898000\n",
+		string(batches[1].body))
+}
+
+func (ts *TestSuite) Test_metricHeaders() {
+	ts.Setenv("SUMOLOGIC_METRICS_CATEGORY", "metrics")
+	ts.Setenv("SUMOLOGIC_METRICS_DIMENSIONS", "service={{.Container.Name}}")
+	ts.Setenv("SUMOLOGIC_METRICS_METADATA", "route={{.Route.ID}}")
+	adapter := ts.mkAdapter(&router.Route{ID: "foo", Address: noServer})
+
+	ts.Equal(http.Header{
+		"Content-Type":      {"application/vnd.sumologic.carbon2"},
+		"X-Sumo-Category":   {"metrics"},
+		"X-Sumo-Dimensions": {"service=web"},
+		"X-Sumo-Metadata":   {"route=foo"},
+	}, adapter.metricHeaders(mkContainerMessage("abc", "web")))
+}
+
+func (ts *TestSuite) Test_reportMetrics_posts_carbon2() {
+	bodies := make(chan string, 1)
+	url := ts.serve(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			ts.Equal("application/vnd.sumologic.carbon2",
+				r.Header.Get("Content-Type"))
+			ts.Equal("metrics", r.Header.Get("X-Sumo-Category"))
+			bodies <- string(body)
+		}))
+	ts.Setenv("SUMOLOGIC_METRIC_RULES", "errors=error")
+	ts.Setenv("SUMOLOGIC_METRICS_ENDPOINT", url)
+	ts.Setenv("SUMOLOGIC_METRICS_CATEGORY", "metrics")
+	clock := newFakeClock()
+	adapter := ts.WithoutError(NewAdapterWithClock(&router.Route{
+		ID:      "foo",
+		Address: "http://localhost:1/unused",
+	}, clock)).(*Adapter)
+	ts.AddCleanup(adapter.Close)
+
+	adapter.metrics.count(mkContainerMessage("abc", "foo"))
+	clock.WaitForTimers(1)
+	clock.Advance(time.Minute)
+
+	select {
+	case body := <-bodies:
+		ts.Equal(
+			"metric=errors container=foo container_id=abc  0 1514898060\n", body)
+	case <-time.After(time.Second):
+		ts.Fail("Timeout waiting for metrics.")
+	}
+}
@@ -0,0 +1,115 @@
+package sumologic
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+// maxMultilineLines caps how many lines are joined into a single event, so
+// that a container that never logs a line matching the pattern doesn't hold
+// everything it logs in memory.
+const maxMultilineLines = 500
+
+// multiline joins lines that continue an event (e.g. the frames of a stack
+// trace) onto the line that started it, so that they're sent as a single
+// event. Lines are grouped per container and stream. A group ends when a
+// line that starts a new event arrives, or when no more lines have arrived
+// for it within the timeout. A nil *multiline sends every line on its own.
+type multiline struct {
+	mu      sync.Mutex
+	start   *regexp.Regexp
+	timeout time.Duration
+	groups  map[string]*lineGroup
+}
+
+// lineGroup is an event whose lines are still arriving.
+type lineGroup struct {
+	lines []*router.Message
+	last  time.Time
+}
+
+// newMultiline returns a multiline joiner that starts a new event at each
+// line matching start, or nil if there's no pattern.
+func newMultiline(start *regexp.Regexp, timeout time.Duration) *multiline {
+	if start == nil || start.String() == "" || timeout <= 0 {
+		return nil
+	}
+	return &multiline{
+		start:   start,
+		timeout: timeout,
+		groups:  map[string]*lineGroup{},
+	}
+}
+
+func multilineKey(msg *router.Message) string {
+	if msg.Container == nil {
+		return "/" + msg.Source
+	}
+	return msg.Container.ID + "/" + msg.Source
+}
+
+// add adds a line to its container's event. It returns the previous event if
+// the line starts a new one, or nil.
+func (m *multiline) add(msg *router.Message, now time.Time) *lineGroup {
+	key := multilineKey(msg)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	group, ok := m.groups[key]
+	if ok && !m.start.MatchString(msg.Data) && len(group.lines) < maxMultilineLines {
+		group.lines = append(group.lines, msg)
+		group.last = now
+		return nil
+	}
+	// A continuation line with no event to join starts one of its own.
+	m.groups[key] = &lineGroup{lines: []*router.Message{msg}, last: now}
+	return group
+}
+
+// expired removes and returns the events that haven't had a line added
+// within the timeout, or all of them if all is true.
+func (m *multiline) expired(now time.Time, all bool) []*lineGroup {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var groups []*lineGroup
+	for key, group := range m.groups {
+		if all || now.Sub(group.last) >= m.timeout {
+			groups = append(groups, group)
+			delete(m.groups, key)
+		}
+	}
+	return groups
+}
+
+// joined returns the message for a finished event. Events of a single line
+// are sent as they arrived.
+func (s *Adapter) joined(group *lineGroup, config *Config) *router.Message {
+	first := group.lines[0]
+	if len(group.lines) == 1 {
+		return first
+	}
+	data := make([]string, len(group.lines))
+	for i, line := range group.lines {
+		data[i] = line.Data
+		if i > 0 {
+			s.annotations.take(line)
+		}
+	}
+	msg := *first
+	msg.Data = strings.Join(data, "\n")
+	s.annotations.move(first, &msg)
+	s.annotate(&msg, config, func(p *Processing) { p.MultilineJoined = true })
+	return &msg
+}
+
+// flushMultiline passes on the events that have stopped growing, or every
+// event if all is true.
+func (s *Adapter) flushMultiline(all bool) {
+	config := s.config()
+	for _, group := range s.multiline.expired(s.clock.Now(), all) {
+		s.forward(s.joined(group, config), config)
+	}
+}
@@ -0,0 +1,86 @@
+package sumologic
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+func mkLine(id string, data string) *router.Message {
+	msg := mkContainerMessage(id, "/"+id)
+	msg.Data = data
+	return msg
+}
+
+func (ts *TestSuite) Test_newMultiline_disabled() {
+	ts.Nil(newMultiline(nil, time.Second))
+	ts.Nil(newMultiline(regexp.MustCompile(""), time.Second))
+	ts.Nil(newMultiline(regexp.MustCompile(`^\S`), 0))
+}
+
+func (ts *TestSuite) Test_multiline_groups_by_container() {
+	m := newMultiline(regexp.MustCompile(`^\S`), time.Second)
+	ts.Nil(m.add(mkLine("abc", "Exception"), mkTime(0)))
+	ts.Nil(m.add(mkLine("def", "other"), mkTime(0)))
+	ts.Nil(m.add(mkLine("abc", "  at a"), mkTime(1)))
+	ts.Nil(m.add(mkLine("def", "  at b"), mkTime(1)))
+	group := m.add(mkLine("abc", "Next"), mkTime(2))
+	ts.Len(group.lines, 2)
+	ts.Equal("  at a", group.lines[1].Data)
+
+	expired := m.expired(mkTime(2), false)
+	ts.Len(expired, 1)
+	ts.Equal("other", expired[0].lines[0].Data)
+	ts.Len(m.expired(mkTime(2), true), 1)
+	ts.Empty(m.expired(mkTime(2), true))
+}
+
+func (ts *TestSuite) Test_multiline_caps_lines() {
+	m := newMultiline(regexp.MustCompile(`^\S`), time.Second)
+	ts.Nil(m.add(mkLine("abc", "Exception"), mkTime(0)))
+	for i := 1; i < maxMultilineLines; i++ {
+		ts.Nil(m.add(mkLine("abc", "  at a"), mkTime(0)))
+	}
+	group := m.add(mkLine("abc", "  at a"), mkTime(0))
+	ts.Len(group.lines, maxMultilineLines)
+}
+
+func (ts *TestSuite) Test_Stream_joins_multiline_events() {
+	ts.Setenv("SUMOLOGIC_MULTILINE_PATTERN", `^\S`)
+	ts.Setenv("SUMOLOGIC_WORKERS", "1")
+	ts.Setenv("SUMOLOGIC_PROCESSING_FLAGS", "true")
+	requests := make(chan *RequestData, 2)
+	adapter := ts.FakeSumoWithClock(requests, newFakeClock())
+
+	ch := make(chan *router.Message)
+	go adapter.Stream(ch)
+	for _, line := range []string{"Exception", "  at a", "  at b", "Next"} {
+		ch <- mkLine("abc", line)
+	}
+	close(ch)
+
+	request := <-requests
+	ts.Equal("Exception\n  at a\n  at b", request.Body["message"])
+	ts.Equal(jsonobj{"multiline_joined": true}, request.Body["_processing"])
+	request = <-requests
+	ts.Equal("Next", request.Body["message"])
+	ts.Equal(jsonobj{}, request.Body["_processing"])
+}
+
+func (ts *TestSuite) Test_flushMultiline_after_timeout() {
+	ts.Setenv("SUMOLOGIC_MULTILINE_PATTERN", `^\S`)
+	ts.Setenv("SUMOLOGIC_MULTILINE_FLUSH_MS", "500")
+	requests := make(chan *RequestData, 1)
+	clock := newFakeClock()
+	adapter := ts.FakeSumoWithClock(requests, clock)
+
+	adapter.receive(mkLine("abc", "Exception"))
+	adapter.receive(mkLine("abc", "  at a"))
+	clock.Advance(499 * time.Millisecond)
+	adapter.flushMultiline(false)
+	ts.Empty(requests)
+	clock.Advance(time.Millisecond)
+	adapter.flushMultiline(false)
+	ts.Equal("Exception\n  at a", (<-requests).Body["message"])
+}
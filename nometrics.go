@@ -0,0 +1,36 @@
+//go:build sumologic_nometrics
+// +build sumologic_nometrics
+
+package sumologic
+
+import (
+	"time"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+// metricRule stands in for the metrics module, which isn't compiled in, so
+// no metrics are ever reported.
+type metricRule struct{}
+
+type metricCounter struct {
+	interval time.Duration
+}
+
+// getmetricrulesopt reports the metric rules if they're set, and returns
+// none.
+func (o routeOptions) getmetricrulesopt(name string) []metricRule {
+	if value := o.lookupopt(name); value != "" {
+		parseFailed(name, value, errModuleMissing("metrics"))
+	}
+	return nil
+}
+
+func newMetricCounter(
+	rules []metricRule, interval time.Duration) *metricCounter {
+	return nil
+}
+
+func (m *metricCounter) count(msg *router.Message) {}
+
+func (s *Adapter) reportMetrics() {}
@@ -0,0 +1,25 @@
+//go:build sumologic_noscript && sumologic_nospool && sumologic_nometrics
+// +build sumologic_noscript,sumologic_nospool,sumologic_nometrics
+
+package sumologic
+
+import (
+	"github.com/gliderlabs/logspout/router"
+)
+
+func (ts *TestSuite) Test_stubs_reject_options_for_missing_modules() {
+	ts.CaptureLogs()
+	ts.Setenv("SUMOLOGIC_SCRIPT", "return false")
+	ts.Setenv("SUMOLOGIC_METRIC_RULES", "errors=ERROR")
+	ts.Setenv("SUMOLOGIC_BUFFER_DIR", "/tmp/spool")
+	ts.Empty(compiledModules())
+
+	problems := validateConfig(
+		&router.Route{Address: "https://collectors.example.com/receiver"})
+	ts.Equal([]string{
+		`SUMOLOGIC_METRIC_RULES: can't parse "errors=ERROR": ` +
+			"logspout was built without the metrics module",
+		"SUMOLOGIC_SCRIPT: logspout was built without the script module",
+	}, problems)
+	ts.Nil(newSpool("/tmp/spool", "https://example.com/", 100))
+}
@@ -0,0 +1,39 @@
+//go:build sumologic_noscript
+// +build sumologic_noscript
+
+package sumologic
+
+import (
+	"github.com/gliderlabs/logspout/router"
+)
+
+// script stands in for the script module, which isn't compiled in, so no
+// script is ever run.
+type script struct{}
+
+// getscriptopt retrieves the script option that's set, if any, so that it
+// can be reported.
+func (o routeOptions) getscriptopt(name string, fileName string) string {
+	if source := o.getopt(name, ""); source != "" {
+		return source
+	}
+	return o.getopt(fileName, "")
+}
+
+// checkScript returns an error if a script is configured.
+func checkScript(source string) error {
+	if source != "" {
+		return errModuleMissing("script")
+	}
+	return nil
+}
+
+func newScript(source string) *script {
+	return nil
+}
+
+func (sc *script) apply(msg *router.Message) (*router.Message, bool) {
+	return msg, true
+}
+
+func (sc *script) close() {}
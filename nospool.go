@@ -0,0 +1,34 @@
+//go:build sumologic_nospool
+// +build sumologic_nospool
+
+package sumologic
+
+import (
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// spoolReplayInterval is how often spooled requests would be retried.
+const spoolReplayInterval = 10 * time.Second
+
+// spool stands in for the spool module, which isn't compiled in, so nothing
+// is ever spooled.
+type spool struct{}
+
+// newSpool returns nil, logging an error if a buffer directory is
+// configured.
+func newSpool(dir string, endPoint string, maxMB int64) *spool {
+	if dir != "" {
+		log.WithError(errModuleMissing("spool")).WithField("dir", dir).Error(
+			"Unable to use buffer directory, not buffering failed sends")
+	}
+	return nil
+}
+
+func (s *spool) add(now time.Time, body []byte, headers http.Header,
+	containers map[string]int64) {
+}
+
+func (s *Adapter) replaySpool() {}
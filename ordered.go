@@ -0,0 +1,95 @@
+package sumologic
+
+import (
+	"hash/fnv"
+
+	"github.com/gliderlabs/logspout/router"
+	log "github.com/sirupsen/logrus"
+)
+
+// orderedQueues sends each container's messages one at a time, in the order
+// they arrived, for SUMOLOGIC_ORDERED. Every container is assigned one of a
+// fixed set of queues by its ID, and each queue has a single worker, so
+// messages from the same container are never in flight at once, while
+// different containers still share the route's workers. A nil
+// *orderedQueues sends everything through the adapter's shared queue.
+type orderedQueues struct {
+	queues []chan *router.Message
+}
+
+// newOrderedQueues returns the ordered queues for a config, or nil if
+// messages don't need to be sent in order.
+func newOrderedQueues(config *Config) *orderedQueues {
+	if !config.ordered {
+		return nil
+	}
+	workers := config.workers
+	if workers <= 0 {
+		workers = 1
+	}
+	o := &orderedQueues{}
+	for i := int64(0); i < workers; i++ {
+		o.queues = append(o.queues, newQueue(config.queueSize))
+	}
+	return o
+}
+
+// queueFor returns the queue for a message's container.
+func (o *orderedQueues) queueFor(msg *router.Message) chan *router.Message {
+	id := ""
+	if msg.Container != nil {
+		id = msg.Container.ID
+	}
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	return o.queues[h.Sum32()%uint32(len(o.queues))]
+}
+
+// keepOrder turns off the paths that would send an ordered route's messages
+// out of order: batches, which are sent by the flush timer as well as by the
+// workers, and the buffer, whose requests are replayed alongside newer ones.
+// Requests that fail are written to the dead-letter directory instead, if
+// there is one.
+func (s *Adapter) keepOrder() {
+	if s.ordered == nil {
+		return
+	}
+	if s.batches != nil {
+		log.Warn("Not batching, since SUMOLOGIC_ORDERED is set")
+		s.batches = nil
+	}
+	if s.spool != nil {
+		log.Warn("Not buffering failed sends, since SUMOLOGIC_ORDERED is set")
+		s.spool = nil
+	}
+}
+
+// startOrderedWorkers starts a single worker for each ordered queue.
+func (s *Adapter) startOrderedWorkers() {
+	for _, queue := range s.ordered.queues {
+		s.startWorkers(queue, 1)
+	}
+}
+
+// queued returns how many messages are waiting in the ordered queues.
+func (o *orderedQueues) queued() int64 {
+	if o == nil {
+		return 0
+	}
+	queued := 0
+	for _, queue := range o.queues {
+		queued += len(queue)
+	}
+	return int64(queued)
+}
+
+// close closes every queue, so that their workers stop once they've sent
+// what's queued.
+func (o *orderedQueues) close() {
+	if o == nil {
+		return
+	}
+	for _, queue := range o.queues {
+		close(queue)
+	}
+}
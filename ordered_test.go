@@ -0,0 +1,101 @@
+package sumologic
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+func (ts *TestSuite) Test_ordered_disabled_by_default() {
+	adapter := ts.mkAdapter(&router.Route{Address: noServer})
+	ts.Nil(adapter.ordered)
+}
+
+func (ts *TestSuite) Test_orderedQueues_queueFor() {
+	ts.Setenv("SUMOLOGIC_ORDERED", "true")
+	ts.Setenv("SUMOLOGIC_WORKERS", "2")
+	adapter := ts.mkAdapter(&router.Route{Address: noServer})
+	ts.Len(adapter.ordered.queues, 2)
+
+	abc := adapter.queueFor(mkLine("abc", "one"))
+	ts.True(abc == adapter.queueFor(mkLine("abc", "two")))
+	ts.False(abc == adapter.queueFor(mkLine("def", "three")))
+	ts.False(abc == adapter.queue)
+	// Messages without a container still have a queue.
+	ts.NotNil(adapter.queueFor(&router.Message{Data: "four"}))
+}
+
+func (ts *TestSuite) Test_Stream_ordered_holds_up_only_the_same_container() {
+	ts.Setenv("SUMOLOGIC_ORDERED", "true")
+	ts.Setenv("SUMOLOGIC_WORKERS", "2")
+	release := make(chan struct{})
+	arrived := make(chan string, 10)
+	adapter := ts.adapterFor(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			message := ts.ReadJSON(r.Body)["message"].(string)
+			arrived <- message
+			if message == "abc one" {
+				<-release
+			}
+		}), newRealClock())
+	ts.AddCleanup(func() { close(release) })
+
+	ch := make(chan *router.Message)
+	go adapter.Stream(ch)
+	ts.AddCleanup(func() { close(ch) })
+	ch <- mkLine("abc", "abc one")
+	ts.Equal("abc one", <-arrived)
+	ch <- mkLine("abc", "abc two")
+	ch <- mkLine("def", "def one")
+	ts.Equal("def one", <-arrived)
+	select {
+	case message := <-arrived:
+		ts.Fail("abc's messages should be sent in order", message)
+	case <-time.After(50 * time.Millisecond):
+	}
+	release <- struct{}{}
+	ts.Equal("abc two", <-arrived)
+}
+
+func (ts *TestSuite) Test_Stream_ordered_keeps_each_containers_order() {
+	ts.Setenv("SUMOLOGIC_ORDERED", "true")
+	ts.Setenv("SUMOLOGIC_WORKERS", "4")
+	requests := make(chan *RequestData, 100)
+	adapter := ts.FakeSumo(requests)
+
+	ch := make(chan *router.Message)
+	go adapter.Stream(ch)
+	ts.AddCleanup(func() { close(ch) })
+	ids := []string{"abc", "def", "ghi"}
+	expected := map[string][]string{}
+	for i := 0; i < 30; i++ {
+		id := ids[i%len(ids)]
+		data := id + " " + strconv.Itoa(i)
+		ch <- mkLine(id, data)
+		expected[id] = append(expected[id], data)
+	}
+	received := map[string][]string{}
+	for i := 0; i < 30; i++ {
+		data := (<-requests).Body["message"].(string)
+		id := strings.Fields(data)[0]
+		received[id] = append(received[id], data)
+	}
+	ts.Equal(expected, received)
+}
+
+func (ts *TestSuite) Test_ordered_skips_batches_and_buffer() {
+	ts.CaptureLogs()
+	dir := ts.WithoutError(ioutil.TempDir("", "spool")).(string)
+	ts.AddCleanup(func() { os.RemoveAll(dir) })
+	ts.Setenv("SUMOLOGIC_ORDERED", "true")
+	ts.Setenv("SUMOLOGIC_BATCH_SIZE", "10")
+	ts.Setenv("SUMOLOGIC_BUFFER_DIR", dir)
+	adapter := ts.mkAdapter(&router.Route{Address: noServer})
+	ts.Nil(adapter.batches)
+	ts.Nil(adapter.spool)
+}
@@ -0,0 +1,47 @@
+package sumologic
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// overflowed reroutes an oversized event to the overflow category, cut down
+// to SUMOLOGIC_OVERFLOW_TRUNCATE_BYTES, so that a container logging huge
+// events still has some visibility without them holding up (or being
+// rejected from) its usual category. It returns the headers to send the
+// event with, and false if the event wasn't changed.
+func overflowed(data *Data, headers http.Header, config *Config) (http.Header, bool) {
+	if config.overflowCategory == "" ||
+		int64(len(data.Message)) <= config.overflowTruncateBytes {
+		return headers, false
+	}
+	data.Message = truncate(data.Message, config.overflowTruncateBytes)
+	// What's left of a json message isn't json any more.
+	data.parsed = nil
+	if data.Processing != nil {
+		data.Processing.Truncated = true
+	}
+	rerouted := http.Header{}
+	for key, values := range headers {
+		rerouted[key] = values
+	}
+	rerouted.Set("X-Sumo-Category", config.overflowCategory)
+	return rerouted, true
+}
+
+// oversized returns the headers to send an event with, rerouting it to the
+// overflow category if it's larger than SUMOLOGIC_OVERFLOW_THRESHOLD_BYTES.
+func oversized(data *Data, headers http.Header, config *Config) http.Header {
+	if int64(len(data.Message)) <= config.overflowThresholdBytes {
+		return headers
+	}
+	headers, _ = overflowed(data, headers, config)
+	return headers
+}
+
+// truncate cuts text down to at most max bytes (without splitting a
+// character), noting how much was cut.
+func truncate(text string, max int64) string {
+	cut := cutAt(text, max)
+	return fmt.Sprintf("%s... [truncated %d bytes]", text[:cut], len(text)-cut)
+}
@@ -0,0 +1,92 @@
+package sumologic
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+func (ts *TestSuite) Test_truncate() {
+	ts.Equal("hello... [truncated 6 bytes]", truncate("hello world", 5))
+	// The cut is moved back rather than splitting the é.
+	ts.Equal("caf... [truncated 3 bytes]", truncate("café!", 4))
+}
+
+func (ts *TestSuite) Test_oversized() {
+	ts.Setenv("SUMOLOGIC_OVERFLOW_CATEGORY", "overflow")
+	ts.Setenv("SUMOLOGIC_OVERFLOW_THRESHOLD_BYTES", "10")
+	ts.Setenv("SUMOLOGIC_OVERFLOW_TRUNCATE_BYTES", "5")
+	config := buildConfig(&router.Route{})
+	headers := http.Header{"X-Sumo-Category": {"app"}, "X-Sumo-Name": {"foo"}}
+
+	data := &Data{Message: "short"}
+	ts.Equal(headers, oversized(data, headers, config))
+	ts.Equal("short", data.Message)
+
+	data = &Data{Message: "much too long", Processing: &Processing{}}
+	rerouted := oversized(data, headers, config)
+	ts.Equal("overflow", rerouted.Get("X-Sumo-Category"))
+	ts.Equal("foo", rerouted.Get("X-Sumo-Name"))
+	ts.Equal("app", headers.Get("X-Sumo-Category"))
+	ts.Equal("much ... [truncated 8 bytes]", data.Message)
+	ts.True(data.Processing.Truncated)
+}
+
+func (ts *TestSuite) Test_oversized_without_overflow_category() {
+	ts.Setenv("SUMOLOGIC_OVERFLOW_THRESHOLD_BYTES", "10")
+	config := buildConfig(&router.Route{})
+	data := &Data{Message: "much too long"}
+	headers := http.Header{"X-Sumo-Category": {"app"}}
+	ts.Equal(headers, oversized(data, headers, config))
+	ts.Equal("much too long", data.Message)
+}
+
+func (ts *TestSuite) Test_Send_reroutes_rejected_events() {
+	ts.CaptureLogs()
+	ts.Setenv("SUMOLOGIC_OVERFLOW_CATEGORY", "overflow")
+	ts.Setenv("SUMOLOGIC_OVERFLOW_TRUNCATE_BYTES", "10")
+	ts.Setenv("SUMOLOGIC_SOURCE_CATEGORY", "app")
+	auditPath := filepath.Join(ts.auditDir(), "audit.log")
+	ts.Setenv("SUMOLOGIC_AUDIT_FILE", auditPath)
+	deadLetterDir := ts.WithoutError(ioutil.TempDir("", "deadletter")).(string)
+	ts.AddCleanup(func() { os.RemoveAll(deadLetterDir) })
+	ts.Setenv("SUMOLOGIC_DEAD_LETTER_DIR", deadLetterDir)
+	categories := make(chan string, 2)
+	messages := make(chan string, 1)
+	adapter := ts.adapterFor(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			categories <- r.Header.Get("X-Sumo-Category")
+			if len(body) > 400 {
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+				return
+			}
+			var data Data
+			ts.NoError(json.Unmarshal(body, &data))
+			messages <- data.Message
+		}), newRealClock())
+
+	msg := mkContainerMessage("abc", "foo")
+	msg.Data = strings.Repeat("x", 500)
+	ts.NoError(adapter.Send(msg))
+	ts.Equal("app", <-categories)
+	ts.Equal("overflow", <-categories)
+	ts.Equal("xxxxxxxxxx... [truncated 490 bytes]", <-messages)
+
+	// The event was delivered in the end, so the rejected attempt isn't
+	// recorded as a failure.
+	status := adapter.Status()
+	ts.EqualValues(1, status.Sent)
+	ts.EqualValues(0, status.Failed)
+	ts.Empty(adapter.deadLetters.files())
+	adapter.Close()
+	records := ts.audited(auditPath)
+	ts.Len(records, 1)
+	ts.Equal(auditSent, records[0].Status)
+	ts.Equal("overflow", records[0].Category)
+}
@@ -0,0 +1,96 @@
+package sumologic
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// How json messages are sent, when they're parsed.
+const (
+	// parseJSONMessage sends the parsed object as the event's message.
+	parseJSONMessage = "message"
+	// parseJSONMerge sends the parsed object's fields alongside the
+	// container's metadata, in place of the message.
+	parseJSONMerge = "merge"
+)
+
+// getparsejsonmodeopt retrieves how parsed json messages are sent.
+func (o routeOptions) getparsejsonmodeopt(name string) string {
+	value := o.getopt(name, parseJSONMessage)
+	if value != parseJSONMessage && value != parseJSONMerge {
+		parseFailed(name, value, nil)
+		return parseJSONMessage
+	}
+	return value
+}
+
+// parseJSON returns a message as json, if it's a json object, or nil.
+func parseJSON(message string) json.RawMessage {
+	trimmed := strings.TrimSpace(message)
+	if !strings.HasPrefix(trimmed, "{") || !json.Valid([]byte(trimmed)) {
+		return nil
+	}
+	return json.RawMessage(trimmed)
+}
+
+// MarshalJSON encodes an event. A message that was parsed as json is sent as
+// an object rather than a string, or has its fields merged into the event.
+func (d *Data) MarshalJSON() ([]byte, error) {
+	// The alias has the same fields but not this method, so that it can be
+	// encoded the usual way.
+	type data Data
+	if d.parsed == nil {
+		return json.Marshal((*data)(d))
+	}
+	if !d.mergeParsed {
+		return json.Marshal(&struct {
+			Message json.RawMessage `json:"message"`
+			*data
+		}{d.parsed, (*data)(d)})
+	}
+
+	encoded, err := json.Marshal((*data)(d))
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]json.RawMessage{}
+	if err = json.Unmarshal(encoded, &fields); err != nil {
+		return nil, err
+	}
+	delete(fields, "message")
+	parsed := map[string]json.RawMessage{}
+	if err = json.Unmarshal(d.parsed, &parsed); err != nil {
+		return nil, err
+	}
+	// The event's own fields win over the message's, so that queries on
+	// them can be relied on.
+	for key, value := range parsed {
+		if _, ok := fields[key]; !ok {
+			fields[key] = value
+		}
+	}
+	return json.Marshal(fields)
+}
+
+// UnmarshalJSON decodes an event, such as an archived one, including one
+// whose message was sent as an object. Fields that were merged into the
+// event in place of its message aren't recovered.
+func (d *Data) UnmarshalJSON(b []byte) error {
+	type data Data
+	event := struct {
+		Message json.RawMessage `json:"message"`
+		*data
+	}{data: (*data)(d)}
+	if err := json.Unmarshal(b, &event); err != nil {
+		return err
+	}
+	if len(event.Message) > 0 && event.Message[0] == '{' {
+		d.parsed = event.Message
+		d.Message = string(event.Message)
+		return nil
+	}
+	if len(event.Message) > 0 {
+		return json.Unmarshal(event.Message, &d.Message)
+	}
+	return nil
+}
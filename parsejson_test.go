@@ -0,0 +1,95 @@
+package sumologic
+
+import (
+	"encoding/json"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+func (ts *TestSuite) Test_parseJSON() {
+	ts.Equal(json.RawMessage(`{"level":"info"}`),
+		parseJSON(` {"level":"info"}`+"\n"))
+	for _, data := range []string{
+		"Some data.",
+		`["a","b"]`,
+		`42`,
+		`{"level":"info"`,
+	} {
+		ts.Nil(parseJSON(data), data)
+	}
+}
+
+func (ts *TestSuite) Test_buildData_parses_json_only_when_enabled() {
+	msg := mkLine("abc", `{"level":"info"}`)
+	ts.Nil(buildData(msg, buildConfig(&router.Route{})).parsed)
+
+	ts.Setenv("SUMOLOGIC_PARSE_JSON", "true")
+	data := buildData(msg, buildConfig(&router.Route{}))
+	ts.Equal(json.RawMessage(`{"level":"info"}`), data.parsed)
+	ts.False(data.mergeParsed)
+}
+
+func (ts *TestSuite) Test_Data_MarshalJSON_sends_message_object() {
+	ts.Setenv("SUMOLOGIC_PARSE_JSON", "true")
+	data := buildData(mkLine("abc", `{"level":"info","n":1}`),
+		buildConfig(&router.Route{}))
+	var event jsonobj
+	ts.NoError(json.Unmarshal(ts.WithoutError(json.Marshal(data)).([]byte), &event))
+	ts.Equal(jsonobj{"level": "info", "n": float64(1)}, event["message"])
+	ts.Equal("abc", event["container"].(jsonobj)["docker_id"])
+}
+
+func (ts *TestSuite) Test_Data_MarshalJSON_merges_fields() {
+	ts.Setenv("SUMOLOGIC_PARSE_JSON", "true")
+	ts.Setenv("SUMOLOGIC_PARSE_JSON_MODE", "merge")
+	data := buildData(mkLine("abc", `{"level":"info","container":"mine"}`),
+		buildConfig(&router.Route{}))
+	var event jsonobj
+	ts.NoError(json.Unmarshal(ts.WithoutError(json.Marshal(data)).([]byte), &event))
+	ts.Equal("info", event["level"])
+	ts.Equal("abc", event["container"].(jsonobj)["docker_id"],
+		"the event's own fields win")
+	ts.NotContains(event, "message")
+}
+
+func (ts *TestSuite) Test_Data_UnmarshalJSON_with_message_object() {
+	data := &Data{}
+	ts.NoError(json.Unmarshal(
+		[]byte(`{"message":{"level":"info"},"timestamp":"1"}`), data))
+	ts.Equal(`{"level":"info"}`, data.Message)
+	ts.Equal("1", data.Timestamp)
+	ts.Equal(`{"message":{"level":"info"},"container":null,"timestamp":"1"}`,
+		string(ts.WithoutError(json.Marshal(data)).([]byte)))
+
+	data = &Data{}
+	ts.NoError(json.Unmarshal([]byte(`{"message":"Some data."}`), data))
+	ts.Equal("Some data.", data.Message)
+	ts.Nil(data.parsed)
+}
+
+func (ts *TestSuite) Test_getparsejsonmodeopt_with_bad_value() {
+	ts.CaptureLogs()
+	ts.Setenv("SUMOLOGIC_PARSE_JSON_MODE", "flatten")
+	ts.Equal(parseJSONMessage, envOptions.getparsejsonmodeopt("SUMOLOGIC_PARSE_JSON_MODE"))
+}
+
+func (ts *TestSuite) Test_Stream_parses_json_messages() {
+	ts.Setenv("SUMOLOGIC_PARSE_JSON", "true")
+	ts.Setenv("SUMOLOGIC_PROCESSING_FLAGS", "true")
+	ts.Setenv("SUMOLOGIC_WORKERS", "1")
+	requests := make(chan *RequestData, 2)
+	adapter := ts.FakeSumo(requests)
+
+	ch := make(chan *router.Message)
+	go adapter.Stream(ch)
+	ch <- mkLine("abc", `{"level":"error","msg":"Oops."}`)
+	ch <- mkLine("abc", "Not json.")
+	close(ch)
+
+	request := <-requests
+	ts.Equal(jsonobj{"level": "error", "msg": "Oops."}, request.Body["message"])
+	ts.Equal(true, request.Body["_processing"].(jsonobj)["json_parsed"])
+	request = <-requests
+	ts.Equal("Not json.", request.Body["message"])
+	ts.NotContains(request.Body["_processing"], "json_parsed")
+}
@@ -0,0 +1,78 @@
+package sumologic
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// payloadLimit is the largest request body Sumo Logic recommends
+	// sending. Requests aren't compressed, so their size is compared with
+	// this as it is.
+	payloadLimit = 1024 * 1024
+	// payloadWindow is how many recent requests are considered.
+	payloadWindow = 100
+	// payloadWarnSize is how close a request has to come to the limit to
+	// count towards a warning.
+	payloadWarnSize = payloadLimit * 8 / 10
+	// payloadWarnCount is how many recent requests have to come that close
+	// before a warning is logged.
+	payloadWarnCount = 10
+	// payloadWarnInterval is the least time between warnings.
+	payloadWarnInterval = 10 * time.Minute
+)
+
+// payloadTracker keeps track of the sizes of recent request bodies, and
+// warns when they regularly approach Sumo Logic's payload size limit, so that
+// the config can be tuned before requests start failing outright.
+type payloadTracker struct {
+	mu         sync.Mutex
+	clock      Clock
+	sizes      [payloadWindow]int64
+	next       int
+	lastWarned time.Time
+}
+
+func newPayloadTracker(clock Clock) *payloadTracker {
+	return &payloadTracker{clock: clock}
+}
+
+// record adds the size of a request body to the window, and logs a warning
+// if too many recent ones have been close to the limit.
+func (p *payloadTracker) record(size int64, config *Config) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sizes[p.next] = size
+	p.next = (p.next + 1) % payloadWindow
+
+	near, largest := 0, int64(0)
+	for _, size := range p.sizes {
+		if size >= payloadWarnSize {
+			near++
+		}
+		if size > largest {
+			largest = size
+		}
+	}
+	now := p.clock.Now()
+	if near < payloadWarnCount ||
+		(!p.lastWarned.IsZero() && now.Sub(p.lastWarned) < payloadWarnInterval) {
+		return
+	}
+	p.lastWarned = now
+
+	suggestion := "Messages are close to the limit on their own; " +
+		"check for oversized log lines"
+	if config.batchSize > 1 {
+		suggestion = "Lower SUMOLOGIC_BATCH_SIZE"
+	}
+	log.WithFields(log.Fields{
+		"near_limit":    near,
+		"of_last":       payloadWindow,
+		"largest_bytes": largest,
+		"limit_bytes":   payloadLimit,
+		"suggestion":    suggestion,
+	}).Warn("Requests are regularly approaching Sumo Logic's payload size limit")
+}
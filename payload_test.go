@@ -0,0 +1,61 @@
+package sumologic
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+func (ts *TestSuite) Test_payloadTracker_warns_when_near_limit() {
+	hook, _ := ts.CaptureLogs()
+	clock := newFakeClock()
+	p := newPayloadTracker(clock)
+	config := &Config{batchSize: 500}
+
+	for i := 0; i < payloadWarnCount-1; i++ {
+		p.record(payloadWarnSize, config)
+		p.record(1000, config)
+	}
+	ts.Nil(hook.LastEntry())
+	p.record(payloadLimit, config)
+
+	entry := hook.LastEntry()
+	ts.Equal(logrus.WarnLevel, entry.Level)
+	ts.Equal("Requests are regularly approaching Sumo Logic's payload size limit",
+		entry.Message)
+	ts.Equal(payloadWarnCount, entry.Data["near_limit"])
+	ts.Equal(int64(payloadLimit), entry.Data["largest_bytes"])
+	ts.Equal("Lower SUMOLOGIC_BATCH_SIZE", entry.Data["suggestion"])
+}
+
+func (ts *TestSuite) Test_payloadTracker_warns_at_most_every_interval() {
+	hook, _ := ts.CaptureLogs()
+	clock := newFakeClock()
+	p := newPayloadTracker(clock)
+	config := &Config{batchSize: 1}
+
+	for i := 0; i < payloadWarnCount; i++ {
+		p.record(payloadLimit, config)
+	}
+	ts.Len(hook.Entries, 1)
+	ts.Contains(hook.LastEntry().Data["suggestion"], "oversized log lines")
+
+	p.record(payloadLimit, config)
+	ts.Len(hook.Entries, 1)
+	clock.Advance(payloadWarnInterval)
+	p.record(payloadLimit, config)
+	ts.Len(hook.Entries, 2)
+}
+
+func (ts *TestSuite) Test_payloadTracker_forgets_old_requests() {
+	hook, _ := ts.CaptureLogs()
+	p := newPayloadTracker(newFakeClock())
+	config := &Config{}
+
+	for i := 0; i < payloadWarnCount-1; i++ {
+		p.record(payloadLimit, config)
+	}
+	for i := 0; i < payloadWindow; i++ {
+		p.record(1000, config)
+	}
+	p.record(payloadLimit, config)
+	ts.Empty(hook.Entries)
+}
@@ -0,0 +1,35 @@
+package sumologic
+
+import (
+	"net/http"
+	"time"
+)
+
+// connPool holds how many connections the HTTP client keeps to Sumologic,
+// so that sustained sending reuses them rather than making a new TLS
+// handshake for each request.
+type connPool struct {
+	maxIdle        int64
+	maxIdlePerHost int64
+	maxPerHost     int64
+	idleTimeoutMs  int64
+}
+
+// getconnpoolopts retrieves the connection pool settings. Idle connections
+// are kept for up to one per worker by default, so that each can reuse one.
+func (o routeOptions) getconnpoolopts(workers int64) connPool {
+	return connPool{
+		maxIdle:        o.getintopt("SUMOLOGIC_MAX_IDLE_CONNS", 100),
+		maxIdlePerHost: o.getintopt("SUMOLOGIC_MAX_IDLE_CONNS_PER_HOST", workers),
+		maxPerHost:     o.getintopt("SUMOLOGIC_MAX_CONNS_PER_HOST", 0),
+		idleTimeoutMs:  o.getintopt("SUMOLOGIC_IDLE_CONN_TIMEOUT_MS", 90000),
+	}
+}
+
+// apply sets a transport's connection pool settings.
+func (p connPool) apply(transport *http.Transport) {
+	transport.MaxIdleConns = int(p.maxIdle)
+	transport.MaxIdleConnsPerHost = int(p.maxIdlePerHost)
+	transport.IdleConnTimeout = time.Duration(p.idleTimeoutMs) * time.Millisecond
+	setMaxConnsPerHost(transport, int(p.maxPerHost))
+}
@@ -0,0 +1,19 @@
+//go:build !go1.11
+// +build !go1.11
+
+package sumologic
+
+import (
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// setMaxConnsPerHost can't cap the connections a transport makes before Go
+// 1.11, so SUMOLOGIC_MAX_INFLIGHT has to be used instead.
+func setMaxConnsPerHost(transport *http.Transport, max int) {
+	if max > 0 {
+		log.Warn("SUMOLOGIC_MAX_CONNS_PER_HOST needs Go 1.11 or later, " +
+			"use SUMOLOGIC_MAX_INFLIGHT instead")
+	}
+}
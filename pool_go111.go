@@ -0,0 +1,14 @@
+//go:build go1.11
+// +build go1.11
+
+package sumologic
+
+import (
+	"net/http"
+)
+
+// setMaxConnsPerHost caps the connections a transport makes to each host,
+// if max is positive.
+func setMaxConnsPerHost(transport *http.Transport, max int) {
+	transport.MaxConnsPerHost = max
+}
@@ -0,0 +1,14 @@
+//go:build go1.11
+// +build go1.11
+
+package sumologic
+
+import (
+	"github.com/gliderlabs/logspout/router"
+)
+
+func (ts *TestSuite) Test_newTransport_max_conns_per_host() {
+	ts.Equal(0, newTransport(buildConfig(&router.Route{})).MaxConnsPerHost)
+	ts.Setenv("SUMOLOGIC_MAX_CONNS_PER_HOST", "12")
+	ts.Equal(12, newTransport(buildConfig(&router.Route{})).MaxConnsPerHost)
+}
@@ -0,0 +1,65 @@
+package sumologic
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+func (ts *TestSuite) Test_getconnpoolopts_defaults() {
+	ts.Equal(connPool{
+		maxIdle:        100,
+		maxIdlePerHost: 16,
+		idleTimeoutMs:  90000,
+	}, buildConfig(&router.Route{}).pool)
+
+	// Each worker can keep a connection to reuse.
+	ts.Setenv("SUMOLOGIC_WORKERS", "4")
+	ts.EqualValues(4, buildConfig(&router.Route{}).pool.maxIdlePerHost)
+}
+
+func (ts *TestSuite) Test_newTransport_pool_settings() {
+	ts.Setenv("SUMOLOGIC_MAX_IDLE_CONNS", "50")
+	ts.Setenv("SUMOLOGIC_MAX_IDLE_CONNS_PER_HOST", "8")
+	ts.Setenv("SUMOLOGIC_IDLE_CONN_TIMEOUT_MS", "30000")
+	transport := newTransport(buildConfig(&router.Route{}))
+	ts.Equal(50, transport.MaxIdleConns)
+	ts.Equal(8, transport.MaxIdleConnsPerHost)
+	ts.Equal(30*time.Second, transport.IdleConnTimeout)
+}
+
+func (ts *TestSuite) Test_clients_not_shared_across_pool_settings() {
+	address := "https://collectors.example.com/receiver"
+	a := ts.mkAdapter(&router.Route{ID: "a", Address: address})
+	ts.Setenv("SUMOLOGIC_MAX_IDLE_CONNS_PER_HOST", "2")
+	b := ts.mkAdapter(&router.Route{ID: "b", Address: address})
+	ts.False(a.client == b.client)
+}
+
+func (ts *TestSuite) Test_Send_reuses_connections() {
+	var mu sync.Mutex
+	connections := 0
+	server := httptest.NewUnstartedServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			mu.Lock()
+			connections++
+			mu.Unlock()
+		}
+	}
+	server.Start()
+	ts.AddCleanup(server.Close)
+	adapter := ts.mkAdapter(&router.Route{Address: server.URL})
+
+	for i := 0; i < 3; i++ {
+		ts.NoError(adapter.Send(mkContainerMessage("abc", "/foo")))
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	ts.Equal(1, connections)
+}
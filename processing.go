@@ -0,0 +1,94 @@
+package sumologic
+
+import (
+	"sync"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+// Processing records which transformations the adapter applied to an event
+// on its way through, so that it's clear downstream whether the event was
+// modified in flight.
+type Processing struct {
+	Unwrapped       bool `json:"unwrapped,omitempty"`
+	Truncated       bool `json:"truncated,omitempty"`
+	Redacted        bool `json:"redacted,omitempty"`
+	Sanitized       bool `json:"sanitized,omitempty"`
+	Sampled         bool `json:"sampled,omitempty"`
+	MultilineJoined bool `json:"multiline_joined,omitempty"`
+	JSONParsed      bool `json:"json_parsed,omitempty"`
+	// SampleRate is how many messages a sampled event stands for.
+	SampleRate int64 `json:"sample_rate,omitempty"`
+	// Skipped is how many messages from the container were left out by
+	// SUMOLOGIC_SAMPLE_RATE since the previous event.
+	Skipped int64 `json:"skipped,omitempty"`
+}
+
+// annotations holds the processing flags for messages that are on their way
+// through the adapter, until their events are built.
+type annotations struct {
+	mu       sync.Mutex
+	messages map[*router.Message]*Processing
+}
+
+func newAnnotations() *annotations {
+	return &annotations{messages: map[*router.Message]*Processing{}}
+}
+
+// take returns the processing flags for a message, if any, and forgets them.
+func (a *annotations) take(msg *router.Message) *Processing {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	processing := a.messages[msg]
+	delete(a.messages, msg)
+	return processing
+}
+
+// move transfers a message's processing flags to a message built from it.
+func (a *annotations) move(from *router.Message, to *router.Message) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if processing, ok := a.messages[from]; ok {
+		delete(a.messages, from)
+		a.messages[to] = processing
+	}
+}
+
+// annotate records a transformation applied to a message, if processing
+// flags are enabled.
+func (s *Adapter) annotate(
+	msg *router.Message, config *Config, f func(*Processing)) {
+	if !config.processingFlags {
+		return
+	}
+	s.annotations.record(msg, f)
+}
+
+// record records a transformation applied to a message, whether or not
+// processing flags are enabled.
+func (a *annotations) record(msg *router.Message, f func(*Processing)) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	processing, ok := a.messages[msg]
+	if !ok {
+		processing = &Processing{}
+		a.messages[msg] = processing
+	}
+	f(processing)
+}
+
+// processing returns the processing flags to attach to a message's event,
+// or nil if processing flags aren't enabled. Events sampled by
+// SUMOLOGIC_SAMPLE_RATE or truncated by SUMOLOGIC_MAX_MESSAGE_BYTES always
+// have them, so that what they stand for isn't lost.
+func (s *Adapter) processing(msg *router.Message, config *Config) *Processing {
+	processing := s.annotations.take(msg)
+	if !config.processingFlags && (processing == nil ||
+		!processing.Sampled && !processing.Truncated) {
+		return nil
+	}
+	if processing == nil {
+		processing = &Processing{}
+	}
+	return processing
+}
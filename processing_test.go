@@ -0,0 +1,61 @@
+package sumologic
+
+import (
+	"github.com/gliderlabs/logspout/router"
+)
+
+func (ts *TestSuite) streamOne(adapter *Adapter, msg *router.Message) {
+	ch := make(chan *router.Message)
+	done := make(chan struct{})
+	go func() {
+		adapter.Stream(ch)
+		close(done)
+	}()
+	ch <- msg
+	close(ch)
+	<-done
+}
+
+func (ts *TestSuite) Test_processing_flags_disabled_by_default() {
+	requests := make(chan *RequestData, 1)
+	adapter := ts.FakeSumo(requests)
+	msg := mkContainerMessage("abc", "/foo")
+	msg.Data = `{"log":"Some data.\n","stream":"stdout"}`
+	ts.streamOne(adapter, msg)
+
+	ts.NotContains((<-requests).Body, "_processing")
+	ts.Empty(adapter.annotations.messages)
+}
+
+func (ts *TestSuite) Test_processing_flags_unmodified() {
+	ts.Setenv("SUMOLOGIC_PROCESSING_FLAGS", "true")
+	requests := make(chan *RequestData, 1)
+	adapter := ts.FakeSumo(requests)
+	ts.streamOne(adapter, mkContainerMessage("abc", "/foo"))
+
+	ts.Equal(jsonobj{}, (<-requests).Body["_processing"])
+}
+
+func (ts *TestSuite) Test_processing_flags_unwrapped() {
+	ts.Setenv("SUMOLOGIC_PROCESSING_FLAGS", "true")
+	requests := make(chan *RequestData, 1)
+	adapter := ts.FakeSumo(requests)
+	msg := mkContainerMessage("abc", "/foo")
+	msg.Data = `{"log":"Some data.\n","stream":"stdout"}`
+	ts.streamOne(adapter, msg)
+
+	ts.Equal(jsonobj{"unwrapped": true}, (<-requests).Body["_processing"])
+	ts.Empty(adapter.annotations.messages)
+}
+
+func (ts *TestSuite) Test_processing_flags_forgotten_when_dropped() {
+	ts.Setenv("SUMOLOGIC_PROCESSING_FLAGS", "true")
+	ts.Setenv("SUMOLOGIC_MIN_MESSAGE_LENGTH", "100")
+	adapter := ts.FakeSumo(nil)
+	msg := mkContainerMessage("abc", "/foo")
+	msg.Data = `{"log":"Some data.\n","stream":"stdout"}`
+	ts.streamOne(adapter, msg)
+
+	ts.Equal(int64(1), adapter.Status().Dropped)
+	ts.Empty(adapter.annotations.messages)
+}
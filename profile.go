@@ -0,0 +1,55 @@
+package sumologic
+
+// profiles are named sets of defaults for the options that govern
+// throughput, roughly matched to the ingest limits of Sumologic account
+// tiers. Options that are set explicitly still take precedence.
+var profiles = map[string]map[string]string{
+	"low": {
+		"SUMOLOGIC_BATCH_SIZE":         "10",
+		"SUMOLOGIC_WORKERS":            "2",
+		"SUMOLOGIC_MAX_MSGS_PER_SEC":   "200",
+		"SUMOLOGIC_MAX_BYTES_PER_SEC":  "262144",
+		"SUMOLOGIC_MAX_INFLIGHT_BYTES": "1048576",
+		"SUMOLOGIC_SLOW_START_MS":      "60000",
+		"SUMOLOGIC_SLOW_START_RATE":    "5",
+		"SUMOLOGIC_RETRIES":            "5",
+		"SUMOLOGIC_BACKOFF":            "1000",
+	},
+	"standard": {
+		"SUMOLOGIC_BATCH_SIZE":         "100",
+		"SUMOLOGIC_WORKERS":            "8",
+		"SUMOLOGIC_MAX_MSGS_PER_SEC":   "2000",
+		"SUMOLOGIC_MAX_BYTES_PER_SEC":  "2097152",
+		"SUMOLOGIC_MAX_INFLIGHT_BYTES": "8388608",
+		"SUMOLOGIC_SLOW_START_MS":      "30000",
+		"SUMOLOGIC_SLOW_START_RATE":    "20",
+		"SUMOLOGIC_RETRIES":            "3",
+		"SUMOLOGIC_BACKOFF":            "500",
+	},
+	"high": {
+		"SUMOLOGIC_BATCH_SIZE":         "500",
+		"SUMOLOGIC_WORKERS":            "32",
+		"SUMOLOGIC_MAX_MSGS_PER_SEC":   "20000",
+		"SUMOLOGIC_MAX_BYTES_PER_SEC":  "20971520",
+		"SUMOLOGIC_MAX_INFLIGHT_BYTES": "67108864",
+		"SUMOLOGIC_SLOW_START_MS":      "10000",
+		"SUMOLOGIC_SLOW_START_RATE":    "100",
+		"SUMOLOGIC_RETRIES":            "2",
+		"SUMOLOGIC_BACKOFF":            "100",
+	},
+}
+
+// checkProfile logs the profile named by SUMOLOGIC_PROFILE if there's no such
+// profile.
+func (o routeOptions) checkProfile() {
+	name := o.lookupopt("SUMOLOGIC_PROFILE")
+	if _, ok := profiles[name]; name != "" && !ok {
+		parseFailed("SUMOLOGIC_PROFILE", name, nil)
+	}
+}
+
+// profileDefault returns the default for an option from the profile named by
+// SUMOLOGIC_PROFILE, or "" if there isn't one.
+func (o routeOptions) profileDefault(name string) string {
+	return profiles[o.lookupopt("SUMOLOGIC_PROFILE")][name]
+}
@@ -0,0 +1,39 @@
+package sumologic
+
+import (
+	"github.com/gliderlabs/logspout/router"
+	"github.com/sirupsen/logrus"
+)
+
+func (ts *TestSuite) Test_profiles_set_defaults() {
+	ts.Setenv("SUMOLOGIC_PROFILE", "low")
+	ts.Setenv("SUMOLOGIC_RETRIES", "7")
+	config := buildConfig(&router.Route{})
+	ts.EqualValues(1048576, config.maxInflight)
+	ts.EqualValues(60000, config.slowStartMs)
+	ts.EqualValues(10, config.batchSize)
+	ts.EqualValues(2, config.workers)
+	ts.EqualValues(200, config.maxMsgsPerSec)
+	ts.EqualValues(262144, config.maxBytesPerSec)
+	ts.EqualValues(7, config.retries)
+	ts.EqualValues(10000, config.timeout)
+}
+
+func (ts *TestSuite) Test_profiles_cover_the_same_options() {
+	for name, defaults := range profiles {
+		ts.Equal(len(profiles["standard"]), len(defaults), name)
+		for option := range profiles["standard"] {
+			ts.Contains(defaults, option, name)
+		}
+	}
+}
+
+func (ts *TestSuite) Test_profiles_unknown() {
+	hook, _ := ts.CaptureLogs()
+	ts.Setenv("SUMOLOGIC_PROFILE", "huge")
+	config := buildConfig(&router.Route{})
+	ts.EqualValues(2, config.retries)
+	ts.Equal(logrus.ErrorLevel, hook.LastEntry().Level)
+	ts.Equal("Failed to parse", hook.LastEntry().Message)
+	ts.Equal("huge", hook.LastEntry().Data["SUMOLOGIC_PROFILE"])
+}
@@ -0,0 +1,74 @@
+package sumologic
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// getproxyopt returns the URL of the proxy to send requests through, if it's
+// set to one that can be parsed, or "" otherwise. A proxy without a scheme is
+// taken to be an http one, e.g. "proxy.internal:3128".
+func (o routeOptions) getproxyopt(name string) string {
+	value := strings.TrimSpace(o.lookupopt(name))
+	if value == "" {
+		return ""
+	}
+	if !strings.Contains(value, "://") {
+		value = "http://" + value
+	}
+	u, err := url.Parse(value)
+	if err != nil {
+		parseFailed(name, maskProxy(value), maskError(err))
+		return ""
+	}
+	if (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5") ||
+		u.Host == "" {
+		parseFailed(name, maskProxy(value),
+			errors.New("expected an http, https or socks5 URL"))
+		return ""
+	}
+	return u.String()
+}
+
+// maskProxy hides the password in a proxy URL, if it has one.
+func maskProxy(proxy string) string {
+	u, err := url.Parse(proxy)
+	if err != nil || u.User == nil {
+		return proxy
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), "xxxxx")
+	}
+	return u.String()
+}
+
+// proxy returns the function transports use to choose a proxy for a request:
+// SUMOLOGIC_PROXY_URL for every request, if it's set, or else whatever
+// HTTPS_PROXY, HTTP_PROXY and NO_PROXY say.
+func (config *Config) proxy() func(*http.Request) (*url.URL, error) {
+	if config.proxyURL == "" {
+		return http.ProxyFromEnvironment
+	}
+	u, err := url.Parse(config.proxyURL)
+	if err != nil {
+		// getproxyopt has already checked it, so this shouldn't happen.
+		return http.ProxyFromEnvironment
+	}
+	return http.ProxyURL(u)
+}
+
+// dialAddress returns the host and port to connect to, to check that an
+// endpoint can be reached: the proxy's, if requests to it go through one,
+// since the endpoint itself may not be reachable directly.
+func (config *Config) dialAddress(endPoint string) string {
+	u, err := url.Parse(endPoint)
+	if err == nil {
+		proxy, err := config.proxy()(&http.Request{URL: u})
+		if err == nil && proxy != nil {
+			return endpointAddress(proxy.String())
+		}
+	}
+	return endpointAddress(endPoint)
+}
@@ -0,0 +1,100 @@
+package sumologic
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// rateLimiter caps the rate at which events and bytes are sent, so that a
+// runaway container can't blow through the ingest quota or saturate the
+// host's egress. Each limit is a token bucket holding up to a second's worth
+// of its rate, so short bursts are let through at once. A nil *rateLimiter
+// doesn't limit anything.
+type rateLimiter struct {
+	mu     sync.Mutex
+	clock  Clock
+	events tokenBucket
+	bytes  tokenBucket
+}
+
+// tokenBucket is a single limit. Reservations can take it below empty, in
+// which case the caller waits for it to refill to zero.
+type tokenBucket struct {
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter returns a rateLimiter allowing up to eventsPerSec events and
+// bytesPerSec bytes a second, or nil if neither is positive. A limit that
+// isn't positive isn't applied.
+func newRateLimiter(clock Clock, eventsPerSec, bytesPerSec int64) *rateLimiter {
+	if eventsPerSec <= 0 && bytesPerSec <= 0 {
+		return nil
+	}
+	now := clock.Now()
+	return &rateLimiter{
+		clock:  clock,
+		events: newTokenBucket(eventsPerSec, now),
+		bytes:  newTokenBucket(bytesPerSec, now),
+	}
+}
+
+func newTokenBucket(rate int64, now time.Time) tokenBucket {
+	if rate <= 0 {
+		return tokenBucket{}
+	}
+	return tokenBucket{rate: float64(rate), tokens: float64(rate), last: now}
+}
+
+// reserve takes n tokens from the bucket and returns how long the caller
+// needs to wait before they're available.
+func (b *tokenBucket) reserve(now time.Time, n int64) time.Duration {
+	if b.rate <= 0 {
+		return 0
+	}
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.rate {
+		b.tokens = b.rate
+	}
+	b.last = now
+	b.tokens -= float64(n)
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// delay reserves a request holding the given number of events and bytes, and
+// returns how long the caller needs to wait before sending it.
+func (l *rateLimiter) delay(events, bytes int64) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.clock.Now()
+	d := l.events.reserve(now, events)
+	if b := l.bytes.reserve(now, bytes); b > d {
+		d = b
+	}
+	return d
+}
+
+// wait blocks until a request holding the given number of events and bytes
+// may be sent, or the context is done.
+func (l *rateLimiter) wait(ctx context.Context, events, bytes int64) error {
+	if l == nil {
+		return nil
+	}
+	d := l.delay(events, bytes)
+	if d <= 0 {
+		return nil
+	}
+	timer := l.clock.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
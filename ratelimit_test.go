@@ -0,0 +1,82 @@
+package sumologic
+
+import (
+	"context"
+	"time"
+)
+
+func (ts *TestSuite) Test_newRateLimiter_disabled() {
+	ts.Nil(newRateLimiter(newFakeClock(), 0, 0))
+	ts.Nil(newRateLimiter(newFakeClock(), -1, 0))
+
+	var l *rateLimiter
+	ts.NoError(l.wait(context.Background(), 1000, 1000000))
+}
+
+func (ts *TestSuite) Test_rateLimiter_events() {
+	clock := newFakeClock()
+	l := newRateLimiter(clock, 2, 0)
+	// A second's worth is let through at once.
+	ts.Equal(time.Duration(0), l.delay(1, 1000000))
+	ts.Equal(time.Duration(0), l.delay(1, 1000000))
+	ts.Equal(500*time.Millisecond, l.delay(1, 1000000))
+	ts.Equal(time.Second, l.delay(1, 1000000))
+
+	clock.Advance(time.Second)
+	ts.Equal(time.Second/2, l.delay(1, 0))
+}
+
+func (ts *TestSuite) Test_rateLimiter_bytes() {
+	clock := newFakeClock()
+	l := newRateLimiter(clock, 0, 1000)
+	ts.Equal(time.Duration(0), l.delay(100, 600))
+	ts.Equal(200*time.Millisecond, l.delay(100, 600))
+
+	// Idle time only refills the bucket up to a second's worth.
+	clock.Advance(time.Minute)
+	ts.Equal(time.Duration(0), l.delay(1, 1000))
+	ts.Equal(time.Second, l.delay(1, 1000))
+}
+
+func (ts *TestSuite) Test_rateLimiter_uses_the_longer_delay() {
+	clock := newFakeClock()
+	l := newRateLimiter(clock, 1, 1000)
+	ts.Equal(time.Duration(0), l.delay(1, 100))
+	ts.Equal(time.Second, l.delay(1, 100))
+	ts.Equal(2100*time.Millisecond, l.delay(1, 2900))
+}
+
+func (ts *TestSuite) Test_rateLimiter_wait_cancelled() {
+	clock := newFakeClock()
+	l := newRateLimiter(clock, 1, 0)
+	l.delay(1, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- l.wait(ctx, 1, 0) }()
+	clock.WaitForTimers(1)
+	cancel()
+	ts.Equal(context.Canceled, <-done)
+}
+
+func (ts *TestSuite) Test_Send_rate_limited() {
+	ts.Setenv("SUMOLOGIC_MAX_MSGS_PER_SEC", "1")
+	clock := newFakeClock()
+	requests := make(chan *RequestData, 2)
+	adapter := ts.FakeSumoWithClock(requests, clock)
+
+	ts.NoError(adapter.Send(mkLine("abc", "one")))
+	ts.Equal("one", (<-requests).Body["message"])
+
+	done := make(chan error)
+	go func() { done <- adapter.Send(mkLine("abc", "two")) }()
+	clock.WaitForTimers(1)
+	select {
+	case <-done:
+		ts.Fail("Sent before the rate limit allowed it.")
+	default:
+	}
+	clock.Advance(time.Second)
+	ts.NoError(<-done)
+	ts.Equal("two", (<-requests).Body["message"])
+}
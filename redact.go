@@ -0,0 +1,57 @@
+package sumologic
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+// redactPresets are patterns for common kinds of sensitive data, which can be
+// given by name in SUMOLOGIC_REDACT_PATTERNS instead of a regex.
+var redactPresets = map[string]string{
+	// 13 to 19 digits, optionally separated by spaces or dashes.
+	"credit_card":  `\b(?:\d[ -]?){12,18}\d\b`,
+	"bearer_token": `(?i)\bbearer\s+[a-z0-9\-._~+/]+=*`,
+	"email":        `(?i)\b[a-z0-9._%+\-]+@[a-z0-9.\-]+\.[a-z]{2,}\b`,
+}
+
+// getredactpatternsopt retrieves an environment variable as a list of
+// patterns to redact if it's set to a non-empty string of semicolon-separated
+// regexes or preset names, e.g. "credit_card;password=\S+". Patterns that
+// can't be parsed are logged and ignored.
+func (o routeOptions) getredactpatternsopt(name string) []*regexp.Regexp {
+	var patterns []*regexp.Regexp
+	for _, text := range strings.Split(o.lookupopt(name), ";") {
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+		if preset, ok := redactPresets[text]; ok {
+			text = preset
+		}
+		re, err := regexp.Compile(text)
+		if err != nil {
+			parseFailed(name, text, err)
+			continue
+		}
+		patterns = append(patterns, re)
+	}
+	return patterns
+}
+
+// redact returns a copy of a message with everything matching the redaction
+// patterns replaced, so that it never leaves the host. Messages without
+// anything to redact are returned unchanged.
+func redact(msg *router.Message, config *Config) *router.Message {
+	data := msg.Data
+	for _, re := range config.redactPatterns {
+		data = re.ReplaceAllLiteralString(data, config.redactReplacement)
+	}
+	if data == msg.Data {
+		return msg
+	}
+	redacted := *msg
+	redacted.Data = data
+	return &redacted
+}
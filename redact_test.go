@@ -0,0 +1,89 @@
+package sumologic
+
+import (
+	"regexp"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+func (ts *TestSuite) Test_getredactpatternsopt() {
+	ts.CaptureLogs()
+	ts.Nil(envOptions.getredactpatternsopt("SUMOLOGIC_REDACT_PATTERNS"))
+	ts.Setenv("SUMOLOGIC_REDACT_PATTERNS", `credit_card; password=\S+ ;(;`)
+	patterns := envOptions.getredactpatternsopt("SUMOLOGIC_REDACT_PATTERNS")
+	ts.Len(patterns, 2)
+	ts.Equal(redactPresets["credit_card"], patterns[0].String())
+	ts.Equal(`password=\S+`, patterns[1].String())
+}
+
+func (ts *TestSuite) Test_redact_presets() {
+	for preset, cases := range map[string]map[string]string{
+		"credit_card": {
+			"card 4111 1111 1111 1111 ok": "card [REDACTED] ok",
+			"card 4111-1111-1111-1111":    "card [REDACTED]",
+			"card 4111111111111111":       "card [REDACTED]",
+			"order 12345 for 3 items":     "order 12345 for 3 items",
+		},
+		"bearer_token": {
+			"Authorization: Bearer abc.DEF-123_x/y+z==": "Authorization: [REDACTED]",
+			"no token here": "no token here",
+		},
+		"email": {
+			"sent to jane.doe+x@example.co.za today": "sent to [REDACTED] today",
+			"user@localhost":                         "user@localhost",
+		},
+	} {
+		config := &Config{
+			redactPatterns: []*regexp.Regexp{
+				regexp.MustCompile(redactPresets[preset])},
+			redactReplacement: "[REDACTED]",
+		}
+		for data, expected := range cases {
+			ts.Equal(expected, redact(mkLine("abc", data), config).Data,
+				preset+": "+data)
+		}
+	}
+}
+
+func (ts *TestSuite) Test_redact_copies_message() {
+	config := &Config{
+		redactPatterns: []*regexp.Regexp{
+			regexp.MustCompile(`password=\S+`), regexp.MustCompile(`secret`)},
+		redactReplacement: "***",
+	}
+	msg := mkLine("abc", "login password=hunter2 secret")
+	redacted := redact(msg, config)
+	ts.Equal("login *** ***", redacted.Data)
+	ts.Equal("login password=hunter2 secret", msg.Data)
+	ts.True(redacted.Container == msg.Container)
+
+	unchanged := mkLine("abc", "nothing to see")
+	ts.True(redact(unchanged, config) == unchanged)
+}
+
+func (ts *TestSuite) Test_Stream_redacts_messages() {
+	ts.Setenv("SUMOLOGIC_WORKERS", "1")
+	ts.Setenv("SUMOLOGIC_PROCESSING_FLAGS", "true")
+	ts.Setenv("SUMOLOGIC_REDACT_PATTERNS", `bearer_token;password=\S+`)
+	ts.Setenv("SUMOLOGIC_REDACT_REPLACEMENT", "<redacted>")
+	requests := make(chan *RequestData, 2)
+	adapter := ts.FakeSumo(requests)
+
+	ch := make(chan *router.Message)
+	done := make(chan struct{})
+	go func() {
+		adapter.Stream(ch)
+		close(done)
+	}()
+	ch <- mkLine("abc", "GET / Authorization: Bearer abc123 password=x")
+	ch <- mkLine("abc", "GET /healthz")
+	close(ch)
+	<-done
+
+	body := (<-requests).Body
+	ts.Equal("GET / Authorization: <redacted> <redacted>", body["message"])
+	ts.Equal(jsonobj{"redacted": true}, body["_processing"])
+	body = (<-requests).Body
+	ts.Equal("GET /healthz", body["message"])
+	ts.Equal(jsonobj{}, body["_processing"])
+}
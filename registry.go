@@ -0,0 +1,34 @@
+package sumologic
+
+import (
+	"fmt"
+	"sort"
+)
+
+// modules holds the optional subsystems that are compiled in. Each one
+// registers itself from its own file, which a custom build of logspout can
+// leave out with the build tag sumologic_no<module> (e.g. sumologic_noscript)
+// to keep the binary small. A stub takes its place, which complains about any
+// of the module's options that are set.
+var modules = map[string]bool{}
+
+// registerModule records that a module is compiled in.
+func registerModule(name string) {
+	modules[name] = true
+}
+
+// compiledModules returns the names of the modules that are compiled in.
+func compiledModules() []string {
+	names := make([]string, 0, len(modules))
+	for name := range modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// errModuleMissing is the error for an option that needs a module that isn't
+// compiled in.
+func errModuleMissing(module string) error {
+	return fmt.Errorf("logspout was built without the %s module", module)
+}
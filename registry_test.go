@@ -0,0 +1,10 @@
+package sumologic
+
+func (ts *TestSuite) Test_compiledModules_are_sorted() {
+	original := modules
+	modules = map[string]bool{}
+	ts.AddCleanup(func() { modules = original })
+	registerModule("spool")
+	registerModule("metrics")
+	ts.Equal([]string{"metrics", "spool"}, compiledModules())
+}
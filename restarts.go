@@ -0,0 +1,97 @@
+package sumologic
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+// restartTracker notices when a container is replaced by a new one with the
+// same name (e.g. when it's recreated after crashing), and numbers each
+// replacement, so that the logs from each one can be told apart. A nil
+// *restartTracker doesn't track anything.
+type restartTracker struct {
+	mu sync.Mutex
+	// names holds the latest container with each name.
+	names map[string]containerGeneration
+	// generations holds the generation of each container by ID.
+	generations map[string]int64
+}
+
+type containerGeneration struct {
+	id         string
+	generation int64
+}
+
+// newRestartTracker returns a restartTracker, or nil if restarts aren't
+// being tracked.
+func newRestartTracker(enabled bool) *restartTracker {
+	if !enabled {
+		return nil
+	}
+	return &restartTracker{
+		names:       map[string]containerGeneration{},
+		generations: map[string]int64{},
+	}
+}
+
+// seen records that a message was received from a container, and returns
+// true if it's the first from a container that replaced another with the
+// same name.
+func (r *restartTracker) seen(msg *router.Message) bool {
+	if r == nil || msg.Container == nil || msg.Container.ID == "" {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	id := msg.Container.ID
+	if _, ok := r.generations[id]; ok {
+		return false
+	}
+	latest, replaced := r.names[msg.Container.Name]
+	generation := int64(0)
+	if replaced {
+		generation = latest.generation + 1
+	}
+	r.names[msg.Container.Name] = containerGeneration{id, generation}
+	r.generations[id] = generation
+	return replaced
+}
+
+// generation returns how many times the container a message came from has
+// been replaced.
+func (r *restartTracker) generation(msg *router.Message) int64 {
+	if r == nil || msg.Container == nil {
+		return 0
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.generations[msg.Container.ID]
+}
+
+// forget drops the generation of a container that has been removed. Its name
+// is remembered, so that the container replacing it is still numbered.
+func (r *restartTracker) forget(id string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.generations, id)
+}
+
+// sendRestartEvent reports to Sumologic that a container has been replaced,
+// using the same metadata as the new container's first message.
+func (s *Adapter) sendRestartEvent(msg *router.Message) {
+	defer s.recoverPanic("sendRestartEvent")
+
+	config := s.config()
+	data := buildData(msg, config)
+	data.RestartGeneration = s.restarts.generation(msg)
+	data.Message = fmt.Sprintf("Container %s restarted (generation %d)",
+		msg.Container.Name, data.RestartGeneration)
+	data.Timestamp = formatTimestamp(s.clock.Now())
+	data.Event = "restart"
+	s.send(data, buildHeaders(msg, config), nil)
+}
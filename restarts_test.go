@@ -0,0 +1,64 @@
+package sumologic
+
+import (
+	"github.com/gliderlabs/logspout/router"
+)
+
+func (ts *TestSuite) Test_newRestartTracker_disabled() {
+	r := newRestartTracker(false)
+	ts.Nil(r)
+	ts.False(r.seen(mkContainerMessage("abc", "foo")))
+	ts.EqualValues(0, r.generation(mkContainerMessage("abc", "foo")))
+}
+
+func (ts *TestSuite) Test_restartTracker_counts_replacements() {
+	r := newRestartTracker(true)
+	first := mkContainerMessage("abc", "foo")
+	ts.False(r.seen(first))
+	ts.False(r.seen(first))
+	ts.False(r.seen(mkContainerMessage("xyz", "bar")))
+	ts.EqualValues(0, r.generation(first))
+
+	second := mkContainerMessage("def", "foo")
+	ts.True(r.seen(second))
+	ts.False(r.seen(second))
+	ts.EqualValues(1, r.generation(second))
+	// Late messages from the old container keep its generation.
+	ts.EqualValues(0, r.generation(first))
+
+	r.forget("def")
+	ts.True(r.seen(mkContainerMessage("ghi", "foo")))
+	ts.EqualValues(2, r.generation(mkContainerMessage("ghi", "foo")))
+}
+
+func (ts *TestSuite) Test_Stream_reports_restarts() {
+	ts.Setenv("SUMOLOGIC_TRACK_RESTARTS", "true")
+	requests := make(chan *RequestData, 3)
+	adapter := ts.FakeSumoWithClock(requests, newFakeClock())
+
+	ch := make(chan *router.Message)
+	go adapter.Stream(ch)
+	mkMessage := func(id string, data string) *router.Message {
+		msg := mkContainerMessage(id, "/foo")
+		msg.Data = data
+		return msg
+	}
+	ch <- mkMessage("abc", "before")
+	ts.Nil((<-requests).Body["restart_generation"])
+	ch <- mkMessage("def", "after")
+	ts.AddCleanup(func() { close(ch) })
+
+	var event, log jsonobj
+	for i := 0; i < 2; i++ {
+		request := <-requests
+		if request.Body["event"] == "restart" {
+			event = request.Body
+		} else {
+			log = request.Body
+		}
+	}
+	ts.Equal("after", log["message"])
+	ts.EqualValues(1, log["restart_generation"])
+	ts.Equal("Container /foo restarted (generation 1)", event["message"])
+	ts.EqualValues(1, event["restart_generation"])
+}
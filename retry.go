@@ -0,0 +1,96 @@
+package sumologic
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// maxRetryAfter is the longest a Retry-After header is waited for. A request
+// that's asked to wait longer than this is treated as failed.
+const maxRetryAfter = 5 * time.Minute
+
+// retryableStatus reports whether a response status means the request may
+// succeed if it's sent again.
+func retryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= 500
+}
+
+// retryAfter returns how long a response asks for the request to be retried
+// after, or a negative duration if it doesn't say.
+func retryAfter(resp *http.Response, now time.Time) time.Duration {
+	value := strings.TrimSpace(resp.Header.Get("Retry-After"))
+	if value == "" {
+		return -1
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 0 {
+			return -1
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if wait := t.Sub(now); wait > 0 {
+			return wait
+		}
+		return 0
+	}
+	return -1
+}
+
+// postRetrying posts a request body, and posts it again if the response has
+// a status that's worth retrying, up to SUMOLOGIC_RETRIES times. (The client
+// only retries requests that didn't get a response at all.) It waits for as
+// long as the response's Retry-After header asks, or the configured backoff
+// if there isn't one. The last response is returned once there are no
+// retries left.
+func (s *Adapter) postRetrying(ctx context.Context, client doer,
+	endPoint string, body []byte, headers http.Header) (*http.Response, error) {
+	config := s.config()
+	backoff := newBackoff(config, s.clock)
+	for retry := 0; ; retry++ {
+		resp, err := s.postWith(ctx, client, endPoint, body, headers)
+		if err != nil || !retryableStatus(resp.StatusCode) ||
+			int64(retry) >= config.retries {
+			return resp, err
+		}
+		wait := retryAfter(resp, s.clock.Now())
+		if wait > maxRetryAfter {
+			return resp, nil
+		}
+		if wait < 0 {
+			wait = backoff.Next(retry)
+		}
+		if _, err = ioutil.ReadAll(resp.Body); err != nil {
+			log.WithError(err).Error("Unable to read response body.")
+		}
+		closeBody(resp)
+		s.status.retried()
+		log.WithFields(log.Fields{
+			"StatusCode": resp.StatusCode,
+			"retry_in":   wait.String(),
+		}).Warn("Retrying send to Sumologic")
+
+		timer := s.clock.NewTimer(wait)
+		select {
+		case <-timer.C():
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// observeAttempt accounts for each attempt the client makes at sending one
+// of the adapter's requests, so that the retries it makes by itself show up
+// in the route's status.
+func (s *Adapter) observeAttempt(attempt requestAttempt) {
+	if attempt.Retry > 0 {
+		s.status.retried()
+	}
+}
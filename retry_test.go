@@ -0,0 +1,117 @@
+package sumologic
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+func (ts *TestSuite) Test_retryableStatus() {
+	ts.True(retryableStatus(http.StatusTooManyRequests))
+	ts.True(retryableStatus(http.StatusServiceUnavailable))
+	ts.True(retryableStatus(http.StatusInternalServerError))
+	ts.False(retryableStatus(http.StatusOK))
+	ts.False(retryableStatus(http.StatusBadRequest))
+	ts.False(retryableStatus(http.StatusRequestEntityTooLarge))
+}
+
+func (ts *TestSuite) Test_retryAfter() {
+	now := mkTime(0)
+	for value, expected := range map[string]time.Duration{
+		"":                              -1,
+		"soon":                          -1,
+		"-5":                            -1,
+		"0":                             0,
+		" 120 ":                         2 * time.Minute,
+		"Tue, 02 Jan 2018 13:00:30 GMT": 30 * time.Second,
+		"Tue, 02 Jan 2018 12:00:00 GMT": 0,
+	} {
+		resp := &http.Response{Header: http.Header{}}
+		resp.Header.Set("Retry-After", value)
+		ts.Equal(expected, retryAfter(resp, now), value)
+	}
+}
+
+// FakeSumoResponses starts a fake Sumo Logic server that responds to each
+// request with the next of the given status codes and Retry-After headers,
+// and returns an Adapter pointing at it along with a count of the requests.
+func (ts *TestSuite) FakeSumoResponses(clock Clock,
+	responses ...[2]string) (*Adapter, *int64) {
+	var count int64
+	adapter := ts.adapterFor(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			n := atomic.AddInt64(&count, 1)
+			response := responses[len(responses)-1]
+			if int(n) <= len(responses) {
+				response = responses[n-1]
+			}
+			if response[1] != "" {
+				w.Header().Set("Retry-After", response[1])
+			}
+			code := map[string]int{
+				"200": http.StatusOK,
+				"429": http.StatusTooManyRequests,
+				"503": http.StatusServiceUnavailable,
+			}[response[0]]
+			w.WriteHeader(code)
+		}), clock)
+	return adapter, &count
+}
+
+func (ts *TestSuite) Test_Send_retries_retryable_status() {
+	ts.CaptureLogs()
+	clock := newFakeClock()
+	adapter, count := ts.FakeSumoResponses(clock,
+		[2]string{"429", "30"}, [2]string{"503", ""}, [2]string{"200", ""})
+
+	done := make(chan error)
+	go func() { done <- adapter.Send(mkContainerMessage("abc", "/foo")) }()
+	clock.WaitForTimers(1)
+	clock.Advance(29 * time.Second)
+	ts.Equal(int64(1), atomic.LoadInt64(count))
+	clock.Advance(time.Second)
+	clock.WaitForTimers(1)
+	// No Retry-After, so the default 10ms constant backoff.
+	clock.Advance(10 * time.Millisecond)
+	ts.NoError(<-done)
+	ts.Equal(int64(3), atomic.LoadInt64(count))
+	ts.Equal(int64(1), adapter.Status().Sent)
+	ts.Equal(int64(2), adapter.Status().Retries)
+	ts.Equal(int64(0), adapter.Status().Failed)
+}
+
+func (ts *TestSuite) Test_Send_retries_run_out() {
+	ts.CaptureLogs()
+	ts.Setenv("SUMOLOGIC_RETRIES", "1")
+	clock := newFakeClock()
+	adapter, count := ts.FakeSumoResponses(clock, [2]string{"503", "1"})
+
+	done := make(chan error)
+	go func() { done <- adapter.Send(mkContainerMessage("abc", "/foo")) }()
+	clock.WaitForTimers(1)
+	clock.Advance(time.Second)
+	err := <-done
+	ts.Error(err)
+	ts.True(err.(*SendError).Kind == ErrThrottled)
+	ts.Equal(int64(2), atomic.LoadInt64(count))
+}
+
+func (ts *TestSuite) Test_Send_gives_up_on_long_retry_after() {
+	ts.CaptureLogs()
+	adapter, count := ts.FakeSumoResponses(newFakeClock(),
+		[2]string{"429", "3600"})
+	ts.Error(adapter.Send(mkContainerMessage("abc", "/foo")))
+	ts.Equal(int64(1), atomic.LoadInt64(count))
+}
+
+func (ts *TestSuite) Test_Send_retry_abandoned_on_close() {
+	ts.CaptureLogs()
+	clock := newFakeClock()
+	adapter, _ := ts.FakeSumoResponses(clock, [2]string{"503", ""})
+
+	done := make(chan error)
+	go func() { done <- adapter.Send(mkContainerMessage("abc", "/foo")) }()
+	clock.WaitForTimers(1)
+	adapter.Close()
+	ts.Equal("context canceled", (<-done).Error())
+}
@@ -0,0 +1,189 @@
+package sumologic
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gliderlabs/logspout/router"
+	log "github.com/sirupsen/logrus"
+)
+
+// samplingLevel keeps one in every rate low-severity messages once the queue
+// is at least fill percent full.
+type samplingLevel struct {
+	fill int64
+	rate int64
+}
+
+// getsamplinglevelsopt retrieves an environment variable as a list of
+// sampling levels if it's set to a non-empty string of comma-separated
+// "<percent full>:<rate>" pairs, e.g. "50:2,80:10", ordered by how full the
+// queue has to be. Levels that can't be parsed are logged and ignored.
+func (o routeOptions) getsamplinglevelsopt(name string) []samplingLevel {
+	var levels []samplingLevel
+	for _, text := range strings.Split(o.lookupopt(name), ",") {
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		level, err := parseSamplingLevel(strings.TrimSpace(text))
+		if err != nil {
+			parseFailed(name, text, err)
+			continue
+		}
+		levels = append(levels, level)
+	}
+	sort.Slice(levels, func(i, j int) bool {
+		return levels[i].fill < levels[j].fill
+	})
+	return levels
+}
+
+func parseSamplingLevel(text string) (samplingLevel, error) {
+	parts := strings.SplitN(text, ":", 2)
+	if len(parts) != 2 {
+		return samplingLevel{}, fmt.Errorf("expected <percent full>:<rate>")
+	}
+	fill, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || fill <= 0 || fill > 100 {
+		return samplingLevel{}, fmt.Errorf("bad percentage %q", parts[0])
+	}
+	rate, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || rate <= 0 {
+		return samplingLevel{}, fmt.Errorf("bad sample rate %q", parts[1])
+	}
+	return samplingLevel{fill: fill, rate: rate}, nil
+}
+
+// adaptiveSampler thins out low-severity messages while the send queue is
+// backed up, more aggressively the fuller it gets, and lets everything
+// through again once it drains. Messages from stderr or that match the keep
+// pattern are never sampled. A nil *adaptiveSampler keeps everything.
+type adaptiveSampler struct {
+	levels []samplingLevel
+	keep   *regexp.Regexp
+
+	mu      sync.Mutex
+	current int64
+	seen    int64
+}
+
+// newAdaptiveSampler returns a sampler for the given levels, or nil if there
+// aren't any.
+func newAdaptiveSampler(
+	levels []samplingLevel, keep *regexp.Regexp) *adaptiveSampler {
+	if len(levels) == 0 {
+		return nil
+	}
+	return &adaptiveSampler{levels: levels, keep: keep, current: 1}
+}
+
+// rate returns the sample rate for a queue holding depth messages out of
+// capacity.
+func (a *adaptiveSampler) rate(depth int, capacity int) int64 {
+	if capacity <= 0 {
+		return 1
+	}
+	fill := int64(depth * 100 / capacity)
+	rate := int64(1)
+	for _, level := range a.levels {
+		if fill < level.fill {
+			break
+		}
+		rate = level.rate
+	}
+	return rate
+}
+
+// sample decides whether to keep a message about to be added to a queue,
+// and returns the sample rate it was kept at.
+func (a *adaptiveSampler) sample(
+	msg *router.Message, queue chan *router.Message) (bool, int64) {
+	if a == nil {
+		return true, 1
+	}
+	rate := a.rate(len(queue), cap(queue))
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if rate != a.current {
+		log.WithFields(log.Fields{"rate": rate, "queued": len(queue)}).Info(
+			"Send queue backlog changed, adjusting low-severity sampling")
+		a.current = rate
+		a.seen = 0
+	}
+	if rate == 1 || msg.Source == "stderr" || a.keep.MatchString(msg.Data) {
+		return true, 1
+	}
+	keep := a.seen%rate == 0
+	a.seen++
+	return keep, rate
+}
+
+// sampleRateLabel is the label a container can set to its own sample rate,
+// overriding SUMOLOGIC_SAMPLE_RATE.
+const sampleRateLabel = "sumologic.sample_rate"
+
+// fixedSampler keeps one in every N messages from each container, for noisy
+// containers whose every line isn't worth paying to ingest. N comes from the
+// container's sample rate label, or SUMOLOGIC_SAMPLE_RATE.
+type fixedSampler struct {
+	mu sync.Mutex
+	// seen counts the messages from each container since the last one that
+	// was kept.
+	seen map[string]int64
+}
+
+func newFixedSampler() *fixedSampler {
+	return &fixedSampler{seen: map[string]int64{}}
+}
+
+// sampleRate returns the rate a message's container is sampled at.
+func sampleRate(msg *router.Message, config *Config) int64 {
+	if msg.Container != nil && msg.Container.Config != nil {
+		if label, ok := msg.Container.Config.Labels[sampleRateLabel]; ok {
+			if rate, err := strconv.ParseInt(
+				strings.TrimSpace(label), 10, 64); err == nil && rate > 0 {
+				return rate
+			}
+		}
+	}
+	return config.sampleRate
+}
+
+// sample decides whether to keep a message. It returns the rate the message
+// was sampled at, and how many messages from its container were skipped
+// since the last one that was kept.
+func (f *fixedSampler) sample(
+	msg *router.Message, config *Config) (keep bool, rate int64, skipped int64) {
+	rate = sampleRate(msg, config)
+	if rate <= 1 {
+		return true, 1, 0
+	}
+	id := ""
+	if msg.Container != nil {
+		id = msg.Container.ID
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	seen := f.seen[id]
+	if seen > 0 && seen < rate {
+		f.seen[id]++
+		return false, rate, 0
+	}
+	f.seen[id] = 1
+	// The first message from a container has nothing skipped before it.
+	if seen > 0 {
+		skipped = seen - 1
+	}
+	return true, rate, skipped
+}
+
+// forget drops the count for a container that has been removed.
+func (f *fixedSampler) forget(id string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.seen, id)
+}
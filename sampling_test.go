@@ -0,0 +1,180 @@
+package sumologic
+
+import (
+	"regexp"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+func (ts *TestSuite) Test_getsamplinglevelsopt() {
+	ts.CaptureLogs()
+	ts.Setenv("SUMOLOGIC_ADAPTIVE_SAMPLING", "80:10, 50:2,0:5,101:2,60:0,70")
+	ts.Equal([]samplingLevel{{50, 2}, {80, 10}},
+		envOptions.getsamplinglevelsopt("SUMOLOGIC_ADAPTIVE_SAMPLING"))
+}
+
+func (ts *TestSuite) Test_newAdaptiveSampler_disabled() {
+	a := newAdaptiveSampler(nil, nil)
+	ts.Nil(a)
+	keep, rate := a.sample(mkLine("abc", "Some data."), nil)
+	ts.True(keep)
+	ts.EqualValues(1, rate)
+}
+
+func (ts *TestSuite) Test_adaptiveSampler_rate() {
+	a := newAdaptiveSampler(
+		[]samplingLevel{{50, 2}, {80, 10}}, regexp.MustCompile("ERROR"))
+	ts.EqualValues(1, a.rate(0, 10))
+	ts.EqualValues(1, a.rate(4, 10))
+	ts.EqualValues(2, a.rate(5, 10))
+	ts.EqualValues(10, a.rate(8, 10))
+	ts.EqualValues(10, a.rate(10, 10))
+	ts.EqualValues(1, a.rate(0, 0), "an unbuffered queue is never backed up")
+}
+
+func (ts *TestSuite) Test_adaptiveSampler_samples_low_severity_messages() {
+	ts.CaptureLogs()
+	a := newAdaptiveSampler(
+		[]samplingLevel{{50, 2}}, regexp.MustCompile("ERROR"))
+	queue := make(chan *router.Message, 4)
+	queue <- mkLine("abc", "queued")
+	kept := func(msg *router.Message) bool {
+		keep, _ := a.sample(msg, queue)
+		return keep
+	}
+
+	ts.True(kept(mkLine("abc", "one")))
+	ts.True(kept(mkLine("abc", "two")), "the queue isn't backed up yet")
+
+	queue <- mkLine("abc", "queued")
+	keep, rate := a.sample(mkLine("abc", "three"), queue)
+	ts.True(keep)
+	ts.EqualValues(2, rate)
+	ts.False(kept(mkLine("abc", "four")))
+	ts.True(kept(mkLine("abc", "ERROR: five")))
+	stderr := mkLine("abc", "six")
+	stderr.Source = "stderr"
+	ts.True(kept(stderr))
+	ts.True(kept(mkLine("abc", "seven")))
+	ts.False(kept(mkLine("abc", "eight")))
+
+	<-queue
+	ts.True(kept(mkLine("abc", "nine")), "back to full fidelity")
+	ts.True(kept(mkLine("abc", "ten")))
+}
+
+func (ts *TestSuite) Test_Stream_samples_when_queue_backs_up() {
+	ts.CaptureLogs()
+	ts.Setenv("SUMOLOGIC_WORKERS", "1")
+	ts.Setenv("SUMOLOGIC_QUEUE_SIZE", "4")
+	ts.Setenv("SUMOLOGIC_ADAPTIVE_SAMPLING", "50:2")
+	ts.Setenv("SUMOLOGIC_PROCESSING_FLAGS", "true")
+	release := make(chan struct{})
+	adapter, arrived := ts.FakeSlowSumo(release)
+
+	adapter.receive(mkLine("abc", "zero"))
+	ts.Equal("zero", <-arrived)
+	msgs := map[string]*router.Message{}
+	for _, data := range []string{"one", "two", "three", "four"} {
+		msgs[data] = mkLine("abc", data)
+		adapter.receive(msgs[data])
+	}
+	ts.EqualValues(1, adapter.Status().Dropped)
+	ts.Equal(&Processing{Sampled: true, SampleRate: 2},
+		adapter.annotations.take(msgs["three"]))
+	ts.Nil(adapter.annotations.take(msgs["two"]))
+
+	release <- struct{}{}
+	ts.Equal("one", <-arrived)
+	release <- struct{}{}
+	ts.Equal("two", <-arrived)
+	release <- struct{}{}
+	ts.Equal("three", <-arrived)
+}
+
+func (ts *TestSuite) Test_sampleRate() {
+	config := &Config{sampleRate: 1}
+	ts.EqualValues(1, sampleRate(mkLine("abc", "x"), config))
+	ts.EqualValues(1, sampleRate(&router.Message{Data: "x"}, config))
+	ts.EqualValues(5, sampleRate(mkLabelledMessage(
+		map[string]string{"sumologic.sample_rate": "5"}), config))
+	ts.EqualValues(1, sampleRate(mkLabelledMessage(
+		map[string]string{"sumologic.sample_rate": "lots"}), config))
+
+	config.sampleRate = 10
+	ts.EqualValues(10, sampleRate(mkLine("abc", "x"), config))
+	ts.EqualValues(1, sampleRate(mkLabelledMessage(
+		map[string]string{"sumologic.sample_rate": "1"}), config))
+	ts.EqualValues(10, sampleRate(mkLabelledMessage(
+		map[string]string{"sumologic.sample_rate": "0"}), config))
+}
+
+func (ts *TestSuite) Test_fixedSampler_keeps_one_in_n_per_container() {
+	config := &Config{sampleRate: 3}
+	f := newFixedSampler()
+	type result struct {
+		keep          bool
+		rate, skipped int64
+	}
+	sample := func(id string) result {
+		keep, rate, skipped := f.sample(mkLine(id, "x"), config)
+		return result{keep, rate, skipped}
+	}
+	ts.Equal(result{true, 3, 0}, sample("abc"))
+	ts.Equal(result{true, 3, 0}, sample("def"))
+	ts.Equal(result{false, 3, 0}, sample("abc"))
+	ts.Equal(result{false, 3, 0}, sample("abc"))
+	ts.Equal(result{true, 3, 2}, sample("abc"))
+	ts.Equal(result{false, 3, 0}, sample("def"))
+
+	f.forget("abc")
+	ts.Equal(result{true, 3, 0}, sample("abc"))
+
+	config.sampleRate = 1
+	ts.Equal(result{true, 1, 0}, sample("abc"))
+	ts.Equal(result{true, 1, 0}, sample("abc"))
+}
+
+func (ts *TestSuite) Test_Stream_sample_rate() {
+	ts.Setenv("SUMOLOGIC_WORKERS", "1")
+	ts.Setenv("SUMOLOGIC_SAMPLE_RATE", "3")
+	requests := make(chan *RequestData, 10)
+	adapter := ts.FakeSumo(requests)
+
+	ch := make(chan *router.Message)
+	done := make(chan struct{})
+	go func() {
+		adapter.Stream(ch)
+		close(done)
+	}()
+	for _, data := range []string{"1", "2", "3", "4", "5", "6", "7"} {
+		ch <- mkLine("abc", data)
+	}
+	quiet := mkLabelledMessage(map[string]string{"sumologic.sample_rate": "1"})
+	quiet.Container.ID = "def"
+	quiet.Data = "quiet"
+	ch <- quiet
+	close(ch)
+	<-done
+
+	ts.Equal(jsonobj{"message": "1", "_processing": jsonobj{
+		"sampled": true, "sample_rate": 3.0}},
+		ts.sampledFields((<-requests).Body))
+	ts.Equal(jsonobj{"message": "4", "_processing": jsonobj{
+		"sampled": true, "sample_rate": 3.0, "skipped": 2.0}},
+		ts.sampledFields((<-requests).Body))
+	ts.Equal(jsonobj{"message": "7", "_processing": jsonobj{
+		"sampled": true, "sample_rate": 3.0, "skipped": 2.0}},
+		ts.sampledFields((<-requests).Body))
+	ts.Equal(jsonobj{"message": "quiet"}, ts.sampledFields((<-requests).Body))
+	ts.EqualValues(4, adapter.Status().Dropped)
+}
+
+// sampledFields picks the fields about sampling out of an event.
+func (ts *TestSuite) sampledFields(body jsonobj) jsonobj {
+	fields := jsonobj{"message": body["message"]}
+	if processing, ok := body["_processing"]; ok {
+		fields["_processing"] = processing
+	}
+	return fields
+}
@@ -0,0 +1,65 @@
+package sumologic
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+// ansiEscape matches ANSI escape sequences: CSI sequences such as colours and
+// cursor movement, OSC sequences such as window titles and hyperlinks, and
+// the remaining two-character escapes.
+var ansiEscape = regexp.MustCompile(
+	"\x1b\\[[0-?]*[ -/]*[@-~]" +
+		"|\x1b\\][^\x07\x1b]*(?:\x07|\x1b\\\\)" +
+		"|\x1b[@-Z\\\\-_]")
+
+// sanitize returns a copy of a message with ANSI escape sequences and other
+// control characters removed, if SUMOLOGIC_STRIP_ANSI is set, and invalid
+// UTF-8 replaced, if SUMOLOGIC_REPLACE_INVALID_UTF8 is, so that it doesn't
+// show up as garbage in Sumo. Messages without anything to clean up are
+// returned unchanged.
+func sanitize(msg *router.Message, config *Config) *router.Message {
+	data := msg.Data
+	if config.replaceInvalidUTF8 && !utf8.ValidString(data) {
+		data = replaceInvalidUTF8(data)
+	}
+	if config.stripANSI {
+		data = stripControl(ansiEscape.ReplaceAllLiteralString(data, ""))
+	}
+	if data == msg.Data {
+		return msg
+	}
+	sanitized := *msg
+	sanitized.Data = data
+	return &sanitized
+}
+
+// stripControl removes control characters other than tabs and newlines.
+func stripControl(text string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) && r != '\t' && r != '\n' {
+			return -1
+		}
+		return r
+	}, text)
+}
+
+// replaceInvalidUTF8 replaces each invalid byte in text with U+FFFD.
+func replaceInvalidUTF8(text string) string {
+	var b bytes.Buffer
+	for len(text) > 0 {
+		r, size := utf8.DecodeRuneInString(text)
+		if r == utf8.RuneError && size == 1 {
+			b.WriteRune(utf8.RuneError)
+		} else {
+			b.WriteString(text[:size])
+		}
+		text = text[size:]
+	}
+	return b.String()
+}
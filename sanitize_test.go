@@ -0,0 +1,69 @@
+package sumologic
+
+import (
+	"github.com/gliderlabs/logspout/router"
+)
+
+func (ts *TestSuite) Test_sanitize_disabled() {
+	config := buildConfig(&router.Route{})
+	msg := mkLine("abc", "\x1b[31mred\x1b[0m \xff")
+	ts.True(sanitize(msg, config) == msg)
+}
+
+func (ts *TestSuite) Test_sanitize_strip_ansi() {
+	ts.Setenv("SUMOLOGIC_STRIP_ANSI", "true")
+	config := buildConfig(&router.Route{})
+	for text, expected := range map[string]string{
+		"\x1b[31mred\x1b[0m text":                  "red text",
+		"\x1b[1;32;40mbold\x1b[m":                  "bold",
+		"\x1b[2K\x1b[1Gprogress":                   "progress",
+		"\x1b]0;title\x07shell":                    "shell",
+		"\x1b]8;;http://x\x1b\\link\x1b]8;;\x1b\\": "link",
+		"bell\x07 back\bspace\r":                   "bell backspace",
+		"tabs\tand\nnewlines stay":                 "tabs\tand\nnewlines stay",
+		"unicode is fine: café ✓":                  "unicode is fine: café ✓",
+	} {
+		ts.Equal(expected, sanitize(mkLine("abc", text), config).Data, text)
+	}
+}
+
+func (ts *TestSuite) Test_sanitize_replace_invalid_utf8() {
+	ts.Setenv("SUMOLOGIC_REPLACE_INVALID_UTF8", "true")
+	config := buildConfig(&router.Route{})
+	ts.Equal("bad �� byte", sanitize(mkLine("abc", "bad \xff\xfe byte"), config).Data)
+	ts.Equal("café", sanitize(mkLine("abc", "café"), config).Data)
+	// Escapes are left alone unless they're to be stripped too.
+	ts.Equal("\x1b[31mred", sanitize(mkLine("abc", "\x1b[31mred"), config).Data)
+}
+
+func (ts *TestSuite) Test_sanitize_copies_message() {
+	ts.Setenv("SUMOLOGIC_STRIP_ANSI", "true")
+	config := buildConfig(&router.Route{})
+	msg := mkLine("abc", "\x1b[31mred")
+	sanitized := sanitize(msg, config)
+	ts.Equal("red", sanitized.Data)
+	ts.Equal("\x1b[31mred", msg.Data)
+	ts.Equal(msg.Container, sanitized.Container)
+
+	plain := mkLine("abc", "plain")
+	ts.True(sanitize(plain, config) == plain)
+}
+
+func (ts *TestSuite) Test_Stream_sanitizes_messages() {
+	ts.Setenv("SUMOLOGIC_WORKERS", "1")
+	ts.Setenv("SUMOLOGIC_PROCESSING_FLAGS", "true")
+	ts.Setenv("SUMOLOGIC_STRIP_ANSI", "true")
+	ts.Setenv("SUMOLOGIC_REDACT_PATTERNS", `password=\S+`)
+	requests := make(chan *RequestData, 2)
+	adapter := ts.FakeSumo(requests)
+	// Escapes don't stop sensitive data from being redacted.
+	streamLines(adapter, mkLine("abc", "\x1b[33mpassword=\x1b[1mx\x1b[0m"),
+		mkLine("abc", "plain"))
+
+	body := (<-requests).Body
+	ts.Equal("[REDACTED]", body["message"])
+	ts.Equal(jsonobj{"sanitized": true, "redacted": true}, body["_processing"])
+	body = (<-requests).Body
+	ts.Equal("plain", body["message"])
+	ts.Equal(jsonobj{}, body["_processing"])
+}
@@ -0,0 +1,78 @@
+package sumologic
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// containerKey is the key the container block is sent under. Fields inside
+// it can be renamed and omitted like the event's own.
+const containerKey = "container"
+
+// getomitfieldsopt retrieves the set of payload fields not to send, which
+// are separated by commas.
+func (o routeOptions) getomitfieldsopt(name string) map[string]bool {
+	fields := map[string]bool{}
+	for _, field := range strings.Split(o.getopt(name, ""), ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields[field] = true
+		}
+	}
+	return fields
+}
+
+// reshape adds SUMOLOGIC_EXTRA_FIELDS to an encoded event, then applies
+// SUMOLOGIC_FIELD_MAP and SUMOLOGIC_OMIT_FIELDS to it, renaming and dropping
+// its fields and those of its container block, so that it matches the field
+// extraction rules already set up in Sumo.
+func reshape(encoded []byte, config *Config) ([]byte, error) {
+	if len(config.extraFields) == 0 && len(config.fieldMap) == 0 &&
+		len(config.omitFields) == 0 {
+		return encoded, nil
+	}
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		return nil, err
+	}
+	// The event's own fields win over the extra ones, as they do over the
+	// fields of merged json messages.
+	for key, value := range config.extraFields {
+		if _, ok := fields[key]; ok {
+			continue
+		}
+		extra, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		fields[key] = extra
+	}
+	if block, ok := fields[containerKey]; ok && len(block) > 0 && block[0] == '{' {
+		container := map[string]json.RawMessage{}
+		if err := json.Unmarshal(block, &container); err != nil {
+			return nil, err
+		}
+		reshaped, err := json.Marshal(reshapeFields(container, config))
+		if err != nil {
+			return nil, err
+		}
+		fields[containerKey] = reshaped
+	}
+	return json.Marshal(reshapeFields(fields, config))
+}
+
+// reshapeFields renames and drops the fields of one json object.
+func reshapeFields(
+	fields map[string]json.RawMessage, config *Config) map[string]json.RawMessage {
+
+	reshaped := make(map[string]json.RawMessage, len(fields))
+	for key, value := range fields {
+		if config.omitFields[key] {
+			continue
+		}
+		if renamed, ok := config.fieldMap[key]; ok && renamed != "" {
+			key = renamed
+		}
+		reshaped[key] = value
+	}
+	return reshaped
+}
@@ -0,0 +1,88 @@
+package sumologic
+
+import (
+	"encoding/json"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+// encodedEvent returns the event for a message as it's sent.
+func (ts *TestSuite) encodedEvent(msg *router.Message, config *Config) jsonobj {
+	encoded, err := encodeEvent(buildData(msg, config), config)
+	ts.NoError(err)
+	var body jsonobj
+	ts.NoError(json.Unmarshal(encoded, &body))
+	return body
+}
+
+func (ts *TestSuite) Test_getomitfieldsopt() {
+	ts.Empty(envOptions.getomitfieldsopt("SUMOLOGIC_OMIT_FIELDS"))
+	ts.Setenv("SUMOLOGIC_OMIT_FIELDS", "docker_image, timestamp,,")
+	ts.Equal(map[string]bool{"docker_image": true, "timestamp": true},
+		envOptions.getomitfieldsopt("SUMOLOGIC_OMIT_FIELDS"))
+}
+
+func (ts *TestSuite) Test_encodeEvent_unchanged_by_default() {
+	config := buildConfig(&router.Route{})
+	data := buildData(mkContainerMessage("abc", "/foo"), config)
+	encoded, err := encodeEvent(data, config)
+	ts.NoError(err)
+	ts.Equal(ts.WithoutError(json.Marshal(data)), encoded)
+}
+
+func (ts *TestSuite) Test_encodeEvent_field_map() {
+	ts.Setenv("SUMOLOGIC_FIELD_MAP", "message=log,docker_name=container_name")
+	body := ts.encodedEvent(mkContainerMessage("abc", "/foo"), buildConfig(&router.Route{}))
+	ts.Equal("Some data.", body["log"])
+	ts.NotContains(body, "message")
+	container := body["container"].(map[string]interface{})
+	ts.Equal("foo", container["container_name"])
+	ts.NotContains(container, "docker_name")
+	ts.Equal("abc", container["docker_id"])
+}
+
+func (ts *TestSuite) Test_encodeEvent_omit_fields() {
+	ts.Setenv("SUMOLOGIC_OMIT_FIELDS", "timestamp,docker_image,docker_hostname")
+	ts.Setenv("SUMOLOGIC_FIELD_MAP", "timestamp=ts")
+	body := ts.encodedEvent(mkContainerMessage("abc", "/foo"), buildConfig(&router.Route{}))
+	ts.NotContains(body, "timestamp")
+	ts.NotContains(body, "ts")
+	container := body["container"].(map[string]interface{})
+	ts.NotContains(container, "docker_image")
+	ts.NotContains(container, "docker_hostname")
+	ts.Equal("foo", container["docker_name"])
+}
+
+func (ts *TestSuite) Test_encodeEvent_omit_container() {
+	ts.Setenv("SUMOLOGIC_OMIT_FIELDS", "container")
+	body := ts.encodedEvent(mkContainerMessage("abc", "/foo"), buildConfig(&router.Route{}))
+	ts.Equal(jsonobj{"message": "Some data.", "timestamp": body["timestamp"]}, body)
+}
+
+func (ts *TestSuite) Test_encodeEvent_raw_format_not_reshaped() {
+	ts.Setenv("SUMOLOGIC_FORMAT", "raw")
+	ts.Setenv("SUMOLOGIC_FIELD_MAP", "message=log")
+	config := buildConfig(&router.Route{})
+	encoded, err := encodeEvent(buildData(mkContainerMessage("abc", "/foo"), config), config)
+	ts.NoError(err)
+	ts.Equal("Some data.", string(encoded))
+}
+
+func (ts *TestSuite) Test_encodeEvent_extra_fields() {
+	ts.Setenv("SUMOLOGIC_EXTRA_FIELDS", "environment=prod,region=eu-west-1,message=nope")
+	body := ts.encodedEvent(mkContainerMessage("abc", "/foo"), buildConfig(&router.Route{}))
+	ts.Equal("prod", body["environment"])
+	ts.Equal("eu-west-1", body["region"])
+	// The event's own fields win.
+	ts.Equal("Some data.", body["message"])
+}
+
+func (ts *TestSuite) Test_encodeEvent_extra_fields_renamed() {
+	ts.Setenv("SUMOLOGIC_EXTRA_FIELDS", "environment=prod,region=eu-west-1")
+	ts.Setenv("SUMOLOGIC_FIELD_MAP", "environment=env")
+	ts.Setenv("SUMOLOGIC_OMIT_FIELDS", "region")
+	body := ts.encodedEvent(mkContainerMessage("abc", "/foo"), buildConfig(&router.Route{}))
+	ts.Equal("prod", body["env"])
+	ts.NotContains(body, "environment")
+	ts.NotContains(body, "region")
+}
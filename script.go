@@ -0,0 +1,171 @@
+//go:build !sumologic_noscript
+// +build !sumologic_noscript
+
+package sumologic
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gliderlabs/logspout/router"
+	log "github.com/sirupsen/logrus"
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+)
+
+func init() {
+	registerModule("script")
+}
+
+// scriptTimeout is how long a script may run for each message before it's
+// stopped and the message is sent as it was.
+const scriptTimeout = 100 * time.Millisecond
+
+// script runs a Lua snippet from the config against each message, for
+// one-off transforms and filters that there's no option for. The snippet
+// sees the message as the global table `event`, with the fields message,
+// source, time, container (id, name, image and hostname) and labels. It can
+// change event.message and event.source, and return false to drop the
+// message. A nil *script leaves every message alone.
+type script struct {
+	mu    sync.Mutex
+	state *lua.LState
+	fn    *lua.LFunction
+}
+
+// getscriptopt retrieves the script to run, either inline or from a file.
+func (o routeOptions) getscriptopt(name string, fileName string) string {
+	if source := o.getopt(name, ""); source != "" {
+		return source
+	}
+	path := o.getopt(fileName, "")
+	if path == "" {
+		return ""
+	}
+	source, err := ioutil.ReadFile(path)
+	if err != nil {
+		parseFailed(fileName, path, err)
+		return ""
+	}
+	return string(source)
+}
+
+// checkScript returns an error if a script can't be parsed.
+func checkScript(source string) error {
+	_, err := parse.Parse(strings.NewReader(source), "<script>")
+	return err
+}
+
+// newScript loads a script, or returns nil if there isn't one. Only the
+// base, table, string and math libraries are available to it, without the
+// base library's functions for loading code, which could read files.
+func newScript(source string) *script {
+	if source == "" {
+		return nil
+	}
+	state := lua.NewState(lua.Options{SkipOpenLibs: true})
+	for _, lib := range []struct {
+		name string
+		open lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		state.Push(state.NewFunction(lib.open))
+		state.Push(lua.LString(lib.name))
+		state.Call(1, 0)
+	}
+	for _, name := range []string{
+		"dofile", "loadfile", "load", "loadstring", "require", "module",
+	} {
+		state.SetGlobal(name, lua.LNil)
+	}
+	fn, err := state.LoadString(source)
+	if err != nil {
+		log.WithError(err).Error("Unable to load script, not running it")
+		state.Close()
+		return nil
+	}
+	return &script{state: state, fn: fn}
+}
+
+// apply runs the script against a message. It returns the message to send,
+// which is a copy if the script changed it, and false if the script dropped
+// it. If the script fails, the message is sent as it was.
+func (sc *script) apply(msg *router.Message) (*router.Message, bool) {
+	if sc == nil {
+		return msg, true
+	}
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if sc.state == nil {
+		return msg, true
+	}
+
+	L := sc.state
+	event := L.NewTable()
+	event.RawSetString("message", lua.LString(msg.Data))
+	event.RawSetString("source", lua.LString(msg.Source))
+	event.RawSetString("time", lua.LString(msg.Time.Format(time.RFC3339Nano)))
+	container := L.NewTable()
+	labels := L.NewTable()
+	if msg.Container != nil {
+		container.RawSetString("id", lua.LString(msg.Container.ID))
+		container.RawSetString("name", lua.LString(msg.Container.Name))
+		if msg.Container.Config != nil {
+			container.RawSetString("image", lua.LString(msg.Container.Config.Image))
+			container.RawSetString(
+				"hostname", lua.LString(msg.Container.Config.Hostname))
+			for label, value := range msg.Container.Config.Labels {
+				labels.RawSetString(label, lua.LString(value))
+			}
+		}
+	}
+	event.RawSetString("container", container)
+	event.RawSetString("labels", labels)
+	L.SetGlobal("event", event)
+
+	ctx, cancel := context.WithTimeout(context.Background(), scriptTimeout)
+	defer cancel()
+	L.SetContext(ctx)
+	defer L.RemoveContext()
+	L.Push(sc.fn)
+	if err := L.PCall(0, 1, nil); err != nil {
+		log.WithError(err).Error("Script failed, sending message unchanged")
+		return msg, true
+	}
+	result := L.Get(-1)
+	L.Pop(1)
+	if result == lua.LFalse {
+		return msg, false
+	}
+
+	data := lua.LVAsString(event.RawGetString("message"))
+	source := lua.LVAsString(event.RawGetString("source"))
+	if data == msg.Data && source == msg.Source {
+		return msg, true
+	}
+	transformed := *msg
+	transformed.Data = data
+	transformed.Source = source
+	return &transformed, true
+}
+
+// close releases the script's Lua state once the adapter is done with it.
+// Messages still on their way through are sent unchanged.
+func (sc *script) close() {
+	if sc == nil {
+		return
+	}
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if sc.state != nil {
+		sc.state.Close()
+		sc.state = nil
+	}
+}
@@ -0,0 +1,113 @@
+//go:build !sumologic_noscript
+// +build !sumologic_noscript
+
+package sumologic
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+func (ts *TestSuite) Test_newScript_disabled() {
+	sc := newScript("")
+	ts.Nil(sc)
+	msg := mkContainerMessage("abc", "/foo")
+	applied, keep := sc.apply(msg)
+	ts.True(keep)
+	ts.Equal(msg, applied)
+}
+
+func (ts *TestSuite) Test_script_transforms_message() {
+	sc := newScript(`
+		if event.labels.team == "payments" then
+			event.message = string.upper(event.message) .. " " .. event.container.name
+		end`)
+	msg := mkContainerMessage("abc", "/foo")
+	msg.Data = "hello"
+	msg.Container.Config.Labels = map[string]string{"team": "payments"}
+
+	transformed, keep := sc.apply(msg)
+	ts.True(keep)
+	ts.Equal("HELLO /foo", transformed.Data)
+	ts.Equal("hello", msg.Data)
+
+	msg.Container.Config.Labels = nil
+	unchanged, keep := sc.apply(msg)
+	ts.True(keep)
+	ts.True(unchanged == msg)
+}
+
+func (ts *TestSuite) Test_script_drops_message() {
+	sc := newScript(`if event.message:find("healthcheck") then return false end`)
+	msg := mkContainerMessage("abc", "/foo")
+	msg.Data = "GET /healthcheck 200"
+	_, keep := sc.apply(msg)
+	ts.False(keep)
+	msg.Data = "GET /orders 200"
+	_, keep = sc.apply(msg)
+	ts.True(keep)
+}
+
+func (ts *TestSuite) Test_script_failures_leave_message_alone() {
+	ts.CaptureLogs()
+	msg := mkContainerMessage("abc", "/foo")
+	for _, source := range []string{
+		`error("boom")`,
+		`while true do end`,
+		`os.exit(1)`,
+		`dofile("/etc/passwd")`,
+		`loadfile("/etc/passwd")()`,
+		`load("return 1")()`,
+	} {
+		applied, keep := newScript(source).apply(msg)
+		ts.True(keep, source)
+		ts.True(applied == msg, source)
+	}
+}
+
+func (ts *TestSuite) Test_script_close() {
+	sc := newScript(`return false`)
+	msg := mkContainerMessage("abc", "/foo")
+	sc.close()
+	applied, keep := sc.apply(msg)
+	ts.True(keep)
+	ts.True(applied == msg)
+	sc.close()
+
+	var disabled *script
+	disabled.close()
+}
+
+func (ts *TestSuite) Test_getscriptopt_from_file() {
+	dir := ts.WithoutError(ioutil.TempDir("", "script")).(string)
+	ts.AddCleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "transform.lua")
+	ts.NoError(ioutil.WriteFile(path, []byte(`return false`), 0600))
+	ts.Setenv("SUMOLOGIC_SCRIPT_FILE", path)
+	ts.Equal("return false",
+		envOptions.getscriptopt("SUMOLOGIC_SCRIPT", "SUMOLOGIC_SCRIPT_FILE"))
+	ts.Setenv("SUMOLOGIC_SCRIPT", "return true")
+	ts.Equal("return true",
+		envOptions.getscriptopt("SUMOLOGIC_SCRIPT", "SUMOLOGIC_SCRIPT_FILE"))
+}
+
+func (ts *TestSuite) Test_Stream_script_drops_messages() {
+	ts.Setenv("SUMOLOGIC_SCRIPT", `if event.message == "drop me" then return false end`)
+	requests := make(chan *RequestData, 1)
+	adapter := ts.FakeSumo(requests)
+
+	adapter.receive(mkLine("abc", "drop me"))
+	adapter.receive(mkLine("abc", "keep me"))
+	ts.Equal("keep me", (<-requests).Body["message"])
+	ts.EqualValues(1, adapter.Status().Dropped)
+}
+
+func (ts *TestSuite) Test_NewAdapter_rejects_bad_script() {
+	ts.Setenv("SUMOLOGIC_SCRIPT", "if then")
+	_, err := NewAdapter(&router.Route{Address: noServer})
+	ts.Error(err)
+	ts.Contains(err.Error(), "SUMOLOGIC_SCRIPT")
+}
@@ -0,0 +1,26 @@
+package sumologic
+
+import (
+	"os"
+	"regexp"
+)
+
+// containerID matches a full or abbreviated docker container ID.
+var containerID = regexp.MustCompile(`^[0-9a-f]{12,64}$`)
+
+// selfContainerID returns the ID (or ID prefix) of the container logspout is
+// running in, so that its own output can be skipped rather than fed back to
+// Sumologic, e.g. while it's logging errors about failing to reach it. It's
+// SUMOLOGIC_SELF_CONTAINER_ID if that's set, or otherwise the hostname if
+// that looks like a container ID, which it does unless the container's
+// hostname has been set explicitly. It's "" if neither is available.
+func selfContainerID(opts routeOptions) string {
+	id := opts.getopt("SUMOLOGIC_SELF_CONTAINER_ID", "")
+	if id == "" {
+		id, _ = os.Hostname()
+	}
+	if !containerID.MatchString(id) {
+		return ""
+	}
+	return id
+}
@@ -0,0 +1,28 @@
+package sumologic
+
+import (
+	"github.com/gliderlabs/logspout/router"
+)
+
+func (ts *TestSuite) Test_selfContainerID() {
+	ts.Setenv("SUMOLOGIC_SELF_CONTAINER_ID", "0123456789ab")
+	ts.Equal("0123456789ab", selfContainerID(envOptions))
+	ts.Setenv("SUMOLOGIC_SELF_CONTAINER_ID", "logspout")
+	ts.Equal("", selfContainerID(envOptions))
+	ts.Setenv("SUMOLOGIC_SELF_CONTAINER_ID", "abc")
+	ts.Equal("", selfContainerID(envOptions))
+}
+
+func (ts *TestSuite) Test_dropReason_self() {
+	ts.Setenv("SUMOLOGIC_SELF_CONTAINER_ID", "0123456789ab")
+	adapter := ts.mkAdapter(&router.Route{Address: noServer})
+	ts.Equal("self", adapter.dropReason(
+		mkContainerMessage("0123456789abcdef", "logspout")))
+	ts.Equal("", adapter.dropReason(mkContainerMessage("abcdef012345", "app")))
+	ts.Equal("", adapter.dropReason(&router.Message{Data: "no container"}))
+
+	ts.Setenv("SUMOLOGIC_EXCLUDE_SELF", "false")
+	adapter = ts.mkAdapter(&router.Route{Address: noServer})
+	ts.Equal("", adapter.dropReason(
+		mkContainerMessage("0123456789abcdef", "logspout")))
+}
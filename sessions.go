@@ -0,0 +1,96 @@
+package sumologic
+
+import (
+	"net/http"
+	"sync"
+)
+
+// maxSessions caps the number of dedicated connections an adapter keeps.
+// Categories beyond the cap share the adapter's client.
+const maxSessions = 64
+
+// sessions keeps a dedicated connection to the endpoint for each source
+// category, for containers that log enough to keep one busy. Requests for a
+// category are sent one after another over its connection, so a sustained
+// stream of batches doesn't pay for a new connection (and TLS handshake) each
+// time one is sent while another is in flight. A nil *sessions sends
+// everything over the adapter's shared client.
+type sessions struct {
+	mu       sync.Mutex
+	config   *Config
+	clock    Clock
+	sessions map[string]*session
+}
+
+// session is the dedicated connection for a single category.
+type session struct {
+	mu        sync.Mutex
+	client    *httpClient
+	transport *http.Transport
+}
+
+// newSessions returns the dedicated sessions for a config, or nil if
+// dedicated connections aren't enabled.
+func newSessions(config *Config, clock Clock) *sessions {
+	if !config.dedicatedConns {
+		return nil
+	}
+	return &sessions{
+		config:   config,
+		clock:    clock,
+		sessions: map[string]*session{},
+	}
+}
+
+// acquire returns the session for a category, locked so that the caller has
+// its connection to itself until it calls release. It returns nil if there's
+// no session for the category, and the caller should use the shared client.
+func (p *sessions) acquire(category string) *session {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	c, ok := p.sessions[category]
+	if !ok {
+		if len(p.sessions) >= maxSessions {
+			p.mu.Unlock()
+			return nil
+		}
+		c = newSession(p.config, p.clock)
+		p.sessions[category] = c
+	}
+	p.mu.Unlock()
+	c.mu.Lock()
+	return c
+}
+
+// release lets the next request for the session's category use its
+// connection.
+func (c *session) release() {
+	c.mu.Unlock()
+}
+
+// close drops every session's idle connection.
+func (p *sessions) close() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, c := range p.sessions {
+		c.transport.CloseIdleConnections()
+	}
+}
+
+// newSession builds a client with the same timeout, retry, TLS and proxy
+// settings as the shared one, over a transport that keeps a single connection
+// alive.
+func newSession(config *Config, clock Clock) *session {
+	transport := newTransport(config)
+	transport.MaxIdleConns = 1
+	transport.MaxIdleConnsPerHost = 1
+	return &session{
+		client:    newClientWithTransport(config, clock, transport),
+		transport: transport,
+	}
+}
@@ -0,0 +1,71 @@
+package sumologic
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+func (ts *TestSuite) Test_sessions_disabled_by_default() {
+	adapter := ts.mkAdapter(&router.Route{Address: "https://example.com/"})
+	ts.Nil(adapter.sessions)
+	ts.Nil(adapter.sessions.acquire("foo"))
+}
+
+func (ts *TestSuite) Test_sessions_one_per_category() {
+	ts.Setenv("SUMOLOGIC_DEDICATED_CONNECTIONS", "true")
+	adapter := ts.mkAdapter(&router.Route{Address: "https://example.com/"})
+
+	foo := adapter.sessions.acquire("foo")
+	foo.release()
+	bar := adapter.sessions.acquire("bar")
+	bar.release()
+	ts.False(foo == bar)
+	ts.False(foo.client == adapter.client)
+	again := adapter.sessions.acquire("foo")
+	again.release()
+	ts.True(foo == again)
+}
+
+func (ts *TestSuite) Test_sessions_capped() {
+	ts.Setenv("SUMOLOGIC_DEDICATED_CONNECTIONS", "true")
+	adapter := ts.mkAdapter(&router.Route{Address: "https://example.com/"})
+	for i := 0; i < maxSessions; i++ {
+		adapter.sessions.acquire(string(rune('a' + i))).release()
+	}
+	ts.Nil(adapter.sessions.acquire("one too many"))
+}
+
+func (ts *TestSuite) Test_sessions_reuse_one_connection() {
+	ts.Setenv("SUMOLOGIC_DEDICATED_CONNECTIONS", "true")
+	ts.Setenv("SUMOLOGIC_SOURCE_CATEGORY", "busy")
+	var conns int64
+	server := httptest.NewUnstartedServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(5 * time.Millisecond)
+		}))
+	server.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt64(&conns, 1)
+		}
+	}
+	server.Start()
+	ts.AddCleanup(server.Close)
+	adapter := ts.mkAdapter(&router.Route{Address: server.URL})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ts.NoError(adapter.Send(mkContainerMessage("abc", "/foo")))
+		}()
+	}
+	wg.Wait()
+	ts.EqualValues(1, atomic.LoadInt64(&conns))
+}
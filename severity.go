@@ -0,0 +1,80 @@
+package sumologic
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// severityGroup is the name of the group in SUMOLOGIC_SEVERITY_PATTERN that
+// matches the severity. The whole match is used if there isn't one.
+const severityGroup = "severity"
+
+// getseverityfieldsopt retrieves the json fields to take a message's
+// severity from, in order, which are separated by commas.
+func (o routeOptions) getseverityfieldsopt(name string) []string {
+	var fields []string
+	for _, field := range strings.Split(o.getopt(name, ""), ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// severity returns the severity of a message, lower-cased, or "" if it
+// doesn't have one. It's taken from the first of SUMOLOGIC_SEVERITY_FIELDS a
+// json message has, otherwise from SUMOLOGIC_SEVERITY_PATTERN.
+func severity(message string, config *Config) string {
+	if value := jsonField(message, config.severityFields); value != "" {
+		return strings.ToLower(value)
+	}
+	re := config.severityPattern
+	if !patternSet(re) {
+		return ""
+	}
+	match := re.FindStringSubmatch(message)
+	if match == nil {
+		return ""
+	}
+	value := match[0]
+	for i, name := range re.SubexpNames() {
+		if name == severityGroup {
+			value = match[i]
+		}
+	}
+	return strings.ToLower(strings.TrimSpace(value))
+}
+
+// jsonField returns the first of the given fields a json message has, or ""
+// if it isn't json or has none of them. Numbers, such as bunyan's levels, are
+// returned as they're written.
+func jsonField(message string, fields []string) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	parsed := parseJSON(message)
+	if parsed == nil {
+		return ""
+	}
+	object := map[string]json.RawMessage{}
+	if err := json.Unmarshal(parsed, &object); err != nil {
+		return ""
+	}
+	for _, field := range fields {
+		raw, ok := object[field]
+		if !ok {
+			continue
+		}
+		var value string
+		var number json.Number
+		if err := json.Unmarshal(raw, &value); err != nil {
+			if json.Unmarshal(raw, &number) == nil {
+				value = number.String()
+			}
+		}
+		if value = strings.TrimSpace(value); value != "" {
+			return value
+		}
+	}
+	return ""
+}
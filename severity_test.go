@@ -0,0 +1,63 @@
+package sumologic
+
+import (
+	"encoding/json"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+func (ts *TestSuite) Test_getseverityfieldsopt() {
+	ts.Nil(envOptions.getseverityfieldsopt("SUMOLOGIC_SEVERITY_FIELDS"))
+	ts.Setenv("SUMOLOGIC_SEVERITY_FIELDS", "level, severity,,")
+	ts.Equal([]string{"level", "severity"},
+		envOptions.getseverityfieldsopt("SUMOLOGIC_SEVERITY_FIELDS"))
+}
+
+func (ts *TestSuite) Test_severity_unset() {
+	config := buildConfig(&router.Route{})
+	ts.Equal("", severity("2018-01-01 ERROR oops", config))
+	ts.Equal("", severity(`{"level": "error"}`, config))
+}
+
+func (ts *TestSuite) Test_severity_pattern() {
+	ts.Setenv("SUMOLOGIC_SEVERITY_PATTERN",
+		`^\S+ (?P<severity>DEBUG|INFO|WARN|ERROR)\b`)
+	config := buildConfig(&router.Route{})
+	ts.Equal("error", severity("2018-01-01 ERROR oops", config))
+	ts.Equal("info", severity("2018-01-01 INFO fine", config))
+	ts.Equal("", severity("no level here", config))
+}
+
+func (ts *TestSuite) Test_severity_pattern_without_group() {
+	ts.Setenv("SUMOLOGIC_SEVERITY_PATTERN", `\b(WARN|ERROR)\b`)
+	config := buildConfig(&router.Route{})
+	ts.Equal("warn", severity("disk WARN nearly full", config))
+}
+
+func (ts *TestSuite) Test_severity_json_fields() {
+	ts.Setenv("SUMOLOGIC_SEVERITY_FIELDS", "level,severity")
+	ts.Setenv("SUMOLOGIC_SEVERITY_PATTERN", `ERROR|INFO`)
+	config := buildConfig(&router.Route{})
+	ts.Equal("warn", severity(`{"level": "WARN", "msg": "ERROR"}`, config))
+	ts.Equal("error", severity(`{"severity": "error"}`, config))
+	ts.Equal("30", severity(`{"level": 30}`, config))
+	// Fields that are empty or not there fall through to the next one, and
+	// then to the pattern.
+	ts.Equal("debug", severity(`{"level": "", "severity": "debug"}`, config))
+	ts.Equal("info", severity(`{"msg": "INFO"}`, config))
+	ts.Equal("info", severity("INFO not json", config))
+}
+
+func (ts *TestSuite) Test_buildData_severity() {
+	ts.Setenv("SUMOLOGIC_SEVERITY_PATTERN", `(?P<severity>ERROR|INFO)`)
+	msg := mkContainerMessage("abc", "/foo")
+	msg.Data = "ERROR oops"
+	var body jsonobj
+	ts.NoError(json.Unmarshal(ts.WithoutError(json.Marshal(
+		buildData(msg, buildConfig(&router.Route{})))).([]byte), &body))
+	ts.Equal("error", body["severity"])
+
+	msg.Data = "nothing to see"
+	plain := buildData(msg, buildConfig(&router.Route{}))
+	ts.NotContains(string(ts.WithoutError(json.Marshal(plain)).([]byte)), "severity")
+}
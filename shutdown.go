@@ -0,0 +1,97 @@
+package sumologic
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// shutdown tears the adapter down once its stream has ended, which is how
+// logspout removes a route (including when it reloads its routes), or when
+// logspout is told to stop. It stops taking messages, sends those already
+// queued, flushes batches and the archive, and then closes the adapter,
+// stopping its workers and background jobs and releasing its client.
+// Anything that hasn't been sent within SUMOLOGIC_DRAIN_TIMEOUT_MS is
+// abandoned. Shutting down more than once is harmless.
+func (s *Adapter) shutdown() {
+	s.shutdownOnce.Do(func() {
+		if s.multiline != nil {
+			s.flushMultiline(true)
+		}
+		s.standby.release(s.push)
+		close(s.stopping)
+		s.queueMu.Lock()
+		close(s.queue)
+		s.lanes.close()
+		s.ordered.close()
+		s.queueMu.Unlock()
+
+		drained := make(chan struct{})
+		go func() {
+			s.workers.Wait()
+			close(drained)
+		}()
+		timer := s.clock.NewTimer(
+			time.Duration(s.config().drainTimeoutMs) * time.Millisecond)
+		select {
+		case <-drained:
+			timer.Stop()
+		case <-timer.C():
+			log.WithField("route", s.route.ID).Error(
+				"Timed out sending queued messages for removed route")
+		case <-s.ctx.Done():
+			timer.Stop()
+		}
+		if s.batches != nil {
+			s.flushBatches()
+		}
+		if s.archive != nil {
+			s.flushArchive()
+		}
+		s.Close()
+		log.WithField("route", s.route.ID).Debug("Route shut down")
+	})
+}
+
+// watchSignalsOnce makes sure that only the first adapter sets up signal
+// handling.
+var watchSignalsOnce sync.Once
+
+// watchSignals drains every route before logspout exits when it's told to
+// stop, rather than losing what's queued and batched.
+func watchSignals() {
+	watchSignalsOnce.Do(func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+		go handleSignal(signals, func(sig os.Signal) {
+			signal.Stop(signals)
+			// Now that the signal isn't being caught, sending it again
+			// stops logspout the way it would have without us.
+			if p, err := os.FindProcess(os.Getpid()); err == nil {
+				p.Signal(sig)
+			}
+		})
+	})
+}
+
+// handleSignal waits for a signal, then shuts every adapter down and passes
+// the signal on.
+func handleSignal(signals <-chan os.Signal, passOn func(os.Signal)) {
+	sig := <-signals
+	log.WithField("signal", sig.String()).Info(
+		"Sending queued logs before exiting")
+	var wg sync.WaitGroup
+	for _, a := range adapters.all() {
+		wg.Add(1)
+		go func(a *Adapter) {
+			defer wg.Done()
+			a.shutdown()
+		}(a)
+	}
+	wg.Wait()
+	passOn(sig)
+}
@@ -0,0 +1,97 @@
+package sumologic
+
+import (
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+func (ts *TestSuite) Test_Stream_end_shuts_down() {
+	ts.Setenv("SUMOLOGIC_WORKERS", "1")
+	requests := make(chan *RequestData, 3)
+	adapter := ts.FakeSumo(requests)
+	key := keyForConfig(adapter.config())
+
+	ch := make(chan *router.Message, 3)
+	for _, data := range []string{"one", "two", "three"} {
+		msg := mkContainerMessage("abc", "/foo")
+		msg.Data = data
+		ch <- msg
+	}
+	close(ch)
+	adapter.Stream(ch)
+
+	ts.Len(requests, 3)
+	ts.Error(adapter.ctx.Err())
+	ts.NotContains(adapters.all(), adapter)
+	ts.NotContains(clients.clients, key)
+	ts.NotPanics(adapter.shutdown)
+}
+
+func (ts *TestSuite) Test_Stream_end_flushes_batches() {
+	ts.Setenv("SUMOLOGIC_BATCH_SIZE", "100")
+	requests := make(chan *batchRequest, 1)
+	adapter := ts.FakeSumoBatches(requests, newFakeClock(), 0)
+
+	ch := make(chan *router.Message, 2)
+	ch <- mkContainerMessage("abc", "foo")
+	ch <- mkContainerMessage("abc", "foo")
+	close(ch)
+	adapter.Stream(ch)
+
+	ts.Len((<-requests).messages, 2)
+}
+
+func (ts *TestSuite) Test_Stream_end_gives_up_after_timeout() {
+	ts.CaptureLogs()
+	ts.Setenv("SUMOLOGIC_DRAIN_TIMEOUT_MS", "5000")
+	release := make(chan struct{})
+	clock := newFakeClock()
+	arrived := make(chan string, 1)
+	adapter := ts.mkSlowAdapter(release, arrived, clock)
+
+	ch := make(chan *router.Message, 1)
+	ch <- mkContainerMessage("abc", "/foo")
+	close(ch)
+	done := make(chan struct{})
+	go func() {
+		adapter.Stream(ch)
+		close(done)
+	}()
+	<-arrived
+	clock.WaitForTimers(1)
+	clock.Advance(5 * time.Second)
+	<-done
+	ts.Error(adapter.ctx.Err())
+}
+
+func (ts *TestSuite) Test_enqueue_drops_while_shutting_down() {
+	ts.Setenv("SUMOLOGIC_WORKERS", "1")
+	requests := make(chan *RequestData, 1)
+	adapter := ts.FakeSumo(requests)
+	adapter.shutdown()
+
+	adapter.enqueue(mkContainerMessage("abc", "/foo"))
+	ts.EqualValues(1, adapter.Status().Dropped)
+	ts.Empty(requests)
+}
+
+func (ts *TestSuite) Test_handleSignal_drains_adapters() {
+	ts.Setenv("SUMOLOGIC_BATCH_SIZE", "100")
+	requests := make(chan *batchRequest, 1)
+	adapter := ts.FakeSumoBatches(requests, newFakeClock(), 0)
+	adapter.receive(mkContainerMessage("abc", "foo"))
+	adapter.receive(mkContainerMessage("abc", "foo"))
+
+	signals := make(chan os.Signal, 1)
+	passedOn := make(chan os.Signal, 1)
+	signals <- syscall.SIGTERM
+	handleSignal(signals, func(sig os.Signal) { passedOn <- sig })
+
+	ts.Len((<-requests).messages, 2)
+	ts.Equal(syscall.SIGTERM, <-passedOn)
+	ts.Error(adapter.ctx.Err())
+	ts.NotContains(adapters.all(), adapter)
+}
@@ -0,0 +1,76 @@
+package sumologic
+
+import (
+	"crypto/hmac"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Headers added to signed requests.
+const (
+	signatureHeader          = "X-Logspout-Signature"
+	signatureTimestampHeader = "X-Logspout-Timestamp"
+)
+
+// DefaultSignatureTolerance is a reasonable tolerance for VerifySignature,
+// allowing for clock skew and for requests that take a while to be retried.
+const DefaultSignatureTolerance = 5 * time.Minute
+
+// Errors returned by VerifySignature.
+var (
+	// ErrBadSignature means a request's signature doesn't match its body
+	// and timestamp.
+	ErrBadSignature = errors.New("bad signature")
+	// ErrStaleSignature means a request was signed too long ago (or too far
+	// in the future), so it may be a replay.
+	ErrStaleSignature = errors.New("signature timestamp outside tolerance")
+)
+
+// signature returns the signature for a request body sent at the given
+// time. It covers the timestamp as well as the body, so that a captured
+// request can't be replayed with a fresh timestamp.
+func signature(key []byte, timestamp string, body []byte) string {
+	return "sha256=" + sha256Hex(hmacSHA256(key, timestamp+"."+string(body)))
+}
+
+// sign adds a signature and a signed timestamp to a request's headers, if
+// signing is enabled. The headers are copied rather than changed in place,
+// since they may be shared with other requests.
+func (config *Config) sign(
+	headers http.Header, body []byte, now time.Time) http.Header {
+	if config.signingKey == "" {
+		return headers
+	}
+	headers = copyHeader(headers)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	headers.Set(signatureTimestampHeader, timestamp)
+	headers.Set(signatureHeader,
+		signature([]byte(config.signingKey), timestamp, body))
+	return headers
+}
+
+// VerifySignature checks the signature on a request from the adapter, for
+// gateways that receive them. It returns ErrBadSignature if the signature
+// doesn't match, or ErrStaleSignature if the signed timestamp is more than
+// tolerance away from now, which means the request may have been replayed.
+// The tolerance is the gateway's to choose, as the request can't be trusted
+// to say how old it may be; DefaultSignatureTolerance suits most gateways.
+func VerifySignature(key []byte, body []byte, headers http.Header,
+	now time.Time, tolerance time.Duration) error {
+	timestamp := headers.Get(signatureTimestampHeader)
+	expected := signature(key, timestamp, body)
+	if !hmac.Equal([]byte(expected), []byte(headers.Get(signatureHeader))) {
+		return ErrBadSignature
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrBadSignature
+	}
+	skew := now.Sub(time.Unix(seconds, 0))
+	if skew > tolerance || skew < -tolerance {
+		return ErrStaleSignature
+	}
+	return nil
+}
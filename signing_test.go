@@ -0,0 +1,69 @@
+package sumologic
+
+import (
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+type signedRequest struct {
+	headers http.Header
+	body    []byte
+}
+
+func (ts *TestSuite) FakeSigningSumo(
+	requests chan *signedRequest, clock Clock) *Adapter {
+	return ts.adapterFor(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			requests <- &signedRequest{
+				headers: r.Header,
+				body:    ts.WithoutError(ioutil.ReadAll(r.Body)).([]byte),
+			}
+		}), clock)
+}
+
+func (ts *TestSuite) Test_signing_disabled_by_default() {
+	requests := make(chan *signedRequest, 1)
+	adapter := ts.FakeSigningSumo(requests, newFakeClock())
+	ts.NoError(adapter.Send(mkContainerMessage("abc", "/foo")))
+	request := <-requests
+	ts.Empty(request.headers.Get(signatureHeader))
+	ts.Empty(request.headers.Get(signatureTimestampHeader))
+}
+
+func (ts *TestSuite) Test_signing() {
+	ts.Setenv("SUMOLOGIC_SIGNING_KEY", "sekrit")
+	requests := make(chan *signedRequest, 1)
+	clock := newFakeClock()
+	adapter := ts.FakeSigningSumo(requests, clock)
+	ts.NoError(adapter.Send(mkContainerMessage("abc", "/foo")))
+	request := <-requests
+
+	key := []byte("sekrit")
+	ts.Equal("1514898000", request.headers.Get(signatureTimestampHeader))
+	ts.NoError(VerifySignature(
+		key, request.body, request.headers, clock.Now(), time.Minute))
+	ts.NoError(VerifySignature(key, request.body, request.headers,
+		clock.Now().Add(-time.Minute), time.Minute))
+
+	ts.Equal(ErrStaleSignature, VerifySignature(key, request.body,
+		request.headers, clock.Now().Add(61*time.Second), time.Minute))
+	ts.Equal(ErrBadSignature, VerifySignature(
+		[]byte("wrong"), request.body, request.headers, clock.Now(), time.Minute))
+	ts.Equal(ErrBadSignature, VerifySignature(key, []byte("tampered"),
+		request.headers, clock.Now(), time.Minute))
+
+	replayed := copyHeader(request.headers)
+	replayed.Set(signatureTimestampHeader, "1514898100")
+	ts.Equal(ErrBadSignature, VerifySignature(key, request.body,
+		replayed, clock.Now().Add(100*time.Second), time.Minute))
+}
+
+func (ts *TestSuite) Test_sign_copies_headers() {
+	config := &Config{signingKey: "sekrit"}
+	headers := http.Header{"X-Sumo-Name": []string{"foo"}}
+	signed := config.sign(headers, []byte("body"), mkTime(0))
+	ts.Equal("foo", signed.Get("X-Sumo-Name"))
+	ts.NotEmpty(signed.Get(signatureHeader))
+	ts.Empty(headers.Get(signatureHeader))
+}
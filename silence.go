@@ -0,0 +1,100 @@
+package sumologic
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+// silenceDetector keeps track of when each container last logged anything,
+// so that containers which have gone quiet can be reported. A nil
+// *silenceDetector doesn't track anything.
+type silenceDetector struct {
+	mu         sync.Mutex
+	clock      Clock
+	threshold  time.Duration
+	containers map[string]*containerActivity
+}
+
+type containerActivity struct {
+	lastSeen time.Time
+	lastMsg  *router.Message
+	reported bool
+}
+
+// silentContainer describes a container that hasn't logged for a while.
+type silentContainer struct {
+	lastMsg *router.Message
+	silence time.Duration
+}
+
+// newSilenceDetector returns a silenceDetector for the given threshold, or
+// nil if the threshold isn't positive.
+func newSilenceDetector(
+	clock Clock, threshold time.Duration) *silenceDetector {
+	if threshold <= 0 {
+		return nil
+	}
+	return &silenceDetector{
+		clock:      clock,
+		threshold:  threshold,
+		containers: map[string]*containerActivity{},
+	}
+}
+
+// seen records that a message was received from a container.
+func (d *silenceDetector) seen(msg *router.Message) {
+	if d == nil || msg.Container == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.containers[msg.Container.ID] = &containerActivity{
+		lastSeen: d.clock.Now(),
+		lastMsg:  msg,
+	}
+}
+
+// silent returns the containers that have been quiet for longer than the
+// threshold. Each silence is only returned once; the container has to log
+// something again before it can be returned again.
+func (d *silenceDetector) silent() []silentContainer {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := d.clock.Now()
+	var result []silentContainer
+	for _, activity := range d.containers {
+		silence := now.Sub(activity.lastSeen)
+		if activity.reported || silence < d.threshold {
+			continue
+		}
+		activity.reported = true
+		result = append(result, silentContainer{activity.lastMsg, silence})
+	}
+	return result
+}
+
+// reportSilence sends an event to Sumologic for each container that has
+// stopped logging.
+func (s *Adapter) reportSilence() {
+	for _, container := range s.silence.silent() {
+		s.sendSilenceEvent(container)
+	}
+}
+
+// sendSilenceEvent reports a silent container to Sumologic, using the same
+// metadata as that container's last message.
+func (s *Adapter) sendSilenceEvent(container silentContainer) {
+	defer s.recoverPanic("sendSilenceEvent")
+
+	config := s.config()
+	msg := container.lastMsg
+	data := buildData(msg, config)
+	data.Message = fmt.Sprintf("No logs from container %s for %s",
+		msg.Container.Name, container.silence.Round(time.Second))
+	data.Timestamp = formatTimestamp(s.clock.Now())
+	data.Event = "silence"
+	s.send(data, buildHeaders(msg, config), nil)
+}
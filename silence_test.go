@@ -0,0 +1,92 @@
+package sumologic
+
+import (
+	"time"
+
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+)
+
+func mkContainerMessage(id string, name string) *router.Message {
+	return &router.Message{
+		Data: "Some data.",
+		Container: &docker.Container{
+			ID:     id,
+			Name:   name,
+			Config: &docker.Config{},
+		},
+	}
+}
+
+func (ts *TestSuite) Test_newSilenceDetector_disabled() {
+	ts.Nil(newSilenceDetector(newFakeClock(), 0))
+
+	var d *silenceDetector
+	d.seen(mkContainerMessage("abc", "foo"))
+}
+
+func (ts *TestSuite) Test_silenceDetector_reports_once() {
+	clock := newFakeClock()
+	d := newSilenceDetector(clock, time.Minute)
+	d.seen(mkContainerMessage("abc", "foo"))
+	d.seen(&router.Message{})
+
+	clock.Advance(59 * time.Second)
+	ts.Empty(d.silent())
+
+	clock.Advance(2 * time.Second)
+	silent := d.silent()
+	ts.Len(silent, 1)
+	ts.Equal("foo", silent[0].lastMsg.Container.Name)
+	ts.Equal(61*time.Second, silent[0].silence)
+
+	clock.Advance(time.Minute)
+	ts.Empty(d.silent())
+}
+
+func (ts *TestSuite) Test_silenceDetector_resets_when_seen() {
+	clock := newFakeClock()
+	d := newSilenceDetector(clock, time.Minute)
+	d.seen(mkContainerMessage("abc", "foo"))
+
+	clock.Advance(time.Minute)
+	ts.Len(d.silent(), 1)
+
+	d.seen(mkContainerMessage("abc", "foo"))
+	clock.Advance(time.Minute)
+	ts.Len(d.silent(), 1)
+}
+
+func (ts *TestSuite) Test_Stream_reports_silent_container() {
+	ts.Setenv("SUMOLOGIC_SILENCE_THRESHOLD_MS", "60000")
+	clock := newFakeClock()
+	requests := make(chan *RequestData, 2)
+	adapter := ts.FakeSumoWithClock(requests, clock)
+
+	ch := make(chan *router.Message)
+	go adapter.Stream(ch)
+	ch <- mkContainerMessage("abc", "foo")
+	ts.verifyExpectedRequests([]RequestData{{
+		Headers: map[string]string{"X-Sumo-Name": "foo", "X-Sumo-Host": ""},
+		Body: mkExpectedBody(jsonobj{
+			"message":   "Some data.",
+			"container": jsonobj{"docker_id": "abc", "docker_name": "foo"},
+		}),
+	}}, requests)
+
+	for i := 0; i < 4; i++ {
+		clock.WaitForTimers(1)
+		clock.Advance(15 * time.Second)
+	}
+
+	ts.verifyExpectedRequests([]RequestData{{
+		Headers: map[string]string{"X-Sumo-Name": "foo", "X-Sumo-Host": ""},
+		Body: mkExpectedBody(jsonobj{
+			"message":   "No logs from container foo for 1m0s",
+			"timestamp": "1514898060000",
+			"event":     "silence",
+			"container": jsonobj{"docker_id": "abc", "docker_name": "foo"},
+		}),
+	}}, requests)
+	close(ch)
+}
@@ -0,0 +1,87 @@
+package sumologic
+
+import (
+	"unicode/utf8"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+// What to do with messages larger than SUMOLOGIC_MAX_MESSAGE_BYTES.
+const (
+	// sizePolicyTruncate sends as much of the message as fits.
+	sizePolicyTruncate = "truncate"
+	// sizePolicySplit sends the whole message, as several events that each
+	// fit.
+	sizePolicySplit = "split"
+	// sizePolicyDrop doesn't send the message at all.
+	sizePolicyDrop = "drop"
+)
+
+// getsizepolicyopt retrieves what to do with oversized messages.
+func (o routeOptions) getsizepolicyopt(name string) string {
+	value := o.getopt(name, sizePolicyTruncate)
+	switch value {
+	case sizePolicyTruncate, sizePolicySplit, sizePolicyDrop:
+		return value
+	}
+	parseFailed(name, value, nil)
+	return sizePolicyTruncate
+}
+
+// limitSize applies SUMOLOGIC_MAX_MESSAGE_SIZE_POLICY to a message larger
+// than SUMOLOGIC_MAX_MESSAGE_BYTES, returning the messages to send in its
+// place, which is none if it's dropped. Truncated messages are always marked
+// as such, with "truncated": true, so that it's clear they're not the whole
+// story, except in raw format, which sends nothing but the message.
+func (s *Adapter) limitSize(
+	msg *router.Message, config *Config) []*router.Message {
+	max := config.maxMessageBytes
+	if max <= 0 || int64(len(msg.Data)) <= max {
+		return []*router.Message{msg}
+	}
+	switch config.sizePolicy {
+	case sizePolicyDrop:
+		s.drop(msg, "too large")
+		return nil
+	case sizePolicySplit:
+		parts := splitMessage(msg, max)
+		s.annotations.move(msg, parts[0])
+		return parts
+	}
+	truncated := *msg
+	truncated.Data = msg.Data[:cutAt(msg.Data, max)]
+	s.annotations.move(msg, &truncated)
+	s.annotations.record(&truncated, func(p *Processing) { p.Truncated = true })
+	return []*router.Message{&truncated}
+}
+
+// splitMessage splits a message into copies holding consecutive pieces of
+// its text, each at most max bytes long.
+func splitMessage(msg *router.Message, max int64) []*router.Message {
+	var parts []*router.Message
+	for data := msg.Data; data != ""; {
+		cut := cutAt(data, max)
+		part := *msg
+		part.Data = data[:cut]
+		parts = append(parts, &part)
+		data = data[cut:]
+	}
+	return parts
+}
+
+// cutAt returns where to cut text so that it's at most max bytes long,
+// without splitting a character. A character longer than max is kept whole
+// rather than cut to nothing.
+func cutAt(text string, max int64) int {
+	if int64(len(text)) <= max {
+		return len(text)
+	}
+	cut := int(max)
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	if cut == 0 {
+		_, cut = utf8.DecodeRuneInString(text)
+	}
+	return cut
+}
@@ -0,0 +1,106 @@
+package sumologic
+
+import (
+	"github.com/gliderlabs/logspout/router"
+)
+
+// streamLines streams messages through an adapter until they've all been
+// handled.
+func streamLines(adapter *Adapter, msgs ...*router.Message) {
+	ch := make(chan *router.Message)
+	done := make(chan struct{})
+	go func() {
+		adapter.Stream(ch)
+		close(done)
+	}()
+	for _, msg := range msgs {
+		ch <- msg
+	}
+	close(ch)
+	<-done
+}
+
+func (ts *TestSuite) Test_getsizepolicyopt() {
+	ts.Equal(sizePolicyTruncate,
+		envOptions.getsizepolicyopt("SUMOLOGIC_MAX_MESSAGE_SIZE_POLICY"))
+	ts.Setenv("SUMOLOGIC_MAX_MESSAGE_SIZE_POLICY", "split")
+	ts.Equal(sizePolicySplit,
+		envOptions.getsizepolicyopt("SUMOLOGIC_MAX_MESSAGE_SIZE_POLICY"))
+	ts.Setenv("SUMOLOGIC_MAX_MESSAGE_SIZE_POLICY", "shrink")
+	ts.Equal(sizePolicyTruncate,
+		envOptions.getsizepolicyopt("SUMOLOGIC_MAX_MESSAGE_SIZE_POLICY"))
+}
+
+func (ts *TestSuite) Test_cutAt() {
+	ts.Equal(5, cutAt("hello world", 5))
+	ts.Equal(5, cutAt("hello", 10))
+	// The cut is moved back rather than splitting the é, unless that would
+	// leave nothing.
+	ts.Equal(3, cutAt("café!", 4))
+	ts.Equal(2, cutAt("éa", 1))
+}
+
+func (ts *TestSuite) Test_splitMessage() {
+	msg := mkLine("abc", "hello wörld")
+	var parts []string
+	for _, part := range splitMessage(msg, 4) {
+		ts.Equal(msg.Container, part.Container)
+		parts = append(parts, part.Data)
+	}
+	ts.Equal([]string{"hell", "o w", "örl", "d"}, parts)
+	ts.Equal("hello wörld", msg.Data)
+}
+
+func (ts *TestSuite) Test_Stream_truncates_large_messages() {
+	ts.Setenv("SUMOLOGIC_WORKERS", "1")
+	ts.Setenv("SUMOLOGIC_MAX_MESSAGE_BYTES", "5")
+	requests := make(chan *RequestData, 2)
+	adapter := ts.FakeSumo(requests)
+	streamLines(adapter, mkLine("abc", "hello world"), mkLine("abc", "short"))
+
+	body := (<-requests).Body
+	ts.Equal("hello", body["message"])
+	ts.Equal(true, body["truncated"])
+	ts.Equal(jsonobj{"truncated": true}, body["_processing"])
+	body = (<-requests).Body
+	ts.Equal("short", body["message"])
+	ts.NotContains(body, "truncated")
+	ts.NotContains(body, "_processing")
+}
+
+func (ts *TestSuite) Test_Stream_truncates_large_messages_in_raw_format() {
+	ts.Setenv("SUMOLOGIC_WORKERS", "1")
+	ts.Setenv("SUMOLOGIC_MAX_MESSAGE_BYTES", "5")
+	ts.Setenv("SUMOLOGIC_FORMAT", "raw")
+	bodies := make(chan string, 1)
+	adapter := ts.FakeSumoRaw(bodies)
+	streamLines(adapter, mkLine("abc", "hello world"))
+
+	// There's nowhere to mark a raw event as truncated.
+	ts.Equal("hello", <-bodies)
+}
+
+func (ts *TestSuite) Test_Stream_splits_large_messages() {
+	ts.Setenv("SUMOLOGIC_WORKERS", "1")
+	ts.Setenv("SUMOLOGIC_MAX_MESSAGE_BYTES", "5")
+	ts.Setenv("SUMOLOGIC_MAX_MESSAGE_SIZE_POLICY", "split")
+	requests := make(chan *RequestData, 3)
+	adapter := ts.FakeSumo(requests)
+	streamLines(adapter, mkLine("abc", "hello world"))
+
+	for _, expected := range []string{"hello", " worl", "d"} {
+		ts.Equal(expected, (<-requests).Body["message"])
+	}
+}
+
+func (ts *TestSuite) Test_Stream_drops_large_messages() {
+	ts.Setenv("SUMOLOGIC_WORKERS", "1")
+	ts.Setenv("SUMOLOGIC_MAX_MESSAGE_BYTES", "5")
+	ts.Setenv("SUMOLOGIC_MAX_MESSAGE_SIZE_POLICY", "drop")
+	requests := make(chan *RequestData, 2)
+	adapter := ts.FakeSumo(requests)
+	streamLines(adapter, mkLine("abc", "hello world"), mkLine("abc", "short"))
+
+	ts.Equal("short", (<-requests).Body["message"])
+	ts.EqualValues(1, adapter.Status().Dropped)
+}
@@ -0,0 +1,100 @@
+package sumologic
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// slowStart paces sends for a while after the endpoint recovers from an
+// outage, so that everything that queued up during the outage isn't released
+// at once. The allowed rate starts at initialRate messages per second and
+// doubles every second until the ramp is over. A nil *slowStart never delays
+// anything.
+type slowStart struct {
+	mu          sync.Mutex
+	clock       Clock
+	duration    time.Duration
+	initialRate float64
+	down        bool
+	recoveredAt time.Time
+	next        time.Time
+}
+
+// newSlowStart returns a slowStart that ramps up over the given duration, or
+// nil if either the duration or the initial rate isn't positive.
+func newSlowStart(
+	clock Clock, duration time.Duration, initialRate int64) *slowStart {
+	if duration <= 0 || initialRate <= 0 {
+		return nil
+	}
+	return &slowStart{
+		clock:       clock,
+		duration:    duration,
+		initialRate: float64(initialRate),
+	}
+}
+
+// failed records that a send to the endpoint failed.
+func (s *slowStart) failed() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.down = true
+}
+
+// succeeded records that a send to the endpoint succeeded. If the endpoint
+// was previously failing, this starts the ramp.
+func (s *slowStart) succeeded() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.down {
+		s.down = false
+		s.recoveredAt = s.clock.Now()
+		s.next = s.recoveredAt
+	}
+}
+
+// delay reserves the next send slot and returns how long the caller needs to
+// wait for it.
+func (s *slowStart) delay() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.clock.Now()
+	elapsed := now.Sub(s.recoveredAt)
+	if s.down || s.recoveredAt.IsZero() || elapsed >= s.duration {
+		return 0
+	}
+	rate := s.initialRate * math.Pow(2, elapsed.Seconds())
+	slot := s.next
+	if slot.Before(now) {
+		slot = now
+	}
+	s.next = slot.Add(time.Duration(float64(time.Second) / rate))
+	return slot.Sub(now)
+}
+
+// wait blocks until the caller may send, or the context is done.
+func (s *slowStart) wait(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+	d := s.delay()
+	if d <= 0 {
+		return nil
+	}
+	timer := s.clock.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,89 @@
+package sumologic
+
+import (
+	"context"
+	"time"
+)
+
+func (ts *TestSuite) Test_newSlowStart_disabled() {
+	ts.Nil(newSlowStart(newFakeClock(), 0, 10))
+	ts.Nil(newSlowStart(newFakeClock(), time.Second, 0))
+
+	var s *slowStart
+	s.failed()
+	s.succeeded()
+	ts.NoError(s.wait(context.Background()))
+}
+
+func (ts *TestSuite) Test_slowStart_no_delay_without_outage() {
+	clock := newFakeClock()
+	s := newSlowStart(clock, time.Minute, 1)
+	s.succeeded()
+	ts.Equal(time.Duration(0), s.delay())
+	ts.Equal(time.Duration(0), s.delay())
+}
+
+func (ts *TestSuite) Test_slowStart_no_delay_while_down() {
+	clock := newFakeClock()
+	s := newSlowStart(clock, time.Minute, 1)
+	s.failed()
+	ts.Equal(time.Duration(0), s.delay())
+	ts.Equal(time.Duration(0), s.delay())
+}
+
+func (ts *TestSuite) Test_slowStart_paces_after_recovery() {
+	clock := newFakeClock()
+	s := newSlowStart(clock, time.Minute, 1)
+	s.failed()
+	s.succeeded()
+	ts.Equal(time.Duration(0), s.delay())
+	ts.Equal(time.Second, s.delay())
+	ts.Equal(2*time.Second, s.delay())
+
+	// After a second, the rate has doubled.
+	clock.Advance(time.Second)
+	ts.Equal(2*time.Second, s.delay())
+	ts.Equal(2*time.Second+500*time.Millisecond, s.delay())
+}
+
+func (ts *TestSuite) Test_slowStart_ramp_ends() {
+	clock := newFakeClock()
+	s := newSlowStart(clock, time.Minute, 1)
+	s.failed()
+	s.succeeded()
+	clock.Advance(time.Minute)
+	ts.Equal(time.Duration(0), s.delay())
+	ts.Equal(time.Duration(0), s.delay())
+}
+
+func (ts *TestSuite) Test_slowStart_wait_for_slot() {
+	clock := newFakeClock()
+	s := newSlowStart(clock, time.Minute, 1)
+	s.failed()
+	s.succeeded()
+	s.delay()
+
+	done := make(chan error)
+	go func() { done <- s.wait(context.Background()) }()
+	clock.WaitForTimers(1)
+
+	select {
+	case <-done:
+		ts.Fail("Didn't wait for the next slot.")
+	default:
+	}
+	clock.Advance(time.Second)
+	ts.NoError(<-done)
+}
+
+func (ts *TestSuite) Test_slowStart_wait_cancelled() {
+	clock := newFakeClock()
+	s := newSlowStart(clock, time.Minute, 1)
+	s.failed()
+	s.succeeded()
+	s.delay()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	ts.Equal(context.Canceled, s.wait(ctx))
+}
@@ -0,0 +1,235 @@
+//go:build !sumologic_nospool
+// +build !sumologic_nospool
+
+package sumologic
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func init() {
+	registerModule("spool")
+}
+
+// spoolReplayInterval is how often spooled requests are retried.
+const spoolReplayInterval = 10 * time.Second
+
+// spoolSuffix marks the files in the buffer directory that hold spooled
+// requests, as opposed to ones still being written.
+const spoolSuffix = ".spool"
+
+// spool persists request bodies that couldn't be delivered to a directory on
+// disk, so that they can be replayed once the endpoint recovers, even if
+// logspout is restarted in the meantime. Once the spool is full, the oldest
+// requests are discarded to make room. A nil *spool doesn't keep anything.
+type spool struct {
+	mu       sync.Mutex
+	dir      string
+	maxBytes int64
+	size     int64
+	seq      int64
+	// files are the spooled requests, oldest first. The directory is only
+	// read when the spool is created, as the spool is the only thing that
+	// writes to it after that.
+	files []spoolFile
+}
+
+// spooledRequest is a request as it's stored on disk.
+type spooledRequest struct {
+	Headers http.Header `json:"headers"`
+	Body    []byte      `json:"body"`
+	Events  int64       `json:"events"`
+	// Containers counts the events in the request by container ID, for the
+	// audit log.
+	Containers map[string]int64 `json:"containers,omitempty"`
+}
+
+// newSpool returns a spool keeping up to maxMB megabytes of requests to
+// endPoint in its own subdirectory of dir, or nil if no directory is
+// configured or it can't be used. Requests spooled by a previous run are
+// picked up.
+func newSpool(dir string, endPoint string, maxMB int64) *spool {
+	if dir == "" {
+		return nil
+	}
+	dir = routeDir(dir, endPoint)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		log.WithError(err).WithField("dir", dir).Error(
+			"Unable to create buffer directory, not buffering failed sends")
+		return nil
+	}
+	s := &spool{dir: dir, maxBytes: maxMB * 1024 * 1024}
+	s.files = s.list()
+	for _, file := range s.files {
+		s.size += file.size
+	}
+	return s
+}
+
+type spoolFile struct {
+	path string
+	size int64
+}
+
+// list reads the spooled requests from the directory, oldest first.
+func (s *spool) list() []spoolFile {
+	infos, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		log.WithError(err).Error("Unable to read buffer directory")
+		return nil
+	}
+	files := []spoolFile{}
+	for _, info := range infos {
+		if info.IsDir() || !strings.HasSuffix(info.Name(), spoolSuffix) {
+			continue
+		}
+		files = append(files, spoolFile{
+			path: filepath.Join(s.dir, info.Name()),
+			size: info.Size(),
+		})
+	}
+	// The names start with a zero-padded timestamp, so they sort by age.
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].path < files[j].path
+	})
+	return files
+}
+
+// add persists a request holding the given number of events from each
+// container that couldn't be delivered.
+func (s *spool) add(now time.Time, body []byte, headers http.Header,
+	containers map[string]int64) {
+	if s == nil {
+		return
+	}
+	data, err := json.Marshal(&spooledRequest{
+		Headers:    headers,
+		Body:       body,
+		Events:     countEvents(containers),
+		Containers: containers,
+	})
+	if err != nil {
+		log.WithError(err).Error("Unable to encode request for buffering")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if int64(len(data)) > s.maxBytes {
+		log.WithField("bytes", len(data)).Error(
+			"Request is larger than the buffer, dropping it")
+		return
+	}
+	for len(s.files) > 0 && s.size+int64(len(data)) > s.maxBytes {
+		log.WithField("file", s.files[0].path).Warn(
+			"Buffer is full, dropping the oldest request")
+		s.remove(s.files[0])
+	}
+
+	s.seq++
+	name := fmt.Sprintf("%020d-%06d", now.UnixNano(), s.seq)
+	tmp := filepath.Join(s.dir, name+".tmp")
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		log.WithError(err).Error("Unable to buffer failed request")
+		os.Remove(tmp)
+		return
+	}
+	path := filepath.Join(s.dir, name+spoolSuffix)
+	if err := os.Rename(tmp, path); err != nil {
+		log.WithError(err).Error("Unable to buffer failed request")
+		os.Remove(tmp)
+		return
+	}
+	s.files = append(s.files, spoolFile{path: path, size: int64(len(data))})
+	s.size += int64(len(data))
+}
+
+// remove deletes a spooled request, which must be called with the lock held.
+// It's forgotten even if the file can't be deleted, so that it isn't
+// replayed again and again.
+func (s *spool) remove(file spoolFile) {
+	if err := os.Remove(file.path); err != nil && !os.IsNotExist(err) {
+		log.WithError(err).Error("Unable to remove buffered request")
+	}
+	for i := range s.files {
+		if s.files[i].path == file.path {
+			s.files = append(s.files[:i], s.files[i+1:]...)
+			s.size -= file.size
+			break
+		}
+	}
+}
+
+// next returns the oldest spooled request, or the newest one if newestFirst
+// is set, or nil if there aren't any.
+func (s *spool) next(newestFirst bool) (*spoolFile, *spooledRequest) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(s.files) > 0 {
+		file := s.files[0]
+		if newestFirst {
+			file = s.files[len(s.files)-1]
+		}
+		data, err := ioutil.ReadFile(file.path)
+		if err == nil {
+			request := &spooledRequest{}
+			if err = json.Unmarshal(data, request); err == nil {
+				return &file, request
+			}
+		}
+		log.WithError(err).WithField("file", file.path).Error(
+			"Unable to read buffered request, dropping it")
+		s.remove(file)
+	}
+	return nil, nil
+}
+
+// done removes a spooled request once it's been dealt with.
+func (s *spool) done(file *spoolFile) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.remove(*file)
+}
+
+// replaySpool sends spooled requests, in SUMOLOGIC_REPLAY_ORDER, until one
+// fails or there are none left.
+func (s *Adapter) replaySpool() {
+	defer s.recoverPanic("replaySpool")
+
+	for s.ctx.Err() == nil {
+		file, request := s.spool.next(
+			s.config().replayOrder == replayNewest)
+		if file == nil {
+			return
+		}
+		ctx, cancel := s.deliveryContext()
+		err := s.attempt(ctx, request.Body, request.Headers, request.Events)
+		cancel()
+		if spoolable(err) || s.ctx.Err() != nil {
+			return
+		}
+		if payloadTooLarge(err) {
+			s.deliveryFailed(err.(*SendError).Err)
+		}
+		if err != nil {
+			log.WithError(err).Error("Dropping buffered request")
+			s.deadLetters.add(s.clock.Now(), request.Body, request.Headers,
+				request.Containers, err)
+		}
+		s.audit.record(s.clock.Now(), request.Containers,
+			int64(len(request.Body)), request.Headers.Get("X-Sumo-Category"),
+			s.auditStatus(err), err)
+		s.spool.done(file)
+	}
+}
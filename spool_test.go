@@ -0,0 +1,206 @@
+//go:build !sumologic_nospool
+// +build !sumologic_nospool
+
+package sumologic
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+func (ts *TestSuite) spooled(dir string) []string {
+	names := []string{}
+	for _, info := range ts.WithoutError(ioutil.ReadDir(dir)).([]os.FileInfo) {
+		names = append(names, info.Name())
+	}
+	return names
+}
+
+func (ts *TestSuite) Test_spool_disabled_by_default() {
+	adapter := ts.mkAdapter(&router.Route{Address: "https://example.com/"})
+	ts.Nil(adapter.spool)
+}
+
+func (ts *TestSuite) Test_spool_replays_failed_sends() {
+	ts.CaptureLogs()
+	code := int64(http.StatusServiceUnavailable)
+	requests := make(chan *RequestData, 2)
+	adapter, _ := ts.FakeFlakySumo(&code, requests, "SUMOLOGIC_BUFFER_DIR")
+	dir := adapter.spool.dir
+
+	one := mkContainerMessage("abc", "/foo")
+	one.Data = "one"
+	two := mkContainerMessage("abc", "/foo")
+	two.Data = "two"
+	ts.Error(adapter.Send(one))
+	ts.Error(adapter.Send(two))
+	ts.Len(ts.spooled(dir), 2)
+
+	adapter.replaySpool()
+	ts.Len(ts.spooled(dir), 2, "still failing, so nothing is replayed")
+
+	atomic.StoreInt64(&code, http.StatusOK)
+	adapter.replaySpool()
+	ts.Empty(ts.spooled(dir))
+	ts.Equal("one", (<-requests).Body["message"])
+	ts.Equal("two", (<-requests).Body["message"])
+	ts.EqualValues(0, adapter.spool.size)
+}
+
+func (ts *TestSuite) Test_spool_replays_newest_first() {
+	ts.CaptureLogs()
+	ts.Setenv("SUMOLOGIC_REPLAY_ORDER", "newest")
+	code := int64(http.StatusServiceUnavailable)
+	requests := make(chan *RequestData, 2)
+	adapter, _ := ts.FakeFlakySumo(&code, requests, "SUMOLOGIC_BUFFER_DIR")
+	dir := adapter.spool.dir
+	ts.Error(adapter.Send(mkLine("abc", "one")))
+	ts.Error(adapter.Send(mkLine("abc", "two")))
+
+	atomic.StoreInt64(&code, http.StatusOK)
+	adapter.replaySpool()
+	ts.Empty(ts.spooled(dir))
+	ts.Equal("two", (<-requests).Body["message"])
+	ts.Equal("one", (<-requests).Body["message"])
+}
+
+func (ts *TestSuite) Test_spool_skips_permanent_failures() {
+	ts.CaptureLogs()
+	code := int64(http.StatusBadRequest)
+	adapter, _ := ts.FakeFlakySumo(&code, nil, "SUMOLOGIC_BUFFER_DIR")
+	dir := adapter.spool.dir
+
+	ts.Error(adapter.Send(mkContainerMessage("abc", "/foo")))
+	ts.Empty(ts.spooled(dir))
+}
+
+func (ts *TestSuite) Test_spool_survives_restart() {
+	ts.CaptureLogs()
+	code := int64(http.StatusServiceUnavailable)
+	requests := make(chan *RequestData, 1)
+	adapter, _ := ts.FakeFlakySumo(&code, requests, "SUMOLOGIC_BUFFER_DIR")
+	dir := adapter.spool.dir
+	ts.Error(adapter.Send(mkContainerMessage("abc", "/foo")))
+	adapter.Close()
+
+	atomic.StoreInt64(&code, http.StatusOK)
+	restarted := ts.WithoutError(NewAdapterWithClock(&router.Route{
+		ID: "foo", Address: adapter.route.Address}, newFakeClock())).(*Adapter)
+	ts.AddCleanup(restarted.Close)
+	ts.Equal(adapter.spool.size, restarted.spool.size)
+	restarted.replaySpool()
+	ts.Equal("Some data.", (<-requests).Body["message"])
+	ts.Empty(ts.spooled(dir))
+}
+
+func (ts *TestSuite) Test_spool_is_kept_per_endpoint() {
+	dir := ts.WithoutError(ioutil.TempDir("", "spool")).(string)
+	ts.AddCleanup(func() { os.RemoveAll(dir) })
+	ts.Setenv("SUMOLOGIC_BUFFER_DIR", dir)
+	one := ts.mkAdapter(&router.Route{Address: "https://example.com/one"})
+	two := ts.mkAdapter(&router.Route{Address: "https://example.com/two"})
+	ts.NotEqual(one.spool.dir, two.spool.dir)
+
+	one.spool.add(newFakeClock().Now(), []byte("one"), http.Header{},
+		map[string]int64{"abc": 1})
+	ts.Len(ts.spooled(one.spool.dir), 1)
+	file, _ := two.spool.next(false)
+	ts.Nil(file, "other routes' requests aren't replayed")
+	ts.EqualValues(0, two.spool.size)
+}
+
+func (ts *TestSuite) Test_spool_drops_oldest_when_full() {
+	hook, _ := ts.CaptureLogs()
+	dir := ts.WithoutError(ioutil.TempDir("", "spool")).(string)
+	ts.AddCleanup(func() { os.RemoveAll(dir) })
+	s := newSpool(dir, "https://example.com/", 1)
+	s.maxBytes = 450
+	dir = s.dir
+
+	clock := newFakeClock()
+	body := []byte(strings.Repeat("x", 100))
+	s.add(clock.Now(), body, http.Header{}, map[string]int64{"abc": 1})
+	first := ts.spooled(dir)[0]
+	s.add(clock.Now(), body, http.Header{}, map[string]int64{"abc": 1})
+	ts.Len(ts.spooled(dir), 2)
+	s.add(clock.Now(), body, http.Header{}, map[string]int64{"abc": 1})
+	ts.Len(ts.spooled(dir), 2)
+	ts.Equal("Buffer is full, dropping the oldest request",
+		hook.LastEntry().Message)
+	ts.NotContains(ts.spooled(dir), first)
+	ts.True(s.size <= s.maxBytes)
+
+	s.add(clock.Now(), []byte(strings.Repeat("x", 400)), http.Header{},
+		map[string]int64{"abc": 1})
+	ts.Equal("Request is larger than the buffer, dropping it",
+		hook.LastEntry().Message)
+}
+
+func (ts *TestSuite) Test_spool_drops_unreadable_requests() {
+	ts.CaptureLogs()
+	dir := ts.WithoutError(ioutil.TempDir("", "spool")).(string)
+	ts.AddCleanup(func() { os.RemoveAll(dir) })
+	endPoint := "https://example.com/"
+	dir = routeDir(dir, endPoint)
+	ts.Require().NoError(os.MkdirAll(dir, 0700))
+	ts.Require().NoError(ioutil.WriteFile(
+		filepath.Join(dir, "0-0"+spoolSuffix), []byte("garbage"), 0600))
+	s := newSpool(filepath.Dir(dir), endPoint, 1)
+
+	file, request := s.next(false)
+	ts.Nil(file)
+	ts.Nil(request)
+	ts.Empty(ts.spooled(dir))
+	ts.EqualValues(0, s.size)
+}
+
+func (ts *TestSuite) Test_audit_records_buffered_and_replayed_requests() {
+	ts.CaptureLogs()
+	path := filepath.Join(ts.auditDir(), "audit.log")
+	ts.Setenv("SUMOLOGIC_AUDIT_FILE", path)
+	code := int64(http.StatusServiceUnavailable)
+	requests := make(chan *RequestData, 1)
+	adapter, _ := ts.FakeFlakySumo(&code, requests, "SUMOLOGIC_BUFFER_DIR")
+
+	ts.Error(adapter.Send(mkContainerMessage("abc", "/foo")))
+	atomic.StoreInt64(&code, http.StatusOK)
+	adapter.replaySpool()
+	<-requests
+
+	records := ts.audited(path)
+	ts.Len(records, 2)
+	ts.Equal(auditBuffered, records[0].Status)
+	ts.Contains(records[0].Error, "503")
+	ts.Equal(auditSent, records[1].Status)
+	ts.Equal(map[string]int64{"abc": 1}, records[1].Containers)
+	ts.Equal(records[0].Bytes, records[1].Bytes)
+}
+
+func (ts *TestSuite) Test_spool_dead_letters_dropped_requests() {
+	ts.CaptureLogs()
+	deadLetterDir := ts.WithoutError(ioutil.TempDir("", "deadletter")).(string)
+	ts.AddCleanup(func() { os.RemoveAll(deadLetterDir) })
+	ts.Setenv("SUMOLOGIC_DEAD_LETTER_DIR", deadLetterDir)
+	code := int64(http.StatusServiceUnavailable)
+	adapter, _ := ts.FakeFlakySumo(
+		&code, make(chan *RequestData, 1), "SUMOLOGIC_BUFFER_DIR")
+	dir := adapter.spool.dir
+
+	ts.Error(adapter.Send(mkLine("abc", "Some data.")))
+	ts.Len(ts.spooled(dir), 1)
+	ts.Empty(ts.deadLettered(adapter.deadLetters.dir),
+		"buffered requests aren't dead")
+
+	atomic.StoreInt64(&code, http.StatusBadRequest)
+	adapter.replaySpool()
+	ts.Empty(ts.spooled(dir))
+	letters := ts.deadLettered(adapter.deadLetters.dir)
+	ts.Require().Len(letters, 1)
+	ts.Equal("permanent failure: unexpected status code 400", letters[0].Error)
+}
@@ -0,0 +1,156 @@
+package sumologic
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+)
+
+// tailInterval is how often TailFile checks for new lines.
+const tailInterval = 250 * time.Millisecond
+
+// StreamReader ships every line read from r, as if it had been logged by a
+// container with the given name, until r is exhausted. It lets the adapter
+// be used outside of logspout, e.g. to ship a process's stdin:
+//
+//	adapter, _ := sumologic.NewAdapter(&router.Route{ID: "stdin"})
+//	adapter.(*sumologic.Adapter).StreamReader(os.Stdin, "myapp")
+//
+// Messages go through the same filtering and enrichment as container logs.
+func (s *Adapter) StreamReader(r io.Reader, name string) error {
+	container := standaloneContainer(name)
+	return s.streamLines(func(logstream chan<- *router.Message) error {
+		reader := bufio.NewReader(r)
+		for {
+			line, err := reader.ReadString('\n')
+			if line != "" {
+				logstream <- s.standaloneMessage(container, "stdin", line)
+			}
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+	})
+}
+
+// TailFile ships every line appended to the file at path, as if it had been
+// logged by a container with the given name, until the adapter is closed.
+// Like `tail -F`, it starts at the end of the file, and follows the path if
+// the file is truncated or replaced (e.g. by log rotation).
+func (s *Adapter) TailFile(path string, name string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	if _, err = file.Seek(0, io.SeekEnd); err != nil {
+		file.Close()
+		return err
+	}
+	container := standaloneContainer(name)
+	return s.streamLines(func(logstream chan<- *router.Message) error {
+		defer func() { file.Close() }()
+		reader := bufio.NewReader(file)
+		partial := ""
+		for {
+			line, err := reader.ReadString('\n')
+			partial += line
+			if err == nil {
+				logstream <- s.standaloneMessage(container, "file", partial)
+				partial = ""
+				continue
+			}
+			if err != io.EOF {
+				return err
+			}
+
+			timer := s.clock.NewTimer(tailInterval)
+			select {
+			case <-timer.C():
+			case <-s.ctx.Done():
+				timer.Stop()
+				return nil
+			}
+			reopened, err := reopenIfRotated(file, path)
+			if err != nil {
+				return err
+			}
+			if reopened != file {
+				file.Close()
+				file = reopened
+				reader.Reset(file)
+				partial = ""
+			}
+		}
+	})
+}
+
+// reopenIfRotated returns a newly opened file if the one at path isn't the
+// open file any more, or if the open file has been truncated. Otherwise it
+// returns the open file. If there's nothing at path (yet), the open file is
+// kept.
+func reopenIfRotated(file *os.File, path string) (*os.File, error) {
+	current, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return file, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	open, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	offset, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	if os.SameFile(current, open) && open.Size() >= offset {
+		return file, nil
+	}
+	return os.Open(path)
+}
+
+// streamLines runs Stream on the messages produced by feed, and waits for
+// feed to finish.
+func (s *Adapter) streamLines(
+	feed func(logstream chan<- *router.Message) error) error {
+	logstream := make(chan *router.Message)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.Stream(logstream)
+	}()
+	err := feed(logstream)
+	close(logstream)
+	<-done
+	return err
+}
+
+// standaloneContainer describes the pretend container that lines read by
+// StreamReader and TailFile come from.
+func standaloneContainer(name string) *docker.Container {
+	hostname, _ := os.Hostname()
+	return &docker.Container{
+		ID:     name,
+		Name:   name,
+		Config: &docker.Config{Hostname: hostname},
+	}
+}
+
+func (s *Adapter) standaloneMessage(
+	container *docker.Container, source string, line string) *router.Message {
+	return &router.Message{
+		Container: container,
+		Source:    source,
+		Data:      strings.TrimRight(line, "\r\n"),
+		Time:      s.clock.Now(),
+	}
+}
@@ -0,0 +1,80 @@
+package sumologic
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+func (ts *TestSuite) Test_StreamReader() {
+	requests := make(chan *RequestData, 2)
+	adapter := ts.FakeSumo(requests)
+
+	ts.NoError(adapter.StreamReader(
+		strings.NewReader("one\r\n\ntwo"), "myapp"))
+	bodies := map[string]string{}
+	for i := 0; i < 2; i++ {
+		request := <-requests
+		ts.Equal("myapp", request.Headers["X-Sumo-Name"])
+		container := request.Body["container"].(jsonobj)
+		ts.Equal("stdin", container["source"])
+		bodies[request.Body["message"].(string)] = container["docker_name"].(string)
+	}
+	ts.Equal(map[string]string{"one": "myapp", "two": "myapp"}, bodies)
+}
+
+func (ts *TestSuite) Test_TailFile() {
+	dir := ts.WithoutError(ioutil.TempDir("", "tail")).(string)
+	ts.AddCleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "app.log")
+	ts.Require().NoError(ioutil.WriteFile(path, []byte("old\n"), 0644))
+	appendFile := func(text string) {
+		f := ts.WithoutError(
+			os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)).(*os.File)
+		defer f.Close()
+		ts.WithoutError(f.WriteString(text))
+	}
+
+	requests := make(chan *RequestData, 10)
+	clock := newFakeClock()
+	adapter := ts.FakeSumoWithClock(requests, clock)
+	done := make(chan error)
+	go func() { done <- adapter.TailFile(path, "myapp") }()
+	message := func() string {
+		select {
+		case request := <-requests:
+			return request.Body["message"].(string)
+		case <-time.After(time.Second):
+			ts.Fail("Timeout waiting for request.")
+			return ""
+		}
+	}
+
+	clock.WaitForTimers(1)
+	appendFile("new\npart")
+	clock.Advance(tailInterval)
+	ts.Equal("new", message())
+
+	clock.WaitForTimers(1)
+	appendFile("ial\n")
+	clock.Advance(tailInterval)
+	ts.Equal("partial", message())
+
+	clock.WaitForTimers(1)
+	ts.Require().NoError(os.Rename(path, path+".1"))
+	appendFile("rotated\n")
+	clock.Advance(tailInterval)
+	ts.Equal("rotated", message())
+
+	clock.WaitForTimers(1)
+	adapter.Close()
+	ts.NoError(<-done)
+	ts.Empty(requests)
+}
+
+func (ts *TestSuite) Test_TailFile_missing() {
+	adapter := ts.FakeSumo(make(chan *RequestData))
+	ts.Error(adapter.TailFile("/nonexistent/app.log", "myapp"))
+}
@@ -0,0 +1,141 @@
+package sumologic
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gliderlabs/logspout/router"
+	log "github.com/sirupsen/logrus"
+)
+
+// standbyCheckInterval is how often the endpoint is checked while messages
+// are being held.
+const standbyCheckInterval = time.Second
+
+// dialEndpoint connects to an endpoint's host, to check that it can be
+// reached.
+var dialEndpoint = func(ctx context.Context, address string) error {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// standby holds the messages received when the adapter starts, until the
+// endpoint can be reached, so that the logs from a host booting aren't lost
+// while its network is still coming up. Once the buffer is full, the oldest
+// messages are dropped to make room. A nil *standby doesn't hold anything.
+type standby struct {
+	mu       sync.Mutex
+	max      int
+	released bool
+	held     []*router.Message
+}
+
+// newStandby returns a standby holding up to max messages, or nil if max
+// isn't positive.
+func newStandby(max int64) *standby {
+	if max <= 0 {
+		return nil
+	}
+	return &standby{max: int(max)}
+}
+
+// hold holds a message, if messages are still being held. It returns the
+// message dropped to make room for it, if any.
+func (b *standby) hold(msg *router.Message) (held bool, dropped *router.Message) {
+	if b == nil {
+		return false, nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.released {
+		return false, nil
+	}
+	if len(b.held) >= b.max {
+		dropped = b.held[0]
+		b.held = b.held[1:]
+	}
+	b.held = append(b.held, msg)
+	return true, dropped
+}
+
+// release stops holding messages, and passes the ones held on in the order
+// they arrived. Messages arriving in the meantime wait for them.
+func (b *standby) release(push func(*router.Message)) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.released {
+		return
+	}
+	b.released = true
+	for _, msg := range b.held {
+		push(msg)
+	}
+	b.held = nil
+}
+
+// holding reports whether messages are still being held.
+func (b *standby) holding() bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return !b.released
+}
+
+// awaitEndpoint checks the endpoint until it can be reached, then releases
+// the messages held in the meantime. They're released anyway once the
+// standby timeout has passed, to take their chances with retries and the
+// buffer.
+func (s *Adapter) awaitEndpoint(timeout time.Duration) {
+	defer s.standby.release(s.push)
+
+	config := s.config()
+	address := config.dialAddress(config.endPoint)
+	deadline := s.clock.Now().Add(timeout)
+	for {
+		if err := dialEndpoint(s.ctx, address); err == nil {
+			log.WithField("route", s.route.ID).Debug(
+				"Endpoint reachable, releasing held messages")
+			return
+		}
+		if !s.clock.Now().Before(deadline) {
+			log.WithField("route", s.route.ID).Warn(
+				"Endpoint still unreachable, releasing held messages anyway")
+			return
+		}
+		timer := s.clock.NewTimer(standbyCheckInterval)
+		select {
+		case <-timer.C():
+		case <-s.ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// endpointAddress returns the host and port to connect to for an endpoint.
+func endpointAddress(endPoint string) string {
+	u, err := url.Parse(endPoint)
+	if err != nil {
+		return endPoint
+	}
+	if u.Port() != "" {
+		return u.Host
+	}
+	port := "443"
+	if u.Scheme == "http" {
+		port = "80"
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
@@ -0,0 +1,99 @@
+package sumologic
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+// FakeEndpointReachability makes the endpoint reachable only once reachable
+// is non-zero, for the duration of the test.
+func (ts *TestSuite) FakeEndpointReachability(reachable *int32) {
+	original := dialEndpoint
+	dialEndpoint = func(ctx context.Context, address string) error {
+		if atomic.LoadInt32(reachable) == 0 {
+			return errors.New("network is unreachable")
+		}
+		return nil
+	}
+	ts.AddCleanup(func() { dialEndpoint = original })
+}
+
+func (ts *TestSuite) Test_newStandby_disabled() {
+	b := newStandby(0)
+	ts.Nil(b)
+	held, _ := b.hold(mkContainerMessage("abc", "/foo"))
+	ts.False(held)
+	ts.False(b.holding())
+}
+
+func (ts *TestSuite) Test_standby_drops_oldest_and_releases_in_order() {
+	b := newStandby(2)
+	var released []string
+	for _, data := range []string{"one", "two", "three"} {
+		held, dropped := b.hold(mkLine("abc", data))
+		ts.True(held)
+		if data == "three" {
+			ts.Equal("one", dropped.Data)
+		} else {
+			ts.Nil(dropped)
+		}
+	}
+	b.release(func(msg *router.Message) { released = append(released, msg.Data) })
+	ts.Equal([]string{"two", "three"}, released)
+	held, _ := b.hold(mkLine("abc", "four"))
+	ts.False(held)
+	ts.False(b.holding())
+}
+
+func (ts *TestSuite) Test_endpointAddress() {
+	ts.Equal("collectors.sumologic.com:443",
+		endpointAddress("https://collectors.sumologic.com/receiver/v1/http/x"))
+	ts.Equal("localhost:80", endpointAddress("http://localhost/receiver"))
+	ts.Equal("127.0.0.1:8080", endpointAddress("http://127.0.0.1:8080/"))
+}
+
+func (ts *TestSuite) Test_Stream_holds_messages_until_endpoint_reachable() {
+	var reachable int32
+	ts.FakeEndpointReachability(&reachable)
+	ts.Setenv("SUMOLOGIC_STANDBY_MAX_MESSAGES", "2")
+	ts.Setenv("SUMOLOGIC_WORKERS", "1")
+	requests := make(chan *RequestData, 2)
+	clock := newFakeClock()
+	adapter := ts.FakeSumoWithClock(requests, clock)
+
+	for _, data := range []string{"one", "two", "three"} {
+		adapter.receive(mkLine("abc", data))
+	}
+	status := adapter.Status()
+	ts.True(status.Standby)
+	ts.EqualValues(1, status.Dropped)
+	ts.Empty(requests)
+
+	clock.WaitForTimers(1)
+	atomic.StoreInt32(&reachable, 1)
+	clock.Advance(standbyCheckInterval)
+	ts.Equal("two", (<-requests).Body["message"])
+	ts.Equal("three", (<-requests).Body["message"])
+	ts.False(adapter.Status().Standby)
+}
+
+func (ts *TestSuite) Test_Stream_releases_messages_after_standby_timeout() {
+	ts.CaptureLogs()
+	var reachable int32
+	ts.FakeEndpointReachability(&reachable)
+	ts.Setenv("SUMOLOGIC_STANDBY_MAX_MESSAGES", "10")
+	ts.Setenv("SUMOLOGIC_STANDBY_TIMEOUT_MS", "1000")
+	requests := make(chan *RequestData, 1)
+	clock := newFakeClock()
+	adapter := ts.FakeSumoWithClock(requests, clock)
+
+	adapter.receive(mkLine("abc", "one"))
+	clock.WaitForTimers(1)
+	clock.Advance(time.Second)
+	ts.Equal("one", (<-requests).Body["message"])
+	ts.False(adapter.Status().Standby)
+}
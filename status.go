@@ -0,0 +1,220 @@
+package sumologic
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// adapters holds every live Adapter by route ID, so that their status can be
+// reported over HTTP.
+var adapters = &adapterRegistry{adapters: map[string]*Adapter{}}
+
+type adapterRegistry struct {
+	mu       sync.Mutex
+	adapters map[string]*Adapter
+}
+
+func (r *adapterRegistry) add(a *Adapter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.adapters[a.route.ID] = a
+}
+
+func (r *adapterRegistry) remove(a *Adapter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.adapters[a.route.ID] == a {
+		delete(r.adapters, a.route.ID)
+	}
+}
+
+func (r *adapterRegistry) all() []*Adapter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	result := make([]*Adapter, 0, len(r.adapters))
+	for _, a := range r.adapters {
+		result = append(result, a)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].route.ID < result[j].route.ID
+	})
+	return result
+}
+
+// deliveryStatus tracks the outcome of sends for a single Adapter.
+type deliveryStatus struct {
+	mu          sync.Mutex
+	clock       Clock
+	sent        int64
+	failed      int64
+	dropped     int64
+	pending     int64
+	retries     int64
+	lastSuccess time.Time
+	lastError   string
+	lastErrorAt time.Time
+	failures    map[string]int64
+	categories  map[string]*CategoryStats
+}
+
+// CategoryStats counts what has been sent to a single source category.
+type CategoryStats struct {
+	Events int64 `json:"events"`
+	Bytes  int64 `json:"bytes"`
+}
+
+// uncategorized is the category that events sent without a source category
+// are accounted under.
+const uncategorized = "(none)"
+
+// RouteStatus is a snapshot of an Adapter's delivery status.
+type RouteStatus struct {
+	ID      string `json:"id"`
+	Healthy bool   `json:"healthy"`
+	Sent    int64  `json:"sent"`
+	Failed  int64  `json:"failed"`
+	Dropped int64  `json:"dropped"`
+	Pending int64  `json:"pending"`
+	// Queued counts messages waiting in the route's queues for a worker.
+	Queued int64 `json:"queued"`
+	Panics int64 `json:"panics"`
+	// Retries counts requests that were sent again, because they didn't get
+	// a response or got one with a status worth retrying.
+	Retries int64 `json:"retries,omitempty"`
+	// Standby is whether messages are being held until the endpoint can be
+	// reached.
+	Standby bool `json:"standby,omitempty"`
+	// FailedOver is whether requests are being sent to a secondary endpoint
+	// because the primary keeps failing.
+	FailedOver bool `json:"failed_over,omitempty"`
+	// Stalled is whether Stream is being held up by failing requests, in
+	// strict delivery mode. Stalls counts how many times that has happened,
+	// and StalledMs how long it has been held up for in total.
+	Stalled       bool   `json:"stalled,omitempty"`
+	Stalls        int64  `json:"stalls,omitempty"`
+	StalledMs     int64  `json:"stalled_ms,omitempty"`
+	LastSuccess   string `json:"last_success,omitempty"`
+	LastError     string `json:"last_error,omitempty"`
+	LastErrorTime string `json:"last_error_time,omitempty"`
+	// Failures counts failed sends by class, e.g. "dns" or "timeout".
+	Failures map[string]int64 `json:"failures,omitempty"`
+	// Categories counts successfully sent events and bytes by source category.
+	Categories map[string]CategoryStats `json:"categories,omitempty"`
+}
+
+func (d *deliveryStatus) begin() {
+	atomic.AddInt64(&d.pending, 1)
+}
+
+func (d *deliveryStatus) end() {
+	atomic.AddInt64(&d.pending, -1)
+}
+
+func (d *deliveryStatus) drop() {
+	atomic.AddInt64(&d.dropped, 1)
+}
+
+func (d *deliveryStatus) retried() {
+	atomic.AddInt64(&d.retries, 1)
+}
+
+func (d *deliveryStatus) succeeded(events int64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.sent += events
+	d.lastSuccess = d.clock.Now()
+}
+
+// ingested accounts a successfully sent request body to its source category.
+func (d *deliveryStatus) ingested(category string, events int64, bytes int64) {
+	if category == "" {
+		category = uncategorized
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.categories == nil {
+		d.categories = map[string]*CategoryStats{}
+	}
+	stats, ok := d.categories[category]
+	if !ok {
+		stats = &CategoryStats{}
+		d.categories[category] = stats
+	}
+	stats.Events += events
+	stats.Bytes += bytes
+}
+
+func (d *deliveryStatus) failedWith(err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.failed++
+	if d.failures == nil {
+		d.failures = map[string]int64{}
+	}
+	d.failures[classifyError(err)]++
+	d.lastError = err.Error()
+	d.lastErrorAt = d.clock.Now()
+}
+
+// Status returns a snapshot of the adapter's delivery status. The route is
+// considered healthy unless its most recent send failed.
+func (s *Adapter) Status() RouteStatus {
+	d := s.status
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	status := RouteStatus{
+		ID:         s.route.ID,
+		Healthy:    !d.lastErrorAt.After(d.lastSuccess),
+		Sent:       d.sent,
+		Failed:     d.failed,
+		Dropped:    atomic.LoadInt64(&d.dropped),
+		Pending:    atomic.LoadInt64(&d.pending),
+		Queued:     s.queued(),
+		Panics:     atomic.LoadInt64(&s.panics),
+		Retries:    atomic.LoadInt64(&d.retries),
+		Standby:    s.standby.holding(),
+		FailedOver: s.failover.failedOver(),
+	}
+	var stalled time.Duration
+	status.Stalled, status.Stalls, stalled = s.stalls.stats()
+	status.StalledMs = int64(stalled / time.Millisecond)
+	if !d.lastSuccess.IsZero() {
+		status.LastSuccess = d.lastSuccess.Format(time.RFC3339)
+	}
+	if !d.lastErrorAt.IsZero() {
+		status.LastError = d.lastError
+		status.LastErrorTime = d.lastErrorAt.Format(time.RFC3339)
+		status.Failures = map[string]int64{}
+		for class, count := range d.failures {
+			status.Failures[class] = count
+		}
+	}
+	if len(d.categories) > 0 {
+		status.Categories = map[string]CategoryStats{}
+		for category, stats := range d.categories {
+			status.Categories[category] = *stats
+		}
+	}
+	return status
+}
+
+// statusHandler serves the status of every sumologic route as json. It's
+// registered with logspout's HTTP server, so it's available at /sumologic.
+func statusHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		statuses := []RouteStatus{}
+		for _, a := range adapters.all() {
+			statuses = append(statuses, a.Status())
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(statuses); err != nil {
+			log.WithError(err).Error("Unable to write status response")
+		}
+	})
+}
@@ -0,0 +1,110 @@
+package sumologic
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+)
+
+func (ts *TestSuite) Test_Status_new_adapter() {
+	adapter := ts.mkAdapter(&router.Route{ID: "foo", Address: noServer})
+	ts.Equal(RouteStatus{ID: "foo", Healthy: true}, adapter.Status())
+}
+
+func (ts *TestSuite) Test_Status_counts_queued_messages() {
+	adapter := ts.mkAdapter(&router.Route{ID: "foo", Address: noServer})
+	// Swap in queues without workers, so that nothing is taken off them.
+	adapter.queue = newQueue(2)
+	adapter.lanes = &lanes{queues: map[string]chan *router.Message{
+		"app": newQueue(2),
+	}}
+	adapter.ordered = &orderedQueues{
+		queues: []chan *router.Message{newQueue(2), newQueue(2)},
+	}
+	adapter.queue <- mkContainerMessage("abc", "/foo")
+	adapter.lanes.queues["app"] <- mkContainerMessage("abc", "/foo")
+	adapter.lanes.queues["app"] <- mkContainerMessage("abc", "/foo")
+	adapter.ordered.queues[1] <- mkContainerMessage("abc", "/foo")
+	ts.EqualValues(4, adapter.Status().Queued)
+}
+
+func (ts *TestSuite) Test_Status_after_success() {
+	requests := make(chan *RequestData, 1)
+	adapter := ts.FakeSumo(requests)
+
+	adapter.sendLog(&router.Message{
+		Container: &docker.Container{Config: &docker.Config{}},
+	})
+	status := adapter.Status()
+	ts.True(status.Healthy)
+	ts.EqualValues(1, status.Sent)
+	ts.EqualValues(0, status.Failed)
+	ts.EqualValues(0, status.Pending)
+	ts.NotEmpty(status.LastSuccess)
+	ts.Empty(status.LastError)
+}
+
+func (ts *TestSuite) Test_Status_accounts_by_category() {
+	requests := make(chan *RequestData, 3)
+	adapter := ts.FakeSumo(requests)
+
+	msg := mkContainerMessage("abc", "foo")
+	msg.Container.Config.Env = []string{"SUMOLOGIC_SOURCE_CATEGORY=team/a"}
+	adapter.sendLog(msg)
+	adapter.sendLog(msg)
+	adapter.sendLog(mkContainerMessage("def", "bar"))
+
+	categories := adapter.Status().Categories
+	ts.Len(categories, 2)
+	ts.EqualValues(2, categories["team/a"].Events)
+	ts.EqualValues(1, categories[uncategorized].Events)
+	ts.True(categories["team/a"].Bytes > categories[uncategorized].Bytes)
+}
+
+func (ts *TestSuite) Test_Status_after_failure() {
+	ts.CaptureLogs()
+	adapter := ts.adapterFor(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}), newRealClock())
+
+	adapter.sendLog(&router.Message{
+		Container: &docker.Container{Config: &docker.Config{}},
+	})
+	status := adapter.Status()
+	ts.False(status.Healthy)
+	ts.EqualValues(0, status.Sent)
+	ts.EqualValues(1, status.Failed)
+	ts.Equal("unexpected status code 503", status.LastError)
+	ts.Equal(map[string]int64{errorClassStatus: 1}, status.Failures)
+	ts.Nil(status.Categories)
+	ts.NotEmpty(status.LastErrorTime)
+}
+
+func (ts *TestSuite) Test_statusHandler_lists_routes() {
+	ts.mkAdapter(&router.Route{ID: "foo", Address: noServer})
+	ts.mkAdapter(&router.Route{ID: "bar", Address: noServer})
+
+	recorder := httptest.NewRecorder()
+	statusHandler().ServeHTTP(
+		recorder, httptest.NewRequest(http.MethodGet, "/sumologic", nil))
+
+	ts.Equal(http.StatusOK, recorder.Code)
+	ts.Equal("application/json", recorder.Header().Get("Content-Type"))
+	ts.JSONEq(`[
+		{"id": "bar", "healthy": true, "sent": 0, "failed": 0, "dropped": 0,
+		 "pending": 0, "queued": 0,
+		 "panics": 0},
+		{"id": "foo", "healthy": true, "sent": 0, "failed": 0, "dropped": 0,
+		 "pending": 0, "queued": 0,
+		 "panics": 0}
+	]`, recorder.Body.String())
+}
+
+func (ts *TestSuite) Test_Close_removes_adapter_from_status() {
+	adapter := ts.mkAdapter(&router.Route{ID: "foo", Address: noServer})
+	adapter.Close()
+	ts.NotContains(adapters.all(), adapter)
+}
@@ -0,0 +1,34 @@
+package sumologic
+
+import (
+	"strconv"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+// The streams logspout reads containers' logs from, as msg.Source.
+const (
+	stdoutStream = "stdout"
+	stderrStream = "stderr"
+)
+
+// dropStdoutLabel is the label a container can set to "true" to only have
+// what it writes to stderr sent.
+const dropStdoutLabel = "sumologic.drop_stdout"
+
+// droppedStream reports whether a message should be dropped because it was
+// written to stdout, and either SUMOLOGIC_DROP_STDOUT is set or the
+// container has opted out of sending its stdout.
+func droppedStream(msg *router.Message, config *Config) bool {
+	if msg.Source != stdoutStream {
+		return false
+	}
+	if config.dropStdout {
+		return true
+	}
+	if msg.Container == nil || msg.Container.Config == nil {
+		return false
+	}
+	drop, err := strconv.ParseBool(msg.Container.Config.Labels[dropStdoutLabel])
+	return err == nil && drop
+}
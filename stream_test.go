@@ -0,0 +1,67 @@
+package sumologic
+
+import (
+	"github.com/gliderlabs/logspout/router"
+)
+
+// mkStreamMessage returns a message the container wrote to a stream.
+func mkStreamMessage(source string) *router.Message {
+	msg := mkContainerMessage("abc", "/foo")
+	msg.Source = source
+	return msg
+}
+
+func (ts *TestSuite) Test_dropReason_stdout() {
+	adapter := ts.mkAdapter(&router.Route{Address: noServer})
+	ts.Equal("", adapter.dropReason(mkStreamMessage(stdoutStream)))
+
+	ts.Setenv("SUMOLOGIC_DROP_STDOUT", "true")
+	adapter = ts.mkAdapter(&router.Route{Address: noServer})
+	ts.Equal("stdout", adapter.dropReason(mkStreamMessage(stdoutStream)))
+	ts.Equal("", adapter.dropReason(mkStreamMessage(stderrStream)))
+	ts.Equal("", adapter.dropReason(&router.Message{Data: "no container"}))
+}
+
+func (ts *TestSuite) Test_dropReason_stdout_label() {
+	adapter := ts.mkAdapter(&router.Route{Address: noServer})
+	msg := mkStreamMessage(stdoutStream)
+	msg.Container.Config.Labels = map[string]string{dropStdoutLabel: "true"}
+	ts.Equal("stdout", adapter.dropReason(msg))
+
+	msg = mkStreamMessage(stderrStream)
+	msg.Container.Config.Labels = map[string]string{dropStdoutLabel: "true"}
+	ts.Equal("", adapter.dropReason(msg))
+
+	msg = mkStreamMessage(stdoutStream)
+	msg.Container.Config.Labels = map[string]string{dropStdoutLabel: "nope"}
+	ts.Equal("", adapter.dropReason(msg))
+}
+
+func (ts *TestSuite) Test_buildHeaders_stderr_category() {
+	ts.Setenv("SUMOLOGIC_SOURCE_CATEGORY", "prod/{{.Container.Name}}")
+	ts.Setenv("SUMOLOGIC_STDERR_SOURCE_CATEGORY", "prod/errors/{{.Container.Name}}")
+	config := buildConfig(&router.Route{})
+	ts.Equal("prod/errors//foo",
+		buildHeaders(mkStreamMessage(stderrStream), config).Get("X-Sumo-Category"))
+	ts.Equal("prod//foo",
+		buildHeaders(mkStreamMessage(stdoutStream), config).Get("X-Sumo-Category"))
+}
+
+func (ts *TestSuite) Test_buildHeaders_stderr_category_empty() {
+	ts.Setenv("SUMOLOGIC_SOURCE_CATEGORY", "prod")
+	ts.Setenv("SUMOLOGIC_STDERR_SOURCE_CATEGORY",
+		`{{index .Container.Config.Labels "errors"}}`)
+	config := buildConfig(&router.Route{})
+	ts.Equal("prod",
+		buildHeaders(mkStreamMessage(stderrStream), config).Get("X-Sumo-Category"))
+}
+
+func (ts *TestSuite) Test_buildHeaders_category_by_stream_template() {
+	ts.Setenv("SUMOLOGIC_SOURCE_CATEGORY",
+		`{{if eq .Source "stderr"}}errors{{else}}app{{end}}`)
+	config := buildConfig(&router.Route{})
+	ts.Equal("errors",
+		buildHeaders(mkStreamMessage(stderrStream), config).Get("X-Sumo-Category"))
+	ts.Equal("app",
+		buildHeaders(mkStreamMessage(stdoutStream), config).Get("X-Sumo-Category"))
+}
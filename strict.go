@@ -0,0 +1,115 @@
+package sumologic
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// strictRetryInterval is how long strict delivery waits between attempts to
+// send a request while the endpoint is failing.
+const strictRetryInterval = time.Second
+
+// stallGate holds up Stream while requests are failing, for strict delivery,
+// where stalling is better than dropping anything. Requests that fail in a
+// way that may be worth retrying are retried until they succeed, and Stream
+// doesn't take any more messages from logspout until they have, leaving
+// them to be buffered upstream. A nil *stallGate never stalls.
+type stallGate struct {
+	mu      sync.Mutex
+	clock   Clock
+	failing int
+	since   time.Time
+	stalls  int64
+	total   time.Duration
+	// open is closed whenever nothing is failing.
+	open chan struct{}
+}
+
+// newStallGate returns a stallGate, or nil if strict delivery isn't enabled.
+func newStallGate(clock Clock, strict bool) *stallGate {
+	if !strict {
+		return nil
+	}
+	open := make(chan struct{})
+	close(open)
+	return &stallGate{clock: clock, open: open}
+}
+
+// stall records that a request has started failing.
+func (g *stallGate) stall() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.failing++
+	if g.failing == 1 {
+		g.since = g.clock.Now()
+		g.stalls++
+		g.open = make(chan struct{})
+	}
+}
+
+// resume records that a failing request has been dealt with.
+func (g *stallGate) resume() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.failing--
+	if g.failing == 0 {
+		g.total += g.clock.Now().Sub(g.since)
+		close(g.open)
+	}
+}
+
+// wait blocks while requests are failing, or until the context is done.
+func (g *stallGate) wait(ctx context.Context) {
+	if g == nil {
+		return
+	}
+	g.mu.Lock()
+	open := g.open
+	g.mu.Unlock()
+	select {
+	case <-open:
+	case <-ctx.Done():
+	}
+}
+
+// stats returns whether the gate is stalled now, how many times it has
+// stalled and how long it has been stalled for in total.
+func (g *stallGate) stats() (bool, int64, time.Duration) {
+	if g == nil {
+		return false, 0, 0
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	total := g.total
+	if g.failing > 0 {
+		total += g.clock.Now().Sub(g.since)
+	}
+	return g.failing > 0, g.stalls, total
+}
+
+// deliverStrictly keeps attempting to send a request that failed in a way
+// that may be worth retrying until it succeeds, the failure becomes one that
+// isn't, or the context is done, stalling Stream in the meantime. If it's
+// the delivery deadline that passed, the last failure is returned so that
+// the request can still be buffered.
+func (s *Adapter) deliverStrictly(ctx context.Context, strData []byte,
+	headers http.Header, events int64, err error) error {
+	s.stalls.stall()
+	defer s.stalls.resume()
+	for spoolable(err) {
+		timer := s.clock.NewTimer(strictRetryInterval)
+		select {
+		case <-timer.C():
+		case <-ctx.Done():
+			timer.Stop()
+			if s.ctx.Err() != nil {
+				return s.ctx.Err()
+			}
+			return err
+		}
+		err = s.attempt(ctx, strData, headers, events)
+	}
+	return err
+}
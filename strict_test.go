@@ -0,0 +1,97 @@
+package sumologic
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+func (ts *TestSuite) Test_stallGate_disabled() {
+	g := newStallGate(newFakeClock(), false)
+	ts.Nil(g)
+	g.wait(nil)
+	stalled, stalls, total := g.stats()
+	ts.False(stalled)
+	ts.Zero(stalls)
+	ts.Zero(total)
+}
+
+func (ts *TestSuite) Test_stallGate_stats() {
+	clock := newFakeClock()
+	g := newStallGate(clock, true)
+	g.stall()
+	g.stall()
+	clock.Advance(time.Second)
+	g.resume()
+	stalled, stalls, total := g.stats()
+	ts.True(stalled)
+	ts.Equal(int64(1), stalls)
+	ts.Equal(time.Second, total)
+
+	clock.Advance(time.Second)
+	g.resume()
+	clock.Advance(time.Second)
+	g.stall()
+	clock.Advance(time.Second)
+	g.resume()
+	stalled, stalls, total = g.stats()
+	ts.False(stalled)
+	ts.Equal(int64(2), stalls)
+	ts.Equal(3*time.Second, total)
+}
+
+func (ts *TestSuite) Test_Stream_strict_delivery_stalls() {
+	ts.CaptureLogs()
+	ts.Setenv("SUMOLOGIC_STRICT_DELIVERY", "true")
+	ts.Setenv("SUMOLOGIC_WORKERS", "1")
+	ts.Setenv("SUMOLOGIC_RETRIES", "0")
+	code := int64(http.StatusServiceUnavailable)
+	requests := make(chan *RequestData, 3)
+	handler := ts.mkHandler(requests)
+	clock := newFakeClock()
+	adapter := ts.adapterFor(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if status := int(atomic.LoadInt64(&code)); status != http.StatusOK {
+				w.WriteHeader(status)
+				return
+			}
+			handler.ServeHTTP(w, r)
+		}), clock)
+
+	ch := make(chan *router.Message)
+	go adapter.Stream(ch)
+	mkMessage := func(data string) *router.Message {
+		msg := mkContainerMessage("abc", "/foo")
+		msg.Data = data
+		return msg
+	}
+	ch <- mkMessage("one")
+	clock.WaitForTimers(1)
+	ch <- mkMessage("two")
+	select {
+	case ch <- mkMessage("three"):
+		ts.Fail("Stream should be stalled")
+	case <-time.After(50 * time.Millisecond):
+	}
+	status := adapter.Status()
+	ts.True(status.Stalled)
+	ts.Equal(int64(1), status.Stalls)
+	ts.Equal(int64(0), status.Dropped)
+
+	atomic.StoreInt64(&code, http.StatusOK)
+	clock.Advance(strictRetryInterval)
+	select {
+	case ch <- mkMessage("three"):
+	case <-time.After(time.Second):
+		ts.Fail("Stream should have resumed")
+	}
+	close(ch)
+	for _, data := range []string{"one", "two", "three"} {
+		ts.Equal(data, (<-requests).Body["message"])
+	}
+	status = adapter.Status()
+	ts.False(status.Stalled)
+	ts.Equal(int64(1000), status.StalledMs)
+}
@@ -0,0 +1,109 @@
+package sumologic
+
+import (
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+// SummaryData holds a container's log volume over a summary interval.
+type SummaryData struct {
+	Lines      int64 `json:"lines"`
+	Bytes      int64 `json:"bytes"`
+	Errors     int64 `json:"errors"`
+	IntervalMs int64 `json:"interval_ms"`
+}
+
+// summarizer counts the lines, bytes and error lines logged by each
+// container. A nil *summarizer doesn't count anything.
+type summarizer struct {
+	mu           sync.Mutex
+	interval     time.Duration
+	errorPattern *regexp.Regexp
+	containers   map[string]*containerSummary
+}
+
+type containerSummary struct {
+	lastMsg *router.Message
+	summary SummaryData
+}
+
+// newSummarizer returns a summarizer for the given interval, or nil if the
+// interval isn't positive.
+func newSummarizer(
+	interval time.Duration, errorPattern *regexp.Regexp) *summarizer {
+	if interval <= 0 {
+		return nil
+	}
+	return &summarizer{
+		interval:     interval,
+		errorPattern: errorPattern,
+		containers:   map[string]*containerSummary{},
+	}
+}
+
+// count adds a message to its container's summary. Messages from stderr or
+// matching the error pattern are counted as errors.
+func (z *summarizer) count(msg *router.Message) {
+	if z == nil || msg.Container == nil {
+		return
+	}
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	c, ok := z.containers[msg.Container.ID]
+	if !ok {
+		c = &containerSummary{}
+		z.containers[msg.Container.ID] = c
+	}
+	c.lastMsg = msg
+	c.summary.Lines++
+	c.summary.Bytes += int64(len(msg.Data))
+	if msg.Source == "stderr" || z.errorPattern.MatchString(msg.Data) {
+		c.summary.Errors++
+	}
+}
+
+// flush returns the summaries collected since the last flush and starts
+// counting from scratch.
+func (z *summarizer) flush() []*containerSummary {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	result := make([]*containerSummary, 0, len(z.containers))
+	for _, c := range z.containers {
+		c.summary.IntervalMs = int64(z.interval / time.Millisecond)
+		result = append(result, c)
+	}
+	z.containers = map[string]*containerSummary{}
+	return result
+}
+
+// reportSummaries sends a summary event to Sumologic for each container that
+// logged anything since the last report.
+func (s *Adapter) reportSummaries() {
+	for _, c := range s.summaries.flush() {
+		s.sendSummaryEvent(c)
+	}
+}
+
+// sendSummaryEvent sends a container's summary to Sumologic, using the same
+// metadata as that container's last message. If a summary category is
+// configured, it's used instead of the container's category.
+func (s *Adapter) sendSummaryEvent(c *containerSummary) {
+	defer s.recoverPanic("sendSummaryEvent")
+
+	config := s.config()
+	summary := c.summary
+	data := buildData(c.lastMsg, config)
+	data.Message = ""
+	data.Timestamp = formatTimestamp(s.clock.Now())
+	data.Event = "summary"
+	data.Summary = &summary
+
+	headers := buildHeaders(c.lastMsg, config)
+	if config.summaryCategory != "" {
+		headers.Set("X-Sumo-Category", config.summaryCategory)
+	}
+	s.send(data, headers, nil)
+}
@@ -0,0 +1,79 @@
+package sumologic
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+func (ts *TestSuite) Test_newSummarizer_disabled() {
+	ts.Nil(newSummarizer(0, regexp.MustCompile("error")))
+
+	var z *summarizer
+	z.count(mkContainerMessage("abc", "foo"))
+}
+
+func (ts *TestSuite) Test_summarizer_counts_per_container() {
+	z := newSummarizer(time.Minute, regexp.MustCompile("error"))
+	z.count(mkContainerMessage("abc", "foo"))
+	z.count(mkContainerMessage("abc", "foo"))
+	errMsg := mkContainerMessage("abc", "foo")
+	errMsg.Data = "an error"
+	z.count(errMsg)
+	stderrMsg := mkContainerMessage("def", "bar")
+	stderrMsg.Source = "stderr"
+	z.count(stderrMsg)
+	z.count(&router.Message{})
+
+	summaries := map[string]SummaryData{}
+	for _, c := range z.flush() {
+		summaries[c.lastMsg.Container.Name] = c.summary
+	}
+	ts.Equal(map[string]SummaryData{
+		"foo": {Lines: 3, Bytes: 28, Errors: 1, IntervalMs: 60000},
+		"bar": {Lines: 1, Bytes: 10, Errors: 1, IntervalMs: 60000},
+	}, summaries)
+
+	ts.Empty(z.flush())
+}
+
+func (ts *TestSuite) Test_Stream_reports_summaries() {
+	ts.Setenv("SUMOLOGIC_SKIP_EMPTY", "false")
+	ts.Setenv("SUMOLOGIC_SUMMARY_INTERVAL_MS", "60000")
+	ts.Setenv("SUMOLOGIC_SUMMARY_CATEGORY", "volume")
+	clock := newFakeClock()
+	requests := make(chan *RequestData, 2)
+	adapter := ts.FakeSumoWithClock(requests, clock)
+
+	ch := make(chan *router.Message)
+	go adapter.Stream(ch)
+	ch <- mkContainerMessage("abc", "foo")
+	ts.verifyExpectedRequests([]RequestData{{
+		Headers: map[string]string{"X-Sumo-Name": "foo", "X-Sumo-Host": ""},
+		Body: mkExpectedBody(jsonobj{
+			"message":   "Some data.",
+			"container": jsonobj{"docker_id": "abc", "docker_name": "foo"},
+		}),
+	}}, requests)
+
+	clock.WaitForTimers(1)
+	clock.Advance(time.Minute)
+
+	ts.verifyExpectedRequests([]RequestData{{
+		Headers: map[string]string{
+			"X-Sumo-Name":     "foo",
+			"X-Sumo-Host":     "",
+			"X-Sumo-Category": "volume",
+		},
+		Body: mkExpectedBody(jsonobj{
+			"timestamp": "1514898060000",
+			"event":     "summary",
+			"summary": jsonobj{
+				"lines": 1.0, "bytes": 10.0, "errors": 0.0, "interval_ms": 60000.0,
+			},
+			"container": jsonobj{"docker_id": "abc", "docker_name": "foo"},
+		}),
+	}}, requests)
+	close(ch)
+}
@@ -2,49 +2,238 @@ package sumologic
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"html/template"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
+	"runtime/debug"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gliderlabs/logspout/router"
-	"github.com/gojektech/heimdall"
 	log "github.com/sirupsen/logrus"
 )
 
 func init() {
 	log.SetOutput(os.Stdout)
 	router.AdapterFactories.Register(NewAdapter, "sumologic")
+	router.HTTPHandlers.Register(statusHandler, "sumologic")
+	router.HTTPHandlers.Register(containersHandler, "debug/containers")
 }
 
 // Adapter streams log messages to a Sumo Logic endpoint.
 type Adapter struct {
-	route  *router.Route
-	client heimdall.Client
-	config *Config
+	route           *router.Route
+	clientMu        sync.RWMutex
+	client          doer
+	snapshot        atomic.Value
+	panics          int64
+	ctx             context.Context
+	cancel          context.CancelFunc
+	inflight        *byteLimiter
+	requests        *requestLimiter
+	slowStart       *slowStart
+	rateLimit       *rateLimiter
+	status          *deliveryStatus
+	clock           Clock
+	silence         *silenceDetector
+	summaries       *summarizer
+	metrics         *metricCounter
+	archive         *archiver
+	dns             *dnsChecker
+	headerCache     *headerCache
+	batches         *batcher
+	containers      *containerTracker
+	sessions        *sessions
+	queue           chan *router.Message
+	spool           *spool
+	payloads        *payloadTracker
+	annotations     *annotations
+	stalls          *stallGate
+	workers         sync.WaitGroup
+	shutdownOnce    sync.Once
+	multiline       *multiline
+	restarts        *restartTracker
+	queueMu         sync.RWMutex
+	stopping        chan struct{}
+	script          *script
+	standby         *standby
+	audit           *auditLog
+	lanes           *lanes
+	ordered         *orderedQueues
+	endpointClients *endpointClients
+	sampler         *adaptiveSampler
+	fixedSampler    *fixedSampler
+	deadLetters     *deadLetters
+	failover        *failover
+	aws             *AWSData
 }
 
 // Config holds the Sumo Logic endpoint configuration.
 type Config struct {
-	endPoint       string
-	sourceName     string
-	sourceCategory string
-	sourceHost     string
-	retries        int64
-	timeout        int64
-	backoff        int64
+	route                  *router.Route
+	endPoint               string
+	sourceName             string
+	sourceCategory         string
+	sourceHost             string
+	retries                int64
+	timeout                int64
+	backoff                int64
+	diagnostics            bool
+	diagCategory           string
+	placeholder            string
+	placeholders           map[string]string
+	maxInflight            int64
+	maxInflightRequests    int64
+	slowStartMs            int64
+	slowStartRate          int64
+	maxMsgsPerSec          int64
+	maxBytesPerSec         int64
+	allowOverride          bool
+	skipEmpty              bool
+	minLength              int64
+	silenceMs              int64
+	summaryMs              int64
+	summaryCategory        string
+	errorPattern           *regexp.Regexp
+	metricRules            []metricRule
+	metricsMs              int64
+	metricsEndPoint        string
+	metricsCategory        string
+	archive                archiveConfig
+	dnsPrecheck            bool
+	dnsCacheMs             int64
+	metricsDimensions      string
+	metricsMetadata        string
+	categorySources        []categorySource
+	selfID                 string
+	suppressions           []*suppressionRule
+	batchSize              int64
+	flushMs                int64
+	dedicatedConns         bool
+	unwrapJSON             bool
+	workers                int64
+	queueSize              int64
+	overflow               string
+	bufferDir              string
+	bufferMaxMB            int64
+	forgetRemoved          bool
+	filterLabels           map[string]string
+	processingFlags        bool
+	backoffType            string
+	backoffMaxMs           int64
+	backoffJitterMs        int64
+	strict                 bool
+	signingKey             string
+	format                 string
+	containerFields        map[string]bool
+	chaos                  *chaos
+	multilinePattern       *regexp.Regexp
+	multilineFlushMs       int64
+	trackRestarts          bool
+	encoding               string
+	kubernetes             bool
+	overflowCategory       string
+	overflowThresholdBytes int64
+	overflowTruncateBytes  int64
+	drainTimeoutMs         int64
+	script                 string
+	standbyMax             int64
+	standbyTimeoutMs       int64
+	auditFile              string
+	auditMaxMB             int64
+	auditMaxFiles          int64
+	deadLetterDir          string
+	deadLetterMaxMB        int64
+	replayOrder            string
+	parseJSON              bool
+	parseJSONMode          string
+	categoryWorkers        int64
+	ordered                bool
+	categoryWeights        map[string]int64
+	samplingLevels         []samplingLevel
+	samplingKeepPattern    *regexp.Regexp
+	sampleRate             int64
+	redactPatterns         []*regexp.Regexp
+	redactReplacement      string
+	extraHeaders           http.Header
+	endPoints              []string
+	endPointTemplate       string
+	containerEndPoints     map[string]string
+	failoverThreshold      int64
+	failbackMs             int64
+	trimContainerSlash     bool
+	shortID                bool
+	fullID                 bool
+	includeEnv             []string
+	tls                    tlsOptions
+	proxyURL               string
+	filterInclude          *regexp.Regexp
+	filterExclude          *regexp.Regexp
+	stderrSourceCategory   string
+	dropStdout             bool
+	severityPattern        *regexp.Regexp
+	severityFields         []string
+	timestampField         string
+	timestampPattern       *regexp.Regexp
+	timestampFormat        string
+	fieldMap               map[string]string
+	extraFields            map[string]string
+	omitFields             map[string]bool
+	bodyTemplate           string
+	host                   *HostData
+	awsMetadata            bool
+	awsIMDSEndpoint        string
+	maxMessageBytes        int64
+	sizePolicy             string
+	maxRequestBytes        int64
+	stripANSI              bool
+	replaceInvalidUTF8     bool
+	pool                   connPool
+	deliveryDeadline       int64
 }
 
 // Data holds the data to send to a Sumo Logic endpoint.
 type Data struct {
-	Message   string         `json:"message"`
-	Container *ContainerData `json:"container"`
-	Timestamp string         `json:"timestamp"`
+	Message           string         `json:"message"`
+	Container         *ContainerData `json:"container"`
+	Timestamp         string         `json:"timestamp"`
+	MetadataMissing   bool           `json:"metadata_missing,omitempty"`
+	Event             string         `json:"event,omitempty"`
+	Summary           *SummaryData   `json:"summary,omitempty"`
+	Backfill          bool           `json:"backfill,omitempty"`
+	Truncated         bool           `json:"truncated,omitempty"`
+	Processing        *Processing    `json:"_processing,omitempty"`
+	RestartGeneration int64          `json:"restart_generation,omitempty"`
+	Severity          string         `json:"severity,omitempty"`
+	// The pod's fields are sent alongside the others, in kubernetes mode.
+	*KubernetesData
+	// The service's fields are sent alongside the others, for containers run
+	// by swarm.
+	*SwarmData
+	// The host's fields are sent alongside the others, in host metadata mode.
+	*HostData
+	// The instance's and task's fields are sent alongside the others, in AWS
+	// metadata mode.
+	*AWSData
+	// parsed is the message as json, if it was parsed, and mergeParsed is
+	// whether its fields are sent in place of the message.
+	parsed      json.RawMessage
+	mergeParsed bool
+	// msg is the message the event was built from, for SUMOLOGIC_BODY_TEMPLATE
+	// to render. Events the adapter makes up itself don't have one.
+	msg *router.Message
 }
 
 // ContainerData holds information about the container we're streaming from.
@@ -55,45 +244,374 @@ type ContainerData struct {
 	ID       string `json:"docker_id"`
 	Image    string `json:"docker_image"`
 	Hostname string `json:"docker_hostname"`
+	// FullID is the container's full ID, when ID is the short form and the
+	// full one has been asked for too.
+	FullID string `json:"docker_full_id,omitempty"`
+	// Env holds the container environment variables named in
+	// SUMOLOGIC_INCLUDE_ENV.
+	Env map[string]string `json:"docker_env,omitempty"`
+	// fields is the set of keys to send, or nil to send them all.
+	fields map[string]bool
 }
 
 // NewAdapter provides an Adapter to the logspout adapter factory.
 func NewAdapter(route *router.Route) (router.LogAdapter, error) {
+	return NewAdapterWithClock(route, newRealClock())
+}
+
+// NewAdapterWithClock provides an Adapter that uses the given Clock for all
+// of its timing, rather than the system clock.
+func NewAdapterWithClock(route *router.Route, clock Clock) (*Adapter, error) {
 
 	config := buildConfig(route)
+	if problems := checkConfig(config); len(problems) > 0 {
+		return nil, fmt.Errorf(
+			"invalid sumologic config: %s", strings.Join(problems, "; "))
+	}
 
-	timeoutInMillis := time.Duration(config.timeout) * time.Millisecond
-	httpClient := heimdall.NewHTTPClient(timeoutInMillis)
-	httpClient.SetRetrier(
-		heimdall.NewRetrier(heimdall.NewConstantBackoff(config.backoff)))
-	httpClient.SetRetryCount(int(config.retries))
+	ctx, cancel := context.WithCancel(context.Background())
 
-	return &Adapter{
-		route:  route,
-		client: httpClient,
-		config: config,
-	}, nil
+	adapter := &Adapter{
+		route:    route,
+		ctx:      ctx,
+		cancel:   cancel,
+		inflight: newByteLimiter(config.maxInflight),
+		requests: newRequestLimiter(config.maxInflightRequests),
+		slowStart: newSlowStart(
+			clock,
+			time.Duration(config.slowStartMs)*time.Millisecond,
+			config.slowStartRate),
+		rateLimit: newRateLimiter(
+			clock, config.maxMsgsPerSec, config.maxBytesPerSec),
+		status: &deliveryStatus{clock: clock},
+		clock:  clock,
+		silence: newSilenceDetector(
+			clock, time.Duration(config.silenceMs)*time.Millisecond),
+		summaries: newSummarizer(
+			time.Duration(config.summaryMs)*time.Millisecond,
+			config.errorPattern),
+		metrics: newMetricCounter(
+			config.metricRules,
+			time.Duration(config.metricsMs)*time.Millisecond),
+		archive:         newArchiver(config.archive),
+		headerCache:     newHeaderCache(),
+		containers:      newContainerTracker(clock),
+		sessions:        newSessions(config, clock),
+		queue:           newQueue(config.queueSize),
+		lanes:           newLanes(config),
+		ordered:         newOrderedQueues(config),
+		endpointClients: newEndpointClients(),
+		sampler: newAdaptiveSampler(
+			config.samplingLevels, config.samplingKeepPattern),
+		fixedSampler: newFixedSampler(),
+		stopping:     make(chan struct{}),
+		spool:        newSpool(config.bufferDir, config.endPoint, config.bufferMaxMB),
+		payloads:     newPayloadTracker(clock),
+		annotations:  newAnnotations(),
+		stalls:       newStallGate(clock, config.strict),
+		restarts:     newRestartTracker(config.trackRestarts),
+		script:       newScript(config.script),
+		standby:      newStandby(config.standbyMax),
+		failover:     newFailover(config),
+		deadLetters: newDeadLetters(
+			config.deadLetterDir, config.endPoint, config.deadLetterMaxMB),
+		audit: newAuditLog(
+			config.auditFile, config.auditMaxMB, config.auditMaxFiles),
+		multiline: newMultiline(config.multilinePattern,
+			time.Duration(config.multilineFlushMs)*time.Millisecond),
+		batches: newBatcher(config.batchSize,
+			time.Duration(config.flushMs)*time.Millisecond,
+			config.maxRequestBytes),
+		dns: newDNSChecker(clock, config.dnsPrecheck,
+			time.Duration(config.dnsCacheMs)*time.Millisecond),
+	}
+	adapter.keepOrder()
+	adapter.aws = awsMetadata(ctx, config)
+	adapter.snapshot.Store(config)
+	adapter.client = clients.acquire(adapter)
+	adapters.add(adapter)
+	if adapter.ordered != nil {
+		adapter.startOrderedWorkers()
+	} else {
+		adapter.startWorkers(adapter.queue, config.workers)
+	}
+	if adapter.standby != nil {
+		go adapter.awaitEndpoint(
+			time.Duration(config.standbyTimeoutMs) * time.Millisecond)
+	}
+	watchSignals()
+	if adapter.silence != nil {
+		go adapter.every(adapter.silence.threshold/4, adapter.reportSilence)
+	}
+	if adapter.summaries != nil {
+		go adapter.every(adapter.summaries.interval, adapter.reportSummaries)
+	}
+	if adapter.metrics != nil {
+		go adapter.every(adapter.metrics.interval, adapter.reportMetrics)
+	}
+	if adapter.archive != nil {
+		go adapter.watchArchive()
+	}
+	if adapter.batches != nil {
+		go adapter.every(adapter.batches.interval, adapter.flushBatches)
+	}
+	if config.forgetRemoved {
+		adapter.watchContainerEvents()
+	}
+	if adapter.spool != nil {
+		go adapter.every(spoolReplayInterval, adapter.replaySpool)
+	}
+	if adapter.deadLetters != nil {
+		go adapter.every(deadLetterSummaryInterval, adapter.reportDeadLetters)
+	}
+	if adapter.failover != nil {
+		go adapter.every(time.Duration(config.failbackMs)*time.Millisecond,
+			adapter.probePrimary)
+	}
+	return adapter, nil
+}
+
+// routeDir returns the subdirectory of dir that requests to endPoint are
+// kept in, so that routes sharing a directory don't replay or count each
+// other's requests. It's named by a hash of the endpoint, which stays the
+// same across restarts and keeps its credentials out of the file system.
+func routeDir(dir string, endPoint string) string {
+	sum := sha256.Sum256([]byte(endPoint))
+	return filepath.Join(dir, hex.EncodeToString(sum[:8]))
+}
+
+// Close cancels any requests to Sumologic that are still in flight. Messages
+// that haven't been delivered yet are dropped.
+func (s *Adapter) Close() {
+	s.cancel()
+	adapters.remove(s)
+	clients.release(s)
+	s.sessions.close()
+	s.audit.close()
+	s.script.close()
+}
+
+// config returns the adapter's current config. The config may be replaced
+// at any time (see Reload), so anything that needs to see a consistent
+// config should call this once and hold on to the result.
+func (s *Adapter) config() *Config {
+	return s.snapshot.Load().(*Config)
+}
+
+// Reload rebuilds the adapter's config from its route and the environment
+// and swaps it in atomically, without interrupting the stream. Messages
+// already being sent finish with the config they started with. Only settings
+// that are applied per message (templates, placeholders, filters and the
+// endpoint) and the client's settings are picked up; the rest only take
+// effect for new routes.
+func (s *Adapter) Reload() {
+	old := s.config()
+	s.snapshot.Store(buildConfig(s.route))
+	// The client is shared with the routes that send to the same endpoint
+	// with the same settings, which may not be the same routes any more.
+	if keyForConfig(old) != keyForConfig(s.config()) {
+		s.clientMu.Lock()
+		s.client = clients.reacquire(s, old)
+		s.clientMu.Unlock()
+	}
+}
+
+// currentClient returns the client to send requests to the route's endpoint
+// with, which Reload may replace.
+func (s *Adapter) currentClient() doer {
+	s.clientMu.RLock()
+	defer s.clientMu.RUnlock()
+	return s.client
 }
 
 func buildConfig(route *router.Route) *Config {
+	opts := routeOptions(route.Options)
+	opts.checkProfile()
 	config := &Config{
-		endPoint:       getopt("SUMOLOGIC_ENDPOINT", route.Address),
-		sourceName:     getopt("SUMOLOGIC_SOURCE_NAME", "{{.Container.Name}}"),
-		sourceCategory: getopt("SUMOLOGIC_SOURCE_CATEGORY", ""),
-		sourceHost: getopt(
+		route:          route,
+		sourceName:     opts.getopt("SUMOLOGIC_SOURCE_NAME", "{{.Container.Name}}"),
+		sourceCategory: opts.getopt("SUMOLOGIC_SOURCE_CATEGORY", ""),
+		sourceHost: opts.getopt(
 			"SUMOLOGIC_SOURCE_HOST", "{{.Container.Config.Hostname}}"),
-		retries: getintopt("SUMOLOGIC_RETRIES", 2),
-		backoff: getintopt("SUMOLOGIC_BACKOFF", 10),
-		timeout: getintopt("SUMOLOGIC_TIMEOUT_MS", 10000),
+		retries:         opts.getintopt("SUMOLOGIC_RETRIES", 2),
+		backoff:         opts.getintopt("SUMOLOGIC_BACKOFF", 10),
+		backoffType:     opts.getbackofftypeopt("SUMOLOGIC_BACKOFF_TYPE"),
+		backoffMaxMs:    opts.getintopt("SUMOLOGIC_BACKOFF_MAX_MS", 10000),
+		backoffJitterMs: opts.getintopt("SUMOLOGIC_BACKOFF_JITTER_MS", 0),
+		timeout:         opts.getintopt("SUMOLOGIC_TIMEOUT_MS", 10000),
+		diagnostics:     opts.getboolopt("SUMOLOGIC_DIAGNOSTIC_EVENTS", false),
+		diagCategory:    opts.getopt("SUMOLOGIC_DIAGNOSTIC_CATEGORY", ""),
+		placeholder:     opts.getopt("SUMOLOGIC_MISSING_METADATA_PLACEHOLDER", ""),
+		placeholders:    opts.getmapopt("SUMOLOGIC_FIELD_PLACEHOLDERS"),
+		maxInflight:     opts.getintopt("SUMOLOGIC_MAX_INFLIGHT_BYTES", 0),
+		slowStartMs:     opts.getintopt("SUMOLOGIC_SLOW_START_MS", 0),
+		slowStartRate:   opts.getintopt("SUMOLOGIC_SLOW_START_RATE", 10),
+		maxMsgsPerSec:   opts.getintopt("SUMOLOGIC_MAX_MSGS_PER_SEC", 0),
+		maxBytesPerSec:  opts.getintopt("SUMOLOGIC_MAX_BYTES_PER_SEC", 0),
+		allowOverride:   opts.getboolopt("SUMOLOGIC_CONTAINER_OVERRIDES", true),
+		skipEmpty:       opts.getboolopt("SUMOLOGIC_SKIP_EMPTY", true),
+		minLength:       opts.getintopt("SUMOLOGIC_MIN_MESSAGE_LENGTH", 0),
+		silenceMs:       opts.getintopt("SUMOLOGIC_SILENCE_THRESHOLD_MS", 0),
+		summaryMs:       opts.getintopt("SUMOLOGIC_SUMMARY_INTERVAL_MS", 0),
+		summaryCategory: opts.getopt("SUMOLOGIC_SUMMARY_CATEGORY", ""),
+		errorPattern: opts.getregexopt("SUMOLOGIC_SUMMARY_ERROR_PATTERN",
+			`(?i)\b(error|fatal|panic|critical)\b`),
+		metricRules:     opts.getmetricrulesopt("SUMOLOGIC_METRIC_RULES"),
+		metricsMs:       opts.getintopt("SUMOLOGIC_METRICS_INTERVAL_MS", 60000),
+		metricsCategory: opts.getopt("SUMOLOGIC_METRICS_CATEGORY", ""),
+	}
+	config.endPoints = splitEndpoints(opts.getendpointopt(route))
+	if len(config.endPoints) > 0 {
+		config.endPoint = config.endPoints[0]
+	}
+	config.endPointTemplate = opts.getopt("SUMOLOGIC_ENDPOINT_TEMPLATE", "")
+	config.containerEndPoints = opts.getcontainerendpointsopt(
+		"SUMOLOGIC_CONTAINER_ENDPOINTS")
+	config.maxInflightRequests = opts.getintopt("SUMOLOGIC_MAX_INFLIGHT", 0)
+	config.failoverThreshold = opts.getintopt("SUMOLOGIC_FAILOVER_THRESHOLD", 5)
+	config.failbackMs = opts.getintopt("SUMOLOGIC_FAILBACK_INTERVAL_MS", 60000)
+	if opts.getboolopt("SUMOLOGIC_EXCLUDE_SELF", true) {
+		config.selfID = selfContainerID(opts)
+	}
+	config.batchSize = opts.getintopt("SUMOLOGIC_BATCH_SIZE", 1)
+	config.flushMs = opts.getintopt("SUMOLOGIC_FLUSH_INTERVAL_MS", 1000)
+	config.maxRequestBytes = opts.getintopt("SUMOLOGIC_MAX_REQUEST_BYTES", 1000000)
+	config.format = opts.getformatopt("SUMOLOGIC_FORMAT")
+	config.containerFields = opts.getcontainerfieldsopt("SUMOLOGIC_CONTAINER_FIELDS")
+	config.chaos = opts.getchaosopt("SUMOLOGIC_CHAOS")
+	config.multilinePattern = opts.getregexopt("SUMOLOGIC_MULTILINE_PATTERN", "")
+	config.multilineFlushMs = opts.getintopt("SUMOLOGIC_MULTILINE_FLUSH_MS", 1000)
+	config.trackRestarts = opts.getboolopt("SUMOLOGIC_TRACK_RESTARTS", false)
+	config.encoding = opts.getencodingopt("SUMOLOGIC_ENCODING")
+	config.kubernetes = opts.getboolopt("SUMOLOGIC_KUBERNETES", false)
+	config.drainTimeoutMs = opts.getintopt("SUMOLOGIC_DRAIN_TIMEOUT_MS", 30000)
+	config.standbyMax = opts.getintopt("SUMOLOGIC_STANDBY_MAX_MESSAGES", 0)
+	config.standbyTimeoutMs = opts.getintopt("SUMOLOGIC_STANDBY_TIMEOUT_MS", 300000)
+	config.script = opts.getscriptopt("SUMOLOGIC_SCRIPT", "SUMOLOGIC_SCRIPT_FILE")
+	config.overflowCategory = opts.getopt("SUMOLOGIC_OVERFLOW_CATEGORY", "")
+	config.overflowThresholdBytes = opts.getintopt(
+		"SUMOLOGIC_OVERFLOW_THRESHOLD_BYTES", 65536)
+	config.overflowTruncateBytes = opts.getintopt(
+		"SUMOLOGIC_OVERFLOW_TRUNCATE_BYTES", 4096)
+	if config.kubernetes && config.sourceCategory == "" {
+		config.sourceCategory = kubernetesCategory
+	}
+	config.trimContainerSlash = opts.getboolopt("SUMOLOGIC_TRIM_CONTAINER_SLASH", true)
+	config.shortID = opts.getboolopt("SUMOLOGIC_SHORT_ID", false)
+	config.fullID = opts.getboolopt("SUMOLOGIC_FULL_ID", false)
+	config.includeEnv = opts.getincludeenvopt("SUMOLOGIC_INCLUDE_ENV")
+	config.proxyURL = opts.getproxyopt("SUMOLOGIC_PROXY_URL")
+	config.tls = tlsOptions{
+		caFile:     opts.getopt("SUMOLOGIC_TLS_CA_FILE", ""),
+		certFile:   opts.getopt("SUMOLOGIC_TLS_CERT_FILE", ""),
+		keyFile:    opts.getopt("SUMOLOGIC_TLS_KEY_FILE", ""),
+		skipVerify: opts.getboolopt("SUMOLOGIC_TLS_SKIP_VERIFY", false),
+	}
+	config.extraHeaders = opts.getheadersopt("SUMOLOGIC_EXTRA_HEADERS")
+	config.signingKey = opts.getopt("SUMOLOGIC_SIGNING_KEY", "")
+	config.strict = opts.getboolopt("SUMOLOGIC_STRICT_DELIVERY", false)
+	config.processingFlags = opts.getboolopt("SUMOLOGIC_PROCESSING_FLAGS", false)
+	config.filterLabels = opts.getmapopt("SUMOLOGIC_FILTER_LABELS")
+	config.filterInclude = opts.getregexopt("SUMOLOGIC_FILTER_INCLUDE", "")
+	config.filterExclude = opts.getregexopt("SUMOLOGIC_FILTER_EXCLUDE", "")
+	config.forgetRemoved = opts.getboolopt("SUMOLOGIC_FORGET_REMOVED_CONTAINERS", true)
+	config.bufferDir = opts.getopt("SUMOLOGIC_BUFFER_DIR", "")
+	config.bufferMaxMB = opts.getintopt("SUMOLOGIC_BUFFER_MAX_MB", 100)
+	config.auditFile = opts.getopt("SUMOLOGIC_AUDIT_FILE", "")
+	config.auditMaxMB = opts.getintopt("SUMOLOGIC_AUDIT_MAX_MB", 10)
+	config.auditMaxFiles = opts.getintopt("SUMOLOGIC_AUDIT_MAX_FILES", 5)
+	config.deadLetterDir = opts.getopt("SUMOLOGIC_DEAD_LETTER_DIR", "")
+	config.deadLetterMaxMB = opts.getintopt("SUMOLOGIC_DEAD_LETTER_MAX_MB", 100)
+	config.replayOrder = opts.getreplayorderopt("SUMOLOGIC_REPLAY_ORDER")
+	config.workers = opts.getintopt("SUMOLOGIC_WORKERS", 16)
+	config.pool = opts.getconnpoolopts(config.workers)
+	config.queueSize = opts.getintopt("SUMOLOGIC_QUEUE_SIZE", 1000)
+	config.overflow = opts.getoverflowopt("SUMOLOGIC_QUEUE_OVERFLOW")
+	config.categoryWorkers = opts.getintopt("SUMOLOGIC_CATEGORY_WORKERS", 0)
+	config.categoryWeights = opts.getweightsopt("SUMOLOGIC_CATEGORY_WEIGHTS")
+	config.ordered = opts.getboolopt("SUMOLOGIC_ORDERED", false)
+	config.samplingLevels = opts.getsamplinglevelsopt("SUMOLOGIC_ADAPTIVE_SAMPLING")
+	config.samplingKeepPattern = opts.getregexopt("SUMOLOGIC_SAMPLING_KEEP_PATTERN",
+		`(?i)\b(warn|warning|error|fatal|panic|critical)\b`)
+	config.sampleRate = opts.getintopt("SUMOLOGIC_SAMPLE_RATE", 1)
+	config.redactPatterns = opts.getredactpatternsopt("SUMOLOGIC_REDACT_PATTERNS")
+	config.redactReplacement = opts.getopt(
+		"SUMOLOGIC_REDACT_REPLACEMENT", "[REDACTED]")
+	config.unwrapJSON = opts.getboolopt("SUMOLOGIC_UNWRAP_DOCKER_JSON", true)
+	config.parseJSON = opts.getboolopt("SUMOLOGIC_PARSE_JSON", false)
+	config.parseJSONMode = opts.getparsejsonmodeopt("SUMOLOGIC_PARSE_JSON_MODE")
+	config.dedicatedConns = opts.getboolopt("SUMOLOGIC_DEDICATED_CONNECTIONS", false)
+	config.suppressions = opts.getsuppressionrulesopt("SUMOLOGIC_SUPPRESSION_RULES")
+	config.categorySources = opts.getcategorysourcesopt(
+		"SUMOLOGIC_SOURCE_CATEGORY_FALLBACKS")
+	config.stderrSourceCategory = opts.getopt("SUMOLOGIC_STDERR_SOURCE_CATEGORY", "")
+	config.dropStdout = opts.getboolopt("SUMOLOGIC_DROP_STDOUT", false)
+	config.severityPattern = opts.getregexopt("SUMOLOGIC_SEVERITY_PATTERN", "")
+	config.severityFields = opts.getseverityfieldsopt("SUMOLOGIC_SEVERITY_FIELDS")
+	config.timestampField = opts.getopt("SUMOLOGIC_TIMESTAMP_FIELD", "")
+	config.timestampPattern = opts.getregexopt("SUMOLOGIC_TIMESTAMP_PATTERN", "")
+	config.timestampFormat = opts.getopt("SUMOLOGIC_TIMESTAMP_FORMAT", time.RFC3339)
+	config.extraFields = opts.getmapopt("SUMOLOGIC_EXTRA_FIELDS")
+	config.fieldMap = opts.getmapopt("SUMOLOGIC_FIELD_MAP")
+	config.omitFields = opts.getomitfieldsopt("SUMOLOGIC_OMIT_FIELDS")
+	config.bodyTemplate = opts.getopt("SUMOLOGIC_BODY_TEMPLATE", "")
+	config.host = hostMetadata(opts)
+	config.awsMetadata = opts.getboolopt("SUMOLOGIC_AWS_METADATA", false)
+	config.awsIMDSEndpoint = opts.getopt("SUMOLOGIC_AWS_IMDS_ENDPOINT",
+		"http://169.254.169.254")
+	config.stripANSI = opts.getboolopt("SUMOLOGIC_STRIP_ANSI", false)
+	config.replaceInvalidUTF8 = opts.getboolopt("SUMOLOGIC_REPLACE_INVALID_UTF8", false)
+	config.maxMessageBytes = opts.getintopt("SUMOLOGIC_MAX_MESSAGE_BYTES", 0)
+	config.sizePolicy = opts.getsizepolicyopt("SUMOLOGIC_MAX_MESSAGE_SIZE_POLICY")
+	config.metricsDimensions = opts.getopt("SUMOLOGIC_METRICS_DIMENSIONS", "")
+	config.metricsMetadata = opts.getopt("SUMOLOGIC_METRICS_METADATA", "")
+	config.metricsEndPoint = opts.getopt(
+		"SUMOLOGIC_METRICS_ENDPOINT", config.endPoint)
+	config.dnsPrecheck = opts.getboolopt("SUMOLOGIC_DNS_PRECHECK", false)
+	config.dnsCacheMs = opts.getintopt("SUMOLOGIC_DNS_CACHE_MS", 30000)
+	config.deliveryDeadline = opts.getintopt("SUMOLOGIC_DELIVERY_DEADLINE_MS", 0)
+	config.archive = archiveConfig{
+		endPoint: opts.getopt(
+			"SUMOLOGIC_ARCHIVE_ENDPOINT", "https://s3.amazonaws.com"),
+		bucket: opts.getopt("SUMOLOGIC_ARCHIVE_BUCKET", ""),
+		prefix: opts.getopt("SUMOLOGIC_ARCHIVE_PREFIX", ""),
+		region: opts.getopt("SUMOLOGIC_ARCHIVE_REGION", "us-east-1"),
+		accessKey: opts.getopt(
+			"SUMOLOGIC_ARCHIVE_ACCESS_KEY", os.Getenv("AWS_ACCESS_KEY_ID")),
+		secretKey: opts.getopt(
+			"SUMOLOGIC_ARCHIVE_SECRET_KEY", os.Getenv("AWS_SECRET_ACCESS_KEY")),
+		flushMs:  opts.getintopt("SUMOLOGIC_ARCHIVE_FLUSH_MS", 60000),
+		maxBytes: opts.getintopt("SUMOLOGIC_ARCHIVE_MAX_BYTES", 5*1024*1024),
+		only:     opts.getboolopt("SUMOLOGIC_ARCHIVE_ONLY", false),
 	}
 	return config
 }
 
-// getopt retrieves an environment variable if it's set to
-// a non-emty string.
-// The supplied default is returned otherwise.
-func getopt(name string, dfault string) string {
-	value := os.Getenv(name)
+// routeOptions are the options of the route a config is built for, which
+// take precedence over the environment. The option helpers (getopt and
+// friends) look options up through them.
+type routeOptions map[string]string
+
+// lookupopt retrieves an option from the route's options, where it's named
+// in lower case without the SUMOLOGIC_ prefix (e.g. source_category for
+// SUMOLOGIC_SOURCE_CATEGORY), if it's set there, or from the environment.
+func (o routeOptions) lookupopt(name string) string {
+	option := strings.ToLower(strings.TrimPrefix(name, "SUMOLOGIC_"))
+	if value := o[option]; value != "" {
+		return value
+	}
+	return os.Getenv(name)
+}
+
+// getopt retrieves an option from the route or environment variable if it's
+// set to a non-emty string.
+// The default from the selected profile, if any, or the supplied default is
+// returned otherwise.
+func (o routeOptions) getopt(name string, dfault string) string {
+	value := o.lookupopt(name)
+	if value == "" {
+		value = o.profileDefault(name)
+	}
 	if value == "" {
 		value = dfault
 	}
@@ -103,44 +621,394 @@ func getopt(name string, dfault string) string {
 // getoptint retrieves an environment variable as an int if it's set
 // to a non-empty string.
 // The supplied default int is returned otherwise.
-func getintopt(name string, dfault int64) int64 {
-	value := os.Getenv(name)
+func (o routeOptions) getintopt(name string, dfault int64) int64 {
+	value := o.getopt(name, "")
 	if value == "" {
 		return dfault
 	}
 	intValue, err := strconv.ParseInt(value, 10, 64)
 	if err != nil {
-		log.WithError(err).WithField(name, value).Error("Failed to parse")
+		parseFailed(name, value, err)
 		return dfault
 	}
 	return intValue
 }
 
+// getboolopt retrieves an environment variable as a bool if it's set
+// to a non-empty string.
+// The supplied default bool is returned otherwise.
+func (o routeOptions) getboolopt(name string, dfault bool) bool {
+	value := o.getopt(name, "")
+	if value == "" {
+		return dfault
+	}
+	boolValue, err := strconv.ParseBool(value)
+	if err != nil {
+		parseFailed(name, value, err)
+		return dfault
+	}
+	return boolValue
+}
+
+// getmapopt retrieves an environment variable as a map if it's set to a
+// non-empty string of comma-separated key=value pairs, e.g. "foo=1,bar=2".
+// Entries without an "=" are logged and ignored.
+func (o routeOptions) getmapopt(name string) map[string]string {
+	result := map[string]string{}
+	for _, pair := range strings.Split(o.lookupopt(name), ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			parseFailed(name, pair, nil)
+			continue
+		}
+		result[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return result
+}
+
+// getregexopt retrieves an environment variable as a compiled regular
+// expression if it's set to a non-empty string.
+// The supplied default is compiled and returned otherwise.
+func (o routeOptions) getregexopt(name string, dfault string) *regexp.Regexp {
+	value := o.getopt(name, dfault)
+	re, err := regexp.Compile(value)
+	if err != nil {
+		parseFailed(name, value, err)
+		return regexp.MustCompile(dfault)
+	}
+	return re
+}
+
 // Stream is a logspout adapter implementation method.
 func (s *Adapter) Stream(logstream chan *router.Message) {
-	for msg := range logstream {
-		go s.sendLog(msg)
+	if s.multiline == nil {
+		for msg := range logstream {
+			s.receive(msg)
+		}
+		s.shutdown()
+		return
+	}
+
+	// Events being joined from multiple lines are checked on the stream's
+	// goroutine, so that they're queued in order with everything else.
+	timer := s.clock.NewTimer(s.multiline.timeout)
+	for {
+		select {
+		case msg, ok := <-logstream:
+			if !ok {
+				timer.Stop()
+				s.flushMultiline(true)
+				s.shutdown()
+				return
+			}
+			s.receive(msg)
+		case <-timer.C():
+			s.flushMultiline(false)
+			timer = s.clock.NewTimer(s.multiline.timeout)
+		}
+	}
+}
+
+// receive takes a message from the stream.
+func (s *Adapter) receive(msg *router.Message) {
+	config := s.config()
+	if filtered(msg, config) {
+		return
+	}
+	msg = normalizeEncoding(msg, config)
+	if config.unwrapJSON {
+		if unwrapped := unwrapDockerJSON(msg); unwrapped != msg {
+			msg = unwrapped
+			s.annotate(msg, config, func(p *Processing) { p.Unwrapped = true })
+		}
+	}
+	if s.multiline != nil {
+		group := s.multiline.add(msg, s.clock.Now())
+		if group == nil {
+			return
+		}
+		msg = s.joined(group, config)
 	}
+	s.forward(msg, config)
+}
+
+// forward passes a message on to be sent, once any lines that continue it
+// have been joined onto it.
+func (s *Adapter) forward(msg *router.Message, config *Config) {
+	s.containers.received(msg)
+	if s.restarts.seen(msg) {
+		go s.sendRestartEvent(msg)
+	}
+	s.silence.seen(msg)
+	s.summaries.count(msg)
+	s.metrics.count(msg)
+	if !s.accept(msg) {
+		return
+	}
+	transformed, keep := s.script.apply(msg)
+	if !keep {
+		s.drop(msg, "script")
+		return
+	}
+	if transformed != msg {
+		s.annotations.move(msg, transformed)
+		msg = transformed
+	}
+	if sanitized := sanitize(msg, config); sanitized != msg {
+		s.annotations.move(msg, sanitized)
+		msg = sanitized
+		s.annotate(msg, config, func(p *Processing) { p.Sanitized = true })
+	}
+	if redacted := redact(msg, config); redacted != msg {
+		s.annotations.move(msg, redacted)
+		msg = redacted
+		s.annotate(msg, config, func(p *Processing) { p.Redacted = true })
+	}
+	for _, part := range s.limitSize(msg, config) {
+		s.enqueue(part)
+	}
+	s.stalls.wait(s.ctx)
+}
+
+// every calls f at the given interval until the adapter is closed.
+func (s *Adapter) every(interval time.Duration, f func()) {
+	for {
+		timer := s.clock.NewTimer(interval)
+		select {
+		case <-timer.C():
+		case <-s.ctx.Done():
+			timer.Stop()
+			return
+		}
+		f()
+	}
+}
+
+// accept decides whether a message should be sent at all. Messages that
+// aren't are counted as dropped.
+func (s *Adapter) accept(msg *router.Message) (accepted bool) {
+	defer s.recoverPanic("accept")
+
+	reason := s.dropReason(msg)
+	if reason != "" {
+		s.drop(msg, reason)
+		return false
+	}
+	return true
+}
+
+// drop drops a message that won't be sent, counting it as dropped.
+func (s *Adapter) drop(msg *router.Message, reason string) {
+	s.status.drop()
+	s.containers.dropped(msg)
+	s.annotations.take(msg)
+	log.WithField("reason", reason).Debug("Dropping message")
+}
+
+// dropReason returns why a message should be dropped, or "" if it shouldn't.
+func (s *Adapter) dropReason(msg *router.Message) string {
+	config := s.config()
+	trimmed := strings.TrimSpace(msg.Data)
+	if config.skipEmpty && trimmed == "" {
+		return "empty"
+	}
+	if int64(utf8.RuneCountInString(trimmed)) < config.minLength {
+		return "too short"
+	}
+	if reason := matchFilterReason(msg.Data, config); reason != "" {
+		return reason
+	}
+	if droppedStream(msg, config) {
+		return "stdout"
+	}
+	if config.selfID != "" && msg.Container != nil &&
+		strings.HasPrefix(msg.Container.ID, config.selfID) {
+		return "self"
+	}
+	if s.suppressed(msg, config) {
+		return "suppressed"
+	}
+	return ""
 }
 
 // sendLog post a log to Sumologic
 func (s *Adapter) sendLog(msg *router.Message) {
+	defer s.recoverPanic("sendLog")
 
-	headers := buildHeaders(msg, s.config)
-	data := buildData(msg)
-
-	strData, err := json.Marshal(data)
+	if s.batches == nil {
+		s.Send(msg)
+		return
+	}
+	data, headers := s.prepare(msg)
+	if data == nil {
+		return
+	}
+	line, err := encodeEvent(data, s.config())
 	if err != nil {
-		log.WithError(err).WithField("message_source", msg.Source).Errorf(
+		log.WithError(err).WithField(
+			"message_source", data.Container.Source).Errorf(
 			"Unable to build json data, skipping send")
 		return
 	}
+	if full := s.batches.add(line, data.Container.ID, headers); full != nil {
+		s.sendBatch(full)
+	}
+}
+
+// Send posts a single message to Sumologic and waits for the outcome,
+// bypassing any batching. If the message can't be delivered, the error is a
+// *SendError (or the context's error, if the adapter was closed in the
+// meantime). Failures are logged and recorded in the adapter's status either
+// way.
+func (s *Adapter) Send(msg *router.Message) error {
+	data, headers := s.prepare(msg)
+	if data == nil {
+		return nil
+	}
+	err := s.send(data, headers, func() (bool, error) {
+		rerouted, ok := overflowed(data, headers, s.config())
+		if !ok {
+			return false, nil
+		}
+		log.WithField("category", s.config().overflowCategory).Warn(
+			"Event too large, sending it truncated to the overflow category")
+		return true, s.send(data, rerouted, nil)
+	})
+	s.containers.delivered(map[string]int64{data.Container.ID: 1}, err)
+	return err
+}
+
+// prepare builds the event and headers for a message, and archives the
+// event. It returns a nil event if it shouldn't be sent to Sumologic.
+func (s *Adapter) prepare(msg *router.Message) (*Data, http.Header) {
+	config := s.config()
+	data := buildData(msg, config)
+	data.Processing = s.processing(msg, config)
+	if data.Processing != nil && data.parsed != nil {
+		data.Processing.JSONParsed = true
+	}
+	data.Truncated = data.Processing != nil && data.Processing.Truncated
+	data.RestartGeneration = s.restarts.generation(msg)
+	data.AWSData = s.aws
+	s.archive.add(data)
+	if config.archive.only {
+		return nil, nil
+	}
+	return data, oversized(data, s.headers(msg, config), config)
+}
+
+// send posts a single event to Sumologic, recording the outcome. If it's too
+// large, tooLarge may deliver it some other way instead (see deliver).
+func (s *Adapter) send(
+	data *Data, headers http.Header, tooLarge func() (bool, error)) error {
+	strData, err := encodeEvent(data, s.config())
+	if err != nil {
+		log.WithError(err).WithField(
+			"message_source", data.Container.Source).Errorf(
+			"Unable to build json data, skipping send")
+		return &SendError{Kind: ErrPermanent, Err: err}
+	}
+	// Backfilled events don't come from a running container.
+	containerID := ""
+	if data.Container != nil {
+		containerID = data.Container.ID
+	}
+	return s.deliver(
+		strData, headers, map[string]int64{containerID: 1}, tooLarge)
+}
+
+// deliver posts a request body holding the given number of events from each
+// container to Sumologic, recording the outcome. If it fails in a way that
+// may be worth retrying, the request is buffered on disk, if that's enabled.
+// If it fails for good, it's written to the dead-letter directory instead,
+// if that's enabled. If it's too large and tooLarge isn't nil, tooLarge is
+// called first to deliver it some other way, e.g. in pieces. If it does, its
+// outcome is returned instead, and the request isn't recorded at all.
+func (s *Adapter) deliver(strData []byte, headers http.Header,
+	containers map[string]int64, tooLarge func() (bool, error)) error {
+	events := countEvents(containers)
+	s.payloads.record(int64(len(strData)), s.config())
+	ctx, cancel := s.deliveryContext()
+	err := s.attempt(ctx, strData, headers, events)
+	if spoolable(err) && s.stalls != nil {
+		err = s.deliverStrictly(ctx, strData, headers, events, err)
+	}
+	cancel()
+	if payloadTooLarge(err) {
+		if tooLarge != nil {
+			if handled, tooLargeErr := tooLarge(); handled {
+				return tooLargeErr
+			}
+		}
+		s.deliveryFailed(err.(*SendError).Err)
+	}
+	if spoolable(err) {
+		s.spool.add(s.clock.Now(), strData, headers, containers)
+	}
+	if s.auditStatus(err) == auditFailed {
+		s.deadLetters.add(s.clock.Now(), strData, headers, containers, err)
+	}
+	s.audit.record(s.clock.Now(), containers, int64(len(strData)),
+		headers.Get("X-Sumo-Category"), s.auditStatus(err), err)
+	return err
+}
+
+// attempt posts a request body to Sumologic once, recording the outcome. It
+// gives up once the context is done, whether that's because the adapter was
+// closed or the delivery deadline passed.
+func (s *Adapter) attempt(ctx context.Context,
+	strData []byte, headers http.Header, events int64) (err error) {
+	s.status.begin()
+	defer s.status.end()
+
+	reserved, err := s.inflight.acquire(ctx, int64(len(strData)))
+	if err != nil {
+		return s.abandoned(err)
+	}
+	defer s.inflight.release(reserved)
+
+	if err = s.slowStart.wait(ctx); err != nil {
+		return s.abandoned(err)
+	}
+	if err = s.rateLimit.wait(ctx, events, int64(len(strData))); err != nil {
+		return s.abandoned(err)
+	}
+
+	config := s.config()
+	endPoint := s.failover.endpoint(config)
+	client := s.currentClient()
+	// A container's own endpoint has no failover, so its failures are
+	// ignored when it's recorded. One that's no longer allowed since the
+	// request was buffered falls back to the route's.
+	own := config.containerEndPoints[headers.Get(endpointHeader)]
+	if own != "" {
+		endPoint, client = own, s.clientFor(own)
+	}
+	defer func() { s.failover.record(config, endPoint, err) }()
+	if err = s.dns.check(ctx, endPoint); err != nil {
+		s.deliveryFailed(err)
+		log.WithError(err).WithField("error_class", errorClassDNS).Error(
+			"Unable to resolve Sumologic endpoint")
+		return &SendError{Kind: ErrNetwork, Err: err}
+	}
 
-	req, reqErr := s.client.Post(
-		s.config.endPoint, strings.NewReader(string(strData)), headers)
+	if own == "" {
+		if session := s.sessions.acquire(headers.Get("X-Sumo-Category")); session != nil {
+			defer session.release()
+			client = session.client
+		}
+	}
+	req, reqErr := s.postRetrying(
+		ctx, client, endPoint, strData, withoutEndpoint(headers))
 	if reqErr != nil {
-		log.WithError(reqErr).Error("Failed to send log to Sumologic")
-		return
+		s.deliveryFailed(reqErr)
+		log.WithError(reqErr).WithField(
+			"error_class", classifyError(reqErr)).Error(
+			"Failed to send log to Sumologic")
+		return newSendError(reqErr)
 	}
 
 	_, err = ioutil.ReadAll(req.Body)
@@ -150,9 +1018,124 @@ func (s *Adapter) sendLog(msg *router.Message) {
 		log.WithError(err).Error("Unable to read response body.")
 	}
 	if req.StatusCode != http.StatusOK {
+		statusErr := &statusError{req.StatusCode}
+		// A request that's too large may yet be delivered some other way, so
+		// it's left to the caller to count as failed.
+		if req.StatusCode != http.StatusRequestEntityTooLarge {
+			s.deliveryFailed(statusErr)
+		}
 		log.WithField(
 			"StatusCode", req.StatusCode).Error("Failed to send log to Sumologic")
+		return newSendError(statusErr)
+	}
+	s.deliverySucceeded(events)
+	s.status.ingested(
+		headers.Get("X-Sumo-Category"), events, int64(len(strData)))
+	return nil
+}
+
+// deliverySucceeded records a successful send of the given number of
+// events.
+func (s *Adapter) deliverySucceeded(events int64) {
+	s.slowStart.succeeded()
+	s.status.succeeded(events)
+}
+
+// deliveryFailed records a failed send.
+func (s *Adapter) deliveryFailed(err error) {
+	s.slowStart.failed()
+	s.status.failedWith(err)
+}
+
+// recoverPanic is deferred by each pipeline stage so that a panic while
+// handling a single message is logged and counted instead of silently killing
+// the goroutine (or the Stream loop) it happened in.
+func (s *Adapter) recoverPanic(stage string) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	atomic.AddInt64(&s.panics, 1)
+	log.WithFields(log.Fields{
+		"stage": stage,
+		"panic": r,
+		"stack": string(debug.Stack()),
+	}).Error("Recovered from panic")
+
+	if s.config().diagnostics {
+		s.sendDiagnostic(fmt.Sprintf(
+			"logspout-sumologic recovered from panic in %s: %v", stage, r))
+	}
+}
+
+// sendDiagnostic posts an event describing a problem with the adapter itself
+// to Sumologic, so that it's visible alongside the container logs.
+func (s *Adapter) sendDiagnostic(text string) {
+	headers := http.Header{}
+	headers.Add("X-Sumo-Name", "logspout-sumologic")
+	if category := s.config().diagCategory; category != "" {
+		headers.Add("X-Sumo-Category", category)
+	}
+
+	strData, err := encodeEvent(&Data{
+		Message:   text,
+		Timestamp: formatTimestamp(s.clock.Now()),
+	}, s.config())
+	if err != nil {
+		log.WithError(err).Error("Unable to build diagnostic event")
+		return
+	}
+
+	req, err := s.post(strData, headers)
+	if err != nil {
+		log.WithError(err).Error("Failed to send diagnostic event to Sumologic")
+		return
 	}
+	closeBody(req)
+}
+
+// post sends a request body to the Sumologic endpoint.
+func (s *Adapter) post(body []byte, headers http.Header) (*http.Response, error) {
+	return s.postTo(s.failover.endpoint(s.config()), body, headers)
+}
+
+// postTo sends a request body to the given endpoint. The request is bound to
+// the adapter's context, so it's abandoned as soon as the adapter is closed.
+// Any error has the endpoint's receiver token masked, since it ends up in
+// logs and the delivery status.
+func (s *Adapter) postTo(
+	endPoint string, body []byte, headers http.Header) (*http.Response, error) {
+	return s.postWith(s.ctx, s.currentClient(), endPoint, body, headers)
+}
+
+// postWith sends a request body to the given endpoint using a particular
+// client, abandoning it once the context is done.
+func (s *Adapter) postWith(ctx context.Context, client doer,
+	endPoint string, body []byte, headers http.Header) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodPost, endPoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, maskError(err)
+	}
+	req = req.WithContext(withAttemptObserver(ctx, s.observeAttempt))
+	config := s.config()
+	req.Header = headers
+	if !config.isContainerEndpoint(endPoint) {
+		req.Header = config.sign(
+			config.withExtraHeaders(headers), body, s.clock.Now())
+	}
+	if resp, err := s.injectFault(config.chaos, req); resp != nil || err != nil {
+		return resp, maskError(err)
+	}
+	if err = s.requests.acquire(ctx); err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		s.requests.release()
+		return nil, maskError(err)
+	}
+	s.requests.hold(resp)
+	return resp, nil
 }
 
 func closeBody(req *http.Response) {
@@ -162,6 +1145,43 @@ func closeBody(req *http.Response) {
 	}
 }
 
+// containerEnv returns the environment variables set in a message's
+// container, or an empty map if there aren't any.
+func containerEnv(msg *router.Message) map[string]string {
+	env := map[string]string{}
+	if msg.Container == nil || msg.Container.Config == nil {
+		return env
+	}
+	for _, kv := range msg.Container.Config.Env {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) == 2 {
+			env[parts[0]] = parts[1]
+		}
+	}
+	return env
+}
+
+// withContainerOverrides returns the config to use for a message, which is
+// the host-level config with any SUMOLOGIC_SOURCE_* variables set in the
+// container's own environment taking precedence.
+func (config *Config) withContainerOverrides(msg *router.Message) *Config {
+	if !config.allowOverride {
+		return config
+	}
+	env := containerEnv(msg)
+	overridden := *config
+	if value := env["SUMOLOGIC_SOURCE_NAME"]; value != "" {
+		overridden.sourceName = value
+	}
+	if value := env["SUMOLOGIC_SOURCE_CATEGORY"]; value != "" {
+		overridden.sourceCategory = value
+	}
+	if value := env["SUMOLOGIC_SOURCE_HOST"]; value != "" {
+		overridden.sourceHost = value
+	}
+	return &overridden
+}
+
 // buildHeaders creates a set of Sumologic classification headers,
 // these header values are derived from env vars and/or container properties,
 // then renderTemplate is called to compile for e.g {{.Container.Name}}
@@ -169,53 +1189,164 @@ func buildHeaders(
 	msg *router.Message, config *Config) http.Header {
 
 	headers := http.Header{}
+	config = config.withContainerOverrides(msg)
 
-	sourceName, nameErr := renderTemplate(msg, config.sourceName)
+	sourceName, nameErr := renderTemplate(msg, config.route, config.sourceName)
 	if nameErr == nil {
+		if config.trimContainerSlash {
+			sourceName = strings.TrimPrefix(sourceName, "/")
+		}
 		headers.Add("X-Sumo-Name", sourceName)
 	}
 
-	sourceHost, hostErr := renderTemplate(msg, config.sourceHost)
+	sourceHost, hostErr := renderTemplate(msg, config.route, config.sourceHost)
 	if hostErr == nil {
 		headers.Add("X-Sumo-Host", sourceHost)
 	}
 
-	if config.sourceCategory != "" {
-		sourceCategory, catErr := renderTemplate(msg, config.sourceCategory)
-		if catErr == nil {
-			headers.Add("X-Sumo-Category", sourceCategory)
-		}
+	if category, ok := sourceCategory(msg, config); ok {
+		headers.Add("X-Sumo-Category", category)
+	}
+
+	if id := containerEndpoint(msg, config); id != "" {
+		headers.Add(endpointHeader, id)
 	}
 	return headers
 }
 
 // buildData builds the message to send to sumologic.
-func buildData(msg *router.Message) *Data {
+// If the message has no container (or container config) attached, the
+// configured placeholder is used for the missing fields and the message is
+// flagged as such rather than being dropped.
+func buildData(msg *router.Message, config *Config) *Data {
 	container := &ContainerData{
 		Source:   msg.Source,
 		Time:     msg.Time.Format(time.RFC3339),
-		Name:     msg.Container.Name,
-		ID:       msg.Container.ID,
-		Image:    msg.Container.Config.Image,
-		Hostname: msg.Container.Config.Hostname,
+		Name:     config.placeholder,
+		ID:       config.placeholder,
+		Image:    config.placeholder,
+		Hostname: config.placeholder,
+		fields:   config.containerFields,
+	}
+	metadataMissing := msg.Container == nil || msg.Container.Config == nil
+	if msg.Container != nil {
+		container.Name = msg.Container.Name
+		if config.trimContainerSlash {
+			container.Name = strings.TrimPrefix(container.Name, "/")
+		}
+		container.ID = msg.Container.ID
+		if config.shortID && len(container.ID) > shortIDLength {
+			container.ID = container.ID[:shortIDLength]
+			if config.fullID {
+				container.FullID = msg.Container.ID
+			}
+		}
+		if msg.Container.Config != nil {
+			container.Image = msg.Container.Config.Image
+			container.Hostname = msg.Container.Config.Hostname
+		}
+		container.Env = includedEnv(msg, config)
+	}
+	applyPlaceholders(container, config.placeholders)
+	data := &Data{
+		Container:       container,
+		Message:         msg.Data,
+		Timestamp:       formatTimestamp(messageTime(msg, config)),
+		MetadataMissing: metadataMissing,
+		msg:             msg,
+	}
+	if config.kubernetes {
+		data.KubernetesData = kubernetesMetadata(msg)
+	}
+	data.SwarmData = swarmMetadata(msg)
+	data.Severity = severity(msg.Data, config)
+	data.HostData = config.host
+	if config.parseJSON {
+		data.parsed = parseJSON(msg.Data)
+		data.mergeParsed = config.parseJSONMode == parseJSONMerge
+	}
+	return data
+}
+
+// applyPlaceholders replaces empty container fields with their configured
+// fallback values, keyed by json field name, so that Sumologic queries
+// grouping by those fields don't lump everything together under "".
+func applyPlaceholders(container *ContainerData, placeholders map[string]string) {
+	fields := map[string]*string{
+		"source":          &container.Source,
+		"docker_name":     &container.Name,
+		"docker_id":       &container.ID,
+		"docker_image":    &container.Image,
+		"docker_hostname": &container.Hostname,
+	}
+	for name, field := range fields {
+		if value, ok := placeholders[name]; ok && *field == "" {
+			*field = value
+		}
 	}
-	return &Data{
-		Container: container,
-		Message:   msg.Data,
-		// Sumologic supports 13 digit/UnixMilli in json messages.
-		Timestamp: strconv.FormatInt(msg.Time.UTC().UnixNano()/1000000, 10),
+}
+
+// formatTimestamp formats a time the way Sumologic expects it in json
+// messages, which is 13 digit/UnixMilli.
+func formatTimestamp(t time.Time) string {
+	return strconv.FormatInt(t.UTC().UnixNano()/1000000, 10)
+}
+
+// templateContext is what source templates are rendered against. It's the
+// router.Message (so e.g. {{.Container.Name}} works as it always has) plus
+// details of the route the message is being sent on, e.g. {{.Route.ID}}.
+type templateContext struct {
+	*router.Message
+	Route      templateRoute
+	Kubernetes KubernetesData
+	Swarm      SwarmData
+}
+
+// templateRoute holds the route details available to templates.
+type templateRoute struct {
+	ID      string
+	Address string
+	Host    string
+	Options map[string]string
+}
+
+func newTemplateRoute(route *router.Route) templateRoute {
+	if route == nil {
+		return templateRoute{}
+	}
+	host := route.Address
+	if u, err := url.Parse(route.Address); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	return templateRoute{
+		ID:      route.ID,
+		Address: route.Address,
+		Host:    host,
+		Options: route.Options,
 	}
 }
 
-// renderTemplate compiles a template string, e.g {{.Container.Name}} using
-// a router.Message as the context.
-func renderTemplate(msg *router.Message, text string) (string, error) {
-	tmpl, err := template.New("info").Parse(text)
+// renderTemplate renders a template string, e.g {{.Container.Name}} using
+// a router.Message and the route it's being sent on as the context. Each
+// template string is only compiled the first time it's rendered.
+func renderTemplate(
+	msg *router.Message, route *router.Route, text string) (string, error) {
+	tmpl, err := templates.get(text)
 	if err != nil {
 		return "", fmt.Errorf("Couldn't parse sumologic source template. %v", err)
 	}
 	buf := new(bytes.Buffer)
-	err = tmpl.Execute(buf, msg)
+	context := &templateContext{
+		Message: msg,
+		Route:   newTemplateRoute(route),
+	}
+	if k8s := kubernetesMetadata(msg); k8s != nil {
+		context.Kubernetes = *k8s
+	}
+	if swarm := swarmMetadata(msg); swarm != nil {
+		context.Swarm = *swarm
+	}
+	err = tmpl.Execute(buf, context)
 	if err != nil {
 		return "", err
 	}
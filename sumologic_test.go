@@ -2,12 +2,15 @@ package sumologic
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -41,10 +44,16 @@ func (ts *TestSuite) AddCleanup(f func()) {
 	ts.cleanups = append([]func(){f}, ts.cleanups...)
 }
 
+func (ts *TestSuite) SetupTest() {
+	// Keep adapters from trying to talk to a real docker daemon.
+	ts.FakeDockerEvents(nil)
+}
+
 func (ts *TestSuite) TearDownTest() {
 	for _, f := range ts.cleanups {
 		f()
 	}
+	ts.cleanups = nil
 }
 
 // Setenv sets an environment variable for the duration of the test. It
@@ -97,15 +106,57 @@ type RequestData struct {
 // then returns an Adapter pointing at that server, the requests channel is
 // passed onto the handler which pushes requests recieved to it.
 func (ts *TestSuite) FakeSumo(requests chan *RequestData) *Adapter {
-	handler := ts.mkHandler(requests)
-	server := httptest.NewServer(handler)
-	ts.AddCleanup(server.Close)
+	return ts.FakeSumoWithClock(requests, newRealClock())
+}
 
-	return ts.mkAdapter(&router.Route{
+// FakeSumoWithClock is like FakeSumo, except that the Adapter uses the given
+// Clock.
+func (ts *TestSuite) FakeSumoWithClock(
+	requests chan *RequestData, clock Clock) *Adapter {
+	return ts.adapterFor(ts.mkHandler(requests), clock)
+}
+
+// adapterFor starts a fake Sumo Logic server that serves requests with the
+// given handler, and returns an Adapter pointing at it that uses the given
+// Clock. Both are closed when the test ends.
+func (ts *TestSuite) adapterFor(handler http.Handler, clock Clock) *Adapter {
+	adapter := ts.WithoutError(NewAdapterWithClock(&router.Route{
 		ID:      "foo",
-		Address: server.URL,
+		Address: ts.serve(handler),
 		Adapter: "sumologic",
-	})
+	}, clock)).(*Adapter)
+	ts.AddCleanup(adapter.Close)
+	return adapter
+}
+
+// serve starts a server with the given handler for the duration of the test,
+// and returns its URL.
+func (ts *TestSuite) serve(handler http.Handler) string {
+	server := httptest.NewServer(handler)
+	ts.AddCleanup(server.Close)
+	return server.URL
+}
+
+// FakeFlakySumo starts a fake Sumo Logic server that responds with whatever
+// status code is stored in code, and returns an Adapter pointing at it that
+// doesn't retry failed sends, along with a temporary directory that the
+// dirEnv option (e.g. SUMOLOGIC_BUFFER_DIR) is set to.
+func (ts *TestSuite) FakeFlakySumo(code *int64, requests chan *RequestData,
+	dirEnv string) (*Adapter, string) {
+	dir := ts.WithoutError(ioutil.TempDir("", "flaky")).(string)
+	ts.AddCleanup(func() { os.RemoveAll(dir) })
+	ts.Setenv(dirEnv, dir)
+	ts.Setenv("SUMOLOGIC_RETRIES", "0")
+	handler := ts.mkHandler(requests)
+	return ts.adapterFor(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			status := int(atomic.LoadInt64(code))
+			if status != http.StatusOK {
+				w.WriteHeader(status)
+				return
+			}
+			handler.ServeHTTP(w, r)
+		}), newFakeClock()), dir
 }
 
 func (ts *TestSuite) mkHandler(requests chan *RequestData) http.Handler {
@@ -126,8 +177,32 @@ func (ts *TestSuite) mkHandler(requests chan *RequestData) http.Handler {
 	})
 }
 
+// noServer is an endpoint that nothing is listening on.
+const noServer = "http://127.0.0.1:1"
+
+// envOptions looks options up in the environment alone, as they are for a
+// route without options of its own.
+var envOptions routeOptions
+
 func (ts *TestSuite) mkAdapter(router *router.Route) *Adapter {
-	return ts.WithoutError(NewAdapter(router)).(*Adapter)
+	adapter := ts.WithoutError(NewAdapter(router)).(*Adapter)
+	ts.AddCleanup(adapter.Close)
+	return adapter
+}
+
+// panicOnceClient wraps a client and panics the first time a request is
+// made, for exercising panic recovery.
+type panicOnceClient struct {
+	doer
+	panicked bool
+}
+
+func (c *panicOnceClient) Do(req *http.Request) (*http.Response, error) {
+	if !c.panicked {
+		c.panicked = true
+		panic("boom")
+	}
+	return c.doer.Do(req)
 }
 
 func mkTime(secondsAfterBase time.Duration) time.Time {
@@ -162,38 +237,93 @@ func (ts *TestSuite) checkRequest(exreqs *[]RequestData, req *RequestData) {
 // Tests.
 
 func (ts *TestSuite) Test_getopt_unset_envar_returns_default() {
-	ts.EqualValues("foo", getopt("UNSET_ENV_VAR", "foo"))
+	ts.EqualValues("foo", envOptions.getopt("UNSET_ENV_VAR", "foo"))
 }
 
 func (ts *TestSuite) Test_getopt_set_envar_empty_returns_default() {
 	ts.Setenv("SET_ENV_VAR", "")
-	ts.EqualValues("foo", getopt("SET_ENV_VAR", "foo"))
+	ts.EqualValues("foo", envOptions.getopt("SET_ENV_VAR", "foo"))
 }
 
 func (ts *TestSuite) Test_getopt_set_envar_nonempty_returns_value() {
 	ts.Setenv("SET_ENV_VAR", "foo")
-	ts.EqualValues("foo", getopt("SET_ENV_VAR", "bar"))
+	ts.EqualValues("foo", envOptions.getopt("SET_ENV_VAR", "bar"))
 }
 
 func (ts *TestSuite) Test_getintopt_unset_envar_returns_default() {
-	ts.EqualValues(1, getintopt("UNSET_ENV_VAR", 1))
+	ts.EqualValues(1, envOptions.getintopt("UNSET_ENV_VAR", 1))
 }
 
 func (ts *TestSuite) Test_getintopt_set_envar_empty_returns_default() {
 	ts.Setenv("SET_ENV_VAR", "")
-	ts.EqualValues(1, getintopt("SET_ENV_VAR", 1))
+	ts.EqualValues(1, envOptions.getintopt("SET_ENV_VAR", 1))
 }
 
 func (ts *TestSuite) Test_getintopt_set_envar_nonempty_returns_value() {
 	ts.Setenv("SET_ENV_VAR", "2")
-	ts.EqualValues(2, getintopt("SET_ENV_VAR", 1))
+	ts.EqualValues(2, envOptions.getintopt("SET_ENV_VAR", 1))
 }
 
 func (ts *TestSuite) Test_getintopt_set_envar_invalid_returns_default() {
 	hook, _ := ts.CaptureLogs()
 
 	ts.Setenv("SET_ENV_VAR", "seven")
-	ts.EqualValues(1, getintopt("SET_ENV_VAR", 1))
+	ts.EqualValues(1, envOptions.getintopt("SET_ENV_VAR", 1))
+	ts.Equal(logrus.ErrorLevel, hook.LastEntry().Level)
+	ts.Equal("Failed to parse", hook.LastEntry().Message)
+}
+
+func (ts *TestSuite) Test_getboolopt_unset_envar_returns_default() {
+	ts.EqualValues(true, envOptions.getboolopt("UNSET_ENV_VAR", true))
+}
+
+func (ts *TestSuite) Test_getboolopt_set_envar_nonempty_returns_value() {
+	ts.Setenv("SET_ENV_VAR", "false")
+	ts.EqualValues(false, envOptions.getboolopt("SET_ENV_VAR", true))
+}
+
+func (ts *TestSuite) Test_getboolopt_set_envar_invalid_returns_default() {
+	hook, _ := ts.CaptureLogs()
+
+	ts.Setenv("SET_ENV_VAR", "seven")
+	ts.EqualValues(true, envOptions.getboolopt("SET_ENV_VAR", true))
+	ts.Equal(logrus.ErrorLevel, hook.LastEntry().Level)
+	ts.Equal("Failed to parse", hook.LastEntry().Message)
+}
+
+func (ts *TestSuite) Test_getmapopt_unset_envar_returns_empty() {
+	ts.Equal(map[string]string{}, envOptions.getmapopt("UNSET_ENV_VAR"))
+}
+
+func (ts *TestSuite) Test_getmapopt_set_envar_returns_pairs() {
+	ts.Setenv("SET_ENV_VAR", "foo=1, bar = two,,baz=")
+	ts.Equal(map[string]string{"foo": "1", "bar": "two", "baz": ""},
+		envOptions.getmapopt("SET_ENV_VAR"))
+}
+
+func (ts *TestSuite) Test_getmapopt_set_envar_invalid_pair_is_skipped() {
+	hook, _ := ts.CaptureLogs()
+
+	ts.Setenv("SET_ENV_VAR", "foo=1,bar")
+	ts.Equal(map[string]string{"foo": "1"}, envOptions.getmapopt("SET_ENV_VAR"))
+	ts.Equal(logrus.ErrorLevel, hook.LastEntry().Level)
+	ts.Equal("Failed to parse", hook.LastEntry().Message)
+}
+
+func (ts *TestSuite) Test_getregexopt_unset_envar_returns_default() {
+	ts.Equal("fo+", envOptions.getregexopt("UNSET_ENV_VAR", "fo+").String())
+}
+
+func (ts *TestSuite) Test_getregexopt_set_envar_nonempty_returns_value() {
+	ts.Setenv("SET_ENV_VAR", "ba+r")
+	ts.Equal("ba+r", envOptions.getregexopt("SET_ENV_VAR", "fo+").String())
+}
+
+func (ts *TestSuite) Test_getregexopt_set_envar_invalid_returns_default() {
+	hook, _ := ts.CaptureLogs()
+
+	ts.Setenv("SET_ENV_VAR", "(")
+	ts.Equal("fo+", envOptions.getregexopt("SET_ENV_VAR", "fo+").String())
 	ts.Equal(logrus.ErrorLevel, hook.LastEntry().Level)
 	ts.Equal("Failed to parse", hook.LastEntry().Message)
 }
@@ -228,6 +358,52 @@ func (ts *TestSuite) Test_buildConfig_without_env_vars() {
 	ts.Equal(expectedEndpoint, config.endPoint)
 }
 
+func (ts *TestSuite) Test_buildConfig_with_route_options() {
+	ts.Setenv("SUMOLOGIC_SOURCE_CATEGORY", "global")
+	ts.Setenv("SUMOLOGIC_RETRIES", "3")
+	ts.Setenv("SUMOLOGIC_TIMEOUT_MS", "500")
+	config := buildConfig(&router.Route{Options: map[string]string{
+		"source_category": "prod/api",
+		"retries":         "5",
+		"filter_labels":   "team=api",
+		"timeout_ms":      "",
+	}})
+	ts.Equal("prod/api", config.sourceCategory)
+	ts.EqualValues(5, config.retries)
+	ts.Equal(map[string]string{"team": "api"}, config.filterLabels)
+	ts.EqualValues(500, config.timeout, "empty options fall back to the env")
+
+	config = buildConfig(&router.Route{})
+	ts.Equal("global", config.sourceCategory, "options are only for their route")
+	ts.EqualValues(3, config.retries)
+}
+
+func (ts *TestSuite) Test_routeOptions_lookupopt() {
+	ts.Setenv("SUMOLOGIC_RETRIES", "3")
+	ts.Setenv("SUMOLOGIC_TIMEOUT_MS", "500")
+	opts := routeOptions{"retries": "5"}
+	ts.Equal("5", opts.lookupopt("SUMOLOGIC_RETRIES"))
+	ts.Equal("500", opts.lookupopt("SUMOLOGIC_TIMEOUT_MS"))
+	ts.Equal("3", envOptions.lookupopt("SUMOLOGIC_RETRIES"))
+}
+
+func (ts *TestSuite) Test_buildConfig_with_route_profile() {
+	config := buildConfig(&router.Route{
+		Options: map[string]string{"profile": "low"}})
+	ts.EqualValues(5, config.retries)
+}
+
+func (ts *TestSuite) Test_NewAdapter_with_route_endpoints() {
+	first := ts.mkAdapter(&router.Route{ID: "first", Options: map[string]string{
+		"endpoint": "https://collectors.sumologic.com/receiver/v1/http/first"}})
+	second := ts.mkAdapter(&router.Route{ID: "second", Options: map[string]string{
+		"endpoint": "https://collectors.sumologic.com/receiver/v1/http/second"}})
+	ts.Equal("https://collectors.sumologic.com/receiver/v1/http/first",
+		first.config().endPoint)
+	ts.Equal("https://collectors.sumologic.com/receiver/v1/http/second",
+		second.config().endPoint)
+}
+
 func (ts *TestSuite) Test_NewAdapter_with_env_vars() {
 	expectedEndpoint := "https://foo.collector.io/receiver/v1/http/Zm9vCg=="
 	ts.Setenv("SUMOLOGIC_ENDPOINT", expectedEndpoint)
@@ -238,8 +414,9 @@ func (ts *TestSuite) Test_NewAdapter_with_env_vars() {
 	}
 
 	adapter := ts.WithoutError(NewAdapter(route)).(*Adapter)
+	ts.AddCleanup(adapter.Close)
 	ts.Equal(route, adapter.route)
-	ts.Equal(expectedEndpoint, adapter.config.endPoint)
+	ts.Equal(expectedEndpoint, adapter.config().endPoint)
 	// TODO: More assertions?
 }
 
@@ -252,20 +429,53 @@ func (ts *TestSuite) Test_NewAdapter_without_env_vars() {
 	}
 
 	adapter := ts.WithoutError(NewAdapter(route)).(*Adapter)
+	ts.AddCleanup(adapter.Close)
 	ts.Equal(route, adapter.route)
-	ts.Equal(expectedEndpoint, adapter.config.endPoint)
+	ts.Equal(expectedEndpoint, adapter.config().endPoint)
 	// TODO: More assertions?
 }
 
+func (ts *TestSuite) Test_Reload_swaps_config() {
+	requests := make(chan *RequestData, 2)
+	adapter := ts.FakeSumo(requests)
+	before := adapter.config()
+	msg := mkContainerMessage("abc", "foo")
+
+	ts.NoError(adapter.Send(msg))
+	ts.Equal("foo", (<-requests).Headers["X-Sumo-Name"])
+
+	ts.Setenv("SUMOLOGIC_SOURCE_NAME", "app-{{.Container.Name}}")
+	adapter.Reload()
+	ts.NoError(adapter.Send(msg))
+	ts.Equal("app-foo", (<-requests).Headers["X-Sumo-Name"])
+	ts.Equal("{{.Container.Name}}", before.sourceName)
+}
+
+func (ts *TestSuite) Test_Reload_while_sending() {
+	requests := make(chan *RequestData, 100)
+	adapter := ts.FakeSumo(requests)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			adapter.Reload()
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		ts.NoError(adapter.Send(mkContainerMessage("abc", "foo")))
+	}
+	<-done
+}
+
 func (ts *TestSuite) Test_renderTemplate_with_empty_string() {
 	msg := &router.Message{}
-	value := ts.WithoutError(renderTemplate(msg, ""))
+	value := ts.WithoutError(renderTemplate(msg, nil, ""))
 	ts.Equal("", value)
 }
 
 func (ts *TestSuite) Test_renderTemplate_with_non_empty_string() {
 	msg := &router.Message{}
-	value := ts.WithoutError(renderTemplate(msg, "foo"))
+	value := ts.WithoutError(renderTemplate(msg, nil, "foo"))
 	ts.Equal("foo", value)
 }
 
@@ -273,13 +483,43 @@ func (ts *TestSuite) Test_renderTemplate_with_template_string() {
 	msg := &router.Message{
 		Container: &docker.Container{Name: "foo"},
 	}
-	value := ts.WithoutError(renderTemplate(msg, "{{.Container.Name}}"))
+	value := ts.WithoutError(renderTemplate(msg, nil, "{{.Container.Name}}"))
 	ts.Equal("foo", value)
 }
 
+func (ts *TestSuite) Test_renderTemplate_with_route() {
+	msg := &router.Message{
+		Container: &docker.Container{Name: "foo"},
+	}
+	route := &router.Route{
+		ID:      "abc123",
+		Address: "https://collectors.sumologic.com/receiver/v1/http/Zm9vCg==",
+		Options: map[string]string{"env": "prod"},
+	}
+	value := ts.WithoutError(renderTemplate(msg, route,
+		`{{.Route.ID}}/{{.Route.Host}}/{{index .Route.Options "env"}}/`+
+			`{{.Container.Name}}`))
+	ts.Equal("abc123/collectors.sumologic.com/prod/foo", value)
+}
+
+func (ts *TestSuite) Test_renderTemplate_without_route() {
+	msg := &router.Message{}
+	value := ts.WithoutError(renderTemplate(msg, nil, "[{{.Route.ID}}]"))
+	ts.Equal("[]", value)
+}
+
+func (ts *TestSuite) Test_buildHeaders_with_route_template() {
+	ts.Setenv("SUMOLOGIC_SOURCE_NAME", "{{.Route.ID}}/{{.Container.Name}}")
+	msg := &router.Message{
+		Container: &docker.Container{Name: "foo", Config: &docker.Config{}},
+	}
+	headers := buildHeaders(msg, buildConfig(&router.Route{ID: "route1"}))
+	ts.Equal("route1/foo", headers.Get("X-Sumo-Name"))
+}
+
 func (ts *TestSuite) Test_renderTemplate_with_bad_template_string() {
 	msg := &router.Message{}
-	value, err := renderTemplate(msg, "{{")
+	value, err := renderTemplate(msg, nil, "{{")
 	ts.EqualError(err,
 		"Couldn't parse sumologic source template. template: "+
 			"info:1: unexpected unclosed action in command")
@@ -288,7 +528,7 @@ func (ts *TestSuite) Test_renderTemplate_with_bad_template_string() {
 
 func (ts *TestSuite) Test_renderTemplate_with_render_error() {
 	msg := &router.Message{}
-	value, err := renderTemplate(msg, "{{.Container.Name}}")
+	value, err := renderTemplate(msg, nil, "{{.Container.Name}}")
 	ts.EqualError(err,
 		"template: info:1:12: executing \"info\" at <.Container.Name>: "+
 			"can't evaluate field Name in type *docker.Container")
@@ -297,14 +537,32 @@ func (ts *TestSuite) Test_renderTemplate_with_render_error() {
 
 func (ts *TestSuite) Test_buildData_with_empty_message() {
 	msg := &router.Message{}
-	ts.Panics(func() { buildData(msg) })
+	data := buildData(msg, buildConfig(&router.Route{}))
+	ts.Equal("", data.Container.Name)
+	ts.Equal("", data.Container.Hostname)
+	ts.True(data.MetadataMissing)
 }
 
 func (ts *TestSuite) Test_buildData_with_empty_container() {
 	msg := &router.Message{
-		Container: &docker.Container{},
+		Container: &docker.Container{Name: "foo"},
+	}
+	data := buildData(msg, buildConfig(&router.Route{}))
+	ts.Equal("foo", data.Container.Name)
+	ts.Equal("", data.Container.Image)
+	ts.True(data.MetadataMissing)
+}
+
+func (ts *TestSuite) Test_buildData_with_empty_container_and_placeholder() {
+	ts.Setenv("SUMOLOGIC_MISSING_METADATA_PLACEHOLDER", "unknown")
+	msg := &router.Message{
+		Container: &docker.Container{Name: "foo"},
 	}
-	ts.Panics(func() { buildData(msg) })
+	data := buildData(msg, buildConfig(&router.Route{}))
+	ts.Equal("foo", data.Container.Name)
+	ts.Equal("unknown", data.Container.Image)
+	ts.Equal("unknown", data.Container.Hostname)
+	ts.True(data.MetadataMissing)
 }
 
 func (ts *TestSuite) Test_buildData_with_simple_message() {
@@ -315,9 +573,49 @@ func (ts *TestSuite) Test_buildData_with_simple_message() {
 			Config: &docker.Config{},
 		},
 	}
-	data := buildData(msg)
+	data := buildData(msg, buildConfig(&router.Route{}))
 	ts.Equal("foo", data.Container.Name)
 	ts.Equal("Some data.", data.Message)
+	ts.False(data.MetadataMissing)
+}
+
+func (ts *TestSuite) Test_buildData_trims_container_slash() {
+	msg := mkContainerMessage("abc", "/foo")
+	ts.Equal("foo", buildData(msg, buildConfig(&router.Route{})).Container.Name)
+
+	ts.Setenv("SUMOLOGIC_TRIM_CONTAINER_SLASH", "false")
+	ts.Equal("/foo", buildData(msg, buildConfig(&router.Route{})).Container.Name)
+}
+
+func (ts *TestSuite) Test_buildHeaders_trims_container_slash() {
+	msg := mkContainerMessage("abc", "/foo")
+	ts.Equal("foo",
+		buildHeaders(msg, buildConfig(&router.Route{})).Get("X-Sumo-Name"))
+
+	ts.Setenv("SUMOLOGIC_SOURCE_NAME", "app-{{.Container.Name | trimSlash}}")
+	ts.Equal("app-foo",
+		buildHeaders(msg, buildConfig(&router.Route{})).Get("X-Sumo-Name"))
+
+	ts.Setenv("SUMOLOGIC_SOURCE_NAME", "{{.Container.Name}}")
+	ts.Setenv("SUMOLOGIC_TRIM_CONTAINER_SLASH", "false")
+	ts.Equal("/foo",
+		buildHeaders(msg, buildConfig(&router.Route{})).Get("X-Sumo-Name"))
+}
+
+func (ts *TestSuite) Test_buildData_with_field_placeholders() {
+	ts.Setenv("SUMOLOGIC_FIELD_PLACEHOLDERS",
+		"docker_hostname=unknown,docker_image=none")
+	msg := &router.Message{
+		Container: &docker.Container{
+			Name:   "foo",
+			Config: &docker.Config{Image: "alpine"},
+		},
+	}
+	data := buildData(msg, buildConfig(&router.Route{}))
+	ts.Equal("foo", data.Container.Name)
+	ts.Equal("alpine", data.Container.Image)
+	ts.Equal("unknown", data.Container.Hostname)
+	ts.Equal("", data.Container.ID)
 }
 
 func (ts *TestSuite) Test_buildHeaders_with_empty_message() {
@@ -359,6 +657,51 @@ func (ts *TestSuite) Test_buildHeaders_with_all_fields() {
 	ts.Equal(expectedHeaders, headers)
 }
 
+func (ts *TestSuite) Test_buildHeaders_with_container_overrides() {
+	expectedHeaders := http.Header{}
+	expectedHeaders.Add("X-Sumo-Name", "app-foo")
+	expectedHeaders.Add("X-Sumo-Host", "example.com")
+	expectedHeaders.Add("X-Sumo-Category", "team/app")
+
+	ts.Setenv("SUMOLOGIC_SOURCE_CATEGORY", "feline")
+	msg := &router.Message{
+		Container: &docker.Container{
+			Name: "foo",
+			Config: &docker.Config{
+				Hostname: "example.com",
+				Env: []string{
+					"PATH=/bin",
+					"SUMOLOGIC_SOURCE_NAME=app-{{.Container.Name}}",
+					"SUMOLOGIC_SOURCE_CATEGORY=team/app",
+					"SUMOLOGIC_SOURCE_HOST=",
+				},
+			},
+		},
+	}
+	config := buildConfig(&router.Route{})
+	headers := buildHeaders(msg, config)
+	ts.Equal(expectedHeaders, headers)
+	ts.Equal("feline", config.sourceCategory)
+}
+
+func (ts *TestSuite) Test_buildHeaders_with_container_overrides_disabled() {
+	expectedHeaders := http.Header{}
+	expectedHeaders.Add("X-Sumo-Name", "foo")
+	expectedHeaders.Add("X-Sumo-Host", "")
+
+	ts.Setenv("SUMOLOGIC_CONTAINER_OVERRIDES", "false")
+	msg := &router.Message{
+		Container: &docker.Container{
+			Name: "foo",
+			Config: &docker.Config{
+				Env: []string{"SUMOLOGIC_SOURCE_CATEGORY=team/app"},
+			},
+		},
+	}
+	headers := buildHeaders(msg, buildConfig(&router.Route{}))
+	ts.Equal(expectedHeaders, headers)
+}
+
 func (ts *TestSuite) Test_sendLog_empty_message() {
 	expectedRequestData := []RequestData{
 		{
@@ -421,7 +764,7 @@ func (ts *TestSuite) Test_sendLog_simple_message() {
 func (ts *TestSuite) Test_sendLog_no_server() {
 	hook, _ := ts.CaptureLogs()
 
-	adapter := ts.mkAdapter(&router.Route{})
+	adapter := ts.mkAdapter(&router.Route{Address: noServer})
 
 	msg := &router.Message{
 		Container: &docker.Container{
@@ -434,7 +777,93 @@ func (ts *TestSuite) Test_sendLog_no_server() {
 	ts.Equal("Failed to send log to Sumologic", hook.LastEntry().Message)
 }
 
+func (ts *TestSuite) Test_sendLog_no_container() {
+	expectedRequestData := []RequestData{
+		{
+			Headers: map[string]string{},
+			Body: mkExpectedBody(jsonobj{
+				"message":          "Some data.",
+				"metadata_missing": true,
+			}),
+		},
+	}
+	requests := make(chan *RequestData, len(expectedRequestData))
+	adapter := ts.FakeSumo(requests)
+
+	adapter.sendLog(&router.Message{Data: "Some data."})
+	ts.verifyExpectedRequests(expectedRequestData, requests)
+}
+
+func (ts *TestSuite) Test_sendLog_recovers_from_panic() {
+	hook, _ := ts.CaptureLogs()
+	requests := make(chan *RequestData, 1)
+	adapter := ts.FakeSumo(requests)
+	adapter.client = &panicOnceClient{doer: adapter.client}
+
+	ts.NotPanics(func() { adapter.sendLog(&router.Message{}) })
+	ts.EqualValues(1, adapter.panics)
+	ts.Equal(logrus.ErrorLevel, hook.LastEntry().Level)
+	ts.Equal("Recovered from panic", hook.LastEntry().Message)
+	ts.Equal("sendLog", hook.LastEntry().Data["stage"])
+	ts.Len(requests, 0)
+}
+
+func (ts *TestSuite) Test_sendLog_panic_sends_diagnostic_event() {
+	ts.CaptureLogs()
+	ts.Setenv("SUMOLOGIC_DIAGNOSTIC_EVENTS", "true")
+	ts.Setenv("SUMOLOGIC_DIAGNOSTIC_CATEGORY", "logspout/diagnostics")
+	requests := make(chan *RequestData, 1)
+	adapter := ts.FakeSumo(requests)
+	adapter.client = &panicOnceClient{doer: adapter.client}
+
+	adapter.sendLog(&router.Message{})
+
+	select {
+	case req := <-requests:
+		ts.Equal(map[string]string{
+			"X-Sumo-Name":     "logspout-sumologic",
+			"X-Sumo-Category": "logspout/diagnostics",
+		}, req.Headers)
+		ts.Contains(req.Body["message"], "recovered from panic in sendLog")
+		ts.Nil(req.Body["container"])
+	case <-time.After(100 * time.Millisecond):
+		ts.Fail("Timeout waiting for diagnostic event.")
+	}
+}
+
+func (ts *TestSuite) Test_Close_cancels_inflight_requests() {
+	hook, _ := ts.CaptureLogs()
+	release := make(chan struct{})
+	ts.Setenv("SUMOLOGIC_RETRIES", "0")
+	adapter := ts.adapterFor(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+		}), newRealClock())
+	ts.AddCleanup(func() { close(release) })
+
+	done := make(chan struct{})
+	go func() {
+		adapter.sendLog(&router.Message{})
+		close(done)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	adapter.Close()
+
+	select {
+	case <-done:
+		ts.Equal("Failed to send log to Sumologic", hook.LastEntry().Message)
+		ts.Contains(hook.LastEntry().Data["error"].(error).Error(),
+			context.Canceled.Error())
+	case <-time.After(time.Second):
+		ts.Fail("Timeout waiting for request to be cancelled.")
+	}
+}
+
 func (ts *TestSuite) Test_Stream_empty_message() {
+	ts.Setenv("SUMOLOGIC_SKIP_EMPTY", "false")
 	expectedRequestData := []RequestData{
 		{
 			Headers: map[string]string{
@@ -461,6 +890,7 @@ func (ts *TestSuite) Test_Stream_empty_message() {
 }
 
 func (ts *TestSuite) Test_Stream_two_messages() {
+	ts.Setenv("SUMOLOGIC_SKIP_EMPTY", "false")
 	expectedRequestData := []RequestData{
 		{
 			Headers: map[string]string{
@@ -518,6 +948,63 @@ func (ts *TestSuite) Test_Stream_two_messages() {
 	ts.verifyExpectedRequests(expectedRequestData, requests)
 }
 
+func (ts *TestSuite) Test_Stream_skips_blank_messages() {
+	expectedRequestData := []RequestData{
+		{
+			Headers: map[string]string{
+				"X-Sumo-Name": "",
+				"X-Sumo-Host": "",
+			},
+			Body: mkExpectedBody(jsonobj{"message": "Some data."}),
+		},
+	}
+	requests := make(chan *RequestData, 3)
+	adapter := ts.FakeSumo(requests)
+
+	ch := make(chan *router.Message)
+	go adapter.Stream(ch)
+
+	for _, data := range []string{"", " \t ", "Some data."} {
+		ch <- &router.Message{
+			Data:      data,
+			Container: &docker.Container{Config: &docker.Config{}},
+		}
+	}
+
+	close(ch)
+	ts.verifyExpectedRequests(expectedRequestData, requests)
+	ts.Len(requests, 0)
+	ts.EqualValues(2, adapter.Status().Dropped)
+}
+
+func (ts *TestSuite) Test_dropReason_min_length() {
+	ts.Setenv("SUMOLOGIC_SKIP_EMPTY", "false")
+	ts.Setenv("SUMOLOGIC_MIN_MESSAGE_LENGTH", "3")
+	adapter := ts.mkAdapter(&router.Route{Address: noServer})
+
+	ts.Equal("too short", adapter.dropReason(&router.Message{Data: ""}))
+	ts.Equal("too short", adapter.dropReason(&router.Message{Data: " .. "}))
+	ts.Equal("too short", adapter.dropReason(&router.Message{Data: "éé"}))
+	ts.Equal("", adapter.dropReason(&router.Message{Data: "ééé"}))
+	ts.Equal("", adapter.dropReason(&router.Message{Data: "Some data."}))
+}
+
+func (ts *TestSuite) Test_dropReason_min_length_unset() {
+	ts.Setenv("SUMOLOGIC_SKIP_EMPTY", "false")
+	adapter := ts.mkAdapter(&router.Route{Address: noServer})
+	ts.Equal("", adapter.dropReason(&router.Message{Data: ""}))
+	ts.Equal("", adapter.dropReason(&router.Message{Data: "."}))
+}
+
+func (ts *TestSuite) Test_accept_recovers_from_panic() {
+	hook, _ := ts.CaptureLogs()
+	adapter := ts.mkAdapter(&router.Route{Address: noServer})
+
+	ts.False(adapter.accept(nil))
+	ts.Equal("Recovered from panic", hook.LastEntry().Message)
+	ts.Equal("accept", hook.LastEntry().Data["stage"])
+}
+
 // Some HTTP test helper things.
 
 func getobj(obj jsonobj, k string) jsonobj {
@@ -0,0 +1,261 @@
+package sumologic
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+// cronField is the set of values a single cron field matches.
+type cronField map[int]bool
+
+// cronSchedule is a standard five-field cron expression: minute, hour, day of
+// month, month and day of week.
+type cronSchedule struct {
+	minute, hour, dom, month, dow cronField
+	anyDom, anyDow                bool
+}
+
+// parseCron parses a five-field cron expression. Each field may be "*", a
+// number, a range "a-b", either of those with a step "/n", or a
+// comma-separated list of any of them.
+func parseCron(fields []string) (cronSchedule, error) {
+	if len(fields) != 5 {
+		return cronSchedule{}, fmt.Errorf("expected 5 cron fields, got %d", len(fields))
+	}
+	var s cronSchedule
+	var err error
+	parsers := []struct {
+		field    *cronField
+		min, max int
+	}{
+		{&s.minute, 0, 59},
+		{&s.hour, 0, 23},
+		{&s.dom, 1, 31},
+		{&s.month, 1, 12},
+		{&s.dow, 0, 7},
+	}
+	for i, p := range parsers {
+		if *p.field, err = parseCronField(fields[i], p.min, p.max); err != nil {
+			return cronSchedule{}, err
+		}
+	}
+	// Sunday is both 0 and 7.
+	if s.dow[7] {
+		s.dow[0] = true
+	}
+	s.anyDom = fields[2] == "*"
+	s.anyDow = fields[4] == "*"
+	return s, nil
+}
+
+func parseCronField(text string, min int, max int) (cronField, error) {
+	field := cronField{}
+	for _, part := range strings.Split(text, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("bad step in cron field %q", text)
+			}
+			step = n
+			part = part[:i]
+		}
+		lo, hi := min, max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, fmt.Errorf("bad cron field %q", text)
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = strconv.Atoi(bounds[1]); err != nil {
+					return nil, fmt.Errorf("bad cron field %q", text)
+				}
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return nil, fmt.Errorf("cron field %q out of range", text)
+		}
+		for v := lo; v <= hi; v += step {
+			field[v] = true
+		}
+	}
+	return field, nil
+}
+
+// matches reports whether the schedule fires in the minute containing t. As
+// in cron, if both the day of month and day of week are restricted, either
+// one matching is enough.
+func (s cronSchedule) matches(t time.Time) bool {
+	if !s.minute[t.Minute()] || !s.hour[t.Hour()] || !s.month[int(t.Month())] {
+		return false
+	}
+	dom, dow := s.dom[t.Day()], s.dow[int(t.Weekday())]
+	if s.anyDom || s.anyDow {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// suppressionRule drops (or heavily samples) the messages from matching
+// containers or categories for a while each time its schedule fires.
+type suppressionRule struct {
+	schedule  cronSchedule
+	window    time.Duration
+	container string
+	category  string
+	sample    int64
+
+	mu          sync.Mutex
+	checked     time.Time
+	activeUntil time.Time
+	seen        int64
+}
+
+// getsuppressionrulesopt retrieves an environment variable as a list of
+// suppression rules if it's set to a non-empty string of semicolon-separated
+// rules. Each rule is a cron schedule, how long the suppression window lasts,
+// and what it applies to, e.g. "0 2 * * * 90m container=nightly-*" or
+// "*/30 * * * 1-5 5m category=batch/* sample=100". Rules that can't be parsed
+// are logged and ignored.
+func (o routeOptions) getsuppressionrulesopt(name string) []*suppressionRule {
+	var rules []*suppressionRule
+	for _, text := range strings.Split(o.lookupopt(name), ";") {
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		rule, err := parseSuppressionRule(strings.Fields(text))
+		if err != nil {
+			parseFailed(name, text, err)
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+func parseSuppressionRule(fields []string) (*suppressionRule, error) {
+	if len(fields) < 7 {
+		return nil, fmt.Errorf("expected a schedule, a duration and a match")
+	}
+	schedule, err := parseCron(fields[:5])
+	if err != nil {
+		return nil, err
+	}
+	window, err := time.ParseDuration(fields[5])
+	if err != nil || window <= 0 {
+		return nil, fmt.Errorf("bad duration %q", fields[5])
+	}
+	rule := &suppressionRule{schedule: schedule, window: window}
+	for _, option := range fields[6:] {
+		kv := strings.SplitN(option, "=", 2)
+		if len(kv) != 2 || kv[1] == "" {
+			return nil, fmt.Errorf("bad option %q", option)
+		}
+		switch kv[0] {
+		case "container":
+			rule.container = kv[1]
+		case "category":
+			rule.category = kv[1]
+		case "sample":
+			if rule.sample, err = strconv.ParseInt(kv[1], 10, 64); err != nil ||
+				rule.sample <= 0 {
+				return nil, fmt.Errorf("bad sample rate %q", kv[1])
+			}
+		default:
+			return nil, fmt.Errorf("unknown option %q", option)
+		}
+		if _, err = path.Match(kv[1], ""); err != nil {
+			return nil, err
+		}
+	}
+	if rule.container == "" && rule.category == "" {
+		return nil, fmt.Errorf("expected a container or category to match")
+	}
+	return rule, nil
+}
+
+// active reports whether a suppression window is open at the given time.
+// Each minute since the last check (or in the last window, at first) is
+// checked against the schedule at most once.
+func (r *suppressionRule) active(now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	minute := now.Truncate(time.Minute)
+	from := minute.Add(-r.window).Truncate(time.Minute).Add(time.Minute)
+	if next := r.checked.Add(time.Minute); next.After(from) {
+		from = next
+	}
+	for m := from; !m.After(minute); m = m.Add(time.Minute) {
+		if r.schedule.matches(m) {
+			r.activeUntil = m.Add(r.window)
+		}
+	}
+	if minute.After(r.checked) {
+		r.checked = minute
+	}
+	return now.Before(r.activeUntil)
+}
+
+// matches reports whether the rule applies to a container name and category.
+func (r *suppressionRule) matches(container string, category string) bool {
+	if r.container != "" {
+		if ok, _ := path.Match(r.container, container); !ok {
+			return false
+		}
+	}
+	if r.category != "" {
+		if ok, _ := path.Match(r.category, category); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// suppress reports whether a matching message should be dropped. Without a
+// sample rate, every message is; otherwise the first of every sample
+// messages is kept.
+func (r *suppressionRule) suppress() bool {
+	if r.sample == 0 {
+		return true
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	keep := r.seen%r.sample == 0
+	r.seen++
+	return !keep
+}
+
+// suppressed reports whether a message should be dropped because it falls in
+// an open suppression window.
+func (s *Adapter) suppressed(msg *router.Message, config *Config) bool {
+	if len(config.suppressions) == 0 {
+		return false
+	}
+	now := s.clock.Now()
+	name := ""
+	if msg.Container != nil {
+		name = strings.TrimPrefix(msg.Container.Name, "/")
+	}
+	category, categoryKnown := "", false
+	for _, rule := range config.suppressions {
+		if !rule.active(now) {
+			continue
+		}
+		if rule.category != "" && !categoryKnown {
+			category = s.headers(msg, config).Get("X-Sumo-Category")
+			categoryKnown = true
+		}
+		if rule.matches(name, category) {
+			return rule.suppress()
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package sumologic
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gliderlabs/logspout/router"
+	"github.com/sirupsen/logrus"
+)
+
+func (ts *TestSuite) Test_parseCron() {
+	s := ts.WithoutError(parseCron(strings.Fields("*/15 13 * * 1-5"))).(cronSchedule)
+	ts.True(s.matches(mkTime(0)))          // Tue 13:00
+	ts.True(s.matches(mkTime(15 * 60)))    // Tue 13:15
+	ts.False(s.matches(mkTime(5 * 60)))    // Tue 13:05
+	ts.False(s.matches(mkTime(3600)))      // Tue 14:00
+	ts.False(s.matches(mkTime(4 * 86400))) // Sat 13:00
+
+	s = ts.WithoutError(parseCron(strings.Fields("0 13 1,2 * 7"))).(cronSchedule)
+	ts.True(s.matches(mkTime(0)))          // the 2nd
+	ts.True(s.matches(mkTime(5 * 86400)))  // Sunday the 7th
+	ts.False(s.matches(mkTime(1 * 86400))) // Wednesday the 3rd
+
+	for _, bad := range []string{
+		"* * * *", "60 * * * *", "* 5-2 * * *", "*/0 * * * *", "a * * * *",
+	} {
+		_, err := parseCron(strings.Fields(bad))
+		ts.Error(err, bad)
+	}
+}
+
+func (ts *TestSuite) Test_suppressionRule_active() {
+	rule := ts.WithoutError(parseSuppressionRule(
+		strings.Fields("30 13 * * * 90m container=x"))).(*suppressionRule)
+	ts.False(rule.active(mkTime(29 * 60)))
+	ts.True(rule.active(mkTime(30 * 60)))
+	ts.True(rule.active(mkTime(119*60 + 59)))
+	ts.False(rule.active(mkTime(120 * 60)))
+
+	// A window that opened before the first check is still found.
+	rule = ts.WithoutError(parseSuppressionRule(
+		strings.Fields("30 13 * * * 90m container=x"))).(*suppressionRule)
+	ts.True(rule.active(mkTime(100 * 60)))
+	ts.False(rule.active(mkTime(86400)))
+}
+
+func (ts *TestSuite) Test_getsuppressionrulesopt() {
+	hook, _ := ts.CaptureLogs()
+	ts.Setenv("SET_ENV_VAR", "0 2 * * * 90m container=nightly-*;"+
+		"*/30 * * * 1-5 5m category=batch/* sample=100;"+
+		"0 2 * * * 90m;0 2 * * * soon container=x;0 2 * * * 1h sample=0 container=x;"+
+		"0 2 * * * 1h colour=red;0 2 * * * 1h container=[")
+	rules := envOptions.getsuppressionrulesopt("SET_ENV_VAR")
+	ts.Len(rules, 2)
+	ts.Equal("nightly-*", rules[0].container)
+	ts.Equal(90*time.Minute, rules[0].window)
+	ts.Equal("batch/*", rules[1].category)
+	ts.EqualValues(100, rules[1].sample)
+	ts.Len(hook.AllEntries(), 5)
+	ts.Equal(logrus.ErrorLevel, hook.LastEntry().Level)
+	ts.Equal("Failed to parse", hook.LastEntry().Message)
+}
+
+func (ts *TestSuite) Test_dropReason_suppressed() {
+	ts.Setenv("SUMOLOGIC_SUPPRESSION_RULES",
+		"0 13 * * * 1h container=nightly-*;0 13 * * * 1h category=batch/* sample=3")
+	clock := newFakeClock()
+	adapter := ts.WithoutError(NewAdapterWithClock(
+		&router.Route{Address: noServer}, clock)).(*Adapter)
+	ts.AddCleanup(adapter.Close)
+
+	ts.Equal("suppressed", adapter.dropReason(
+		mkContainerMessage("abc", "/nightly-report")))
+	ts.Equal("", adapter.dropReason(mkContainerMessage("abc", "/web")))
+
+	batch := mkContainerMessage("def", "/job")
+	batch.Container.Config.Env = []string{"SUMOLOGIC_SOURCE_CATEGORY=batch/jobs"}
+	var reasons []string
+	for i := 0; i < 4; i++ {
+		reasons = append(reasons, adapter.dropReason(batch))
+	}
+	ts.Equal([]string{"", "suppressed", "suppressed", ""}, reasons)
+
+	clock.Advance(time.Hour)
+	ts.Equal("", adapter.dropReason(
+		mkContainerMessage("abc", "/nightly-report")))
+}
@@ -0,0 +1,37 @@
+package sumologic
+
+import (
+	"github.com/gliderlabs/logspout/router"
+)
+
+// Labels swarm sets on the containers it runs for a service's tasks.
+const (
+	swarmServiceLabel = "com.docker.swarm.service.name"
+	swarmTaskLabel    = "com.docker.swarm.task.id"
+	swarmNodeLabel    = "com.docker.swarm.node.id"
+)
+
+// SwarmData holds the service and task a container belongs to, for
+// containers run by swarm.
+type SwarmData struct {
+	ServiceName string `json:"service_name"`
+	TaskID      string `json:"task_id"`
+	NodeID      string `json:"node_id"`
+}
+
+// swarmMetadata returns the service and task a message's container belongs
+// to, or nil if swarm isn't running it.
+func swarmMetadata(msg *router.Message) *SwarmData {
+	if msg.Container == nil || msg.Container.Config == nil {
+		return nil
+	}
+	labels := msg.Container.Config.Labels
+	if labels[swarmServiceLabel] == "" {
+		return nil
+	}
+	return &SwarmData{
+		ServiceName: labels[swarmServiceLabel],
+		TaskID:      labels[swarmTaskLabel],
+		NodeID:      labels[swarmNodeLabel],
+	}
+}
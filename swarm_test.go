@@ -0,0 +1,57 @@
+package sumologic
+
+import (
+	"encoding/json"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+// mkSwarmMessage returns a message from a container running one of a swarm
+// service's tasks.
+func mkSwarmMessage() *router.Message {
+	msg := mkContainerMessage("abc", "/web.1.x2x9q")
+	msg.Container.Config.Labels = map[string]string{
+		swarmServiceLabel: "web",
+		swarmTaskLabel:    "x2x9qlhk7i3n",
+		swarmNodeLabel:    "n0d3id",
+	}
+	return msg
+}
+
+func (ts *TestSuite) Test_swarmMetadata() {
+	ts.Equal(&SwarmData{
+		ServiceName: "web", TaskID: "x2x9qlhk7i3n", NodeID: "n0d3id",
+	}, swarmMetadata(mkSwarmMessage()))
+}
+
+func (ts *TestSuite) Test_swarmMetadata_not_swarm() {
+	ts.Nil(swarmMetadata(mkContainerMessage("abc", "/foo")))
+	ts.Nil(swarmMetadata(&router.Message{}))
+	msg := mkContainerMessage("abc", "/foo")
+	msg.Container.Config.Labels = map[string]string{swarmTaskLabel: "x2x9q"}
+	ts.Nil(swarmMetadata(msg))
+}
+
+func (ts *TestSuite) Test_buildData_swarm_fields() {
+	var body jsonobj
+	ts.NoError(json.Unmarshal(ts.WithoutError(json.Marshal(
+		buildData(mkSwarmMessage(), buildConfig(&router.Route{})))).([]byte), &body))
+	ts.Equal("web", body["service_name"])
+	ts.Equal("x2x9qlhk7i3n", body["task_id"])
+	ts.Equal("n0d3id", body["node_id"])
+
+	plain := buildData(mkContainerMessage("abc", "/foo"), buildConfig(&router.Route{}))
+	ts.NotContains(string(ts.WithoutError(json.Marshal(plain)).([]byte)), "service_name")
+}
+
+func (ts *TestSuite) Test_buildHeaders_swarm_templates() {
+	ts.Setenv("SUMOLOGIC_SOURCE_CATEGORY", "prod/{{.Swarm.ServiceName}}")
+	ts.Setenv("SUMOLOGIC_SOURCE_NAME", "{{.Swarm.ServiceName}}.{{.Swarm.TaskID}}")
+	config := buildConfig(&router.Route{})
+	headers := buildHeaders(mkSwarmMessage(), config)
+	ts.Equal("prod/web", headers.Get("X-Sumo-Category"))
+	ts.Equal("web.x2x9qlhk7i3n", headers.Get("X-Sumo-Name"))
+
+	headers = buildHeaders(mkContainerMessage("abc", "/foo"), config)
+	ts.Equal("prod/", headers.Get("X-Sumo-Category"))
+}
@@ -0,0 +1,160 @@
+package sumologic
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"text/template"
+	"text/template/parse"
+)
+
+// maxCachedTemplates caps how many compiled templates are kept. Templates
+// come from the config and from containers overriding it, so there are
+// normally only a handful; any beyond the cap are compiled each time
+// they're used.
+const maxCachedTemplates = 1000
+
+// templateFuncs are the helper functions available to templates, on top of
+// text/template's builtins. label and env depend on the message being
+// rendered, so they're only placeholders here; bindContextFuncs turns calls
+// to them into calls to the template context's Label and Env methods.
+var templateFuncs = template.FuncMap{
+	"label": func(string) string { return "" },
+	"env":   func(string) string { return "" },
+	"lower": strings.ToLower,
+	// The value being worked on comes last, so that these can be used in
+	// pipelines, e.g. {{.Container.Name | trimPrefix "/" | replace "_" "-"}}.
+	"replace": func(old, new, s string) string {
+		return strings.Replace(s, old, new, -1)
+	},
+	"trimPrefix": func(prefix, s string) string {
+		return strings.TrimPrefix(s, prefix)
+	},
+	"trimSlash": func(s string) string {
+		return strings.TrimPrefix(s, "/")
+	},
+	"default": func(dfault, s string) string {
+		if s == "" {
+			return dfault
+		}
+		return s
+	},
+	"json": func(v interface{}) (string, error) {
+		encoded, err := json.Marshal(v)
+		return string(encoded), err
+	},
+}
+
+// contextFuncs maps the template functions that look things up on the
+// message being rendered to the template context methods that implement
+// them.
+var contextFuncs = map[string]string{"label": "Label", "env": "Env"}
+
+// Label returns one of the labels of the container the message came from, or
+// "" if it doesn't have it. Templates call it as label.
+func (c *templateContext) Label(key string) string {
+	if c.Container == nil || c.Container.Config == nil {
+		return ""
+	}
+	return c.Container.Config.Labels[key]
+}
+
+// Env returns one of the variables in the environment of the container the
+// message came from, or "" if it doesn't have it. Templates call it as env.
+func (c *templateContext) Env(name string) string {
+	return containerEnv(c.Message)[name]
+}
+
+// bindContextFuncs rewrites the calls to contextFuncs in a parsed template
+// into calls to the methods on the template context, i.e. {{label "x"}} into
+// {{$.Label "x"}}, so that the compiled template can be shared by every
+// message rather than having the functions bound to each one.
+func bindContextFuncs(node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			bindContextFuncs(child)
+		}
+	case *parse.ActionNode:
+		bindContextFuncs(n.Pipe)
+	case *parse.IfNode:
+		bindContextFuncs(&n.BranchNode)
+	case *parse.RangeNode:
+		bindContextFuncs(&n.BranchNode)
+	case *parse.WithNode:
+		bindContextFuncs(&n.BranchNode)
+	case *parse.BranchNode:
+		bindContextFuncs(n.Pipe)
+		bindContextFuncs(n.List)
+		bindContextFuncs(n.ElseList)
+	case *parse.TemplateNode:
+		bindContextFuncs(n.Pipe)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			bindContextFuncs(cmd)
+		}
+	case *parse.CommandNode:
+		for i, arg := range n.Args {
+			ident, ok := arg.(*parse.IdentifierNode)
+			if !ok {
+				bindContextFuncs(arg)
+				continue
+			}
+			if method, ok := contextFuncs[ident.Ident]; ok {
+				n.Args[i] = &parse.VariableNode{
+					NodeType: parse.NodeVariable,
+					Pos:      ident.Pos,
+					Ident:    []string{"$", method},
+				}
+			}
+		}
+	case *parse.ChainNode:
+		bindContextFuncs(n.Node)
+	}
+}
+
+// templates holds compiled templates by their text, so that each one is
+// only parsed once rather than for every message it's rendered for.
+var templates = &templateCache{compiled: map[string]*compiledTemplate{}}
+
+type templateCache struct {
+	mu       sync.RWMutex
+	compiled map[string]*compiledTemplate
+}
+
+// compiledTemplate is a parsed template, or the error parsing it.
+type compiledTemplate struct {
+	tmpl *template.Template
+	err  error
+}
+
+// get returns the compiled template for a template string.
+func (c *templateCache) get(text string) (*template.Template, error) {
+	c.mu.RLock()
+	compiled, ok := c.compiled[text]
+	c.mu.RUnlock()
+	if ok {
+		return compiled.tmpl, compiled.err
+	}
+
+	tmpl, err := template.New("info").Funcs(templateFuncs).Parse(text)
+	if err == nil {
+		for _, t := range tmpl.Templates() {
+			if t.Tree != nil {
+				bindContextFuncs(t.Tree.Root)
+			}
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.compiled) < maxCachedTemplates {
+		c.compiled[text] = &compiledTemplate{tmpl, err}
+	}
+	return tmpl, err
+}
@@ -0,0 +1,120 @@
+package sumologic
+
+import (
+	"fmt"
+
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+)
+
+func (ts *TestSuite) Test_templateCache_compiles_once() {
+	c := &templateCache{compiled: map[string]*compiledTemplate{}}
+	first := ts.WithoutError(c.get("{{.Container.Name}}"))
+	ts.True(first == ts.WithoutError(c.get("{{.Container.Name}}")))
+
+	_, err := c.get("{{.Container.Name")
+	ts.Error(err)
+	_, again := c.get("{{.Container.Name")
+	ts.Equal(err, again)
+	ts.Len(c.compiled, 2)
+}
+
+func (ts *TestSuite) Test_templateCache_capped() {
+	c := &templateCache{compiled: map[string]*compiledTemplate{}}
+	for i := 0; i < maxCachedTemplates+10; i++ {
+		ts.WithoutError(c.get(fmt.Sprintf("static-%d", i)))
+	}
+	ts.Len(c.compiled, maxCachedTemplates)
+}
+
+func (ts *TestSuite) Test_NewAdapter_compiles_templates() {
+	ts.Setenv("SUMOLOGIC_SOURCE_CATEGORY", "compiled/{{.Container.Name}}")
+	ts.mkAdapter(&router.Route{Address: noServer})
+	templates.mu.RLock()
+	defer templates.mu.RUnlock()
+	ts.Contains(templates.compiled, "compiled/{{.Container.Name}}")
+}
+
+func (ts *TestSuite) Test_renderTemplate_does_not_escape_values() {
+	msg := &router.Message{
+		Container: &docker.Container{Name: `/a&b <c> "d" 'e'`},
+	}
+	value := ts.WithoutError(renderTemplate(msg, nil, "{{.Container.Name}}"))
+	ts.Equal(`/a&b <c> "d" 'e'`, value)
+}
+
+func (ts *TestSuite) Test_renderTemplate_escapes_on_request() {
+	msg := &router.Message{
+		Container: &docker.Container{Name: "a&b <c>"},
+	}
+	value := ts.WithoutError(renderTemplate(msg, nil,
+		"{{html .Container.Name}}|{{urlquery .Container.Name}}"))
+	ts.Equal("a&amp;b &lt;c&gt;|a%26b+%3Cc%3E", value)
+}
+
+func (ts *TestSuite) Test_buildHeaders_with_special_characters() {
+	ts.Setenv("SUMOLOGIC_SOURCE_CATEGORY", "{{.Container.Name}}/{{.Route.ID}}")
+	msg := &router.Message{
+		Container: &docker.Container{
+			Name:   `R&D "billing" <eu>`,
+			Config: &docker.Config{Hostname: "host's"},
+		},
+	}
+	headers := buildHeaders(msg, buildConfig(&router.Route{ID: "a+b"}))
+	ts.Equal(`R&D "billing" <eu>`, headers.Get("X-Sumo-Name"))
+	ts.Equal(`R&D "billing" <eu>/a+b`, headers.Get("X-Sumo-Category"))
+	ts.Equal("host's", headers.Get("X-Sumo-Host"))
+}
+
+func (ts *TestSuite) Test_renderTemplate_functions() {
+	msg := &router.Message{
+		Container: &docker.Container{
+			Name: "/Billing_API",
+			Config: &docker.Config{
+				Labels: map[string]string{
+					"com.docker.compose.project": "Shop"},
+				Env: []string{"TEAM=payments", "EMPTY="},
+			},
+		},
+	}
+	for text, expected := range map[string]string{
+		`{{label "com.docker.compose.project" | lower}}/{{.Container.Name | trimPrefix "/"}}`: "shop/Billing_API",
+		`{{.Container.Name | trimPrefix "/" | replace "_" "-" | lower}}`:                      "billing-api",
+		`{{env "TEAM"}}`:                                          "payments",
+		`{{env "MISSING" | default "none"}}`:                      "none",
+		`{{env "EMPTY" | default "none"}}`:                        "none",
+		`{{label "missing" | default "misc"}}`:                    "misc",
+		`{{label "com.docker.compose.project" | default "misc"}}`: "Shop",
+		// They look things up on the message however the dot has moved.
+		`{{with .Container}}{{label "com.docker.compose.project"}}{{end}}`:    "Shop",
+		`{{if true}}{{printf "%s-%s" (env "TEAM") (label "missing")}}{{end}}`: "payments-",
+	} {
+		ts.Equal(expected,
+			ts.WithoutError(renderTemplate(msg, nil, text)).(string), text)
+	}
+}
+
+func (ts *TestSuite) Test_renderTemplate_functions_bound_per_message() {
+	text := `{{label "team"}}/{{env "APP"}}`
+	mkMsg := func(team, app string) *router.Message {
+		return &router.Message{Container: &docker.Container{
+			Config: &docker.Config{
+				Labels: map[string]string{"team": team},
+				Env:    []string{"APP=" + app},
+			},
+		}}
+	}
+	ts.Equal("web/shop", ts.WithoutError(
+		renderTemplate(mkMsg("web", "shop"), nil, text)).(string))
+	ts.Equal("data/etl", ts.WithoutError(
+		renderTemplate(mkMsg("data", "etl"), nil, text)).(string))
+	ts.Equal("/", ts.WithoutError(
+		renderTemplate(&router.Message{}, nil, text)).(string))
+}
+
+func (ts *TestSuite) Test_checkConfig_accepts_template_functions() {
+	ts.Setenv("SUMOLOGIC_ENDPOINT", "https://collectors.example.com/a")
+	ts.Setenv("SUMOLOGIC_SOURCE_CATEGORY",
+		`{{label "team" | default "misc" | lower}}/{{env "APP" | replace "_" "-"}}`)
+	ts.Empty(validateConfig(&router.Route{}))
+}
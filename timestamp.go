@@ -0,0 +1,68 @@
+package sumologic
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+// timestampGroup is the name of the group in SUMOLOGIC_TIMESTAMP_PATTERN that
+// matches the timestamp. The whole match is used if there isn't one.
+const timestampGroup = "timestamp"
+
+// messageTime returns when a message was logged: the timestamp embedded in
+// it, if SUMOLOGIC_TIMESTAMP_FIELD or SUMOLOGIC_TIMESTAMP_PATTERN finds one
+// that can be parsed, otherwise when logspout received it.
+func messageTime(msg *router.Message, config *Config) time.Time {
+	if config.timestampField != "" {
+		value := jsonField(msg.Data, []string{config.timestampField})
+		if t, ok := parseTimestamp(value, config.timestampFormat); ok {
+			return t
+		}
+	}
+	re := config.timestampPattern
+	if patternSet(re) {
+		if match := re.FindStringSubmatch(msg.Data); match != nil {
+			value := match[0]
+			for i, name := range re.SubexpNames() {
+				if name == timestampGroup {
+					value = match[i]
+				}
+			}
+			if t, ok := parseTimestamp(value, config.timestampFormat); ok {
+				return t
+			}
+		}
+	}
+	return msg.Time
+}
+
+// parseTimestamp parses a timestamp using a time layout, or as seconds or
+// milliseconds since the epoch if it's a number. Numbers too large to be
+// seconds in the near future are taken to be milliseconds.
+func parseTimestamp(value string, layout string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, false
+	}
+	if number, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if number >= 1e11 {
+			return time.Unix(0, number*int64(time.Millisecond)), true
+		}
+		return time.Unix(number, 0), true
+	}
+	if number, err := strconv.ParseFloat(value, 64); err == nil {
+		if number >= 1e11 {
+			number /= 1000
+		}
+		return time.Unix(0, int64(math.Round(number*1e6))*int64(time.Microsecond)), true
+	}
+	t, err := time.Parse(layout, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
@@ -0,0 +1,95 @@
+package sumologic
+
+import (
+	"time"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+// mkTimedMessage returns a message logspout received at noon on 2018-01-02.
+func mkTimedMessage(data string) *router.Message {
+	msg := mkContainerMessage("abc", "/foo")
+	msg.Data = data
+	msg.Time = time.Date(2018, 1, 2, 12, 0, 0, 0, time.UTC)
+	return msg
+}
+
+func (ts *TestSuite) Test_parseTimestamp() {
+	t, ok := parseTimestamp("2018-01-02T03:04:05.678Z", time.RFC3339)
+	ts.True(ok)
+	ts.Equal(time.Date(2018, 1, 2, 3, 4, 5, 678000000, time.UTC), t.UTC())
+
+	t, ok = parseTimestamp("1514862245", time.RFC3339)
+	ts.True(ok)
+	ts.Equal("1514862245000", formatTimestamp(t))
+
+	t, ok = parseTimestamp("1514862245678", time.RFC3339)
+	ts.True(ok)
+	ts.Equal("1514862245678", formatTimestamp(t))
+
+	t, ok = parseTimestamp("1514862245.5", time.RFC3339)
+	ts.True(ok)
+	ts.Equal("1514862245500", formatTimestamp(t))
+
+	_, ok = parseTimestamp("yesterday", time.RFC3339)
+	ts.False(ok)
+	_, ok = parseTimestamp("", time.RFC3339)
+	ts.False(ok)
+}
+
+func (ts *TestSuite) Test_messageTime_unset() {
+	msg := mkTimedMessage(`{"time": "2018-01-02T03:04:05Z"}`)
+	ts.Equal(msg.Time, messageTime(msg, buildConfig(&router.Route{})))
+}
+
+func (ts *TestSuite) Test_messageTime_field() {
+	ts.Setenv("SUMOLOGIC_TIMESTAMP_FIELD", "time")
+	config := buildConfig(&router.Route{})
+	msg := mkTimedMessage(`{"time": "2018-01-02T03:04:05Z", "msg": "hi"}`)
+	ts.Equal(time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC),
+		messageTime(msg, config).UTC())
+
+	msg = mkTimedMessage(`{"time": 1514862245}`)
+	ts.Equal("1514862245000", formatTimestamp(messageTime(msg, config)))
+
+	// Logs without a time that can be parsed keep the time they were
+	// received.
+	for _, data := range []string{
+		`{"time": "soon"}`, `{"msg": "hi"}`, "not json"} {
+		msg = mkTimedMessage(data)
+		ts.Equal(msg.Time, messageTime(msg, config))
+	}
+}
+
+func (ts *TestSuite) Test_messageTime_pattern() {
+	ts.Setenv("SUMOLOGIC_TIMESTAMP_PATTERN", `^\[(?P<timestamp>[^\]]+)\]`)
+	ts.Setenv("SUMOLOGIC_TIMESTAMP_FORMAT", "2006-01-02 15:04:05.000")
+	config := buildConfig(&router.Route{})
+	msg := mkTimedMessage("[2018-01-02 03:04:05.678] INFO hi")
+	ts.Equal(time.Date(2018, 1, 2, 3, 4, 5, 678000000, time.UTC),
+		messageTime(msg, config))
+
+	msg = mkTimedMessage("INFO hi")
+	ts.Equal(msg.Time, messageTime(msg, config))
+	msg = mkTimedMessage("[later] INFO hi")
+	ts.Equal(msg.Time, messageTime(msg, config))
+}
+
+func (ts *TestSuite) Test_messageTime_field_before_pattern() {
+	ts.Setenv("SUMOLOGIC_TIMESTAMP_FIELD", "time")
+	ts.Setenv("SUMOLOGIC_TIMESTAMP_PATTERN", `\d{10}`)
+	config := buildConfig(&router.Route{})
+	msg := mkTimedMessage(`{"time": 1514862245, "other": 1514000000}`)
+	ts.Equal("1514862245000", formatTimestamp(messageTime(msg, config)))
+	msg = mkTimedMessage(`{"other": 1514000000}`)
+	ts.Equal("1514000000000", formatTimestamp(messageTime(msg, config)))
+}
+
+func (ts *TestSuite) Test_buildData_extracted_timestamp() {
+	ts.Setenv("SUMOLOGIC_TIMESTAMP_FIELD", "ts")
+	msg := mkTimedMessage(`{"ts": 1514862245678}`)
+	data := buildData(msg, buildConfig(&router.Route{}))
+	ts.Equal("1514862245678", data.Timestamp)
+	// The container block keeps the time the log was received.
+	ts.Equal("2018-01-02T12:00:00Z", data.Container.Time)
+}
@@ -0,0 +1,87 @@
+package sumologic
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// tlsOptions are the settings for connecting to endpoints over TLS, for
+// private forwarders and TLS-intercepting proxies whose certificates aren't
+// publicly trusted, or that want a client certificate.
+type tlsOptions struct {
+	caFile     string
+	certFile   string
+	keyFile    string
+	skipVerify bool
+}
+
+// load builds the TLS config for the options, or returns nil if they're all
+// the defaults.
+func (o tlsOptions) load() (*tls.Config, error) {
+	if o == (tlsOptions{}) {
+		return nil, nil
+	}
+	config := &tls.Config{InsecureSkipVerify: o.skipVerify}
+	if o.caFile != "" {
+		pem, err := ioutil.ReadFile(o.caFile)
+		if err != nil {
+			return nil, err
+		}
+		// The CA is trusted as well as the system's, so that the public
+		// endpoint still works alongside a private one.
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", o.caFile)
+		}
+		config.RootCAs = pool
+	}
+	if (o.certFile == "") != (o.keyFile == "") {
+		return nil, fmt.Errorf(
+			"SUMOLOGIC_TLS_CERT_FILE and SUMOLOGIC_TLS_KEY_FILE must be set together")
+	}
+	if o.certFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.certFile, o.keyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// clientConfig is the TLS config for the options, as load returns it. If it
+// can't be loaded, the error is logged and the defaults are used, so that the
+// failure shows up in every send rather than stopping the adapter.
+func (o tlsOptions) clientConfig() *tls.Config {
+	config, err := o.load()
+	if err != nil {
+		log.WithError(err).Error("Unable to load TLS settings, using the defaults")
+		return nil
+	}
+	if o.skipVerify {
+		log.Warn("SUMOLOGIC_TLS_SKIP_VERIFY is set, endpoint certificates won't be verified")
+	}
+	return config
+}
+
+// newTransport returns a transport like http.DefaultTransport, with the TLS,
+// proxy and connection pool settings from a config.
+func newTransport(config *Config) *http.Transport {
+	transport := &http.Transport{
+		Proxy:                 config.proxy(),
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		TLSClientConfig:       config.tls.clientConfig(),
+	}
+	config.pool.apply(transport)
+	return transport
+}
@@ -0,0 +1,166 @@
+package sumologic
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+// tlsDir returns a temporary directory for certificates and keys.
+func (ts *TestSuite) tlsDir() string {
+	dir := ts.WithoutError(ioutil.TempDir("", "tls")).(string)
+	ts.AddCleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+// writePEM writes a PEM block to a file in dir and returns its path.
+func (ts *TestSuite) writePEM(dir, name, kind string, der []byte) string {
+	path := filepath.Join(dir, name)
+	ts.Require().NoError(ioutil.WriteFile(
+		path, pem.EncodeToMemory(&pem.Block{Type: kind, Bytes: der}), 0600))
+	return path
+}
+
+// mkClientCert writes a self-signed client certificate and its key to dir,
+// and returns their paths.
+func (ts *TestSuite) mkClientCert(dir string) (string, string) {
+	key := ts.WithoutError(
+		ecdsa.GenerateKey(elliptic.P256(), rand.Reader)).(*ecdsa.PrivateKey)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "logspout"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	cert := ts.WithoutError(x509.CreateCertificate(
+		rand.Reader, template, template, &key.PublicKey, key)).([]byte)
+	keyDER := ts.WithoutError(x509.MarshalECPrivateKey(key)).([]byte)
+	return ts.writePEM(dir, "client.crt", "CERTIFICATE", cert),
+		ts.writePEM(dir, "client.key", "EC PRIVATE KEY", keyDER)
+}
+
+// FakeTLSSumo starts a fake Sumo Logic server over TLS with a certificate
+// that isn't publicly trusted, and returns it along with the path of a CA
+// file that trusts it. The number of client certificates each request came
+// with is sent to peerCerts.
+func (ts *TestSuite) FakeTLSSumo(
+	clientAuth tls.ClientAuthType, peerCerts chan int) (*httptest.Server, string) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			peerCerts <- len(r.TLS.PeerCertificates)
+		}))
+	server.TLS = &tls.Config{ClientAuth: clientAuth}
+	server.StartTLS()
+	ts.AddCleanup(server.Close)
+	ca := ts.writePEM(ts.tlsDir(), "ca.crt", "CERTIFICATE", server.Certificate().Raw)
+	return server, ca
+}
+
+func (ts *TestSuite) Test_tls_defaults() {
+	adapter := ts.mkAdapter(&router.Route{Address: noServer})
+	ts.Equal(tlsOptions{}, adapter.config().tls)
+	ts.Nil(ts.WithoutError(adapter.config().tls.load()))
+}
+
+func (ts *TestSuite) Test_tls_untrusted_server_fails() {
+	ts.CaptureLogs()
+	ts.Setenv("SUMOLOGIC_RETRIES", "0")
+	server, _ := ts.FakeTLSSumo(tls.NoClientCert, make(chan int, 1))
+	adapter := ts.mkAdapter(&router.Route{Address: server.URL})
+	ts.Error(adapter.Send(mkLine("abc", "Some data.")))
+}
+
+func (ts *TestSuite) Test_tls_ca_file() {
+	peerCerts := make(chan int, 1)
+	server, ca := ts.FakeTLSSumo(tls.NoClientCert, peerCerts)
+	ts.Setenv("SUMOLOGIC_TLS_CA_FILE", ca)
+	adapter := ts.mkAdapter(&router.Route{Address: server.URL})
+	ts.NoError(adapter.Send(mkLine("abc", "Some data.")))
+	ts.Equal(0, <-peerCerts)
+}
+
+func (ts *TestSuite) Test_tls_skip_verify() {
+	hook, _ := ts.CaptureLogs()
+	peerCerts := make(chan int, 1)
+	server, _ := ts.FakeTLSSumo(tls.NoClientCert, peerCerts)
+	ts.Setenv("SUMOLOGIC_TLS_SKIP_VERIFY", "true")
+	adapter := ts.mkAdapter(&router.Route{Address: server.URL})
+	ts.Equal("SUMOLOGIC_TLS_SKIP_VERIFY is set, endpoint certificates won't be verified",
+		hook.LastEntry().Message)
+	ts.NoError(adapter.Send(mkLine("abc", "Some data.")))
+	ts.Equal(0, <-peerCerts)
+}
+
+func (ts *TestSuite) Test_tls_client_certificate() {
+	peerCerts := make(chan int, 1)
+	server, ca := ts.FakeTLSSumo(tls.RequireAnyClientCert, peerCerts)
+	cert, key := ts.mkClientCert(ts.tlsDir())
+	ts.Setenv("SUMOLOGIC_TLS_CA_FILE", ca)
+	ts.Setenv("SUMOLOGIC_TLS_CERT_FILE", cert)
+	ts.Setenv("SUMOLOGIC_TLS_KEY_FILE", key)
+	adapter := ts.mkAdapter(&router.Route{Address: server.URL})
+	ts.NoError(adapter.Send(mkLine("abc", "Some data.")))
+	ts.Equal(1, <-peerCerts)
+}
+
+func (ts *TestSuite) Test_tls_client_certificate_with_dedicated_connections() {
+	peerCerts := make(chan int, 1)
+	server, ca := ts.FakeTLSSumo(tls.RequireAnyClientCert, peerCerts)
+	cert, key := ts.mkClientCert(ts.tlsDir())
+	ts.Setenv("SUMOLOGIC_DEDICATED_CONNECTIONS", "true")
+	ts.Setenv("SUMOLOGIC_TLS_CA_FILE", ca)
+	ts.Setenv("SUMOLOGIC_TLS_CERT_FILE", cert)
+	ts.Setenv("SUMOLOGIC_TLS_KEY_FILE", key)
+	adapter := ts.mkAdapter(&router.Route{Address: server.URL})
+	ts.NoError(adapter.Send(mkLine("abc", "Some data.")))
+	ts.Equal(1, <-peerCerts)
+}
+
+func (ts *TestSuite) Test_tls_load_errors() {
+	dir := ts.tlsDir()
+	cert, key := ts.mkClientCert(dir)
+	empty := filepath.Join(dir, "empty.crt")
+	ts.Require().NoError(ioutil.WriteFile(empty, []byte("nothing here"), 0600))
+
+	_, err := tlsOptions{caFile: filepath.Join(dir, "missing.crt")}.load()
+	ts.Error(err)
+	_, err = tlsOptions{caFile: empty}.load()
+	ts.EqualError(err, "no certificates found in "+empty)
+	_, err = tlsOptions{certFile: cert}.load()
+	ts.EqualError(err,
+		"SUMOLOGIC_TLS_CERT_FILE and SUMOLOGIC_TLS_KEY_FILE must be set together")
+	_, err = tlsOptions{certFile: key, keyFile: cert}.load()
+	ts.Error(err)
+	config := ts.WithoutError(
+		tlsOptions{certFile: cert, keyFile: key}.load()).(*tls.Config)
+	ts.Len(config.Certificates, 1)
+}
+
+func (ts *TestSuite) Test_tls_bad_settings_fall_back_to_defaults() {
+	hook, _ := ts.CaptureLogs()
+	ts.Nil(tlsOptions{keyFile: "key.pem"}.clientConfig())
+	ts.Equal("Unable to load TLS settings, using the defaults",
+		hook.LastEntry().Message)
+}
+
+func (ts *TestSuite) Test_checkConfig_reports_tls_problems() {
+	ts.Setenv("SUMOLOGIC_ENDPOINT", "https://collectors.example.com/a")
+	ts.Setenv("SUMOLOGIC_TLS_KEY_FILE", "key.pem")
+	ts.Equal([]string{"SUMOLOGIC_TLS: SUMOLOGIC_TLS_CERT_FILE and " +
+		"SUMOLOGIC_TLS_KEY_FILE must be set together"},
+		validateConfig(&router.Route{}))
+}
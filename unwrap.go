@@ -0,0 +1,60 @@
+package sumologic
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+// dockerRecord is a log record as written by docker's json-file logging
+// driver.
+type dockerRecord struct {
+	Log    *string         `json:"log"`
+	Stream string          `json:"stream"`
+	Time   string          `json:"time"`
+	Attrs  json.RawMessage `json:"attrs"`
+}
+
+// dockerRecordKeys are the only keys a docker json-file record has.
+var dockerRecordKeys = map[string]bool{
+	"log": true, "stream": true, "time": true, "attrs": true,
+}
+
+// unwrapDockerJSON returns the message a docker json-file record holds, if
+// the message is one, taking its stream and time from the record. Some
+// setups hand logspout these records rather than the lines in them, which
+// would otherwise arrive in Sumo double-wrapped. Any other message is
+// returned unchanged.
+func unwrapDockerJSON(msg *router.Message) *router.Message {
+	data := strings.TrimSpace(msg.Data)
+	if !strings.HasPrefix(data, "{") || !strings.Contains(data, `"log"`) {
+		return msg
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(data), &fields); err != nil {
+		return msg
+	}
+	for key := range fields {
+		if !dockerRecordKeys[key] {
+			return msg
+		}
+	}
+	var record dockerRecord
+	if err := json.Unmarshal([]byte(data), &record); err != nil {
+		return msg
+	}
+	if record.Log == nil ||
+		(record.Stream != "stdout" && record.Stream != "stderr") {
+		return msg
+	}
+
+	unwrapped := *msg
+	unwrapped.Data = strings.TrimSuffix(*record.Log, "\n")
+	unwrapped.Source = record.Stream
+	if t, err := time.Parse(time.RFC3339Nano, record.Time); err == nil {
+		unwrapped.Time = t
+	}
+	return &unwrapped
+}
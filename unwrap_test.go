@@ -0,0 +1,77 @@
+package sumologic
+
+import (
+	"time"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+func (ts *TestSuite) Test_unwrapDockerJSON() {
+	msg := mkContainerMessage("abc", "/foo")
+	msg.Source = "stdout"
+	msg.Time = mkTime(0)
+	msg.Data = `{"log":"Some data.\n","stream":"stderr",` +
+		`"time":"2018-01-02T13:00:05.5Z"}`
+
+	unwrapped := unwrapDockerJSON(msg)
+	ts.Equal("Some data.", unwrapped.Data)
+	ts.Equal("stderr", unwrapped.Source)
+	ts.Equal(mkTime(5).Add(500*time.Millisecond), unwrapped.Time.UTC())
+	ts.True(msg.Container == unwrapped.Container)
+	ts.Equal("stdout", msg.Source, "the original message is unchanged")
+}
+
+func (ts *TestSuite) Test_unwrapDockerJSON_keeps_time_if_missing() {
+	msg := &router.Message{
+		Data: `{"log":"Some data.","stream":"stdout"}`, Time: mkTime(0)}
+	unwrapped := unwrapDockerJSON(msg)
+	ts.Equal("Some data.", unwrapped.Data)
+	ts.Equal(mkTime(0), unwrapped.Time)
+}
+
+func (ts *TestSuite) Test_unwrapDockerJSON_ignores_other_messages() {
+	for _, data := range []string{
+		"Some data.",
+		`{"log":"Some data."}`,
+		`{"log":"Some data.","stream":"elsewhere"}`,
+		`{"log":42,"stream":"stdout"}`,
+		`{"log":"Some data.","stream":"stdout","level":"info"}`,
+		`{"log":"Some data.","stream":"stdout"`,
+		`{"message":"Some data."}`,
+	} {
+		msg := &router.Message{Data: data}
+		ts.True(msg == unwrapDockerJSON(msg), data)
+	}
+}
+
+func (ts *TestSuite) Test_Stream_unwraps_docker_json() {
+	requests := make(chan *RequestData, 1)
+	adapter := ts.FakeSumo(requests)
+
+	ch := make(chan *router.Message)
+	go adapter.Stream(ch)
+	msg := mkContainerMessage("abc", "/foo")
+	msg.Data = `{"log":"Some data.\n","stream":"stderr"}`
+	ch <- msg
+	close(ch)
+
+	request := <-requests
+	ts.Equal("Some data.", request.Body["message"])
+	ts.Equal("stderr", request.Body["container"].(jsonobj)["source"])
+}
+
+func (ts *TestSuite) Test_Stream_unwrap_docker_json_disabled() {
+	ts.Setenv("SUMOLOGIC_UNWRAP_DOCKER_JSON", "false")
+	requests := make(chan *RequestData, 1)
+	adapter := ts.FakeSumo(requests)
+
+	ch := make(chan *router.Message)
+	go adapter.Stream(ch)
+	msg := mkContainerMessage("abc", "/foo")
+	msg.Data = `{"log":"Some data.\n","stream":"stderr"}`
+	ch <- msg
+	close(ch)
+
+	request := <-requests
+	ts.Equal(msg.Data, request.Body["message"])
+}
@@ -0,0 +1,135 @@
+package sumologic
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/gliderlabs/logspout/router"
+	log "github.com/sirupsen/logrus"
+)
+
+// configProblems collects the options that couldn't be parsed while a config
+// is being validated. It's nil the rest of the time, when those options are
+// only logged.
+var configProblems *[]string
+
+// parseFailed reports an option that couldn't be parsed.
+func parseFailed(name string, value string, err error) {
+	entry := log.WithField(name, value)
+	if err != nil {
+		entry = entry.WithError(err)
+	}
+	entry.Error("Failed to parse")
+	if configProblems == nil {
+		return
+	}
+	problem := fmt.Sprintf("%s: can't parse %q", name, value)
+	if err != nil {
+		problem += ": " + err.Error()
+	}
+	*configProblems = append(*configProblems, problem)
+}
+
+// validateConfig parses the config for a route the same way NewAdapter does,
+// and returns every problem with it.
+func validateConfig(route *router.Route) []string {
+	problems := []string{}
+	configProblems = &problems
+	defer func() { configProblems = nil }()
+
+	config := buildConfig(route)
+	return append(problems, checkConfig(config)...)
+}
+
+// checkConfig returns the problems with a config that would make every
+// message fail: endpoints that aren't usable URLs, TLS settings that can't be
+// loaded and templates that can't be parsed.
+func checkConfig(config *Config) []string {
+	problems := []string{}
+	if !config.archive.only {
+		endPoints := config.endPoints
+		if len(endPoints) == 0 {
+			endPoints = []string{""}
+		}
+		for _, endPoint := range endPoints {
+			if err := checkEndPoint(endPoint); err != nil {
+				problems = append(problems, "SUMOLOGIC_ENDPOINT: "+err.Error())
+			}
+		}
+	}
+	for _, endPoint := range config.containerEndPoints {
+		if err := checkEndPoint(endPoint); err != nil {
+			problems = append(problems, "SUMOLOGIC_CONTAINER_ENDPOINTS: "+err.Error())
+		}
+	}
+	if len(config.metricRules) > 0 {
+		if err := checkEndPoint(config.metricsEndPoint); err != nil {
+			problems = append(problems, "SUMOLOGIC_METRICS_ENDPOINT: "+err.Error())
+		}
+	}
+	if config.archive.bucket != "" {
+		if err := checkEndPoint(config.archive.endPoint); err != nil {
+			problems = append(problems, "SUMOLOGIC_ARCHIVE_ENDPOINT: "+err.Error())
+		}
+	}
+	if _, err := config.tls.load(); err != nil {
+		problems = append(problems, "SUMOLOGIC_TLS: "+err.Error())
+	}
+	if err := checkScript(config.script); err != nil {
+		problems = append(problems, "SUMOLOGIC_SCRIPT: "+err.Error())
+	}
+	for _, option := range []struct{ name, text string }{
+		{"SUMOLOGIC_SOURCE_NAME", config.sourceName},
+		{"SUMOLOGIC_SOURCE_CATEGORY", config.sourceCategory},
+		{"SUMOLOGIC_SOURCE_HOST", config.sourceHost},
+		{"SUMOLOGIC_STDERR_SOURCE_CATEGORY", config.stderrSourceCategory},
+		{"SUMOLOGIC_BODY_TEMPLATE", config.bodyTemplate},
+		{"SUMOLOGIC_ENDPOINT_TEMPLATE", config.endPointTemplate},
+		{"SUMOLOGIC_METRICS_DIMENSIONS", config.metricsDimensions},
+		{"SUMOLOGIC_METRICS_METADATA", config.metricsMetadata},
+	} {
+		if _, err := templates.get(option.text); err != nil {
+			problems = append(problems, option.name+": "+err.Error())
+		}
+	}
+	return problems
+}
+
+// checkEndPoint returns an error if an endpoint isn't an absolute http or
+// https URL.
+func checkEndPoint(endPoint string) error {
+	if endPoint == "" {
+		return fmt.Errorf("not set")
+	}
+	u, err := url.Parse(endPoint)
+	if err != nil {
+		return maskError(err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf(
+			"%q is not an http or https URL", maskToken(endPoint))
+	}
+	return nil
+}
+
+// ValidateConfig validates the config from the environment and writes a
+// report of the modules compiled in and any problems to w. It returns the
+// exit status for validate-only mode, which is 1 if there are problems. It's
+// for a logspout build's main package to call when SUMOLOGIC_VALIDATE_CONFIG
+// is set, before logspout starts, as docker/modules.go does.
+func ValidateConfig(w io.Writer) int {
+	problems := validateConfig(&router.Route{})
+	fmt.Fprintf(w, "logspout-sumologic: modules: %s\n",
+		strings.Join(compiledModules(), ", "))
+	if len(problems) == 0 {
+		fmt.Fprintln(w, "logspout-sumologic: config OK")
+		return 0
+	}
+	fmt.Fprintf(w, "logspout-sumologic: %d config problem(s):\n", len(problems))
+	for _, problem := range problems {
+		fmt.Fprintln(w, "  "+problem)
+	}
+	return 1
+}
@@ -0,0 +1,65 @@
+package sumologic
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+func (ts *TestSuite) Test_validateConfig_ok() {
+	ts.Equal([]string{}, validateConfig(
+		&router.Route{Address: "https://collectors.example.com/receiver"}))
+}
+
+func (ts *TestSuite) Test_validateConfig_reports_every_problem() {
+	ts.CaptureLogs()
+	ts.Setenv("SUMOLOGIC_ENDPOINT", "collectors.example.com")
+	ts.Setenv("SUMOLOGIC_SOURCE_CATEGORY", "{{.Container.Name")
+	ts.Setenv("SUMOLOGIC_RETRIES", "lots")
+	ts.Setenv("SUMOLOGIC_SUMMARY_ERROR_PATTERN", "(")
+	ts.Setenv("SUMOLOGIC_METRIC_RULES", "oops")
+
+	problems := validateConfig(&router.Route{})
+	ts.Len(problems, 5)
+	ts.Contains(problems[0], "SUMOLOGIC_RETRIES")
+	ts.Contains(problems[1], "SUMOLOGIC_SUMMARY_ERROR_PATTERN")
+	ts.Contains(problems[2], "SUMOLOGIC_METRIC_RULES")
+	ts.Equal(`SUMOLOGIC_ENDPOINT: "collectors.example.com" is not an http or https URL`,
+		problems[3])
+	ts.Contains(problems[4], "SUMOLOGIC_SOURCE_CATEGORY")
+	ts.Nil(configProblems)
+}
+
+func (ts *TestSuite) Test_ValidateConfig() {
+	var out bytes.Buffer
+	ts.Setenv("SUMOLOGIC_ENDPOINT", "https://collectors.example.com/receiver")
+	modules := "logspout-sumologic: modules: " +
+		strings.Join(compiledModules(), ", ") + "\n"
+	ts.Equal(0, ValidateConfig(&out))
+	ts.Equal(modules+"logspout-sumologic: config OK\n", out.String())
+
+	out.Reset()
+	ts.CaptureLogs()
+	ts.Setenv("SUMOLOGIC_ENDPOINT", "")
+	ts.Equal(1, ValidateConfig(&out))
+	ts.Equal(modules+"logspout-sumologic: 1 config problem(s):\n"+
+		"  SUMOLOGIC_ENDPOINT: not set\n", out.String())
+}
+
+func (ts *TestSuite) Test_NewAdapter_rejects_bad_config() {
+	_, err := NewAdapter(&router.Route{})
+	ts.EqualError(err, "invalid sumologic config: SUMOLOGIC_ENDPOINT: not set")
+
+	ts.Setenv("SUMOLOGIC_SOURCE_NAME", "{{.Container.Name")
+	_, err = NewAdapter(&router.Route{Address: "https://collectors.example.com"})
+	ts.Error(err)
+	ts.Contains(err.Error(), "SUMOLOGIC_SOURCE_NAME")
+	ts.Empty(adapters.all())
+}
+
+func (ts *TestSuite) Test_NewAdapter_archive_only_needs_no_endpoint() {
+	ts.Setenv("SUMOLOGIC_ARCHIVE_BUCKET", "logs")
+	ts.Setenv("SUMOLOGIC_ARCHIVE_ONLY", "true")
+	ts.mkAdapter(&router.Route{})
+}
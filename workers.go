@@ -0,0 +1,135 @@
+package sumologic
+
+import (
+	"github.com/gliderlabs/logspout/router"
+)
+
+// Overflow policies for when the send queue is full.
+const (
+	// overflowBlock makes Stream wait for room in the queue, which holds up
+	// logspout's pump for the route rather than losing anything.
+	overflowBlock = "block"
+	// overflowDrop drops the message, counting it as dropped.
+	overflowDrop = "drop"
+)
+
+// getoverflowopt retrieves the overflow policy for the send queue.
+func (o routeOptions) getoverflowopt(name string) string {
+	value := o.getopt(name, overflowBlock)
+	if value != overflowBlock && value != overflowDrop {
+		parseFailed(name, value, nil)
+		return overflowBlock
+	}
+	return value
+}
+
+// newQueue returns a send queue holding up to size messages. A queue of size
+// 0 hands each message straight to a worker.
+func newQueue(size int64) chan *router.Message {
+	if size < 0 {
+		size = 0
+	}
+	return make(chan *router.Message, size)
+}
+
+// startWorkers starts the given number of workers sending messages from a
+// queue, until the queue is closed and empty or the adapter is closed.
+func (s *Adapter) startWorkers(queue chan *router.Message, workers int64) {
+	if workers <= 0 {
+		workers = 1
+	}
+	s.workers.Add(int(workers))
+	for i := int64(0); i < workers; i++ {
+		go s.work(queue)
+	}
+}
+
+func (s *Adapter) work(queue chan *router.Message) {
+	defer s.workers.Done()
+	for {
+		select {
+		case msg, ok := <-queue:
+			if !ok {
+				return
+			}
+			s.sendLog(msg)
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// queued returns how many messages are waiting in the adapter's queues.
+func (s *Adapter) queued() int64 {
+	return int64(len(s.queue)) + s.lanes.queued() + s.ordered.queued()
+}
+
+// enqueue queues a message for the workers to send. If the queue is full,
+// the configured overflow policy decides whether to wait or to drop it.
+// Messages that arrive once the adapter is shutting down are dropped, and
+// those that arrive while it's on standby are held.
+func (s *Adapter) enqueue(msg *router.Message) {
+	if held, dropped := s.standby.hold(msg); held {
+		if dropped != nil {
+			s.drop(dropped, "standby buffer full")
+		}
+		return
+	}
+	s.push(msg)
+}
+
+// push adds a message to the queue.
+func (s *Adapter) push(msg *router.Message) {
+	// The queue is only closed once nothing holds the read lock.
+	s.queueMu.RLock()
+	defer s.queueMu.RUnlock()
+	select {
+	case <-s.stopping:
+		s.drop(msg, "shutting down")
+		return
+	default:
+	}
+	keep, fixedRate, skipped := s.fixedSampler.sample(msg, s.config())
+	if !keep {
+		s.drop(msg, "sampled")
+		return
+	}
+	if fixedRate > 1 {
+		s.annotations.record(msg, func(p *Processing) {
+			p.Sampled = true
+			p.SampleRate = fixedRate
+			p.Skipped = skipped
+		})
+	}
+	queue := s.queueFor(msg)
+	keep, rate := s.sampler.sample(msg, queue)
+	if !keep {
+		s.drop(msg, "sampled")
+		return
+	}
+	if rate > 1 {
+		s.annotate(msg, s.config(), func(p *Processing) {
+			p.Sampled = true
+			if p.SampleRate == 0 {
+				p.SampleRate = 1
+			}
+			p.SampleRate *= rate
+		})
+	}
+	select {
+	case queue <- msg:
+		return
+	default:
+	}
+	if s.config().overflow == overflowDrop {
+		s.drop(msg, "queue full")
+		return
+	}
+	select {
+	case queue <- msg:
+	case <-s.stopping:
+		s.drop(msg, "shutting down")
+	case <-s.ctx.Done():
+		s.drop(msg, "shutting down")
+	}
+}
@@ -0,0 +1,120 @@
+package sumologic
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+// FakeSlowSumo starts a fake Sumo Logic server that doesn't respond to a
+// request until it's released, and returns an Adapter pointing at it along
+// with a channel that receives each request's message as it arrives.
+func (ts *TestSuite) FakeSlowSumo(
+	release chan struct{}) (*Adapter, chan string) {
+	arrived := make(chan string, 10)
+	return ts.mkSlowAdapter(release, arrived, newRealClock()), arrived
+}
+
+func (ts *TestSuite) mkSlowAdapter(
+	release chan struct{}, arrived chan string, clock Clock) *Adapter {
+	adapter := ts.adapterFor(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			arrived <- ts.ReadJSON(r.Body)["message"].(string)
+			<-release
+		}), clock)
+	// The server can't close until the requests it's holding are released.
+	ts.AddCleanup(func() { close(release) })
+	return adapter
+}
+
+func (ts *TestSuite) Test_workers_bound_concurrent_sends() {
+	ts.Setenv("SUMOLOGIC_WORKERS", "2")
+	release := make(chan struct{})
+	adapter, arrived := ts.FakeSlowSumo(release)
+
+	ch := make(chan *router.Message)
+	go adapter.Stream(ch)
+	ts.AddCleanup(func() { close(ch) })
+	for _, data := range []string{"one", "two", "three"} {
+		msg := mkContainerMessage("abc", "/foo")
+		msg.Data = data
+		ch <- msg
+	}
+
+	<-arrived
+	<-arrived
+	ts.EqualValues(2, atomic.LoadInt64(&adapter.status.pending))
+	ts.Eventually(func() bool { return len(adapter.queue) == 1 },
+		time.Second, time.Millisecond)
+
+	release <- struct{}{}
+	ts.Equal("three", <-arrived)
+}
+
+func (ts *TestSuite) Test_workers_drop_on_overflow() {
+	ts.Setenv("SUMOLOGIC_WORKERS", "1")
+	ts.Setenv("SUMOLOGIC_QUEUE_SIZE", "1")
+	ts.Setenv("SUMOLOGIC_QUEUE_OVERFLOW", "drop")
+	release := make(chan struct{})
+	adapter, arrived := ts.FakeSlowSumo(release)
+
+	ch := make(chan *router.Message)
+	go adapter.Stream(ch)
+	ts.AddCleanup(func() { close(ch) })
+	ch <- mkContainerMessage("abc", "/foo")
+	<-arrived
+	ch <- mkContainerMessage("abc", "/foo")
+	ch <- mkContainerMessage("abc", "/foo")
+
+	ts.Eventually(func() bool { return adapter.Status().Dropped == 1 },
+		time.Second, time.Millisecond)
+	ts.Len(adapter.queue, 1)
+}
+
+func (ts *TestSuite) Test_push_drops_when_closed_while_blocked() {
+	adapter := ts.mkAdapter(&router.Route{Address: noServer})
+	// Nothing reads from this queue, so push blocks until the adapter is
+	// closed.
+	adapter.queue = make(chan *router.Message)
+	adapter.Close()
+
+	adapter.push(mkContainerMessage("abc", "/foo"))
+	ts.EqualValues(1, adapter.Status().Dropped)
+}
+
+func (ts *TestSuite) Test_workers_block_on_overflow() {
+	ts.Setenv("SUMOLOGIC_WORKERS", "1")
+	ts.Setenv("SUMOLOGIC_QUEUE_SIZE", "1")
+	release := make(chan struct{})
+	adapter, arrived := ts.FakeSlowSumo(release)
+
+	ch := make(chan *router.Message)
+	go adapter.Stream(ch)
+	ch <- mkContainerMessage("abc", "/foo")
+	<-arrived
+	ch <- mkContainerMessage("abc", "/foo")
+	ch <- mkContainerMessage("abc", "/foo")
+
+	select {
+	case ch <- mkContainerMessage("abc", "/foo"):
+		ts.Fail("Stream should be blocked on the full queue")
+	default:
+	}
+	ts.Equal(int64(0), adapter.Status().Dropped)
+	ts.EqualValues(1, adapter.Status().Queued)
+	release <- struct{}{}
+	<-arrived
+	close(ch)
+}
+
+func (ts *TestSuite) Test_getoverflowopt() {
+	hook, _ := ts.CaptureLogs()
+	ts.Equal(overflowBlock, envOptions.getoverflowopt("SUMOLOGIC_QUEUE_OVERFLOW"))
+	ts.Setenv("SUMOLOGIC_QUEUE_OVERFLOW", "drop")
+	ts.Equal(overflowDrop, envOptions.getoverflowopt("SUMOLOGIC_QUEUE_OVERFLOW"))
+	ts.Setenv("SUMOLOGIC_QUEUE_OVERFLOW", "explode")
+	ts.Equal(overflowBlock, envOptions.getoverflowopt("SUMOLOGIC_QUEUE_OVERFLOW"))
+	ts.Equal("Failed to parse", hook.LastEntry().Message)
+}